@@ -1,19 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"time"
-)
-
-var c chan int
-
-func handle(int) {}
-
-func main() {
-	select {
-	case m := <-c:
-		handle(m)
-	case <-time.After(time.Second / 10):
-		fmt.Println("timed out")
-	}
-}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+type T struct{}
+
+func (T) where() string {
+	return here(0)
+}
+
+func here(skip int) string {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	return fmt.Sprintf("%s %s:%d %v", runtime.FuncForPC(pc).Name(), filepath.Base(file), line, ok)
+}
+
+func callers() []string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var names []string
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, "main.") {
+			names = append(names, frame.Function)
+		}
+		if !more {
+			break
+		}
+	}
+	return names
+}
+
+func main() {
+	fmt.Println(here(0))
+	fmt.Println(T{}.where())
+	func() {
+		fmt.Println(here(0))
+		fmt.Println(callers())
+	}()
+
+	stack := string(debug.Stack())
+	fmt.Println(strings.HasPrefix(stack, "goroutine 1 [running]:\n"))
+	fmt.Println(strings.Contains(stack, "main.main("))
+}
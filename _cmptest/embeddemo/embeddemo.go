@@ -0,0 +1,46 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed hello.txt
+var hello string
+
+//go:embed hello.txt
+var helloBytes []byte
+
+//go:embed hello.txt static
+var files embed.FS
+
+var (
+	//go:embed all:static
+	allFiles embed.FS
+)
+
+func main() {
+	fmt.Printf("%q\n", hello)
+	helloBytes[0] = 'H'
+	fmt.Printf("%q\n", helloBytes)
+
+	err := fs.WalkDir(files, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Println(path, d.IsDir())
+		return nil
+	})
+	fmt.Println(err)
+
+	data, err := files.ReadFile("static/css/site.css")
+	fmt.Printf("%q %v\n", data, err)
+	_, err = files.ReadFile("static/.hidden")
+	fmt.Println(err)
+
+	entries, _ := allFiles.ReadDir("static")
+	for _, e := range entries {
+		fmt.Println(e.Name())
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+	dir, err := os.MkdirTemp("", "fileops")
+	check(err)
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "data.txt"))
+	check(err)
+	_, err = f.WriteString("hello, world\n")
+	check(err)
+	_, err = f.WriteAt([]byte("HELLO"), 0)
+	check(err)
+	check(f.Sync())
+
+	off, err := f.Seek(7, io.SeekStart)
+	fmt.Println(off, err)
+	buf := make([]byte, 5)
+	n, err := f.Read(buf)
+	fmt.Println(n, err, string(buf[:n]))
+
+	n, err = f.ReadAt(buf, 0)
+	fmt.Println(n, err, string(buf[:n]))
+	n, err = f.ReadAt(buf, 10)
+	fmt.Printf("%d %v %q\n", n, err, buf[:n])
+
+	check(f.Truncate(5))
+	fi, err := os.Stat(f.Name())
+	check(err)
+	fmt.Println(fi.Size())
+
+	off, err = f.Seek(0, io.SeekEnd)
+	fmt.Println(off, err)
+	nn, err := f.ReadFrom(strings.NewReader(" there"))
+	fmt.Println(nn, err)
+	check(f.Chmod(0600))
+	fi, err = os.Stat(f.Name())
+	check(err)
+	fmt.Println(fi.Mode())
+	check(f.Close())
+
+	data, err := os.ReadFile(f.Name())
+	fmt.Printf("%q %v\n", data, err)
+
+	check(os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	entries, err := os.ReadDir(dir)
+	check(err)
+	for _, e := range entries {
+		info, err := e.Info()
+		check(err)
+		fmt.Println(e, info.Name(), info.IsDir())
+	}
+
+	// Deadlines.
+	g, err := os.Open(filepath.Join(dir, "data.txt"))
+	check(err)
+	fmt.Println(g.SetReadDeadline(time.Now()) == os.ErrNoDeadline)
+	g.Close()
+
+	r, w, err := os.Pipe()
+	check(err)
+	check(r.SetReadDeadline(time.Now().Add(50 * time.Millisecond)))
+	start := time.Now()
+	_, err = r.Read(buf)
+	fmt.Println(errors.Is(err, os.ErrDeadlineExceeded), time.Since(start) >= 50*time.Millisecond)
+	var te interface{ Timeout() bool }
+	fmt.Println(errors.As(err, &te) && te.Timeout())
+
+	check(r.SetReadDeadline(time.Time{}))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ping"))
+	}()
+	n, err = r.Read(buf)
+	fmt.Println(n, err, string(buf[:n]))
+
+	// Close wakes up a Read waiting for the pipe.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		r.Close()
+	}()
+	_, err = r.Read(buf)
+	fmt.Println(errors.Is(err, os.ErrClosed))
+	w.Close()
+}
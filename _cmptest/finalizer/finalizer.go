@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type handle struct {
+	fd   int
+	data [64]byte
+}
+
+func (h *handle) Close() error {
+	return nil
+}
+
+var closed atomic.Int32
+
+func allocHandles(n int) {
+	for i := 0; i < n; i++ {
+		h := &handle{fd: i}
+		runtime.SetFinalizer(h, func(h *handle) {
+			closed.Add(1)
+		})
+	}
+}
+
+// guarded embeds the sync types at non-zero offsets, which must not be
+// mistaken for objects of their own by the runtime.
+type guarded struct {
+	n  int
+	mu sync.Mutex
+	rw sync.RWMutex
+	wg sync.WaitGroup
+}
+
+var released atomic.Int32
+
+func allocGuarded(n int) {
+	for i := 0; i < n; i++ {
+		g := &guarded{}
+		cond := sync.NewCond(&g.mu)
+		g.wg.Add(1)
+		go func() {
+			g.mu.Lock()
+			g.n++
+			g.mu.Unlock()
+			cond.Signal()
+			g.wg.Done()
+		}()
+		g.mu.Lock()
+		for g.n == 0 {
+			cond.Wait()
+		}
+		g.mu.Unlock()
+		g.rw.RLock()
+		g.rw.RUnlock()
+		g.wg.Wait()
+		runtime.SetFinalizer(g, func(g *guarded) {
+			released.Add(1)
+		})
+	}
+}
+
+func main() {
+	allocHandles(1000)
+	allocGuarded(100)
+	for i := 0; i < 100 && (closed.Load() == 0 || released.Load() == 0); i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	fmt.Println("finalized:", closed.Load() > 0)
+	fmt.Println("guarded finalized:", released.Load() > 0)
+
+	h := &handle{}
+	runtime.SetFinalizer(h, func(c io.Closer) { c.Close() })
+	runtime.SetFinalizer(h, nil)
+	runtime.SetFinalizer(h, func(h any) {})
+	runtime.KeepAlive(h)
+	fmt.Println("done")
+}
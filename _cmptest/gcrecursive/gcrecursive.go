@@ -0,0 +1,83 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// U holds a pointer to T, which holds a U: the runtime type of one of them
+// is built while the other isn't initialized yet.
+type U struct{ p *T }
+
+type T struct {
+	u U
+	n int
+}
+
+// E is like the Element of container/list.
+type E struct {
+	next, prev *E
+	list       *L
+	Value      int
+}
+
+type L struct {
+	root E
+	len  int
+}
+
+const n = 1000
+
+var sink [][]int
+
+func garbage() {
+	for i := 0; i < 100; i++ {
+		s := make([]int, 64)
+		for j := range s {
+			s[j] = -1
+		}
+		sink = append(sink, s)
+	}
+	sink = nil
+	runtime.GC()
+}
+
+func main() {
+	// the values are only referenced from memory allocated by reflect
+	ts := reflect.MakeSlice(reflect.TypeOf([]T(nil)), n, n).Interface().([]T)
+	for i := range ts {
+		ts[i].u.p = &T{n: i}
+	}
+	ls := make([]*L, n)
+	for i := range ls {
+		l := reflect.New(reflect.TypeOf(L{})).Interface().(*L)
+		l.root.next = &E{list: l, Value: i}
+		ls[i] = l
+	}
+	garbage()
+	garbage()
+
+	ok := true
+	for i := range ts {
+		ok = ok && ts[i].u.p.n == i
+	}
+	fmt.Println("[]T:", ok)
+	ok = true
+	for i, l := range ls {
+		ok = ok && l.root.next.Value == i && l.root.next.list == l
+	}
+	fmt.Println("*L:", ok)
+
+	l := list.New()
+	for i := 0; i < n; i++ {
+		l.PushBack(i)
+	}
+	garbage()
+	sum := 0
+	for e := l.Front(); e != nil; e = e.Next() {
+		sum += e.Value.(int)
+	}
+	fmt.Println("list:", l.Len(), sum)
+}
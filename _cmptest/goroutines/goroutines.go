@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+const n = 20000
+
+func main() {
+	// A chain of n goroutines, each passing on what it receives plus one.
+	first := make(chan int)
+	in := first
+	for i := 0; i < n; i++ {
+		out := make(chan int)
+		go func(in <-chan int, out chan<- int) {
+			out <- <-in + 1
+		}(in, out)
+		in = out
+	}
+	first <- 0
+	fmt.Println("chain:", <-in)
+
+	// Fan in from goroutines which yield while producing.
+	results := make(chan int, 16)
+	for i := 0; i < 100; i++ {
+		go func(i int) {
+			sum := 0
+			for j := 0; j < 10; j++ {
+				sum += i * j
+				runtime.Gosched()
+			}
+			results <- sum
+		}(i)
+	}
+	total := 0
+	for i := 0; i < 100; i++ {
+		total += <-results
+	}
+	fmt.Println("total:", total)
+
+	// Ping-pong with select.
+	ping, pong, done := make(chan int), make(chan int), make(chan bool)
+	go func() {
+		for v := range ping {
+			pong <- v * 2
+		}
+		close(done)
+	}()
+	sum := 0
+	for i := 0; i < 1000; i++ {
+		select {
+		case ping <- i:
+		}
+		sum += <-pong
+	}
+	close(ping)
+	<-done
+	fmt.Println("ping-pong:", sum)
+}
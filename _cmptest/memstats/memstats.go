@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+)
+
+var sink [][]byte
+
+func main() {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < 1000; i++ {
+		sink = append(sink, make([]byte, 4096))
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	fmt.Println("allocated:", after.TotalAlloc-before.TotalAlloc >= 1000*4096)
+	fmt.Println("heap:", after.HeapSys >= after.HeapAlloc, after.HeapAlloc >= 1000*4096)
+	fmt.Println("collected:", after.NumGC > before.NumGC, after.NumForcedGC-before.NumForcedGC)
+	fmt.Println("enabled:", after.EnableGC, after.LastGC > 0)
+
+	fmt.Println(debug.SetGCPercent(100))
+	fmt.Println(debug.SetGCPercent(50))
+	fmt.Println(debug.SetGCPercent(-1))
+	fmt.Println(debug.SetGCPercent(100))
+
+	fmt.Println(debug.SetMemoryLimit(-1) == math.MaxInt64)
+	fmt.Println(debug.SetMemoryLimit(1 << 40))
+	fmt.Println(debug.SetMemoryLimit(math.MaxInt64))
+
+	// the limit is soft: the heap grows past it rather than failing
+	debug.SetMemoryLimit(1 << 20)
+	for i := 0; i < 64; i++ {
+		sink = append(sink, make([]byte, 64<<10))
+	}
+	fmt.Println("soft limit:", len(sink))
+	debug.SetMemoryLimit(math.MaxInt64)
+	debug.FreeOSMemory()
+
+	var stats debug.GCStats
+	debug.ReadGCStats(&stats)
+	fmt.Println("gcstats:", stats.NumGC > 0)
+}
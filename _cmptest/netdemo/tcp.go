@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"time"
+)
+
+func main() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Println("Listen:", err)
+		return
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			fmt.Println("Accept:", err)
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("echo: " + line))
+		}
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		fmt.Println("Dial:", err)
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for _, msg := range []string{"hello", "world"} {
+		fmt.Fprintf(conn, "%s\n", msg)
+		line, err := r.ReadString('\n')
+		if err != nil {
+			fmt.Println("Read:", err)
+			return
+		}
+		fmt.Print(line)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err = r.ReadByte(); err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			fmt.Println("read timeout")
+		}
+	}
+}
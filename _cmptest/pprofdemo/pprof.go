@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+var sink [][]byte
+
+func alloc() {
+	for i := 0; i < 1000; i++ {
+		sink = append(sink, make([]byte, 1024))
+	}
+}
+
+//go:noinline
+func waitForever(started chan<- bool, block <-chan bool) {
+	started <- true
+	<-block
+}
+
+func checkProto(name string, buf *bytes.Buffer) {
+	zr, err := gzip.NewReader(buf)
+	if err != nil {
+		fmt.Println(name, "gzip:", err)
+		return
+	}
+	data, err := io.ReadAll(zr)
+	fmt.Println(name, "proto:", err == nil && len(data) > 0)
+}
+
+func main() {
+	runtime.MemProfileRate = 1
+
+	var cpu bytes.Buffer
+	if err := pprof.StartCPUProfile(&cpu); err != nil {
+		panic(err)
+	}
+	fmt.Println("second start:", pprof.StartCPUProfile(io.Discard))
+	n := 0
+	for i := 0; i < 10000000; i++ {
+		n += i % 7
+	}
+	pprof.StopCPUProfile()
+	fmt.Println("sum:", n)
+	checkProto("cpu", &cpu)
+
+	alloc()
+	fmt.Println("heap records:", pprof.Lookup("heap").Count() > 0)
+	fmt.Println("goroutines:", pprof.Lookup("goroutine").Count() > 0)
+	fmt.Println("unknown:", pprof.Lookup("unknown") == nil)
+
+	for _, name := range []string{"heap", "allocs", "goroutine", "threadcreate"} {
+		var buf bytes.Buffer
+		if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+			panic(err)
+		}
+		checkProto(name, &buf)
+	}
+
+	// the stack of a parked goroutine is unwound to where it waits
+	started, block := make(chan bool), make(chan bool)
+	go waitForever(started, block)
+	<-started
+	time.Sleep(10 * time.Millisecond)
+
+	var text bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&text, 1)
+	fmt.Println(bytes.HasPrefix(text.Bytes(), []byte("goroutine profile: total ")))
+	fmt.Println("parked:", bytes.Contains(text.Bytes(), []byte("main.waitForever+")))
+	close(block)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+func main() {
+	// channels
+	ct := reflect.ChanOf(reflect.BothDir, reflect.TypeOf(""))
+	fmt.Println(ct, reflect.ChanOf(reflect.RecvDir, reflect.TypeOf(0)))
+	rt := reflect.ChanOf(reflect.RecvDir, reflect.TypeOf(0))
+	fmt.Println(reflect.ChanOf(reflect.BothDir, rt), reflect.ChanOf(reflect.BothDir, rt) == reflect.TypeOf(make(chan (<-chan int))), rt)
+	ch := reflect.MakeChan(ct, 2)
+	ch.Send(reflect.ValueOf("hello"))
+	fmt.Println(ch.TrySend(reflect.ValueOf("world")), ch.TrySend(reflect.ValueOf("!")), ch.Len())
+	x, ok := ch.Recv()
+	fmt.Println(x, ok)
+
+	ch2 := reflect.ValueOf(make(chan int))
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch2},
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectDefault},
+	}
+	chosen, v, ok := reflect.Select(cases)
+	fmt.Println(chosen, v, ok)
+	chosen, _, ok = reflect.Select(cases)
+	fmt.Println(chosen, ok)
+
+	ch.Close()
+	x, ok = ch.TryRecv()
+	fmt.Printf("%q %v\n", x, ok)
+
+	// constructors
+	at := reflect.ArrayOf(3, reflect.TypeOf(byte(0)))
+	arr := reflect.New(at).Elem()
+	fmt.Println(at, reflect.Copy(arr, reflect.ValueOf("abcd")), arr.Interface())
+
+	st := reflect.StructOf([]reflect.StructField{
+		{Name: "Name", Type: reflect.TypeOf(""), Tag: `json:"name"`},
+		{Name: "Age", Type: reflect.TypeOf(int8(0))},
+		{Name: "Score", Type: reflect.TypeOf(0.0)},
+	})
+	sv := reflect.New(st).Elem()
+	sv.Field(0).SetString("llgo")
+	sv.Field(2).SetFloat(99.5)
+	fmt.Println(st.NumField(), st.Field(2).Offset == st.Size()-8, st.Field(0).Tag.Get("json"))
+	fmt.Printf("%+v\n", sv.Interface())
+
+	// slices
+	s := reflect.ValueOf([]int{1, 2})
+	s = reflect.AppendSlice(s, reflect.ValueOf([]int{3, 4, 5}))
+	fmt.Println(s.Interface())
+
+	words := []string{"c", "a", "b"}
+	swap := reflect.Swapper(words)
+	swap(0, 2)
+	fmt.Println(words)
+	type pair struct{ a, b int }
+	pairs := []pair{{1, 2}, {3, 4}, {0, 0}}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].a < pairs[j].a })
+	fmt.Println(pairs)
+}
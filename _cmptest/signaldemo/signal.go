@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, os.Interrupt)
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	fmt.Println("received:", <-c)
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+	fmt.Println("received:", <-c)
+	signal.Stop(c)
+
+	signal.Ignore(syscall.SIGUSR2)
+	fmt.Println("ignored:", signal.Ignored(syscall.SIGUSR2), signal.Ignored(syscall.SIGUSR1))
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	signal.Reset(syscall.SIGUSR2)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	select {
+	case <-ctx.Done():
+		fmt.Println("context:", ctx.Err())
+	case <-time.After(5 * time.Second):
+		fmt.Println("timeout")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// More goroutines than workers block in the sync package, so that they
+// only make progress if they don't block their workers.
+var n = 8 * runtime.GOMAXPROCS(0)
+
+func main() {
+	// Goroutines wait for a mutex held by main while it sleeps.
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	count := 0
+	mu.Lock()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				mu.Lock()
+				count++
+				mu.Unlock()
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	mu.Unlock()
+	wg.Wait()
+	fmt.Println("mutex:", count == n*100)
+
+	// Goroutines wait for a broadcast, then each signals the next.
+	cond := sync.NewCond(&mu)
+	ready, turn := false, 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			for !ready {
+				cond.Wait()
+			}
+			turn++
+			mu.Unlock()
+			cond.Signal()
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	ready = true
+	cond.Broadcast()
+	mu.Unlock()
+	wg.Wait()
+	fmt.Println("cond:", turn == n)
+
+	// Readers wait for a writer, and the writer for the readers.
+	var rw sync.RWMutex
+	shared := 0
+	rw.Lock()
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			rw.RLock()
+			_ = shared
+			time.Sleep(time.Millisecond)
+			rw.RUnlock()
+		}()
+		go func() {
+			defer wg.Done()
+			rw.Lock()
+			shared++
+			rw.Unlock()
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	rw.Unlock()
+	wg.Wait()
+	fmt.Println("rwmutex:", shared == n)
+
+	// Sleeping goroutines don't delay each other.
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(50 * time.Millisecond)
+		}()
+	}
+	wg.Wait()
+	fmt.Println("sleep:", time.Since(start) < time.Duration(n)*50*time.Millisecond)
+
+	var once sync.Once
+	for i := 0; i < 3; i++ {
+		once.Do(func() { fmt.Println("once") })
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var c chan int
+
+func handle(int) {}
+
+func main() {
+	select {
+	case m := <-c:
+		handle(m)
+	case <-time.After(time.Second / 10):
+		fmt.Println("timed out")
+	}
+
+	done := make(chan bool)
+	time.AfterFunc(time.Millisecond, func() {
+		done <- true
+	})
+	fmt.Println("AfterFunc:", <-done)
+
+	t := time.NewTimer(time.Hour)
+	fmt.Println("Stop:", t.Stop())
+	t.Reset(time.Millisecond)
+	<-t.C
+	fmt.Println("Reset: fired")
+
+	ticker := time.NewTicker(time.Millisecond)
+	for i := 0; i < 3; i++ {
+		<-ticker.C
+	}
+	ticker.Stop()
+	fmt.Println("Ticker: 3 ticks")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	fmt.Println("context:", ctx.Err())
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type event struct {
+	Name string    `json:"name"`
+	At   time.Time `json:"at"`
+}
+
+func main() {
+	t, err := time.Parse(time.RFC3339Nano, "2024-03-10T12:30:45.123456789+08:00")
+	fmt.Println(t.UTC(), err)
+
+	t, err = time.Parse("Jan _2 2006 3:04PM MST", "Feb  9 2023 7:15PM UTC")
+	fmt.Println(t, err)
+
+	_, err = time.Parse(time.DateOnly, "2024-02-30")
+	fmt.Println(err)
+
+	var e event
+	err = json.Unmarshal([]byte(`{"name":"launch","at":"2021-12-25T08:00:00Z"}`), &e)
+	fmt.Println(e.Name, e.At, err)
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, s := range []string{"2024-01-15 09:00", "2024-07-15 09:00"} {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		fmt.Println(t, t.Format("MST -0700"), t.Location())
+		fmt.Println(t.In(time.UTC).Format(time.RFC1123Z))
+	}
+}
@@ -23,6 +23,8 @@ import (
 	"go/ast"
 	"go/constant"
 	"go/types"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"unsafe"
@@ -156,6 +158,27 @@ func TestReplaceGoName(t *testing.T) {
 	}
 }
 
+func TestGoFuncName(t *testing.T) {
+	cases := []struct{ name, want string }{
+		{"main.main", "main.main"},
+		{"main.main$1", "main.main.func1"},
+		{"main.main$1$2", "main.main.func1.2"},
+		{"main.(*T).M$1", "main.(*T).M.func1"},
+		{"main.T.M$bound", "main.T.M-fm"},
+		{"main.T.M$thunk", "main.T.M"},
+		{"main.init#2", "main.init.1"},
+		{"main.init$hasPatch", "main.init"},
+		{"main.F[int,main.S[string]]", "main.F[...]"},
+		{"main.(*List[int]).Push", "main.(*List[...]).Push"},
+		{"main.a$b", "main.a$b"},
+	}
+	for _, c := range cases {
+		if got := goFuncName(c.name); got != c.want {
+			t.Errorf("goFuncName(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
 func TestIsAllocVargs(t *testing.T) {
 	if isAllocVargs(nil, ssaAlloc(&ssa.Return{})) {
 		t.Fatal("isVargs?")
@@ -460,3 +483,36 @@ func TestInstantiate(t *testing.T) {
 		t.Fatal("error")
 	}
 }
+
+func TestParseGoEmbed(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+		err  bool
+	}{
+		{" a.txt", []string{"a.txt"}, false},
+		{"  static/*  all:tmpl ", []string{"static/*", "all:tmpl"}, false},
+		{` "a b.txt" ` + "`c d`", []string{"a b.txt", "c d"}, false},
+		{` "a\tb"`, []string{"a\tb"}, false},
+		{` "a.txt`, nil, true},
+		{" `a.txt", nil, true},
+		{` "a"b`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseGoEmbed(tt.args)
+		if (err != nil) != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseGoEmbed(%q) = %q, %v", tt.args, got, err)
+		}
+	}
+}
+
+func TestEmbedFileLess(t *testing.T) {
+	list := []string{"b.txt", "a/", "a/z.txt", "a/b/", "a/b/c.txt", "a.txt"}
+	sort.Slice(list, func(i, j int) bool {
+		return embedFileLess(list[i], list[j])
+	})
+	want := []string{"a/", "a.txt", "b.txt", "a/b/", "a/z.txt", "a/b/c.txt"}
+	if !reflect.DeepEqual(list, want) {
+		t.Fatalf("got %q, want %q", list, want)
+	}
+}
@@ -52,6 +52,7 @@ var (
 	enableCallTracing bool
 	enableDbg         bool
 	enableDbgSyms     bool
+	enablePCLine      bool
 	disableInline     bool
 )
 
@@ -73,6 +74,12 @@ func EnableTrace(b bool) {
 	enableCallTracing = b
 }
 
+// EnablePCLine enables the PC-line tables used by the runtime to report Go
+// function names and lines, see llssa.Function.InitPCLine.
+func EnablePCLine(b bool) {
+	enablePCLine = b
+}
+
 // -----------------------------------------------------------------------------
 
 type instrOrValue interface {
@@ -111,6 +118,9 @@ type context struct {
 	bvals  map[ssa.Value]llssa.Expr    // block values
 	vargs  map[*ssa.Alloc][]llssa.Expr // varargs
 
+	escapes   map[*ssa.Function]*escapeSummary // escape analysis summaries
+	noEscapes map[ssa.Value]bool               // allocations of the function on its stack
+
 	patches  Patches
 	blkInfos []blocks.Info
 
@@ -251,7 +261,7 @@ func (p *context) compileFuncDecl(pkg llssa.Package, f *ssa.Function) (llssa.Fun
 		}
 	}
 	// set compiled to check generic function global instantiation
-	pkg.Prog.SetFuncCompiled(name)
+	pkg.SetFuncCompiled(name)
 	isCgo := isCgoExternSymbol(f)
 	if nblk := len(f.Blocks); nblk > 0 {
 		p.cgoCalled = false
@@ -283,7 +293,16 @@ func (p *context) compileFuncDecl(pkg llssa.Package, f *ssa.Function) (llssa.Fun
 				bodyPos := p.getFuncBodyPos(f)
 				b.DebugFunction(fn, pos, bodyPos)
 			}
+			if enablePCLine {
+				file, line := "<autogenerated>", 1
+				if pos := p.goProg.Fset.Position(f.Pos()); pos.IsValid() {
+					file, line = pos.Filename, pos.Line
+				}
+				fn.InitPCLine(goFuncName(name), file, line)
+				b.SetPCLine(line)
+			}
 			p.bvals = make(map[ssa.Value]llssa.Expr)
+			p.noEscapes = p.analyzeEscapes(f)
 			off := make([]int, len(f.Blocks))
 			if isCgo {
 				p.cgoArgs = make([]llssa.Expr, len(f.Params))
@@ -629,7 +648,11 @@ func (p *context) compileInstrOrValue(b llssa.Builder, iv instrOrValue, asValue
 			return
 		}
 		elem := p.type_(t.Elem(), llssa.InGo)
-		ret = b.Alloc(elem, v.Heap)
+		if p.noEscapes[v] {
+			ret = b.AllocStack(elem)
+		} else {
+			ret = b.Alloc(elem, v.Heap)
+		}
 	case *ssa.IndexAddr:
 		vx := v.X
 		if _, ok := p.isVArgs(vx); ok { // varargs: this is a varargs index
@@ -707,7 +730,11 @@ func (p *context) compileInstrOrValue(b llssa.Builder, iv instrOrValue, asValue
 	case *ssa.MakeClosure:
 		fn := p.compileValue(b, v.Fn)
 		bindings := p.compileValues(b, v.Bindings, 0)
-		ret = b.MakeClosure(fn, bindings)
+		if p.noEscapes[v] {
+			ret = b.MakeStackClosure(fn, bindings)
+		} else {
+			ret = b.MakeClosure(fn, bindings)
+		}
 	case *ssa.TypeAssert:
 		x := p.compileValue(b, v.X)
 		t := p.type_(v.AssertedType, llssa.InGo)
@@ -777,6 +804,11 @@ func (p *context) getDebugLocScope(v *ssa.Function, pos token.Pos) *types.Scope
 }
 
 func (p *context) compileInstr(b llssa.Builder, instr ssa.Instruction) {
+	if enablePCLine {
+		if pos := instr.Pos(); pos.IsValid() {
+			b.SetPCLine(p.fset.Position(pos).Line)
+		}
+	}
 	if iv, ok := instr.(instrOrValue); ok {
 		p.compileInstrOrValue(b, iv, false)
 		return
@@ -964,12 +996,14 @@ type Patches = map[string]Patch
 
 // NewPackage compiles a Go package to LLVM IR package.
 func NewPackage(prog llssa.Program, pkg *ssa.Package, files []*ast.File) (ret llssa.Package, err error) {
-	ret, _, err = NewPackageEx(prog, nil, pkg, files)
+	ret, _, err = NewPackageEx(prog, nil, pkg, files, nil)
 	return
 }
 
-// NewPackageEx compiles a Go package to LLVM IR package.
-func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files []*ast.File) (ret llssa.Package, externs []string, err error) {
+// NewPackageEx compiles a Go package to LLVM IR package. embeds describes
+// the files embedded by //go:embed directives in files, it may be nil if
+// there are none.
+func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files []*ast.File, embeds *EmbedCfg) (ret llssa.Package, externs []string, err error) {
 	pkgProg := pkg.Prog
 	pkgTypes := pkg.Pkg
 	oldTypes := pkgTypes
@@ -998,6 +1032,7 @@ func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files [
 		patches: patches,
 		skips:   make(map[string]none),
 		vargs:   make(map[*ssa.Alloc][]llssa.Expr),
+		escapes: make(map[*ssa.Function]*escapeSummary),
 		loaded: map[*types.Package]*pkgInfo{
 			types.Unsafe: {kind: PkgDeclOnly}, // TODO(xsw): PkgNoInit or PkgDeclOnly?
 		},
@@ -1036,6 +1071,12 @@ func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files [
 		ctx.initAfter = nil
 		fn()
 	}
+	if err = ctx.initEmbeds(pkg, files, embeds); err != nil {
+		return
+	}
+	if enablePCLine {
+		ret.EndPCLine()
+	}
 	externs = ctx.cgoSymbols
 	for fnName, exportName := range ctx.cgoExports {
 		fn := ret.FuncOf(fnName)
@@ -1046,6 +1087,50 @@ func NewPackageEx(prog llssa.Program, patches Patches, pkg *ssa.Package, files [
 	return
 }
 
+// ReusePackage applies the side effects of compiling a Go package (patched
+// types, linknames) without generating code for it. It is used when the
+// compiled object of the package is reused, so that packages depending on it
+// are compiled as if it were compiled in this session.
+func ReusePackage(prog llssa.Program, patches Patches, pkg *ssa.Package, files []*ast.File) {
+	oldTypes := pkg.Pkg
+	patch, hasPatch := patches[llssa.PathOf(oldTypes)]
+	if hasPatch {
+		pkg.Pkg = patch.Types
+		patch.Alt.Pkg = patch.Types
+	}
+	ctx := importPackage(prog, patches, pkg, files, oldTypes.Name() == "C")
+	if hasPatch {
+		typepatch.Merge(patch.Types, oldTypes, ctx.skips, ctx.skipall)
+	}
+}
+
+// ImportPackage registers the linknames of a Go package compiled or reused by
+// another program to prog, so that packages depending on it can be compiled by
+// prog. Unlike ReusePackage, it doesn't patch the types of the package again.
+func ImportPackage(prog llssa.Program, patches Patches, pkg *ssa.Package, files []*ast.File) {
+	importPackage(prog, patches, pkg, files, pkg.Pkg.Name() == "C")
+}
+
+func importPackage(prog llssa.Program, patches Patches, pkg *ssa.Package, files []*ast.File, cPkg bool) *context {
+	pkgTypes := pkg.Pkg
+	pkgPath := llssa.PathOf(pkgTypes)
+	if pkgPath == llssa.PkgRuntime {
+		prog.SetRuntime(pkgTypes)
+	}
+	ctx := &context{
+		prog:       prog,
+		fset:       pkg.Prog.Fset,
+		goProg:     pkg.Prog,
+		goTyps:     pkgTypes,
+		goPkg:      pkg,
+		patches:    patches,
+		skips:      make(map[string]none),
+		cgoExports: make(map[string]string),
+	}
+	ctx.initFiles(pkgPath, files, cPkg)
+	return ctx
+}
+
 func initFnNameOfHasPatch(name string) string {
 	return name + "$hasPatch"
 }
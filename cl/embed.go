@@ -0,0 +1,303 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// EmbedCfg describes the files embedded in a package, like the -embedcfg
+// file the go command passes to the gc compiler.
+type EmbedCfg struct {
+	// Patterns maps each //go:embed pattern to the files it matches, as
+	// slash-separated paths relative to the package directory.
+	Patterns map[string][]string
+
+	// Files maps the files to their paths on disk.
+	Files map[string]string
+}
+
+const (
+	embedUnknown = iota
+	embedString
+	embedBytes
+	embedFiles
+)
+
+// embedKind returns how a variable of type typ is initialized by //go:embed.
+func embedKind(typ types.Type) int {
+	if t, ok := typ.(*types.Named); ok {
+		if obj := t.Obj(); obj.Name() == "FS" && obj.Pkg() != nil && obj.Pkg().Path() == "embed" {
+			return embedFiles
+		}
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		if t.Kind() == types.String {
+			return embedString
+		}
+	case *types.Slice:
+		if elem, ok := t.Elem().Underlying().(*types.Basic); ok && elem.Kind() == types.Uint8 {
+			return embedBytes
+		}
+	}
+	return embedUnknown
+}
+
+// initEmbeds initializes the package-level variables with //go:embed
+// directives in files with the files they embed.
+func (p *context) initEmbeds(pkg *ssa.Package, files []*ast.File, cfg *EmbedCfg) error {
+	for _, file := range files {
+		importsEmbed := false
+		for _, imp := range file.Imports {
+			if imp.Path.Value == `"embed"` {
+				importsEmbed = true
+				break
+			}
+		}
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.GenDecl)
+			if !ok || decl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.ValueSpec)
+				doc := spec.Doc
+				if doc == nil && !decl.Lparen.IsValid() {
+					doc = decl.Doc
+				}
+				patterns, pos, err := goEmbedPatterns(doc)
+				if err != nil {
+					return p.embedError(pos, err.Error())
+				}
+				if patterns == nil {
+					continue
+				}
+				if !importsEmbed {
+					return p.embedError(pos, `go:embed only allowed in Go files that import "embed"`)
+				}
+				if len(spec.Names) > 1 {
+					return p.embedError(pos, "go:embed cannot apply to multiple vars")
+				}
+				if len(spec.Values) > 0 {
+					return p.embedError(pos, "go:embed cannot apply to var with initializer")
+				}
+				if err := p.initEmbed(pkg, spec.Names[0].Name, patterns, cfg); err != nil {
+					return p.embedError(pos, err.Error())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *context) embedError(pos token.Pos, msg string) error {
+	return fmt.Errorf("%v: %s", p.fset.Position(pos), msg)
+}
+
+// initEmbed initializes the package-level variable name with the files
+// matching patterns.
+func (p *context) initEmbed(pkg *ssa.Package, name string, patterns []string, cfg *EmbedCfg) error {
+	gbl, ok := pkg.Members[name].(*ssa.Global)
+	if !ok {
+		return nil // the blank identifier
+	}
+	typ := gbl.Type().(*types.Pointer).Elem()
+	kind := embedKind(typ)
+	if kind == embedUnknown {
+		return fmt.Errorf("go:embed cannot apply to var of type %v", typ)
+	}
+	if cfg == nil {
+		return errors.New("go:embed used, but no embed configuration")
+	}
+	var list []string
+	have := make(map[string]bool)
+	for _, pattern := range patterns {
+		files, ok := cfg.Patterns[pattern]
+		if !ok {
+			return fmt.Errorf("invalid go:embed: build system did not map pattern: %s", pattern)
+		}
+		for _, file := range files {
+			if have[file] {
+				continue
+			}
+			have[file] = true
+			list = append(list, file)
+			if kind == embedFiles {
+				for dir := path.Dir(file); dir != "." && !have[dir+"/"]; dir = path.Dir(dir) {
+					have[dir+"/"] = true
+					list = append(list, dir+"/")
+				}
+			}
+		}
+	}
+	varName, _, _ := p.varName(gbl.Pkg.Pkg, gbl)
+	g := p.pkg.VarOf(varName)
+	if g == nil {
+		return nil
+	}
+	if kind != embedFiles {
+		if len(list) != 1 {
+			return fmt.Errorf("invalid go:embed: multiple files for type %v", typ)
+		}
+		data, err := os.ReadFile(cfg.Files[list[0]])
+		if err != nil {
+			return err
+		}
+		if kind == embedString {
+			p.pkg.InitEmbedString(g, string(data))
+		} else {
+			p.pkg.InitEmbedBytes(g, string(data))
+		}
+		return nil
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return embedFileLess(list[i], list[j])
+	})
+	files := make([]llssa.EmbedFile, len(list))
+	for i, file := range list {
+		files[i].Name = file
+		if strings.HasSuffix(file, "/") {
+			continue
+		}
+		data, err := os.ReadFile(cfg.Files[file])
+		if err != nil {
+			return err
+		}
+		files[i].Data = string(data)
+		sum := sha256.Sum256(data)
+		copy(files[i].Hash[:], sum[:])
+	}
+	p.pkg.InitEmbedFS(g, files)
+	return nil
+}
+
+// embedFileLess orders the files of an embed.FS, by directory, then by name
+// within it, which is the order embed.FS searches them in.
+func embedFileLess(x, y string) bool {
+	xdir, xelem := embedSplit(x)
+	ydir, yelem := embedSplit(y)
+	return xdir < ydir || xdir == ydir && xelem < yelem
+}
+
+func embedSplit(name string) (dir, elem string) {
+	name = strings.TrimSuffix(name, "/")
+	i := strings.LastIndexByte(name, '/')
+	if i < 0 {
+		return ".", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// goEmbedPatterns returns the patterns of the //go:embed directives in doc
+// and the position of the first one. The patterns are nil if there are none.
+func goEmbedPatterns(doc *ast.CommentGroup) (patterns []string, pos token.Pos, err error) {
+	if doc == nil {
+		return
+	}
+	for _, c := range doc.List {
+		args, ok := strings.CutPrefix(c.Text, "//go:embed")
+		if !ok || args != "" && !unicode.IsSpace(rune(args[0])) {
+			continue
+		}
+		if patterns == nil {
+			pos = c.Pos()
+		}
+		list, e := parseGoEmbed(args)
+		if e != nil {
+			return nil, c.Pos(), e
+		}
+		if len(list) == 0 {
+			return nil, c.Pos(), errors.New("usage: //go:embed pattern...")
+		}
+		patterns = append(patterns, list...)
+	}
+	return
+}
+
+// parseGoEmbed parses the arguments of a //go:embed directive: patterns
+// separated by spaces, which may be Go string literals.
+func parseGoEmbed(args string) ([]string, error) {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var pattern string
+	Switch:
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if unicode.IsSpace(c) {
+					i = j
+					break
+				}
+			}
+			pattern, args = args[:i], args[i:]
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			pattern, args = args[1:1+i], args[2+i:]
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					q, err := strconv.Unquote(args[:i+1])
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args[:i+1])
+					}
+					pattern, args = q, args[i+1:]
+					break Switch
+				}
+			}
+			return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+		}
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		list = append(list, pattern)
+	}
+	return list, nil
+}
+
+// -----------------------------------------------------------------------------
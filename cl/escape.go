@@ -0,0 +1,379 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"os"
+
+	llssa "github.com/goplus/llgo/ssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// -----------------------------------------------------------------------------
+
+// maxStackAlloc is the size of the largest value moved to the stack, and
+// maxStackFrame the total size of those of a function. The stacks of
+// goroutines don't grow, unlike those of gc, so they are smaller than the
+// 64KB of gc for implicit allocations, and scaled down to small stacks.
+const (
+	maxStackAlloc = 4 << 10
+	maxStackFrame = 16 << 10
+)
+
+var (
+	enableEscape bool
+	debugEscape  bool
+	stackSize    uint64
+)
+
+// EnableEscape enables the escape analysis, which allocates the values that
+// don't outlive the call of a function in its stack frame instead of the
+// heap: the variables and the composite literals x/tools moves to the heap
+// (*ssa.Alloc with Heap set), and the contexts of closures.
+func EnableEscape(b bool) {
+	enableEscape = b
+}
+
+// EnableEscapeDebug prints the decisions of the escape analysis to stderr,
+// like -gcflags=-m of gc.
+func EnableEscapeDebug(b bool) {
+	debugEscape = b
+}
+
+// SetStackSize sets the size of the stacks of goroutines, which bounds the
+// values moved to the stack by the escape analysis. 0 means the default of
+// the runtime.
+func SetStackSize(size uint64) {
+	stackSize = size
+}
+
+// stackLimits returns the size of the largest value moved to the stack and
+// the total size of those of a function.
+func (p *context) stackLimits() (maxAlloc, maxFrame uint64) {
+	size := stackSize
+	if size == 0 {
+		size = 256 << 10
+		if p.prog.PointerSize() == 8 {
+			size = 1 << 20
+		}
+	}
+	return min(maxStackAlloc, size/32), min(maxStackFrame, size/8)
+}
+
+// escapeSummary tells how the parameters and the free variables of a
+// function flow, indexed by parameter, then by free variable.
+type escapeSummary struct {
+	escapes  []bool // outlive the call
+	toResult []bool // flow to the results, and so to the caller
+	done     bool
+}
+
+// analyzeEscapes returns the allocations of f that don't escape from it, and
+// so may be in its stack frame.
+//
+// A pointer to an allocation doesn't escape if it is only loaded from and
+// stored to, compared, and passed to the functions of the package whose
+// summaries tell that the parameter doesn't escape, and so on for the
+// pointers and slices derived from it. The pointer must not be live when
+// the allocation is run again in a loop, as they share the space in the
+// frame, which is ensured by not passing it to a phi in the loop.
+//
+// The allocations are bounded by stackLimits, each one having its own space
+// in the frame. Those past the limit of the frame stay on the heap.
+func (p *context) analyzeEscapes(f *ssa.Function) map[ssa.Value]bool {
+	if !enableEscape || isCgoExternSymbol(f) {
+		return nil
+	}
+	maxAlloc, maxFrame := p.stackLimits()
+	var ret map[ssa.Value]bool
+	var cycles map[*ssa.BasicBlock]bool
+	var frame uint64
+	for _, blk := range f.Blocks {
+		for _, instr := range blk.Instrs {
+			var v ssa.Value
+			var size uint64
+			switch instr := instr.(type) {
+			case *ssa.Alloc:
+				if !instr.Heap || instr.Comment == "varargs" {
+					continue
+				}
+				elem := p.type_(instr.Type().(*types.Pointer).Elem(), llssa.InGo)
+				v, size = instr, p.prog.SizeOf(elem)
+			case *ssa.MakeClosure:
+				v = instr
+				for _, binding := range instr.Bindings {
+					size += p.prog.SizeOf(p.type_(binding.Type(), llssa.InGo))
+				}
+			default:
+				continue
+			}
+			if size > maxAlloc {
+				continue
+			}
+			if cycles == nil {
+				cycles = inCycles(f)
+			}
+			escapes, toResult := p.valueFlows(v, cycles[blk])
+			noEscape := !escapes && !toResult && frame+size <= maxFrame
+			if noEscape {
+				if ret == nil {
+					ret = make(map[ssa.Value]bool)
+				}
+				ret[v] = true
+				frame += size
+			}
+			if debugEscape {
+				p.printEscape(v, noEscape)
+			}
+		}
+	}
+	return ret
+}
+
+// printEscape prints the escape analysis decision for v.
+func (p *context) printEscape(v ssa.Value, noEscape bool) {
+	pos := p.fset.Position(v.Pos())
+	what, isVar := "func literal", false
+	if alloc, ok := v.(*ssa.Alloc); ok {
+		what, isVar = p.allocDesc(alloc)
+	}
+	switch {
+	case noEscape:
+		fmt.Fprintf(os.Stderr, "%v: %s does not escape\n", pos, what)
+	case isVar:
+		fmt.Fprintf(os.Stderr, "%v: moved to heap: %s\n", pos, what)
+	default:
+		fmt.Fprintf(os.Stderr, "%v: %s escapes to heap\n", pos, what)
+	}
+}
+
+// allocDesc describes the allocation v like gc, and reports whether it is
+// a variable.
+func (p *context) allocDesc(v *ssa.Alloc) (string, bool) {
+	qf := types.RelativeTo(p.goTyps)
+	elem := v.Type().(*types.Pointer).Elem()
+	switch v.Comment {
+	case "new":
+		return "new(" + types.TypeString(elem, qf) + ")", false
+	case "complit":
+		return "&" + types.TypeString(elem, qf) + "{...}", false
+	case "slicelit", "makeslice":
+		if arr, ok := elem.Underlying().(*types.Array); ok {
+			if v.Comment == "makeslice" {
+				return "make([]" + types.TypeString(arr.Elem(), qf) + ", ...)", false
+			}
+			return "[]" + types.TypeString(arr.Elem(), qf) + "{...}", false
+		}
+	}
+	return v.Comment, true
+}
+
+// inCycles returns the blocks of f in a cycle.
+func inCycles(f *ssa.Function) map[*ssa.BasicBlock]bool {
+	ret := make(map[*ssa.BasicBlock]bool)
+	for _, blk := range f.Blocks {
+		seen := make(map[*ssa.BasicBlock]bool)
+		work := append([]*ssa.BasicBlock(nil), blk.Succs...)
+		for len(work) > 0 {
+			b := work[len(work)-1]
+			work = work[:len(work)-1]
+			if b == blk {
+				ret[blk] = true
+				break
+			}
+			if !seen[b] {
+				seen[b] = true
+				work = append(work, b.Succs...)
+			}
+		}
+	}
+	return ret
+}
+
+// valueFlows reports whether the value root of a pointer, slice or closure,
+// or one derived from it, escapes from its function, or flows to the results
+// of the function. inLoop tells if root is defined in a loop.
+func (p *context) valueFlows(root ssa.Value, inLoop bool) (escapes, toResult bool) {
+	seen := map[ssa.Value]bool{root: true}
+	work := []ssa.Value{root}
+	derive := func(v ssa.Value) {
+		if !seen[v] {
+			seen[v] = true
+			work = append(work, v)
+		}
+	}
+	for len(work) > 0 {
+		x := work[len(work)-1]
+		work = work[:len(work)-1]
+		refs := x.Referrers()
+		if refs == nil {
+			continue
+		}
+		for _, ref := range *refs {
+			switch instr := ref.(type) {
+			case *ssa.DebugRef, *ssa.BinOp:
+			case *ssa.UnOp:
+				if instr.Op != token.MUL {
+					return true, false
+				}
+			case *ssa.Store:
+				if instr.Val == x {
+					return true, false
+				}
+			case *ssa.FieldAddr:
+				derive(instr)
+			case *ssa.IndexAddr:
+				derive(instr)
+			case *ssa.Slice:
+				derive(instr)
+			case *ssa.SliceToArrayPointer:
+				derive(instr)
+			case *ssa.ChangeType:
+				derive(instr)
+			case *ssa.Extract:
+				derive(instr)
+			case *ssa.Phi:
+				if inLoop {
+					return true, false
+				}
+				derive(instr)
+			case *ssa.Return:
+				toResult = true
+			case *ssa.MakeClosure:
+				// x is bound to free variables of the closure.
+				fn, ok := instr.Fn.(*ssa.Function)
+				if !ok {
+					return true, false
+				}
+				s := p.escapeSummary(fn)
+				if s == nil {
+					return true, false
+				}
+				for i, binding := range instr.Bindings {
+					if j := len(fn.Params) + i; binding == x && (s.escapes[j] || s.toResult[j]) {
+						return true, false
+					}
+				}
+				derive(instr)
+			case *ssa.Call:
+				esc, res := p.callFlows(&instr.Call, x)
+				if esc {
+					return true, false
+				}
+				if res {
+					derive(instr)
+				}
+			default: // such as *ssa.Go, *ssa.Defer, *ssa.MakeInterface, *ssa.Convert
+				return true, false
+			}
+		}
+	}
+	return false, toResult
+}
+
+// callFlows reports whether x escapes from the call, or flows to its result.
+func (p *context) callFlows(call *ssa.CallCommon, x ssa.Value) (escapes, toResult bool) {
+	if call.IsInvoke() {
+		return true, false
+	}
+	if fn, ok := call.Value.(*ssa.Builtin); ok {
+		switch fn.Name() {
+		case "len", "cap", "copy", "print", "println":
+			return false, false
+		case "append":
+			return false, call.Args[0] == x
+		case "ssa:wrapnilchk":
+			return false, true
+		}
+		return true, false
+	}
+	var fn *ssa.Function
+	switch v := call.Value.(type) {
+	case *ssa.Function:
+		fn = v
+	case *ssa.MakeClosure:
+		fn, _ = v.Fn.(*ssa.Function)
+	}
+	var s *escapeSummary
+	for i, arg := range call.Args {
+		if arg != x {
+			continue
+		}
+		if s == nil {
+			if fn == nil {
+				return true, false
+			}
+			if s = p.escapeSummary(fn); s == nil {
+				return true, false
+			}
+		}
+		if s.escapes[i] {
+			return true, false
+		}
+		toResult = toResult || s.toResult[i]
+	}
+	// Calling the closure x is fine, whose free variables don't escape and
+	// don't flow to its results, which is checked where they are bound.
+	return false, toResult
+}
+
+// escapeSummary returns the summary of fn, or nil if it can't be analyzed:
+// if it isn't a Go function of the package with a body, or its summary is
+// being computed, for recursive functions.
+func (p *context) escapeSummary(fn *ssa.Function) *escapeSummary {
+	if s, ok := p.escapes[fn]; ok {
+		if s == nil || !s.done {
+			return nil
+		}
+		return s
+	}
+	if !p.canAnalyze(fn) {
+		p.escapes[fn] = nil
+		return nil
+	}
+	n := len(fn.Params) + len(fn.FreeVars)
+	s := &escapeSummary{escapes: make([]bool, n), toResult: make([]bool, n)}
+	p.escapes[fn] = s
+	for i, param := range fn.Params {
+		s.escapes[i], s.toResult[i] = p.valueFlows(param, false)
+	}
+	for i, fv := range fn.FreeVars {
+		j := len(fn.Params) + i
+		s.escapes[j], s.toResult[j] = p.valueFlows(fv, false)
+	}
+	s.done = true
+	return s
+}
+
+// canAnalyze reports whether the summary of fn tells how its code compiled
+// with the package uses its parameters. The functions of patched packages,
+// which may be replaced, and those linked to other symbols are excluded.
+func (p *context) canAnalyze(fn *ssa.Function) bool {
+	if len(fn.Blocks) == 0 || fn.Pkg != p.goPkg || fn.Origin() != nil || p.state != pkgNormal {
+		return false
+	}
+	if isCgoExternSymbol(fn) || checkCgo(fn.Name()) {
+		return false
+	}
+	pkg, name, ftype := p.funcName(fn)
+	return ftype == goFunc && name == funcName(pkg, fn, false)
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,199 @@
+//go:build !llgo
+// +build !llgo
+
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cl_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/goplus/gogen/packages"
+	"github.com/goplus/llgo/cl"
+	"github.com/goplus/llgo/ssa/ssatest"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const escapeSrc = `package foo
+
+type T struct{ a, b int }
+
+func sum(p *T) int { return p.a + p.b }
+
+func id(p *T) *T { return p }
+
+var g *T
+
+func local() int {
+	p := &T{1, 2}
+	return sum(p)
+}
+
+func returned() *T {
+	return &T{1, 2}
+}
+
+func viaId() *T {
+	return id(new(T))
+}
+
+func global() {
+	p := new(T)
+	g = p
+}
+
+func closure() int {
+	x := 1
+	f := func() int { return x + 1 }
+	return f()
+}
+
+func loop(n int) *T {
+	var q *T
+	for i := 0; i < n; i++ {
+		p := new(T)
+		if q != nil {
+			p.a = q.a + 1
+		}
+		q = p
+	}
+	return nil
+}
+`
+
+// compileFuncs compiles src with the escape analysis enabled, and returns the
+// IR of its functions by name.
+func compileFuncs(t *testing.T, src string) map[string]string {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal("ParseFile failed:", err)
+	}
+	files := []*ast.File{f}
+	pkg := types.NewPackage(f.Name.Name, f.Name.Name)
+	imp := packages.NewImporter(fset)
+	foo, _, err := ssautil.BuildPackage(
+		&types.Config{Importer: imp}, fset, pkg, files, ssa.SanityCheckFunctions)
+	if err != nil {
+		t.Fatal("BuildPackage failed:", err)
+	}
+	prog := ssatest.NewProgramEx(t, nil, imp)
+	prog.TypeSizes(types.SizesFor("gc", runtime.GOARCH))
+
+	cl.EnableEscape(true)
+	defer cl.EnableEscape(false)
+	ret, err := cl.NewPackage(prog, foo, files)
+	if err != nil {
+		t.Fatal("cl.NewPackage failed:", err)
+	}
+	funcs := make(map[string]string)
+	for _, def := range strings.Split(ret.String(), "\ndefine ")[1:] {
+		if i := strings.Index(def, "@foo."); i >= 0 {
+			name := def[i+len("@foo."):]
+			name = name[:strings.IndexAny(name, "(\"")]
+			funcs[name] = def
+		}
+	}
+	return funcs
+}
+
+func TestEscape(t *testing.T) {
+	funcs := compileFuncs(t, escapeSrc)
+	tests := []struct {
+		fn   string
+		heap bool
+	}{
+		{"local", false},
+		{"returned", true},
+		{"viaId", true},
+		{"global", true},
+		{"closure", false},
+		{"loop", true},
+	}
+	for _, tt := range tests {
+		def, ok := funcs[tt.fn]
+		if !ok {
+			t.Fatalf("function %s not found", tt.fn)
+		}
+		heap := strings.Contains(def, "runtime.AllocZ")
+		if heap != tt.heap {
+			t.Errorf("%s: heap allocation = %v, want %v\n%s", tt.fn, heap, tt.heap, def)
+		}
+		if !tt.heap && !strings.Contains(def, "alloca") {
+			t.Errorf("%s: no stack allocation\n%s", tt.fn, def)
+		}
+	}
+}
+
+const escapeLimitSrc = `package foo
+
+type small [16]int
+
+type page [4000]byte
+
+func large() byte {
+	p := new([8192]byte)
+	p[0] = 1
+	return p[1]
+}
+
+func medium() int {
+	p := new(small)
+	p[0] = 1
+	return p[1]
+}
+
+func frame() byte {
+	a, b, c, d, e := new(page), new(page), new(page), new(page), new(page)
+	a[0], b[0], c[0], d[0], e[0] = 1, 2, 3, 4, 5
+	return a[1] + b[1] + c[1] + d[1] + e[1]
+}
+`
+
+func TestEscapeLimits(t *testing.T) {
+	tests := []struct {
+		stackSize uint64
+		fn        string
+		heap      int
+	}{
+		{0, "large", 1},
+		{0, "medium", 0},
+		{0, "frame", 1},
+		{2 << 10, "medium", 1},
+		{2 << 10, "frame", 5},
+	}
+	for _, tt := range tests {
+		cl.SetStackSize(tt.stackSize)
+		funcs := compileFuncs(t, escapeLimitSrc)
+		cl.SetStackSize(0)
+		def, ok := funcs[tt.fn]
+		if !ok {
+			t.Fatalf("function %s not found", tt.fn)
+		}
+		if heap := strings.Count(def, "runtime.AllocZ\"(i64 "); heap != tt.heap {
+			t.Errorf("stack size %d: %s: heap allocations = %d, want %d\n%s", tt.stackSize, tt.fn, heap, tt.heap, def)
+		}
+	}
+}
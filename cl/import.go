@@ -24,6 +24,7 @@ import (
 	"go/token"
 	"go/types"
 	"os"
+	"strconv"
 	"strings"
 
 	"golang.org/x/tools/go/ssa"
@@ -431,6 +432,76 @@ func funcName(pkg *types.Package, fn *ssa.Function, org bool) string {
 	return llssa.FuncName(pkg, fnName, recv, org)
 }
 
+// goFuncName returns the name of a function as reported by the gc runtime,
+// such as "main.main.func1" for the closure "main.main$1".
+func goFuncName(name string) string {
+	name = strings.TrimSuffix(name, "$hasPatch")
+	var b strings.Builder
+	closure := false
+	for i := 0; i < len(name); {
+		switch ch := name[i]; ch {
+		case '[': // type arguments
+			depth, j := 0, i
+			for ; j < len(name); j++ {
+				if name[j] == '[' {
+					depth++
+				} else if name[j] == ']' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			b.WriteString("[...]")
+			i = j + 1
+		case '$':
+			rest := name[i+1:]
+			n := numPrefix(rest)
+			switch {
+			case n > 0:
+				if closure {
+					b.WriteString(".")
+				} else {
+					b.WriteString(".func")
+					closure = true
+				}
+				b.WriteString(rest[:n])
+				i += 1 + n
+			case strings.HasPrefix(rest, "bound"):
+				b.WriteString("-fm")
+				i += 1 + len("bound")
+			case strings.HasPrefix(rest, "thunk"):
+				i += 1 + len("thunk")
+			default:
+				b.WriteByte(ch)
+				i++
+			}
+		case '#': // init#1 => init.0
+			rest := name[i+1:]
+			if n := numPrefix(rest); n > 0 {
+				idx, _ := strconv.Atoi(rest[:n])
+				b.WriteString("." + strconv.Itoa(idx-1))
+				i += 1 + n
+			} else {
+				b.WriteByte(ch)
+				i++
+			}
+		default:
+			b.WriteByte(ch)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// numPrefix returns the length of the leading decimal digits of s.
+func numPrefix(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
 func checkCgo(fnName string) bool {
 	return len(fnName) > 4 && fnName[0] == '_' && fnName[2] == 'g' && fnName[3] == 'o' &&
 		(fnName[1] == 'C' || fnName[1] == 'c') &&
@@ -72,10 +72,8 @@ func NewPassArgs(flag *flag.FlagSet) *PassArgs {
 func PassBuildFlags(cmd *Command) *PassArgs {
 	p := NewPassArgs(&cmd.Flag)
 	p.Bool("n", "x")
-	p.Bool("a")
-	p.Bool("linkshared", "race", "msan", "asan",
-		"trimpath", "work")
-	p.Var("p", "asmflags", "compiler", "buildmode",
+	p.Bool("linkshared", "trimpath", "work")
+	p.Var("asmflags", "compiler",
 		"gcflags", "gccgoflags", "installsuffix",
 		"ldflags", "pkgdir", "toolexec", "buildvcs")
 	return p
@@ -29,8 +29,11 @@ var Cmd = &base.Command{
 	Short:     "Remove object files and cached files",
 }
 
+var cleanCache bool
+
 func init() {
 	Cmd.Run = runCmd
+	Cmd.Flag.BoolVar(&cleanCache, "cache", false, "Remove the entire llgo build cache")
 	flags.AddBuildFlags(&Cmd.Flag)
 }
 
@@ -44,6 +47,11 @@ func runCmd(cmd *base.Command, args []string) {
 	conf.Tags = flags.Tags
 	conf.Verbose = flags.Verbose
 
+	if cleanCache {
+		build.CleanCache(conf.Verbose)
+		return
+	}
+
 	args = cmd.Flag.Args()
 	build.Clean(args, conf)
 }
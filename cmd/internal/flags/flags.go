@@ -2,6 +2,8 @@ package flags
 
 import (
 	"flag"
+	"runtime"
+	"time"
 
 	"github.com/goplus/llgo/internal/build"
 	"github.com/goplus/llgo/internal/buildenv"
@@ -21,12 +23,26 @@ var AbiMode int
 var CheckLinkArgs bool
 var CheckLLFiles bool
 var GenLLFiles bool
+var ForceRebuild bool
+var Parallel int
+var BuildMode string
+var Race bool
+var ASan bool
+var MSan bool
+var UBSan bool
 
 func AddBuildFlags(fs *flag.FlagSet) {
 	fs.BoolVar(&Verbose, "v", false, "Verbose mode")
 	fs.StringVar(&Tags, "tags", "", "Build tags")
 	fs.StringVar(&BuildEnv, "buildenv", "", "Build environment")
 	fs.StringVar(&Target, "target", "", "Target platform (e.g., rp2040, wasi)")
+	fs.BoolVar(&ForceRebuild, "a", false, "Force rebuilding of packages that are already up-to-date")
+	fs.IntVar(&Parallel, "p", runtime.NumCPU(), "Number of jobs, such as compile commands, to run in parallel")
+	fs.StringVar(&BuildMode, "buildmode", "exe", "Build mode: exe, c-archive or c-shared")
+	fs.BoolVar(&Race, "race", false, "Enable data race detection")
+	fs.BoolVar(&ASan, "asan", false, "Enable memory error detection with AddressSanitizer")
+	fs.BoolVar(&MSan, "msan", false, "Enable uninitialized memory detection with MemorySanitizer")
+	fs.BoolVar(&UBSan, "ubsan", false, "Enable undefined behavior detection in C code with UBSan")
 	if buildenv.Dev {
 		fs.IntVar(&AbiMode, "abi", 2, "ABI mode (default 2). 0 = none, 1 = cfunc, 2 = allfunc.")
 		fs.BoolVar(&CheckLinkArgs, "check-linkargs", false, "check link args valid")
@@ -35,6 +51,28 @@ func AddBuildFlags(fs *flag.FlagSet) {
 	}
 }
 
+var Timeout time.Duration
+
+func AddRunFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&Timeout, "timeout", 0, "Kill the program if it runs longer than the duration (default none, or 10m under an emulator)")
+}
+
+var Cover bool
+var CoverMode string
+var CoverProfile string
+
+func AddTestFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&Cover, "cover", false, "Enable coverage analysis")
+	fs.StringVar(&CoverMode, "covermode", "", "Coverage mode: set, count or atomic (default set, or atomic with -race)")
+	fs.StringVar(&CoverProfile, "coverprofile", "", "Write a coverage profile to the file, implies -cover")
+}
+
+var Port string
+
+func AddFlashFlags(fs *flag.FlagSet) {
+	fs.StringVar(&Port, "port", "", "Serial port of the device, or mount point of its volume for the msd flash method")
+}
+
 var Gen bool
 
 func AddCmpTestFlags(fs *flag.FlagSet) {
@@ -45,11 +83,26 @@ func UpdateConfig(conf *build.Config) {
 	conf.Tags = Tags
 	conf.Verbose = Verbose
 	conf.Target = Target
+	conf.ForceRebuild = ForceRebuild
+	conf.Parallel = Parallel
+	conf.BuildMode = build.BuildMode(BuildMode)
+	conf.Race = Race
+	conf.ASan = ASan
+	conf.MSan = MSan
+	conf.UBSan = UBSan
 	switch conf.Mode {
 	case build.ModeBuild:
 		conf.OutFile = OutputFile
 	case build.ModeCmpTest:
 		conf.GenExpect = Gen
+	case build.ModeRun:
+		conf.Timeout = Timeout
+	case build.ModeTest:
+		conf.Timeout = Timeout
+		conf.CoverMode = coverMode()
+		conf.CoverProfile = CoverProfile
+	case build.ModeFlash:
+		conf.Port = Port
 	}
 	if buildenv.Dev {
 		conf.AbiMode = build.AbiMode(AbiMode)
@@ -58,3 +111,17 @@ func UpdateConfig(conf *build.Config) {
 		conf.GenLL = GenLLFiles
 	}
 }
+
+// coverMode returns the coverage mode of the test flags, empty if coverage
+// is disabled.
+func coverMode() string {
+	switch {
+	case CoverMode != "":
+		return CoverMode
+	case !Cover && CoverProfile == "":
+		return ""
+	case Race:
+		return "atomic"
+	}
+	return "set"
+}
@@ -0,0 +1,54 @@
+//go:build !llgo
+// +build !llgo
+
+package flags_test
+
+import (
+	"testing"
+
+	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/cmd/internal/build"
+	"github.com/goplus/llgo/cmd/internal/clean"
+	"github.com/goplus/llgo/cmd/internal/flags"
+	"github.com/goplus/llgo/cmd/internal/flash"
+	"github.com/goplus/llgo/cmd/internal/get"
+	"github.com/goplus/llgo/cmd/internal/install"
+	"github.com/goplus/llgo/cmd/internal/run"
+	"github.com/goplus/llgo/cmd/internal/test"
+)
+
+// The flag sets of the commands are built by their init functions, which
+// panic if a flag is defined twice, so that importing them is the test.
+var buildCmds = map[string]*base.Command{
+	"build":   build.Cmd,
+	"clean":   clean.Cmd,
+	"flash":   flash.Cmd,
+	"get":     get.Cmd,
+	"install": install.Cmd,
+	"run":     run.Cmd,
+	"cmptest": run.CmpTestCmd,
+	"test":    test.Cmd,
+}
+
+func TestBuildFlags(t *testing.T) {
+	for name, cmd := range buildCmds {
+		for _, f := range []string{"v", "tags", "target", "a", "p", "race", "asan", "msan", "ubsan"} {
+			if cmd.Flag.Lookup(f) == nil {
+				t.Errorf("llgo %s: flag -%s not defined", name, f)
+			}
+		}
+	}
+}
+
+func TestParseSanitizerFlags(t *testing.T) {
+	defer func() { flags.Race, flags.ASan, flags.MSan = false, false, false }()
+	if err := build.Cmd.Flag.Parse([]string{"-race", "-asan", "-msan", "-x", "."}); err != nil {
+		t.Fatal(err)
+	}
+	if !flags.Race || !flags.ASan || !flags.MSan {
+		t.Errorf("-race -asan -msan = %v %v %v, want all set", flags.Race, flags.ASan, flags.MSan)
+	}
+	if args := build.Cmd.Flag.Args(); len(args) != 1 || args[0] != "." {
+		t.Errorf("args = %v, want [.]", args)
+	}
+}
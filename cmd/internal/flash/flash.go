@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package flash implements the "llgo flash" command.
+package flash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/cmd/internal/flags"
+	"github.com/goplus/llgo/internal/build"
+	"github.com/goplus/llgo/internal/mockable"
+)
+
+var (
+	errNoTarget = errors.New("llgo flash: -target is required")
+)
+
+// llgo flash
+var Cmd = &base.Command{
+	UsageLine: "llgo flash -target platform [-port port] [build flags] [package]",
+	Short:     "Compile and flash program to a device",
+}
+
+func init() {
+	Cmd.Run = runCmd
+	base.PassBuildFlags(Cmd)
+	flags.AddBuildFlags(&Cmd.Flag)
+	flags.AddFlashFlags(&Cmd.Flag)
+}
+
+func runCmd(cmd *base.Command, args []string) {
+
+	if err := cmd.Flag.Parse(args); err != nil {
+		return
+	}
+
+	conf := build.NewDefaultConf(build.ModeFlash)
+	flags.UpdateConfig(conf)
+	if conf.Target == "" {
+		fmt.Fprintln(os.Stderr, errNoTarget)
+		mockable.Exit(1)
+		return
+	}
+
+	args = cmd.Flag.Args()
+	_, err := build.Do(args, conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		mockable.Exit(1)
+	}
+}
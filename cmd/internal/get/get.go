@@ -18,7 +18,27 @@
 package get
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
 	"github.com/goplus/llgo/cmd/internal/base"
+	"github.com/goplus/llgo/cmd/internal/flags"
+	"github.com/goplus/llgo/internal/build"
+	"github.com/goplus/llgo/internal/mockable"
+	"golang.org/x/mod/module"
+)
+
+var (
+	errNoGoMod = errors.New("llgo: go.mod file not found in current directory or any parent directory; see 'go help modules'")
 )
 
 // llgo get
@@ -27,10 +47,250 @@ var Cmd = &base.Command{
 	Short:     "Add dependencies to current module and install them",
 }
 
+var getFlags *base.PassArgs
+
 func init() {
 	Cmd.Run = runCmd
+	getFlags = base.NewPassArgs(&Cmd.Flag)
+	getFlags.Bool("t", "u")
+	flags.AddBuildFlags(&Cmd.Flag)
 }
 
 func runCmd(cmd *base.Command, args []string) {
-	panic("todo")
+
+	if err := cmd.Flag.Parse(args); err != nil {
+		return
+	}
+
+	conf := build.NewDefaultConf(build.ModeGet)
+	flags.UpdateConfig(conf)
+
+	args = cmd.Flag.Args()
+	err := get(args, conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		mockable.Exit(1)
+	}
+}
+
+// get resolves the module requirements of args with the go command, which
+// updates go.mod and go.sum and downloads the modules, and then builds the
+// packages of args with llgo, so that their dependencies are compiled and
+// their external libraries checked ahead of time. go get updates a copy of
+// go.mod and go.sum, which replaces them once the packages are built, so
+// that they are left as is if llgo can't build them.
+func get(args []string, conf *build.Config) error {
+	gomod, err := findGoMod()
+	if err != nil {
+		return err
+	}
+	paths, err := pkgPaths(args)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp("", "llgo-get")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	modFile := filepath.Join(tmpDir, "go.mod")
+	if err := copyModFile(gomod, modFile); err != nil {
+		return err
+	}
+
+	tags := "llgo"
+	if conf.Tags != "" {
+		tags += "," + conf.Tags
+	}
+	goArgs := append([]string{"get", "-modfile=" + modFile, "-tags=" + tags}, getFlags.Args...)
+	if conf.Verbose {
+		goArgs = append(goArgs, "-v")
+	}
+	goArgs = append(goArgs, args...)
+	cmd := exec.Command("go", goArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("llgo: go get failed: %w", err)
+	}
+
+	// Nothing to build if the requirements were only removed or the go
+	// version updated, such as by "llgo get go@1.24".
+	if len(paths) > 0 {
+		pkgs, err := buildablePkgs(paths, modFile, tags)
+		if err != nil {
+			return err
+		}
+		if len(pkgs) > 0 {
+			conf.ModFile = modFile
+			if _, err := build.Do(pkgs, conf); err != nil {
+				return err
+			}
+		}
+	}
+	return copyModFile(modFile, gomod)
+}
+
+// listed is a package or a module printed by go list -json.
+type listed struct {
+	ImportPath string
+	Path       string
+	Dir        string
+	GoFiles    []string
+	CgoFiles   []string
+	Error      *struct{ Err string }
+}
+
+// goList runs go list -e -json with the go.mod modFile and returns what it
+// prints.
+func goList(modFile string, args ...string) ([]listed, error) {
+	args = append([]string{"list", "-e", "-modfile=" + modFile}, args...)
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("llgo: go list failed: %w", err)
+	}
+	var ret []listed
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var v listed
+		if err := dec.Decode(&v); err == io.EOF {
+			return ret, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("llgo: go list: %w", err)
+		}
+		ret = append(ret, v)
+	}
+}
+
+// buildablePkgs returns the packages paths, as in the arguments of llgo get,
+// stand for, with the go.mod modFile. A module without a package at its
+// root, such as golang.org/x/tools, or a directory without Go files stands
+// for no package, as the packages of a module are built once they are
+// imported.
+func buildablePkgs(paths []string, modFile, tags string) ([]string, error) {
+	var modPaths []string
+	for _, path := range paths {
+		if !isLocalPath(path) && !strings.Contains(path, "...") {
+			modPaths = append(modPaths, path)
+		}
+	}
+	if len(modPaths) > 0 {
+		// go list would look a module up for the root of a module without
+		// Go files, as for a package missing in the build list
+		mods, err := goList(modFile, append([]string{"-m", "-json=Path,Dir,Error"}, modPaths...)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, mod := range mods {
+			if mod.Error != nil || mod.Dir == "" {
+				continue // a package
+			}
+			if files, _ := filepath.Glob(filepath.Join(mod.Dir, "*.go")); len(files) == 0 {
+				paths = slices.DeleteFunc(paths, func(path string) bool { return path == mod.Path })
+			}
+		}
+		if len(paths) == 0 {
+			return nil, nil
+		}
+	}
+
+	pkgs, err := goList(modFile, append([]string{"-tags=" + tags, "-json=ImportPath,Dir,GoFiles,CgoFiles,Error"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	var errs []error
+	for _, pkg := range pkgs {
+		switch {
+		case pkg.Error == nil:
+			ret = append(ret, pkg.ImportPath)
+		case pkg.Dir != "" && len(pkg.GoFiles) == 0 && len(pkg.CgoFiles) == 0:
+			// a directory without Go files
+		default:
+			errs = append(errs, errors.New(pkg.Error.Err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("llgo: %w", errors.Join(errs...))
+	}
+	return ret, nil
+}
+
+// copyModFile copies the go.mod file from to the file to, and the go.sum
+// file next to it, if any.
+func copyModFile(from, to string) error {
+	sumOf := func(gomod string) string {
+		return strings.TrimSuffix(gomod, ".mod") + ".sum"
+	}
+	for _, file := range [][2]string{{from, to}, {sumOf(from), sumOf(to)}} {
+		data, err := os.ReadFile(file[0])
+		if errors.Is(err, fs.ErrNotExist) && file[0] != from {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file[1], data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pkgPaths returns the packages to build for the arguments of llgo get,
+// which are packages or modules with an optional version query suffix
+// "@query". Those removed by the query "none" and the go and toolchain
+// pseudo-modules are left out.
+func pkgPaths(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{"."}, nil
+	}
+	pkgs := make([]string, 0, len(args))
+	for _, arg := range args {
+		path, query, _ := strings.Cut(arg, "@")
+		if path == "" {
+			return nil, fmt.Errorf("llgo: malformed module path %q: empty path", arg)
+		}
+		switch {
+		case query == "none":
+			continue
+		case path == "go" || path == "toolchain":
+			continue // not a module to build
+		case !isLocalPath(path) && !strings.Contains(path, "..."):
+			if err := module.CheckImportPath(path); err != nil {
+				return nil, fmt.Errorf("llgo: %w", err)
+			}
+		}
+		pkgs = append(pkgs, path)
+	}
+	return pkgs, nil
+}
+
+// findGoMod returns the go.mod file of the main module, the first one in
+// the current directory or its parents.
+func findGoMod() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if fi, err := os.Stat(gomod); err == nil && !fi.IsDir() {
+			return gomod, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errNoGoMod
+		}
+		dir = parent
+	}
+}
+
+// isLocalPath reports whether path is a file system path rather than an
+// import path.
+func isLocalPath(path string) bool {
+	return path == "." || path == ".." || strings.HasPrefix(path, "./") ||
+		strings.HasPrefix(path, "../") || filepath.IsAbs(path)
 }
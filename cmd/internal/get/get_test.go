@@ -0,0 +1,106 @@
+//go:build !llgo
+// +build !llgo
+
+package get
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPkgPaths(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"."}},
+		{[]string{"golang.org/x/tools@latest", "./cmd/..."}, []string{"golang.org/x/tools", "./cmd/..."}},
+		{[]string{"example.com/old@none", "go@1.24", "toolchain@none"}, []string{}},
+	}
+	for _, tt := range tests {
+		got, err := pkgPaths(tt.args)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("pkgPaths(%q) = %q, %v, want %q", tt.args, got, err, tt.want)
+		}
+	}
+	for _, arg := range []string{"@latest", "Example.com/bad path"} {
+		if _, err := pkgPaths([]string{arg}); err == nil {
+			t.Errorf("pkgPaths(%q): no error", arg)
+		}
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuildablePkgs(t *testing.T) {
+	t.Setenv("GOPROXY", "off")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":       "module example.com/m\n\ngo 1.21\n",
+		"a/a.go":       "package a\n",
+		"a/b/b.go":     "package b\n",
+		"tools/doc.md": "no Go files\n",
+	})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	modFile := filepath.Join(dir, "go.mod")
+
+	pkgs, err := buildablePkgs([]string{"example.com/m", "example.com/m/a/...", "./tools"}, modFile, "llgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com/m/a", "example.com/m/a/b"}; !slices.Equal(pkgs, want) {
+		t.Errorf("buildablePkgs = %q, want %q", pkgs, want)
+	}
+	if _, err := buildablePkgs([]string{"example.com/m/missing"}, modFile, "llgo"); err == nil {
+		t.Error("buildablePkgs of a missing package: no error")
+	}
+}
+
+func TestCopyModFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/go.mod": "module example.com/m\n",
+		"src/go.sum": "example.com/dep v1.0.0 h1:x\n",
+		"dst/go.mod": "module example.com/old\n",
+	})
+	from, to := filepath.Join(dir, "src", "go.mod"), filepath.Join(dir, "dst", "go.mod")
+	if err := copyModFile(from, to); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		want, _ := os.ReadFile(filepath.Join(dir, "src", name))
+		got, err := os.ReadFile(filepath.Join(dir, "dst", name))
+		if err != nil || string(got) != string(want) {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	// go.sum is optional, go.mod isn't
+	os.Remove(filepath.Join(dir, "src", "go.sum"))
+	if err := copyModFile(from, to); err != nil {
+		t.Errorf("copyModFile without go.sum: %v", err)
+	}
+	if err := copyModFile(filepath.Join(dir, "none", "go.mod"), to); err == nil {
+		t.Error("copyModFile of a missing go.mod: no error")
+	}
+}
@@ -49,6 +49,7 @@ func init() {
 	CmpTestCmd.Run = runCmpTest
 	base.PassBuildFlags(Cmd)
 	flags.AddBuildFlags(&Cmd.Flag)
+	flags.AddRunFlags(&Cmd.Flag)
 	flags.AddBuildFlags(&CmpTestCmd.Flag)
 	flags.AddCmpTestFlags(&CmpTestCmd.Flag)
 }
@@ -11,13 +11,15 @@ import (
 
 // llgo test
 var Cmd = &base.Command{
-	UsageLine: "llgo test [-target platform] [build flags] package [arguments...]",
+	UsageLine: "llgo test [-target platform] [-cover] [build flags] package [arguments...]",
 	Short:     "Compile and run Go test",
 }
 
 func init() {
 	Cmd.Run = runCmd
 	flags.AddBuildFlags(&Cmd.Flag)
+	flags.AddRunFlags(&Cmd.Flag)
+	flags.AddTestFlags(&Cmd.Flag)
 }
 
 func runCmd(cmd *base.Command, args []string) {
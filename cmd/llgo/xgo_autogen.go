@@ -7,6 +7,8 @@ import (
 	"github.com/goplus/cobra/xcmd"
 	"github.com/goplus/llgo/cmd/internal/build"
 	"github.com/goplus/llgo/cmd/internal/clean"
+	"github.com/goplus/llgo/cmd/internal/flash"
+	"github.com/goplus/llgo/cmd/internal/get"
 	"github.com/goplus/llgo/cmd/internal/install"
 	"github.com/goplus/llgo/cmd/internal/run"
 	"github.com/goplus/llgo/cmd/internal/test"
@@ -29,6 +31,10 @@ type Cmd_cmptest struct {
 	xcmd.Command
 	*App
 }
+type Cmd_flash struct {
+	xcmd.Command
+	*App
+}
 type Cmd_get struct {
 	xcmd.Command
 	*App
@@ -61,12 +67,13 @@ func (this *App) Main() {
 	_xgo_obj0 := &Cmd_build{App: this}
 	_xgo_obj1 := &Cmd_clean{App: this}
 	_xgo_obj2 := &Cmd_cmptest{App: this}
-	_xgo_obj3 := &Cmd_get{App: this}
-	_xgo_obj4 := &Cmd_install{App: this}
-	_xgo_obj5 := &Cmd_run{App: this}
-	_xgo_obj6 := &Cmd_test{App: this}
-	_xgo_obj7 := &Cmd_version{App: this}
-	xcmd.Gopt_App_Main(this, _xgo_obj0, _xgo_obj1, _xgo_obj2, _xgo_obj3, _xgo_obj4, _xgo_obj5, _xgo_obj6, _xgo_obj7)
+	_xgo_obj3 := &Cmd_flash{App: this}
+	_xgo_obj4 := &Cmd_get{App: this}
+	_xgo_obj5 := &Cmd_install{App: this}
+	_xgo_obj6 := &Cmd_run{App: this}
+	_xgo_obj7 := &Cmd_test{App: this}
+	_xgo_obj8 := &Cmd_version{App: this}
+	xcmd.Gopt_App_Main(this, _xgo_obj0, _xgo_obj1, _xgo_obj2, _xgo_obj3, _xgo_obj4, _xgo_obj5, _xgo_obj6, _xgo_obj7, _xgo_obj8)
 }
 //line cmd/llgo/build_cmd.gox:20
 func (this *Cmd_build) Main(_xgo_arg0 string) {
@@ -122,17 +129,37 @@ func (this *Cmd_cmptest) Main(_xgo_arg0 string) {
 func (this *Cmd_cmptest) Classfname() string {
 	return "cmptest"
 }
-//line cmd/llgo/get_cmd.gox:16
+//line cmd/llgo/flash_cmd.gox:20
+func (this *Cmd_flash) Main(_xgo_arg0 string) {
+	this.Command.Main(_xgo_arg0)
+//line cmd/llgo/flash_cmd.gox:20:1
+	this.Use("flash [flags] [package]")
+//line cmd/llgo/flash_cmd.gox:22:1
+	this.Short("Compile and flash program to a device")
+//line cmd/llgo/flash_cmd.gox:24:1
+	this.FlagOff()
+//line cmd/llgo/flash_cmd.gox:26:1
+	this.Run__1(func(args []string) {
+//line cmd/llgo/flash_cmd.gox:27:1
+		flash.Cmd.Run(flash.Cmd, args)
+	})
+}
+func (this *Cmd_flash) Classfname() string {
+	return "flash"
+}
+//line cmd/llgo/get_cmd.gox:20
 func (this *Cmd_get) Main(_xgo_arg0 string) {
 	this.Command.Main(_xgo_arg0)
-//line cmd/llgo/get_cmd.gox:16:1
+//line cmd/llgo/get_cmd.gox:20:1
 	this.Use("get [flags] [packages]")
-//line cmd/llgo/get_cmd.gox:18:1
+//line cmd/llgo/get_cmd.gox:22:1
 	this.Short("Add dependencies to current module and install them")
-//line cmd/llgo/get_cmd.gox:20:1
+//line cmd/llgo/get_cmd.gox:24:1
+	this.FlagOff()
+//line cmd/llgo/get_cmd.gox:26:1
 	this.Run__1(func(args []string) {
-//line cmd/llgo/get_cmd.gox:21:1
-		panic("todo")
+//line cmd/llgo/get_cmd.gox:27:1
+		get.Cmd.Run(get.Cmd, args)
 	})
 }
 func (this *Cmd_get) Classfname() string {
@@ -12,14 +12,12 @@ require (
 	github.com/goplus/llvm v0.8.5
 	github.com/goplus/mod v0.17.1
 	github.com/qiniu/x v1.15.1
+	golang.org/x/mod v0.27.0
 	golang.org/x/tools v0.36.0
 )
 
 require github.com/sigurn/crc16 v0.0.0-20240131213347-83fcde1e29d1
 
-require (
-	golang.org/x/mod v0.27.0 // indirect
-	golang.org/x/sync v0.16.0 // indirect
-)
+require golang.org/x/sync v0.16.0 // indirect
 
 replace github.com/goplus/llgo/runtime => ./runtime
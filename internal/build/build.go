@@ -33,6 +33,9 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"golang.org/x/tools/go/ssa"
@@ -43,8 +46,10 @@ import (
 	"github.com/goplus/llgo/internal/crosscompile"
 	"github.com/goplus/llgo/internal/env"
 	"github.com/goplus/llgo/internal/firmware"
+	"github.com/goplus/llgo/internal/flash"
 	"github.com/goplus/llgo/internal/mockable"
 	"github.com/goplus/llgo/internal/packages"
+	"github.com/goplus/llgo/internal/targets"
 	"github.com/goplus/llgo/internal/typepatch"
 	"github.com/goplus/llgo/ssa/abi"
 	xenv "github.com/goplus/llgo/xtool/env"
@@ -63,6 +68,8 @@ const (
 	ModeTest
 	ModeCmpTest
 	ModeGen
+	ModeGet
+	ModeFlash
 )
 
 type AbiMode = cabi.Mode
@@ -76,17 +83,29 @@ type Config struct {
 	Goarch        string
 	Target        string // target name (e.g., "rp2040", "wasi") - takes precedence over Goos/Goarch
 	BinPath       string
-	AppExt        string   // ".exe" on Windows, empty on Unix
-	OutFile       string   // only valid for ModeBuild when len(pkgs) == 1
-	RunArgs       []string // only valid for ModeRun
+	AppExt        string        // ".exe" on Windows, empty on Unix
+	OutFile       string        // only valid for ModeBuild when len(pkgs) == 1
+	RunArgs       []string      // only valid for ModeRun
+	Port          string        // only valid for ModeFlash
+	Timeout       time.Duration // only valid for ModeRun and ModeTest, 0 means the default: none, or 10m under an emulator
+	CoverMode     string        // only valid for ModeTest, "set", "count" or "atomic" to measure statement coverage
+	CoverProfile  string        // only valid for ModeTest, file to write the cover profile of the tests to
 	Mode          Mode
 	AbiMode       AbiMode
-	GenExpect     bool // only valid for ModeCmpTest
+	BuildMode     BuildMode // only valid for ModeBuild, empty means BuildModeExe
+	GenExpect     bool      // only valid for ModeCmpTest
 	Verbose       bool
 	GenLL         bool // generate pkg .ll files
 	CheckLLFiles  bool // check .ll files valid
 	CheckLinkArgs bool // check linkargs valid
+	ForceRebuild  bool // don't reuse package objects from the build cache
+	Race          bool // enable data race detection with ThreadSanitizer
+	ASan          bool // enable memory error detection with AddressSanitizer
+	MSan          bool // enable uninitialized memory detection with MemorySanitizer
+	UBSan         bool // enable undefined behavior detection in C code with UBSan
+	Parallel      int  // number of jobs to run in parallel, 0 means the number of CPUs
 	Tags          string
+	ModFile       string              // go.mod to use instead of the one of the main module, see go help modules
 	GlobalNames   map[string][]string // pkg => names
 	GlobalDatas   map[string]string   // pkg.name => data
 }
@@ -111,12 +130,13 @@ func NewDefaultConf(mode Mode) *Config {
 		goarch = runtime.GOARCH
 	}
 	conf := &Config{
-		Goos:    goos,
-		Goarch:  goarch,
-		BinPath: bin,
-		Mode:    mode,
-		AbiMode: cabi.ModeAllFunc,
-		AppExt:  DefaultAppExt(goos),
+		Goos:     goos,
+		Goarch:   goarch,
+		BinPath:  bin,
+		Mode:     mode,
+		AbiMode:  cabi.ModeAllFunc,
+		AppExt:   DefaultAppExt(goos),
+		Parallel: runtime.NumCPU(),
 	}
 	return conf
 }
@@ -145,7 +165,7 @@ func DefaultAppExt(goos string) string {
 // -----------------------------------------------------------------------------
 
 const (
-	loadFiles   = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles
+	loadFiles   = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedEmbedFiles | packages.NeedEmbedPatterns
 	loadImports = loadFiles | packages.NeedImports
 	loadTypes   = loadImports | packages.NeedTypes | packages.NeedTypesSizes
 	loadSyntax  = loadTypes | packages.NeedSyntax | packages.NeedTypesInfo
@@ -158,6 +178,15 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	if conf.Goarch == "" {
 		conf.Goarch = runtime.GOARCH
 	}
+	if err := checkBuildMode(conf); err != nil {
+		return nil, err
+	}
+	if err := checkSanitizers(conf); err != nil {
+		return nil, err
+	}
+	if err := checkCover(conf); err != nil {
+		return nil, err
+	}
 	// Handle crosscompile configuration first to set correct GOOS/GOARCH
 	export, err := crosscompile.Use(conf.Goos, conf.Goarch, IsWasiThreadsEnabled(), conf.Target)
 	if err != nil {
@@ -171,6 +200,9 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	if conf.Target != "" && export.GOARCH != "" {
 		conf.Goarch = export.GOARCH
 	}
+	if conf.BuildMode.isLib() {
+		export.CCFLAGS = append(export.CCFLAGS, "-fPIC")
+	}
 
 	verbose := conf.Verbose
 	patterns := args
@@ -181,6 +213,9 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	if len(export.BuildTags) > 0 {
 		tags += "," + strings.Join(export.BuildTags, ",")
 	}
+	if sanTags := sanitizeTags(conf); len(sanTags) > 0 {
+		tags += "," + strings.Join(sanTags, ",")
+	}
 	cfg := &packages.Config{
 		Mode:       loadSyntax | packages.NeedDeps | packages.NeedModule | packages.NeedExportFile,
 		BuildFlags: []string{"-tags=" + tags},
@@ -188,6 +223,9 @@ func Do(args []string, conf *Config) ([]Package, error) {
 		Tests:      conf.Mode == ModeTest,
 		Env:        append(slices.Clone(os.Environ()), "GOOS="+conf.Goos, "GOARCH="+conf.Goarch),
 	}
+	if conf.ModFile != "" {
+		cfg.BuildFlags = append(cfg.BuildFlags, "-modfile="+conf.ModFile)
+	}
 	if conf.Mode == ModeTest {
 		cfg.Mode |= packages.NeedForTest
 	}
@@ -202,6 +240,9 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	cl.EnableDebug(IsDbgEnabled())
 	cl.EnableDbgSyms(IsDbgSymsEnabled())
 	cl.EnableTrace(IsTraceEnabled())
+	cl.EnablePCLine(IsPCLineEnabled())
+	cl.EnableEscape(IsEscapeEnabled())
+	cl.SetStackSize(export.StackSize)
 	llssa.Initialize(llssa.InitAll)
 
 	target := &llssa.Target{
@@ -210,6 +251,12 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	}
 
 	prog := llssa.NewProgram(target)
+	prog.EnableTypedAlloc(IsTypedAllocEnabled())
+	for _, kind := range conf.sanitizers() {
+		if kind != sanitizeUndefined.kind {
+			prog.EnableSanitizer(kind)
+		}
+	}
 	sizes := func(sizes types.Sizes, compiler, arch string) types.Sizes {
 		if arch == "wasm" {
 			sizes = &types.StdSizes{WordSize: 4, MaxAlign: 4}
@@ -227,6 +274,11 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	if patterns == nil {
 		patterns = []string{"."}
 	}
+	if conf.CoverMode != "" {
+		if err := coverPackages(conf, cfg, patterns); err != nil {
+			return nil, err
+		}
+	}
 	initial, err := packages.LoadEx(dedup, sizes, cfg, patterns...)
 	check(err)
 	mode := conf.Mode
@@ -238,6 +290,8 @@ func Do(args []string, conf *Config) ([]Package, error) {
 			}
 		case ModeRun:
 			return nil, fmt.Errorf("cannot run multiple packages")
+		case ModeFlash:
+			return nil, fmt.Errorf("cannot flash multiple packages")
 		case ModeTest:
 			newInitial := make([]*packages.Package, 0, len(initial))
 			for _, pkg := range initial {
@@ -254,9 +308,9 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	altPkgs, err := packages.LoadEx(dedup, sizes, cfg, altPkgPaths...)
 	check(err)
 
-	noRt := 1
+	var rtUsed atomic.Bool // set by the workers of compilePkgs
 	prog.SetRuntime(func() *types.Package {
-		noRt = 0
+		rtUsed.Store(true)
 		return altPkgs[0].Types
 	})
 	prog.SetPython(func() *types.Package {
@@ -277,6 +331,10 @@ func Do(args []string, conf *Config) ([]Package, error) {
 	env := llvm.New("")
 	os.Setenv("PATH", env.BinDir()+":"+os.Getenv("PATH")) // TODO(xsw): check windows
 
+	parallel := conf.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
 	output := conf.OutFile != ""
 	ctx := &context{env: env, conf: cfg, progSSA: progSSA, prog: prog, dedup: dedup,
 		patches: patches, built: make(map[string]none), initial: initial, mode: mode,
@@ -285,11 +343,27 @@ func Do(args []string, conf *Config) ([]Package, error) {
 		needPyInit:   make(map[*packages.Package]bool),
 		buildConf:    conf,
 		crossCompile: export,
-		cTransformer: cabi.NewTransformer(prog, conf.AbiMode),
+		compilers:    []*pkgCompiler{newPkgCompiler(prog, conf.AbiMode)},
+		aPkgs:        make(map[string]*aPackage),
+		jobs:         newJobQueue(parallel),
 	}
+	ctx.cache = newBuildCache(ctx)
+	defer ctx.jobs.wait() // run the cleanups if the build fails before waiting
+
+	// update globals importpath.name=value
+	addGlobalString(conf, "runtime.defaultGOROOT="+runtime.GOROOT(), nil)
+	addGlobalString(conf, "runtime.buildVersion="+runtime.Version(), nil)
+
+	if mode == ModeGet {
+		if err := checkLinkLibs(ctx, initial); err != nil {
+			return nil, err
+		}
+	}
+
 	pkgs, err := buildAllPkgs(ctx, initial, verbose)
 	check(err)
 	if mode == ModeGen {
+		check(ctx.jobs.wait())
 		for _, pkg := range pkgs {
 			if pkg.Package == initial[0] {
 				return []*aPackage{pkg}, nil
@@ -298,18 +372,25 @@ func Do(args []string, conf *Config) ([]Package, error) {
 		return nil, fmt.Errorf("initial package not found")
 	}
 
+	noRt := 1
+	if rtUsed.Load() {
+		noRt = 0
+	}
 	dpkg, err := buildAllPkgs(ctx, altPkgs[noRt:], verbose)
 	check(err)
+	check(ctx.jobs.wait())
 	allPkgs := append([]*aPackage{}, pkgs...)
 	allPkgs = append(allPkgs, dpkg...)
-
-	// update globals importpath.name=value
-	addGlobalString(conf, "runtime.defaultGOROOT="+runtime.GOROOT(), nil)
-	addGlobalString(conf, "runtime.buildVersion="+runtime.Version(), nil)
+	if mode == ModeGet {
+		return allPkgs, nil
+	}
 
 	global, err := createGlobals(ctx, ctx.prog, pkgs)
 	check(err)
 
+	if mode == ModeTest && conf.CoverProfile != "" {
+		check(os.WriteFile(conf.CoverProfile, []byte("mode: "+conf.CoverMode+"\n"), 0644))
+	}
 	for _, pkg := range initial {
 		if needLink(pkg, mode) {
 			linkMainPkg(ctx, pkg, allPkgs, global, conf, mode, verbose)
@@ -380,13 +461,22 @@ type context struct {
 
 	buildConf    *Config
 	crossCompile crosscompile.Export
+	libcOnce     sync.Once // sets up the C library of crossCompile
+	libcErr      error
 
-	cTransformer *cabi.Transformer
+	compilers []*pkgCompiler       // the workers of compilePkgs
+	aPkgs     map[string]*aPackage // built packages by ID
+	cache     *buildCache          // nil if the build cache is disabled
+	jobs      *jobQueue
 
 	testFail bool
 }
 
-func (c *context) compiler() *clang.Cmd {
+// clangConfig returns the clang config of the build. The C library of the
+// target is set up the first time, as it may need to be downloaded.
+func (c *context) clangConfig() clang.Config {
+	c.libcOnce.Do(func() { c.libcErr = c.crossCompile.UseLibc() })
+	check(c.libcErr)
 	config := clang.NewConfig(
 		c.crossCompile.CC,
 		c.crossCompile.CCFLAGS,
@@ -394,20 +484,18 @@ func (c *context) compiler() *clang.Cmd {
 		c.crossCompile.LDFLAGS,
 		c.crossCompile.Linker,
 	)
-	cmd := clang.NewCompiler(config)
+	config.Sanitizers = c.buildConf.sanitizers()
+	return config
+}
+
+func (c *context) compiler() *clang.Cmd {
+	cmd := clang.NewCompiler(c.clangConfig())
 	cmd.Verbose = c.buildConf.Verbose
 	return cmd
 }
 
 func (c *context) linker() *clang.Cmd {
-	config := clang.NewConfig(
-		c.crossCompile.CC,
-		c.crossCompile.CCFLAGS,
-		c.crossCompile.CFLAGS,
-		c.crossCompile.LDFLAGS,
-		c.crossCompile.Linker,
-	)
-	cmd := clang.NewLinker(config)
+	cmd := clang.NewLinker(c.clangConfig())
 	cmd.Verbose = c.buildConf.Verbose
 	return cmd
 }
@@ -424,6 +512,7 @@ func buildAllPkgs(ctx *context, initial []*packages.Package, verbose bool) (pkgs
 		return nil, fmt.Errorf("cannot build SSA for packages")
 	}
 	built := ctx.built
+	var newPkgs, toBuild []*aPackage
 	for _, aPkg := range pkgs {
 		pkg := aPkg.Package
 		if _, ok := built[pkg.ID]; ok {
@@ -431,75 +520,121 @@ func buildAllPkgs(ctx *context, initial []*packages.Package, verbose bool) (pkgs
 			continue
 		}
 		built[pkg.ID] = none{}
-		switch kind, param := cl.PkgKindOf(pkg.Types); kind {
+		ctx.aPkgs[pkg.ID] = aPkg
+		newPkgs = append(newPkgs, aPkg)
+		switch kind, _ := cl.PkgKindOf(pkg.Types); kind {
 		case cl.PkgDeclOnly:
 			// skip packages that only contain declarations
 			// and set no export file
 			pkg.ExportFile = ""
 		case cl.PkgLinkIR, cl.PkgLinkExtern, cl.PkgPyModule:
 			if len(pkg.GoFiles) > 0 {
-				err := buildPkg(ctx, aPkg, verbose)
-				if err != nil {
-					return nil, err
-				}
+				toBuild = append(toBuild, aPkg)
 			} else {
 				// panic("todo")
 				// TODO(xsw): support packages out of llgo
 				pkg.ExportFile = ""
 			}
-			if kind == cl.PkgLinkExtern {
-				// need to be linked with external library
-				// format: ';' separated alternative link methods. e.g.
-				//   link: $LLGO_LIB_PYTHON; $(pkg-config --libs python3-embed); -lpython3
-				altParts := strings.Split(param, ";")
-				expdArgs := make([]string, 0, len(altParts))
-				for _, param := range altParts {
-					param = strings.TrimSpace(param)
-					if strings.ContainsRune(param, '$') {
-						expdArgs = append(expdArgs, xenv.ExpandEnvToArgs(param)...)
-						ctx.nLibdir++
-					} else {
-						fields := strings.Fields(param)
-						expdArgs = append(expdArgs, fields...)
-					}
-					if len(expdArgs) > 0 {
-						break
-					}
-				}
-				if len(expdArgs) == 0 {
-					panic(fmt.Sprintf("'%s' cannot locate the external library", param))
-				}
-
-				pkgLinkArgs := make([]string, 0, 3)
-				if expdArgs[0][0] == '-' {
-					pkgLinkArgs = append(pkgLinkArgs, expdArgs...)
-				} else {
-					linkFile := expdArgs[0]
-					dir, lib := filepath.Split(linkFile)
-					pkgLinkArgs = append(pkgLinkArgs, "-l"+lib)
-					if dir != "" {
-						pkgLinkArgs = append(pkgLinkArgs, "-L"+dir)
-						ctx.nLibdir++
-					}
-				}
-				if ctx.buildConf.CheckLinkArgs {
-					if err := ctx.compiler().CheckLinkArgs(pkgLinkArgs, isWasmTarget(ctx.buildConf.Goos)); err != nil {
-						panic(fmt.Sprintf("test link args '%s' failed\n\texpanded to: %v\n\tresolved to: %v\n\terror: %v", param, expdArgs, pkgLinkArgs, err))
-					}
-				}
-				aPkg.LinkArgs = append(aPkg.LinkArgs, pkgLinkArgs...)
-			}
 		default:
-			err := buildPkg(ctx, aPkg, verbose)
+			toBuild = append(toBuild, aPkg)
+		}
+	}
+	if err = compilePkgs(ctx, toBuild, verbose); err != nil {
+		return nil, err
+	}
+	// link args and the runtime initialization are settled in import order
+	for _, aPkg := range newPkgs {
+		pkg := aPkg.Package
+		switch kind, param := cl.PkgKindOf(pkg.Types); kind {
+		case cl.PkgDeclOnly, cl.PkgLinkIR, cl.PkgPyModule:
+		case cl.PkgLinkExtern:
+			// need to be linked with external library
+			pkgLinkArgs, nLibdir, err := expandLinkArgs(param)
 			if err != nil {
-				return nil, err
+				panic(err.Error())
 			}
-			setNeedRuntimeOrPyInit(ctx, pkg, aPkg.LPkg.NeedRuntime, aPkg.LPkg.NeedPyInit)
+			ctx.nLibdir += nLibdir
+			if ctx.buildConf.CheckLinkArgs {
+				if err := ctx.compiler().CheckLinkArgs(pkgLinkArgs, isWasmTarget(ctx.buildConf.Goos)); err != nil {
+					panic(fmt.Sprintf("test link args '%s' failed\n\tresolved to: %v\n\terror: %v", param, pkgLinkArgs, err))
+				}
+			}
+			aPkg.LinkArgs = append(aPkg.LinkArgs, pkgLinkArgs...)
+		default:
+			setNeedRuntimeOrPyInit(ctx, pkg, aPkg.NeedRt, aPkg.NeedPyInit)
 		}
 	}
 	return
 }
 
+// expandLinkArgs expands param of a "link: param" LLGoPackage declaration
+// to the link arguments of the external library. param is a ';' separated
+// list of alternative link methods, e.g.
+//
+//	link: $LLGO_LIB_PYTHON; $(pkg-config --libs python3-embed); -lpython3
+//
+// and the first one expanding to any arguments is used. nLibdir is the
+// number of library directories it adds.
+func expandLinkArgs(param string) (linkArgs []string, nLibdir int, err error) {
+	altParts := strings.Split(param, ";")
+	expdArgs := make([]string, 0, len(altParts))
+	for _, param := range altParts {
+		param = strings.TrimSpace(param)
+		if strings.ContainsRune(param, '$') {
+			expdArgs = append(expdArgs, xenv.ExpandEnvToArgs(param)...)
+			nLibdir++
+		} else {
+			fields := strings.Fields(param)
+			expdArgs = append(expdArgs, fields...)
+		}
+		if len(expdArgs) > 0 {
+			break
+		}
+	}
+	if len(expdArgs) == 0 {
+		return nil, 0, fmt.Errorf("'%s' cannot locate the external library", param)
+	}
+
+	linkArgs = make([]string, 0, 3)
+	if expdArgs[0][0] == '-' {
+		linkArgs = append(linkArgs, expdArgs...)
+	} else {
+		linkFile := expdArgs[0]
+		dir, lib := filepath.Split(linkFile)
+		linkArgs = append(linkArgs, "-l"+lib)
+		if dir != "" {
+			linkArgs = append(linkArgs, "-L"+dir)
+			nLibdir++
+		}
+	}
+	return
+}
+
+// checkLinkLibs checks that the external libraries of initial and their
+// dependencies, declared by LLGoPackage = "link: ...", can be linked, and
+// reports all the missing ones at once.
+func checkLinkLibs(ctx *context, initial []*packages.Package) error {
+	var errs []error
+	wasm := isWasmTarget(ctx.buildConf.Goos)
+	packages.Visit(initial, nil, func(pkg *packages.Package) {
+		if pkg.Types == nil {
+			return
+		}
+		kind, param := cl.PkgKindOf(pkg.Types)
+		if kind != cl.PkgLinkExtern {
+			return
+		}
+		linkArgs, _, err := expandLinkArgs(param)
+		if err == nil {
+			err = ctx.compiler().CheckLinkArgs(linkArgs, wasm)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: cannot find system library for link: %s: %w", pkg.PkgPath, param, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
 var (
 	errXflags = errors.New("-X flag requires argument of the form importpath.name=value")
 )
@@ -631,7 +766,7 @@ func compileExtraFiles(ctx *context, verbose bool) ([]string, error) {
 // based on configuration and build context.
 func generateOutputFilenames(outFile, binPath, appExt, binExt, pkgName string, mode Mode, isMultiplePkgs bool) (app, orgApp string, err error) {
 	if outFile == "" {
-		if mode == ModeBuild && isMultiplePkgs {
+		if mode == ModeBuild && isMultiplePkgs || mode == ModeFlash {
 			// For multiple packages in ModeBuild mode, use temporary file
 			name := pkgName
 			if binExt != "" {
@@ -684,13 +819,20 @@ func linkMainPkg(ctx *context, pkg *packages.Package, pkgs []*aPackage, global l
 	name := path.Base(pkgPath)
 	binFmt := ctx.crossCompile.BinaryFormat
 	binExt := firmware.BinaryExt(binFmt)
+	if mode == ModeFlash {
+		binExt = "" // the flash method decides the firmware format
+	}
+	appExt := conf.AppExt
+	if conf.BuildMode.isLib() {
+		appExt = libExt(conf.BuildMode, conf.Goos)
+	}
 
 	// app: converted firmware output file or executable file
 	// orgApp: before converted output file
 	app, orgApp, err := generateOutputFilenames(
 		conf.OutFile,
 		conf.BinPath,
-		conf.AppExt,
+		appExt,
 		binExt,
 		name,
 		mode,
@@ -756,9 +898,29 @@ func linkMainPkg(ctx *context, pkg *packages.Package, pkgs []*aPackage, global l
 		}
 	}
 
-	err = linkObjFiles(ctx, orgApp, objFiles, linkArgs, verbose)
+	check(ctx.jobs.wait())
+	switch conf.BuildMode {
+	case BuildModeCArchive:
+		err = archiveObjFiles(ctx, app, objFiles, verbose)
+	case BuildModeCShared:
+		err = linkObjFiles(ctx, app, objFiles, append(linkArgs, "-shared"), verbose)
+	default:
+		err = linkObjFiles(ctx, orgApp, objFiles, linkArgs, verbose)
+	}
 	check(err)
+	if conf.BuildMode.isLib() {
+		header := strings.TrimSuffix(app, filepath.Ext(app)) + ".h"
+		var libArgs []string
+		if conf.BuildMode == BuildModeCArchive {
+			libArgs = linkArgs
+		}
+		check(genCHeader(pkg, header, conf.Goarch, libArgs))
+		return
+	}
 
+	if ctx.crossCompile.WITPackage != "" {
+		check(makeComponent(ctx, orgApp, verbose))
+	}
 	if orgApp != app {
 		fmt.Printf("cross compile: %#v\n", ctx.crossCompile)
 		err = firmware.MakeFirmwareImage(orgApp, app, ctx.crossCompile.BinaryFormat, ctx.crossCompile.FormatDetail)
@@ -767,58 +929,131 @@ func linkMainPkg(ctx *context, pkg *packages.Package, pkgs []*aPackage, global l
 
 	switch mode {
 	case ModeTest:
-		cmd := exec.Command(app, conf.RunArgs...)
+		args := conf.RunArgs
+		profile := ""
+		if conf.CoverProfile != "" {
+			profile = app + ".cover"
+			defer os.Remove(profile)
+			args = append(slices.Clip(args), "-test.coverprofile="+profile)
+		}
+		cmd, timeout, err := runCommand(ctx, app, orgApp, args)
+		check(err)
 		cmd.Dir = pkg.Dir
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
-		cmd.Run()
-		if s := cmd.ProcessState; s != nil {
-			exitCode := s.ExitCode()
-			fmt.Fprintf(os.Stderr, "%s: exit code %d\n", app, exitCode)
-			if !ctx.testFail && exitCode != 0 {
-				ctx.testFail = true
-			}
+		exitCode, err := runWithTimeout(cmd, timeout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
 		}
-	case ModeRun:
-		args := make([]string, 0, len(conf.RunArgs)+1)
-		copy(args, conf.RunArgs)
-		if isWasmTarget(conf.Goos) {
-			wasmer := os.ExpandEnv(WasmRuntime())
-			wasmerArgs := strings.Split(wasmer, " ")
-			wasmerCmd := wasmerArgs[0]
-			wasmerArgs = wasmerArgs[1:]
-			switch wasmer {
-			case "wasmtime":
-				args = append(args, "--wasm", "multi-memory=true", app)
-				args = append(args, conf.RunArgs...)
-			case "iwasm":
-				args = append(args, "--stack-size=819200000", "--heap-size=800000000", app)
-				args = append(args, conf.RunArgs...)
-			default:
-				args = append(args, wasmerArgs...)
-				args = append(args, app)
-				args = append(args, conf.RunArgs...)
-			}
-			app = wasmerCmd
-		} else {
-			args = conf.RunArgs
+		if profile != "" {
+			check(appendCoverProfile(conf.CoverProfile, profile))
+		}
+		fmt.Fprintf(os.Stderr, "%s: exit code %d\n", app, exitCode)
+		if !ctx.testFail && exitCode != 0 {
+			ctx.testFail = true
 		}
-		cmd := exec.Command(app, args...)
+	case ModeRun:
+		cmd, timeout, err := runCommand(ctx, app, orgApp, conf.RunArgs)
+		check(err)
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
-		err = cmd.Run()
+		exitCode, err := runWithTimeout(cmd, timeout)
 		if err != nil {
-			panic(err)
-		}
-		if s := cmd.ProcessState; s != nil {
-			mockable.Exit(s.ExitCode())
+			fmt.Fprintln(os.Stderr, err)
 		}
+		mockable.Exit(exitCode)
 	case ModeCmpTest:
 		cmpTest(filepath.Dir(pkg.GoFiles[0]), pkgPath, app, conf.GenExpect, conf.RunArgs)
+	case ModeFlash:
+		defer os.Remove(app)
+		config, err := targets.NewDefaultResolver().Resolve(conf.Target)
+		check(err)
+		check(flash.Flash(config, app, conf.Port, verbose))
 	}
 }
 
+// defaultEmulatorTimeout is the timeout of programs run under an emulator,
+// which hang instead of exiting if they crash on some targets.
+const defaultEmulatorTimeout = 10 * time.Minute
+
+// runCommand returns the command running app, linked from orgApp, with args
+// and its timeout. It runs under the emulator of the target if it has one,
+// or the wasm runtime for wasm programs.
+func runCommand(ctx *context, app, orgApp string, args []string) (*exec.Cmd, time.Duration, error) {
+	conf := ctx.buildConf
+	timeout := conf.Timeout
+	if emulator := ctx.crossCompile.Emulator; emulator != "" {
+		img := ""
+		if strings.Contains(emulator, "{img}") {
+			img = orgApp + ".img"
+			err := firmware.MakeFirmwareImage(orgApp, img, ctx.crossCompile.BinaryFormat+"-img", ctx.crossCompile.FormatDetail)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		emuArgs, err := ctx.crossCompile.EmulatorArgs(orgApp, img, args)
+		if err != nil {
+			return nil, 0, err
+		}
+		if timeout == 0 {
+			timeout = defaultEmulatorTimeout
+		}
+		if conf.Verbose {
+			fmt.Fprintln(os.Stderr, strings.Join(emuArgs, " "))
+		}
+		return exec.Command(emuArgs[0], emuArgs[1:]...), timeout, nil
+	}
+	if isWasmTarget(conf.Goos) {
+		wasmer := os.ExpandEnv(WasmRuntime())
+		wasmerArgs := strings.Split(wasmer, " ")
+		wasmerCmd := wasmerArgs[0]
+		wasmerArgs = wasmerArgs[1:]
+		var runArgs []string
+		switch wasmer {
+		case "wasmtime":
+			runArgs = append(runArgs, "--wasm", "multi-memory=true", app)
+		case "iwasm":
+			runArgs = append(runArgs, "--stack-size=819200000", "--heap-size=800000000", app)
+		default:
+			runArgs = append(runArgs, wasmerArgs...)
+			runArgs = append(runArgs, app)
+		}
+		return exec.Command(wasmerCmd, append(runArgs, args...)...), timeout, nil
+	}
+	return exec.Command(app, args...), timeout, nil
+}
+
+// runWithTimeout runs cmd and returns its exit code. cmd is killed if it is
+// still running after timeout, unless timeout is 0.
+func runWithTimeout(cmd *exec.Cmd, timeout time.Duration) (exitCode int, err error) {
+	if err = cmd.Start(); err != nil {
+		return 1, err
+	}
+	var timedOut atomic.Bool
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			timedOut.Store(true)
+			cmd.Process.Kill()
+		})
+		defer timer.Stop()
+	}
+	err = cmd.Wait()
+	if timedOut.Load() {
+		return 1, fmt.Errorf("%s: timed out after %v", filepath.Base(cmd.Path), timeout)
+	}
+	if e, ok := err.(*exec.ExitError); ok {
+		if exitCode = e.ExitCode(); exitCode < 0 {
+			return 1, err // killed by a signal
+		}
+		return exitCode, nil
+	}
+	if err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
 func linkObjFiles(ctx *context, app string, objFiles, linkArgs []string, verbose bool) error {
 	buildArgs := []string{"-o", app}
 	buildArgs = append(buildArgs, linkArgs...)
@@ -835,20 +1070,42 @@ func linkObjFiles(ctx *context, app string, objFiles, linkArgs []string, verbose
 	return cmd.Link(buildArgs...)
 }
 
+// makeComponent turns the WebAssembly module app into a component of the
+// WIT world of the target with wasm-tools.
+func makeComponent(ctx *context, app string, verbose bool) error {
+	export := &ctx.crossCompile
+	if _, err := os.Stat(export.WITPackage); err != nil {
+		// the WIT files aren't in every LLGO_ROOT, leave the core module
+		fmt.Fprintf(os.Stderr, "warning: WIT package %s not found, %s is not a component\n", export.WITPackage, app)
+		return nil
+	}
+	embed := []string{"component", "embed", export.WITPackage, app, "-o", app}
+	if export.WITWorld != "" {
+		embed = append(embed, "--world", export.WITWorld)
+	}
+	for _, args := range [][]string{embed, {"component", "new", app, "-o", app}} {
+		if verbose {
+			fmt.Fprintln(os.Stderr, "wasm-tools", strings.Join(args, " "))
+		}
+		cmd := exec.Command("wasm-tools", args...)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("wasm-tools %s %s failed: %w", args[0], args[1], err)
+		}
+	}
+	return nil
+}
+
 func isWasmTarget(goos string) bool {
 	return slices.Contains([]string{"wasi", "js", "wasip1"}, goos)
 }
 
-func needStart(conf *Config) bool {
-	if conf.Target == "" {
-		return !isWasmTarget(conf.Goos)
-	}
-	switch conf.Target {
-	case "wasip2":
-		return false
-	default:
-		return true
+func needStart(ctx *context) bool {
+	if ctx.buildConf.Target == "" {
+		return !isWasmTarget(ctx.buildConf.Goos)
 	}
+	// c-shared targets, such as wasip2, are entered by their host
+	return ctx.crossCompile.BuildMode != "c-shared"
 }
 
 func genMainModuleFile(ctx *context, rtPkgPath string, pkg *packages.Package, needRuntime, needPyInit bool) (path string, err error) {
@@ -891,8 +1148,12 @@ call i32 @setvbuf(ptr %stdout_ptr, ptr null, i32 2, %size_t 0)
 call i32 @setvbuf(ptr %stderr_ptr, ptr null, i32 2, %size_t 0)
 	`
 	}
+	if ctx.buildConf.BuildMode.isLib() {
+		return genLibInitFile(ctx, pkg, declSizeT, stdioDecl, stdioNobuf,
+			pyInitDecl+"\n"+rtInitDecl, pyInit+"\n  "+rtInit)
+	}
 	// TODO(lijie): workaround for libc-free
-	// Remove main/_start when -buildmode and libc are ready
+	// Remove main/_start when libc is ready
 	startDefine := `
 define weak void @_start() {
   ; argc = 0
@@ -904,10 +1165,10 @@ define weak void @_start() {
 }
 `
 	mainDefine := "define i32 @main(i32 noundef %0, ptr nocapture noundef readnone %1) local_unnamed_addr"
-	if !needStart(ctx.buildConf) && isWasmTarget(ctx.buildConf.Goos) {
+	if !needStart(ctx) && isWasmTarget(ctx.buildConf.Goos) {
 		mainDefine = "define hidden noundef i32 @__main_argc_argv(i32 noundef %0, ptr nocapture noundef readnone %1) local_unnamed_addr"
 	}
-	if !needStart(ctx.buildConf) {
+	if !needStart(ctx) {
 		startDefine = ""
 	}
 	mainCode := fmt.Sprintf(`; ModuleID = 'main'
@@ -955,7 +1216,9 @@ func is32Bits(goarch string) bool {
 	return goarch == "386" || goarch == "arm" || goarch == "mips" || goarch == "wasm"
 }
 
-func buildPkg(ctx *context, aPkg *aPackage, verbose bool) error {
+// buildPkg compiles a package by c after the generic instances compiled in
+// its dependencies.
+func buildPkg(ctx *context, c *pkgCompiler, aPkg *aPackage, generics []string, verbose bool) error {
 	pkg := aPkg.Package
 	pkgPath := pkg.PkgPath
 	if debugBuild || verbose {
@@ -965,26 +1228,40 @@ func buildPkg(ctx *context, aPkg *aPackage, verbose bool) error {
 		pkg.ExportFile = ""
 		return nil
 	}
-	var syntax = pkg.Syntax
-	if altPkg := aPkg.AltPkg; altPkg != nil {
-		syntax = append(syntax, altPkg.Syntax...)
+	var cacheKey string
+	if ctx.cache != nil && pkg.ExportFile != "" && !hasGlobals(ctx, pkg) {
+		cacheKey = ctx.cache.entryKey(pkg, generics)
+		if ctx.cache.restore(ctx, c.prog, aPkg, cacheKey) {
+			if debugBuild || verbose {
+				fmt.Fprintf(os.Stderr, "==> Cached %s: %s\n", aPkg.PkgPath, pkg.ExportFile)
+			}
+			return nil
+		}
 	}
 	showDetail := verbose && pkgExists(ctx.initial, pkg)
 	if showDetail {
 		llssa.SetDebug(llssa.DbgFlagAll)
 		cl.SetDebug(cl.DbgFlagAll)
 	}
+	// Like -gcflags=-m of gc, the escape analysis decisions are only printed
+	// for the packages named on the command line. Packages are compiled one
+	// at a time then.
+	if IsEscapeDebugEnabled() {
+		cl.EnableEscapeDebug(pkgExists(ctx.initial, pkg))
+	}
 
-	ret, externs, err := cl.NewPackageEx(ctx.prog, ctx.patches, aPkg.SSA, syntax)
+	ret, externs, err := cl.NewPackageEx(c.prog, ctx.patches, aPkg.SSA, aPkg.syntax(), embedCfg(pkg))
 	if showDetail {
 		llssa.SetDebug(0)
 		cl.SetDebug(0)
 	}
 	check(err)
 
-	ctx.cTransformer.TransformModule(ret.Path(), ret.Module())
+	c.cTransformer.TransformModule(ret.Path(), ret.Module())
 
 	aPkg.LPkg = ret
+	aPkg.Compiled, aPkg.Generics = true, genericsOf(ret)
+	aPkg.NeedRt, aPkg.NeedPyInit = ret.NeedRuntime, ret.NeedPyInit
 	cgoLLFiles, cgoLdflags, err := buildCgo(ctx, aPkg, aPkg.Package.Syntax, externs, verbose)
 	if err != nil {
 		return fmt.Errorf("build cgo of %v failed: %v", pkgPath, err)
@@ -1000,6 +1277,7 @@ func buildPkg(ctx *context, aPkg *aPackage, verbose bool) error {
 		aPkg.LLFiles = append(aPkg.LLFiles, altLLFiles...)
 		aPkg.LLFiles = append(aPkg.LLFiles, concatPkgLinkFiles(ctx, aPkg.AltPkg.Package, verbose)...)
 		aPkg.LinkArgs = append(aPkg.LinkArgs, altLdflags...)
+		cgoLdflags = append(cgoLdflags, altLdflags...)
 	}
 	if pkg.ExportFile != "" {
 		pkg.ExportFile, err = exportObject(ctx, pkg.PkgPath, pkg.ExportFile, []byte(ret.String()))
@@ -1009,10 +1287,24 @@ func buildPkg(ctx *context, aPkg *aPackage, verbose bool) error {
 		if debugBuild || verbose {
 			fmt.Fprintf(os.Stderr, "==> Export %s: %s\n", aPkg.PkgPath, pkg.ExportFile)
 		}
+		if cacheKey != "" {
+			ctx.jobs.onDone(func() {
+				if err := ctx.cache.store(aPkg, cgoLdflags, cacheKey); err != nil && verbose {
+					fmt.Fprintf(os.Stderr, "==> Cache %s failed: %v\n", aPkg.PkgPath, err)
+				}
+			})
+		}
 	}
 	return nil
 }
 
+// hasGlobals reports whether some globals of pkg are set by -X flags. Objects
+// of such packages are rewritten after build, so they bypass the build cache.
+func hasGlobals(ctx *context, pkg *packages.Package) bool {
+	_, ok := ctx.buildConf.GlobalNames[pkg.PkgPath]
+	return ok
+}
+
 func exportObject(ctx *context, pkgPath string, exportFile string, data []byte) (string, error) {
 	f, err := os.CreateTemp("", "llgo-*.ll")
 	if err != nil {
@@ -1041,8 +1333,15 @@ func exportObject(ctx *context, pkgPath string, exportFile string, data []byte)
 	if ctx.buildConf.Verbose {
 		fmt.Fprintln(os.Stderr, "clang", args)
 	}
-	cmd := ctx.compiler()
-	return exportFile, cmd.Compile(args...)
+	ctx.jobs.run(func() error {
+		defer os.Remove(f.Name())
+		cmd := ctx.compiler()
+		if err := cmd.Compile(args...); err != nil {
+			return fmt.Errorf("compile %v failed: %v", pkgPath, err)
+		}
+		return nil
+	})
+	return exportFile, nil
 }
 
 func llcCheck(env *llvm.Env, exportFile string) (msg string, err error) {
@@ -1098,6 +1397,21 @@ type aPackage struct {
 
 	LinkArgs []string
 	LLFiles  []string
+
+	NeedRt     bool
+	NeedPyInit bool
+
+	Compiled bool     // compiled or reused from the build cache
+	Generics []string // generic instances compiled in the package
+}
+
+// syntax returns the files of the package and of its alternative package.
+func (p *aPackage) syntax() []*ast.File {
+	syntax := p.Syntax
+	if altPkg := p.AltPkg; altPkg != nil {
+		syntax = append(syntax[:len(syntax):len(syntax)], altPkg.Syntax...)
+	}
+	return syntax
 }
 
 type Package = *aPackage
@@ -1105,6 +1419,7 @@ type Package = *aPackage
 func allPkgs(ctx *context, initial []*packages.Package, verbose bool) (all []*aPackage, errs []*packages.Package) {
 	prog := ctx.progSSA
 	built := ctx.built
+	var created []*ssa.Package
 	packages.Visit(initial, nil, func(p *packages.Package) {
 		if p.Types != nil && !p.IllTyped {
 			pkgPath := p.PkgPath
@@ -1112,30 +1427,47 @@ func allPkgs(ctx *context, initial []*packages.Package, verbose bool) (all []*aP
 				return
 			}
 			var altPkg *packages.Cached
-			var ssaPkg = createSSAPkg(prog, p, verbose)
+			var ssaPkg, isNew = createSSAPkg(prog, p, verbose)
+			if isNew {
+				created = append(created, ssaPkg)
+			}
 			if llruntime.HasAltPkg(pkgPath) {
 				if altPkg = ctx.dedup.Check(altPkgPathPrefix + pkgPath); altPkg == nil {
 					return
 				}
 			}
-			all = append(all, &aPackage{p, ssaPkg, altPkg, nil, nil, nil})
+			all = append(all, &aPackage{Package: p, SSA: ssaPkg, AltPkg: altPkg})
 		} else {
 			errs = append(errs, p)
 		}
 	})
+	buildSSAPkgs(ctx, created)
 	return
 }
 
-func createSSAPkg(prog *ssa.Program, p *packages.Package, verbose bool) *ssa.Package {
-	pkgSSA := prog.ImportedPackage(p.ID)
+func createSSAPkg(prog *ssa.Program, p *packages.Package, verbose bool) (pkgSSA *ssa.Package, isNew bool) {
+	pkgSSA = prog.ImportedPackage(p.ID)
 	if pkgSSA == nil {
 		if debugBuild || verbose {
 			log.Println("==> BuildSSA", p.ID)
 		}
 		pkgSSA = prog.CreatePackage(p.Types, p.Syntax, p.TypesInfo, true)
-		pkgSSA.Build() // TODO(xsw): build concurrently
+		isNew = true
 	}
-	return pkgSSA
+	return
+}
+
+// buildSSAPkgs builds SSA code of packages concurrently. All of them must be
+// created before, since building a package refers to its dependencies.
+func buildSSAPkgs(ctx *context, pkgs []*ssa.Package) {
+	q := newJobQueue(cap(ctx.jobs.sem))
+	for _, pkg := range pkgs {
+		q.run(func() error {
+			pkg.Build()
+			return nil
+		})
+	}
+	check(q.wait())
 }
 
 /*
@@ -1146,7 +1478,6 @@ var (
 		"-a":         false, // -a: force rebuilding of packages that are already up-to-date
 		"-n":         false, // -n: print the commands but do not run them
 		"-p":         true,  // -p n: the number of programs to run in parallel
-		"-race":      false, // -race: enable data race detection
 		"-cover":     false, // -cover: enable coverage analysis
 		"-covermode": true,  // -covermode mode: set the mode for coverage analysis
 		"-v":         false, // -v: print the names of packages as they are compiled
@@ -1167,6 +1498,11 @@ const llgoWasmRuntime = "LLGO_WASM_RUNTIME"
 const llgoWasiThreads = "LLGO_WASI_THREADS"
 const llgoStdioNobuf = "LLGO_STDIO_NOBUF"
 const llgoFullRpath = "LLGO_FULL_RPATH"
+const llgoBuildCache = "LLGO_BUILD_CACHE"
+const llgoPCLine = "LLGO_PCLINE"
+const llgoEscape = "LLGO_ESCAPE"
+const llgoDebugEscape = "LLGO_DEBUG_ESCAPE"
+const llgoTypedAlloc = "LLGO_TYPED_ALLOC"
 
 const defaultWasmRuntime = "wasmtime"
 
@@ -1214,6 +1550,36 @@ func IsFullRpathEnabled() bool {
 	return isEnvOn(llgoFullRpath, true)
 }
 
+func IsBuildCacheEnabled() bool {
+	return isEnvOn(llgoBuildCache, true)
+}
+
+// IsPCLineEnabled reports whether the PC-line tables reporting Go function
+// names and lines at run time are generated, see cl.EnablePCLine.
+func IsPCLineEnabled() bool {
+	return isEnvOn(llgoPCLine, true)
+}
+
+// IsEscapeEnabled reports whether the values that don't escape from the
+// functions are allocated on the stack, see cl.EnableEscape.
+func IsEscapeEnabled() bool {
+	return isEnvOn(llgoEscape, true)
+}
+
+// IsEscapeDebugEnabled reports whether the escape analysis decisions are
+// printed.
+func IsEscapeDebugEnabled() bool {
+	return isEnvOn(llgoDebugEscape, false)
+}
+
+// IsTypedAllocEnabled reports whether the GC is told which words of the
+// allocated values hold pointers, see llssa.Program.EnableTypedAlloc. It
+// may be turned off for C bindings that keep Go pointers in memory typed
+// without pointers.
+func IsTypedAllocEnabled() bool {
+	return isEnvOn(llgoTypedAlloc, true)
+}
+
 func WasmRuntime() string {
 	return defaultEnv(llgoWasmRuntime, defaultWasmRuntime)
 }
@@ -1257,6 +1623,8 @@ func clFiles(ctx *context, files string, pkg *packages.Package, procFile func(li
 func clFile(ctx *context, args []string, cFile, expFile string, procFile func(linkFile string), verbose bool) {
 	llFile := expFile + filepath.Base(cFile)
 	ext := filepath.Ext(cFile)
+	// args is shared by the files of clFiles and read by the job later.
+	args = slices.Clip(args)
 
 	// default clang++ will use c++ to compile c file,will cause symbol be mangled
 	if ext == ".c" {
@@ -1272,9 +1640,13 @@ func clFile(ctx *context, args []string, cFile, expFile string, procFile func(li
 	if verbose {
 		fmt.Fprintln(os.Stderr, "clang", args)
 	}
-	cmd := ctx.compiler()
-	err := cmd.Compile(args...)
-	check(err)
+	ctx.jobs.run(func() error {
+		cmd := ctx.compiler()
+		if err := cmd.Compile(args...); err != nil {
+			return fmt.Errorf("compile %v failed: %v", cFile, err)
+		}
+		return nil
+	})
 	procFile(llFile)
 }
 
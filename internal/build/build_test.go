@@ -8,10 +8,16 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/goplus/llgo/internal/mockable"
+	"github.com/goplus/llgo/internal/packages"
 )
 
 func mockRun(args []string, cfg *Config) {
@@ -264,3 +270,184 @@ func TestGenerateOutputFilenames_EdgeCases(t *testing.T) {
 		os.Remove(orgApp)
 	}
 }
+
+func TestExpandLinkArgs(t *testing.T) {
+	t.Setenv("LLGO_TEST_LIB", "")
+	tests := []struct {
+		param   string
+		args    []string
+		nLibdir int
+	}{
+		{"-lm", []string{"-lm"}, 0},
+		{"$LLGO_TEST_LIB; -lz -lm", []string{"-lz", "-lm"}, 1},
+		{"/opt/lib/foo", []string{"-lfoo", "-L/opt/lib/"}, 1},
+		{"bar", []string{"-lbar"}, 0},
+	}
+	for _, tt := range tests {
+		args, nLibdir, err := expandLinkArgs(tt.param)
+		if err != nil {
+			t.Errorf("expandLinkArgs(%q): %v", tt.param, err)
+			continue
+		}
+		if strings.Join(args, " ") != strings.Join(tt.args, " ") || nLibdir != tt.nLibdir {
+			t.Errorf("expandLinkArgs(%q) = %v, %d, want %v, %d", tt.param, args, nLibdir, tt.args, tt.nLibdir)
+		}
+	}
+	if _, _, err := expandLinkArgs("$LLGO_TEST_LIB"); err == nil {
+		t.Error("expandLinkArgs: no error for a library not found")
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	exitCode, err := runWithTimeout(exec.Command("sh", "-c", "exit 3"), time.Minute)
+	if exitCode != 3 || err != nil {
+		t.Errorf("exit 3: got %d, %v", exitCode, err)
+	}
+	exitCode, err = runWithTimeout(exec.Command("sh", "-c", "exec sleep 10"), 100*time.Millisecond)
+	if exitCode != 1 || err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("sleep 10: got %d, %v", exitCode, err)
+	}
+	if _, err = runWithTimeout(exec.Command("/nonexistent/emulator"), 0); err == nil {
+		t.Error("missing command: no error")
+	}
+}
+
+func TestClFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	// The fake compiler writes its arguments to the output file.
+	cc := filepath.Join(dir, "cc")
+	script := "#!/bin/sh\nfor a; do [ \"$prev\" = -o ] && out=$a; prev=$a; done\necho \"$@\" > \"$out\"\n"
+	if err := os.WriteFile(cc, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &context{buildConf: &Config{}, jobs: newJobQueue(2)}
+	ctx.crossCompile.CC = cc
+	pkg := &packages.Package{GoFiles: []string{filepath.Join(dir, "main.go")}, ExportFile: filepath.Join(dir, "main")}
+	var objFiles []string
+	clFiles(ctx, "a.c; b.c", pkg, func(linkFile string) {
+		objFiles = append(objFiles, linkFile)
+	}, false)
+	if err := ctx.jobs.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(objFiles) != 2 {
+		t.Fatalf("objFiles = %v, want 2 files", objFiles)
+	}
+	for i, file := range []string{"a.c", "b.c"} {
+		data, err := os.ReadFile(objFiles[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "-c " + filepath.Join(dir, file); !strings.Contains(string(data), want) {
+			t.Errorf("%s compiled with %q, want %q", objFiles[i], strings.TrimSpace(string(data)), want)
+		}
+	}
+}
+
+func TestCheckSanitizers(t *testing.T) {
+	tests := []struct {
+		conf Config
+		err  string
+	}{
+		{Config{Goos: "linux", Goarch: "amd64"}, ""},
+		{Config{Goos: "linux", Goarch: "amd64", Race: true}, ""},
+		{Config{Goos: "darwin", Goarch: "arm64", Race: true}, ""},
+		{Config{Goos: "wasip1", Goarch: "wasm", Race: true}, "-race is not supported on wasip1/wasm"},
+		{Config{Goos: "linux", Goarch: "arm", Target: "rp2040", Race: true}, "-race is not supported with -target"},
+		{Config{Goos: "linux", Goarch: "amd64", ASan: true, UBSan: true}, ""},
+		{Config{Goos: "darwin", Goarch: "arm64", MSan: true}, "-msan is not supported on darwin/arm64"},
+		{Config{Goos: "linux", Goarch: "amd64", Race: true, ASan: true}, "-race and -asan are incompatible"},
+		{Config{Goos: "linux", Goarch: "amd64", ASan: true, MSan: true, UBSan: true}, "-asan and -msan are incompatible"},
+		{Config{Goos: "wasip1", Goarch: "wasm", UBSan: true}, "-ubsan is not supported on wasip1/wasm"},
+	}
+	for _, tt := range tests {
+		err := checkSanitizers(&tt.conf)
+		if got := fmt.Sprint(err); tt.err == "" && err != nil || tt.err != "" && got != tt.err {
+			t.Errorf("checkSanitizers(%+v) = %v, want %q", tt.conf, err, tt.err)
+		}
+	}
+	conf := Config{Race: true}
+	if got := conf.sanitizers(); !slices.Equal(got, []string{"thread"}) {
+		t.Errorf("sanitizers() = %v, want [thread]", got)
+	}
+	conf = Config{ASan: true, UBSan: true}
+	if got := conf.sanitizers(); !slices.Equal(got, []string{"address", "undefined"}) {
+		t.Errorf("sanitizers() = %v, want [address undefined]", got)
+	}
+	if got, want := sanitizeTags(&conf), []string{"sanitize.address", "nogc", "nosched", "sanitize.undefined"}; !slices.Equal(got, want) {
+		t.Errorf("sanitizeTags() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckCover(t *testing.T) {
+	tests := []struct {
+		conf Config
+		err  string
+	}{
+		{Config{Mode: ModeTest}, ""},
+		{Config{Mode: ModeTest, CoverMode: "count", CoverProfile: "c.out"}, ""},
+		{Config{Mode: ModeTest, CoverMode: "atomic", Race: true}, ""},
+		{Config{Mode: ModeTest, CoverMode: "set", Race: true}, `-covermode must be "atomic", not "set", when -race is enabled`},
+		{Config{Mode: ModeTest, CoverMode: "often"}, `invalid -covermode "often", must be set, count or atomic`},
+		{Config{Mode: ModeBuild, CoverMode: "set"}, "coverage is only supported by llgo test"},
+		{Config{Mode: ModeTest, CoverProfile: "c.out"}, "-coverprofile requires a coverage mode"},
+	}
+	for _, tt := range tests {
+		err := checkCover(&tt.conf)
+		if got := fmt.Sprint(err); tt.err == "" && err != nil || tt.err != "" && got != tt.err {
+			t.Errorf("checkCover(%+v) = %v, want %q", tt.conf, err, tt.err)
+		}
+	}
+}
+
+func TestCoverPackages(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module example.com/p\n\ngo 1.21\n",
+		"p.go":      "package p\n\nfunc F(x int) int {\n\tif x > 0 {\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+		"cgo.go":    "package p\n\nimport \"C\"\n\nfunc G() {}\n",
+		"p_test.go": "package p\n\nimport \"testing\"\n\nfunc TestF(t *testing.T) { F(1) }\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &packages.Config{
+		Dir:     dir,
+		Env:     append(os.Environ(), "CGO_ENABLED=1"),
+		Overlay: make(map[string][]byte),
+	}
+	conf := &Config{Mode: ModeTest, CoverMode: "count"}
+	if err := coverPackages(conf, cfg, []string{"."}); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Overlay) != 1 {
+		t.Fatalf("overlay has %d files, want p.go only", len(cfg.Overlay))
+	}
+	src := string(cfg.Overlay[filepath.Join(dir, "p.go")])
+	if !strings.Contains(src, `_register("count", "example.com/p/p.go",`) {
+		t.Errorf("p.go is not annotated:\n%s", src)
+	}
+
+	profile := filepath.Join(dir, "c.out")
+	part := filepath.Join(dir, "p.cover")
+	os.WriteFile(profile, []byte("mode: count\n"), 0644)
+	os.WriteFile(part, []byte("mode: count\nexample.com/p/p.go:3.19,4.11 1 2\n"), 0644)
+	if err := appendCoverProfile(profile, part); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendCoverProfile(profile, filepath.Join(dir, "none.cover")); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(profile)
+	if want := "mode: count\nexample.com/p/p.go:3.19,4.11 1 2\n"; string(data) != want {
+		t.Errorf("merged profile:\n%s\nwant:\n%s", data, want)
+	}
+}
@@ -0,0 +1,329 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"go/types"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goplus/llgo/cl"
+	"github.com/goplus/llgo/internal/env"
+	"github.com/goplus/llgo/internal/packages"
+
+	llruntime "github.com/goplus/llgo/runtime"
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// buildCache is a persistent, content-addressed cache of compiled package
+// objects. Its key of a package covers everything that affects the object:
+// the compiler itself, the build configuration, the package sources and the
+// keys of all its dependencies.
+type buildCache struct {
+	dir     string
+	salt    string
+	overlay map[string][]byte
+	dedup   packages.Deduper
+
+	mu   sync.Mutex // protects keys, as packages are compiled concurrently
+	keys map[*packages.Package]string
+}
+
+// cacheManifest describes a package object stored in the build cache.
+type cacheManifest struct {
+	PkgPath    string   `json:"pkgPath"`
+	LLFiles    []string `json:"llFiles,omitempty"`  // relative to the entry directory
+	LinkArgs   []string `json:"linkArgs,omitempty"` // cgo ldflags
+	NeedRt     bool     `json:"needRt,omitempty"`
+	NeedPyInit bool     `json:"needPyInit,omitempty"`
+	Generics   []string `json:"generics,omitempty"` // compiled generic instances
+}
+
+const (
+	cacheExportName   = "pkg.o"
+	cacheManifestName = "manifest.json"
+)
+
+func buildCacheDir() string {
+	return filepath.Join(env.LLGoCacheDir(), "build")
+}
+
+// newBuildCache returns nil if the build cache can't be used for ctx.
+func newBuildCache(ctx *context) *buildCache {
+	conf := ctx.buildConf
+	if !IsBuildCacheEnabled() || conf.ForceRebuild || conf.GenLL || conf.CheckLLFiles || ctx.mode == ModeGen || IsEscapeDebugEnabled() {
+		return nil
+	}
+	return &buildCache{
+		dir:     buildCacheDir(),
+		salt:    cacheSalt(ctx),
+		overlay: ctx.conf.Overlay,
+		dedup:   ctx.dedup,
+		keys:    make(map[*packages.Package]string),
+	}
+}
+
+// compilerID identifies the running llgo binary.
+var compilerID = sync.OnceValue(func() string {
+	h := sha256.New()
+	fmt.Fprintln(h, env.Version(), env.BuildTime())
+	if exe, err := os.Executable(); err == nil {
+		hashFile(h, exe, nil)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+})
+
+// cacheSalt returns the part of package keys shared by all packages of a build.
+func cacheSalt(ctx *context) string {
+	conf := ctx.buildConf
+	export := &ctx.crossCompile
+	h := sha256.New()
+	fmt.Fprintln(h, "compiler", compilerID())
+	fmt.Fprintln(h, "target", conf.Goos, conf.Goarch, conf.Target)
+	// the go.mod of llgo get is temporary, the files of the packages are
+	// hashed by their keys
+	flags := slices.DeleteFunc(slices.Clone(ctx.conf.BuildFlags), func(flag string) bool {
+		return strings.HasPrefix(flag, "-modfile=")
+	})
+	fmt.Fprintln(h, "flags", flags)
+	fmt.Fprintln(h, "abi", conf.AbiMode)
+	fmt.Fprintln(h, "debug", IsDbgEnabled(), IsDbgSymsEnabled(), IsTraceEnabled(), IsOptimizeEnabled(), IsEscapeEnabled(), IsTypedAllocEnabled(), IsPCLineEnabled())
+	fmt.Fprintln(h, "cc", export.CC, export.CCFLAGS, export.CFLAGS)
+	fmt.Fprintln(h, "sanitize", conf.sanitizers())
+	fmt.Fprintln(h, "env", os.Getenv("CCFLAGS"), os.Getenv("CFLAGS"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// key returns the cache key of a package.
+func (c *buildCache) key(pkg *packages.Package) string {
+	if key, ok := c.keys[pkg]; ok {
+		return key
+	}
+	c.keys[pkg] = "" // guard against import cycles
+	h := sha256.New()
+	fmt.Fprintln(h, "salt", c.salt)
+	fmt.Fprintln(h, "pkg", pkg.ID, pkg.PkgPath, pkg.Name)
+	c.hashPkgFiles(h, pkg, pkg.Types)
+	c.hashDeps(h, pkg)
+	if llruntime.HasAltPkg(pkg.PkgPath) && c.dedup != nil {
+		if alt := c.dedup.Check(altPkgPathPrefix + pkg.PkgPath); alt != nil {
+			fmt.Fprintln(h, "alt", alt.ID)
+			c.hashPkgFiles(h, alt.Package, alt.Types)
+			c.hashDeps(h, alt.Package)
+		}
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	c.keys[pkg] = key
+	return key
+}
+
+// entryKey returns the key of the cache entry of pkg compiled after the
+// generic instances. The object of pkg depends on them besides its key, as
+// the methods of generic types in its abi types are only emitted for the
+// instances already compiled.
+func (c *buildCache) entryKey(pkg *packages.Package, generics []string) string {
+	c.mu.Lock()
+	key := c.key(pkg)
+	c.mu.Unlock()
+	h := sha256.New()
+	fmt.Fprintln(h, "key", key)
+	for _, name := range generics {
+		fmt.Fprintln(h, "generic", name)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *buildCache) hashDeps(h hash.Hash, pkg *packages.Package) {
+	paths := make([]string, 0, len(pkg.Imports))
+	for path := range pkg.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintln(h, "import", path, c.key(pkg.Imports[path]))
+	}
+}
+
+// hashPkgFiles hashes the Go files of a package, the C files that may be
+// compiled with it (cgo sources, LLGoFiles) and the headers next to them.
+func (c *buildCache) hashPkgFiles(h hash.Hash, pkg *packages.Package, typs *types.Package) {
+	files := make(map[string]none)
+	for _, list := range [][]string{pkg.GoFiles, pkg.CompiledGoFiles, pkg.OtherFiles, pkg.EmbedFiles} {
+		for _, file := range list {
+			files[file] = none{}
+		}
+	}
+	dirs := make(map[string]none)
+	for _, file := range pkg.GoFiles {
+		dirs[filepath.Dir(file)] = none{}
+	}
+	if typs != nil && len(pkg.GoFiles) > 0 {
+		if o, ok := typs.Scope().Lookup("LLGoFiles").(*types.Const); ok {
+			if val := o.Val(); val.Kind() == constant.String {
+				llgoFiles := constant.StringVal(val)
+				fmt.Fprintln(h, "LLGoFiles", llgoFiles)
+				if pos := strings.IndexByte(llgoFiles, ':'); pos > 0 && strings.HasPrefix(llgoFiles, "$") {
+					llgoFiles = llgoFiles[pos+1:]
+				}
+				dir := filepath.Dir(pkg.GoFiles[0])
+				for _, file := range strings.Split(llgoFiles, ";") {
+					file = filepath.Join(dir, strings.TrimSpace(file))
+					files[file] = none{}
+					dirs[filepath.Dir(file)] = none{}
+				}
+			}
+		}
+	}
+	for dir := range dirs {
+		for _, pattern := range []string{"*.c", "*.h"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, match := range matches {
+				files[match] = none{}
+			}
+		}
+	}
+	names := make([]string, 0, len(files))
+	for file := range files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	for _, file := range names {
+		fmt.Fprintln(h, "file", file)
+		hashFile(h, file, c.overlay)
+	}
+}
+
+func hashFile(h hash.Hash, file string, overlay map[string][]byte) {
+	if data, ok := overlay[file]; ok {
+		h.Write(data)
+		return
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Fprintln(h, "error", err)
+		return
+	}
+	defer f.Close()
+	io.Copy(h, f)
+}
+
+func (c *buildCache) entryDir(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// restore reuses the cached object of a package compiled by prog. It returns
+// false if the package isn't cached.
+func (c *buildCache) restore(ctx *context, prog llssa.Program, aPkg *aPackage, key string) bool {
+	dir := c.entryDir(key)
+	data, err := os.ReadFile(filepath.Join(dir, cacheManifestName))
+	if err != nil {
+		return false
+	}
+	var m cacheManifest
+	if err = json.Unmarshal(data, &m); err != nil || m.PkgPath != aPkg.PkgPath {
+		return false
+	}
+	exportFile := aPkg.ExportFile + ".o"
+	if err = copyFile(filepath.Join(dir, cacheExportName), exportFile); err != nil {
+		return false
+	}
+	cl.ReusePackage(prog, ctx.patches, aPkg.SSA, aPkg.syntax())
+	aPkg.Compiled, aPkg.Generics = true, m.Generics
+	for _, file := range m.LLFiles {
+		aPkg.LLFiles = append(aPkg.LLFiles, filepath.Join(dir, file))
+	}
+	aPkg.LinkArgs = append(aPkg.LinkArgs, m.LinkArgs...)
+	aPkg.NeedRt, aPkg.NeedPyInit = m.NeedRt, m.NeedPyInit
+	aPkg.ExportFile = exportFile
+	return true
+}
+
+// store saves the compiled object of a package to the build cache.
+func (c *buildCache) store(aPkg *aPackage, cgoLdflags []string, key string) (err error) {
+	dir := c.entryDir(key)
+	if _, err = os.Stat(dir); err == nil {
+		return
+	}
+	parent := filepath.Dir(dir)
+	if err = os.MkdirAll(parent, 0755); err != nil {
+		return
+	}
+	tmpDir, err := os.MkdirTemp(parent, "tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err = copyFile(aPkg.ExportFile, filepath.Join(tmpDir, cacheExportName)); err != nil {
+		return
+	}
+	m := &cacheManifest{
+		PkgPath:    aPkg.PkgPath,
+		LinkArgs:   cgoLdflags,
+		NeedRt:     aPkg.NeedRt,
+		NeedPyInit: aPkg.NeedPyInit,
+		Generics:   aPkg.Generics,
+	}
+	for i, file := range aPkg.LLFiles {
+		name := fmt.Sprintf("%d-%s", i, filepath.Base(file))
+		if err = copyFile(file, filepath.Join(tmpDir, name)); err != nil {
+			return
+		}
+		m.LLFiles = append(m.LLFiles, name)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	if err = os.WriteFile(filepath.Join(tmpDir, cacheManifestName), data, 0644); err != nil {
+		return
+	}
+	if err = os.Rename(tmpDir, dir); err != nil {
+		if _, e := os.Stat(dir); e == nil { // stored by another llgo process
+			err = nil
+		}
+	}
+	return
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
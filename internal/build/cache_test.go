@@ -0,0 +1,105 @@
+//go:build !llgo
+// +build !llgo
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goplus/llgo/internal/packages"
+)
+
+func newTestCache(salt string) *buildCache {
+	return &buildCache{salt: salt, keys: make(map[*packages.Package]string)}
+}
+
+func writeFile(t *testing.T, file, data string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	depFile := filepath.Join(dir, "dep.go")
+	mainFile := filepath.Join(dir, "main.go")
+	writeFile(t, depFile, "package dep\n")
+	writeFile(t, mainFile, "package main\n")
+
+	dep := &packages.Package{ID: "dep", PkgPath: "dep", Name: "dep", GoFiles: []string{depFile}}
+	main := &packages.Package{ID: "main", PkgPath: "main", Name: "main", GoFiles: []string{mainFile},
+		Imports: map[string]*packages.Package{"dep": dep}}
+
+	key := newTestCache("salt").key(main)
+	if key == "" {
+		t.Fatal("empty key")
+	}
+	if got := newTestCache("salt").key(main); got != key {
+		t.Errorf("key is not stable: %s != %s", got, key)
+	}
+	if got := newTestCache("other").key(main); got == key {
+		t.Error("key doesn't depend on salt")
+	}
+
+	writeFile(t, depFile, "package dep\n\nvar X int\n")
+	if got := newTestCache("salt").key(main); got == key {
+		t.Error("key doesn't depend on dependencies")
+	}
+
+	c := newTestCache("salt")
+	c.overlay = map[string][]byte{mainFile: []byte("package main\n\nfunc main() {}\n")}
+	if got := c.key(main); got == key {
+		t.Error("key doesn't depend on overlay files")
+	}
+
+	writeFile(t, filepath.Join(dir, "foo.c"), "int foo() { return 0; }\n")
+	before := newTestCache("salt").key(main)
+	writeFile(t, filepath.Join(dir, "foo.c"), "int foo() { return 1; }\n")
+	if got := newTestCache("salt").key(main); got == before {
+		t.Error("key doesn't depend on C files")
+	}
+}
+
+func TestBuildCacheEntryKey(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	writeFile(t, file, "package main\n")
+	main := &packages.Package{ID: "main", PkgPath: "main", Name: "main", GoFiles: []string{file}}
+
+	c := newTestCache("salt")
+	key := c.entryKey(main, nil)
+	if got := c.entryKey(main, nil); got != key {
+		t.Errorf("entry key is not stable: %s != %s", got, key)
+	}
+	withList := c.entryKey(main, []string{"main.List[int].Push"})
+	if withList == key {
+		t.Error("entry key doesn't depend on the compiled generic instances")
+	}
+	if got := c.entryKey(main, []string{"main.List[string].Push"}); got == withList {
+		t.Error("entry key doesn't depend on the names of the generic instances")
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	writeFile(t, src, "hello")
+	writeFile(t, dst, "old content")
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("copyFile: got %q", data)
+	}
+	if err := copyFile(filepath.Join(dir, "none"), dst); err == nil {
+		t.Error("copyFile: expected error for missing source")
+	}
+}
@@ -0,0 +1,404 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/goplus/llgo/internal/packages"
+)
+
+type BuildMode string
+
+const (
+	BuildModeExe      BuildMode = "exe"
+	BuildModeCArchive BuildMode = "c-archive"
+	BuildModeCShared  BuildMode = "c-shared"
+)
+
+// isLib reports whether the build mode produces a library for C instead of
+// an executable.
+func (m BuildMode) isLib() bool {
+	return m == BuildModeCArchive || m == BuildModeCShared
+}
+
+func checkBuildMode(conf *Config) error {
+	switch conf.BuildMode {
+	case "", BuildModeExe:
+		return nil
+	case BuildModeCArchive, BuildModeCShared:
+		if conf.Mode != ModeBuild {
+			return fmt.Errorf("-buildmode=%s is only supported by llgo build", conf.BuildMode)
+		}
+		if conf.Target != "" || isWasmTarget(conf.Goos) {
+			return fmt.Errorf("-buildmode=%s is not supported on this target", conf.BuildMode)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported -buildmode=%s", conf.BuildMode)
+}
+
+// libExt returns the extension of libraries built in a library build mode.
+func libExt(mode BuildMode, goos string) string {
+	if mode == BuildModeCArchive {
+		return ".a"
+	}
+	switch goos {
+	case "darwin":
+		return ".dylib"
+	case "windows":
+		return ".dll"
+	}
+	return ".so"
+}
+
+// genLibInitFile generates the entry module of a library build mode. There is
+// no main function: the runtime and packages are initialized by a constructor
+// that runs when the library is loaded (or the program is started).
+func genLibInitFile(ctx *context, pkg *packages.Package, declSizeT, stdioDecl, stdioNobuf, initDecls, initCalls string) (string, error) {
+	code := fmt.Sprintf(`; ModuleID = 'main'
+source_filename = "main"
+%s
+@__llgo_argc = global i32 0, align 4
+@__llgo_argv = global ptr null, align 8
+%s
+%s
+declare void @"%s.init"()
+define weak void @runtime.init() {
+  ret void
+}
+
+; TODO(lijie): workaround for syscall patch
+define weak void @"syscall.init"() {
+  ret void
+}
+
+@llvm.global_ctors = appending global [1 x { i32, ptr, ptr }] [{ i32, ptr, ptr } { i32 65535, ptr @__llgo_init, ptr null }]
+
+define internal void @__llgo_init() {
+_llgo_0:
+  %s
+  %s
+  call void @runtime.init()
+  call void @"%s.init"()
+  ret void
+}
+`, declSizeT, stdioDecl, initDecls, pkg.PkgPath, stdioNobuf, initCalls, pkg.PkgPath)
+	return exportObject(ctx, pkg.PkgPath+".main", pkg.ExportFile+"-main", []byte(code))
+}
+
+// archiveObjFiles creates a C archive of objFiles. The objects are linked into
+// one relocatable object first, so the constructor initializing the library
+// is always linked in by users of the archive.
+func archiveObjFiles(ctx *context, app string, objFiles []string, verbose bool) error {
+	obj := app + ".o"
+	defer os.Remove(obj)
+	cmd := ctx.linker()
+	cmd.Verbose = verbose
+	// LDFLAGS of executables (e.g. --gc-sections) don't apply to partial links
+	args := []string{"-r", "-nostdlib", "-o", obj}
+	ldflags := ctx.crossCompile.LDFLAGS
+	for i, flag := range ldflags {
+		if flag == "-target" && i+1 < len(ldflags) {
+			args = append(args, flag, ldflags[i+1])
+		} else if strings.HasPrefix(flag, "--target=") || strings.HasPrefix(flag, "-fuse-ld=") {
+			args = append(args, flag)
+		}
+	}
+	if err := cmd.Exec(append(args, objFiles...)...); err != nil {
+		return err
+	}
+	ar := "llvm-ar"
+	if _, err := exec.LookPath(ar); err != nil {
+		ar = "ar"
+	}
+	os.Remove(app)
+	arCmd := exec.Command(ar, "qcs", app, obj)
+	if verbose {
+		fmt.Fprintf(os.Stderr, "%v\n", arCmd)
+	}
+	arCmd.Stdout = os.Stdout
+	arCmd.Stderr = os.Stderr
+	return arCmd.Run()
+}
+
+// cExport is a function exported to C by //export.
+type cExport struct {
+	name string
+	sig  *types.Signature
+}
+
+// cExports returns functions of pkg exported by //export, and the cgo
+// preambles of files declaring them.
+func cExports(pkg *packages.Package) (exports []cExport, preambles []string, err error) {
+	for _, file := range pkg.Syntax {
+		hasExport := false
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Doc == nil {
+				continue
+			}
+			for _, c := range fn.Doc.List {
+				if !strings.HasPrefix(c.Text, "//export ") {
+					continue
+				}
+				name := strings.TrimSpace(c.Text[len("//export "):])
+				if name != fn.Name.Name {
+					continue
+				}
+				obj, ok := pkg.TypesInfo.Defs[fn.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				exports = append(exports, cExport{name, obj.Type().(*types.Signature)})
+				hasExport = true
+			}
+		}
+		if hasExport {
+			preamble, e := cgoPreambleOf(pkg.Fset, file)
+			if e != nil {
+				return nil, nil, e
+			}
+			if preamble != "" {
+				preambles = append(preambles, preamble)
+			}
+		}
+	}
+	return
+}
+
+func cgoPreambleOf(fset *token.FileSet, file *ast.File) (string, error) {
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT && decl.Doc != nil && len(decl.Specs) == 1 {
+			if spec := decl.Specs[0].(*ast.ImportSpec); spec.Path.Value == `"unsafe"` || spec.Path.Value == `"C"` {
+				preamble, _, err := parseCgoPreamble(fset.Position(decl.Doc.Pos()), decl.Doc.Text())
+				return preamble.src, err
+			}
+		}
+	}
+	return "", nil
+}
+
+const cHeaderPrologue = `#include <stddef.h>
+
+#ifndef GO_CGO_PROLOGUE_H
+#define GO_CGO_PROLOGUE_H
+
+#ifdef __cplusplus
+typedef bool GoBool;
+#else
+typedef _Bool GoBool;
+#endif
+typedef signed char GoInt8;
+typedef unsigned char GoUint8;
+typedef short GoInt16;
+typedef unsigned short GoUint16;
+typedef int GoInt32;
+typedef unsigned int GoUint32;
+typedef long long GoInt64;
+typedef unsigned long long GoUint64;
+typedef %s GoInt;
+typedef %s GoUint;
+typedef size_t GoUintptr;
+typedef float GoFloat32;
+typedef double GoFloat64;
+typedef float _Complex GoComplex64;
+typedef double _Complex GoComplex128;
+
+typedef struct { const char *p; ptrdiff_t n; } _GoString_;
+typedef _GoString_ GoString;
+typedef void *GoMap;
+typedef void *GoChan;
+typedef struct { void *t; void *v; } GoInterface;
+typedef struct { void *data; GoInt len; GoInt cap; } GoSlice;
+
+#endif
+`
+
+// genCHeader writes the C header declaring functions exported by pkg.
+func genCHeader(pkg *packages.Package, file, goarch string, linkArgs []string) error {
+	exports, preambles, err := cExports(pkg)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("/* Code generated by llgo -buildmode. DO NOT EDIT. */\n\n")
+	if len(linkArgs) > 0 {
+		fmt.Fprintf(&b, "/* Link with: %s */\n\n", strings.Join(linkArgs, " "))
+	}
+	for _, preamble := range preambles {
+		b.WriteString("/* Start of preamble from import \"C\" comments. */\n\n")
+		b.WriteString(preamble)
+		b.WriteString("\n/* End of preamble from import \"C\" comments. */\n\n")
+	}
+	goInt, goUint := "long long", "unsigned long long"
+	if is32Bits(goarch) {
+		goInt, goUint = "int", "unsigned int"
+	}
+	fmt.Fprintf(&b, cHeaderPrologue, goInt, goUint)
+	b.WriteString("\n#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+	for _, exp := range exports {
+		decl, err := cFuncDecl(exp)
+		if err != nil {
+			return err
+		}
+		b.WriteString(decl)
+	}
+	b.WriteString("\n#ifdef __cplusplus\n}\n#endif\n")
+	return os.WriteFile(file, []byte(b.String()), 0644)
+}
+
+func cFuncDecl(exp cExport) (string, error) {
+	var b strings.Builder
+	sig := exp.sig
+	if sig.Variadic() {
+		return "", fmt.Errorf("//export %s: variadic functions are not supported", exp.name)
+	}
+	results := sig.Results()
+	ret := "void"
+	switch results.Len() {
+	case 0:
+	case 1:
+		t, err := cTypeOf(results.At(0).Type())
+		if err != nil {
+			return "", fmt.Errorf("//export %s: %v", exp.name, err)
+		}
+		ret = t
+	default:
+		// multiple results are returned as a struct, like cgo does
+		ret = "struct " + exp.name + "_return"
+		fmt.Fprintf(&b, "%s {\n", ret)
+		for i := 0; i < results.Len(); i++ {
+			t, err := cTypeOf(results.At(i).Type())
+			if err != nil {
+				return "", fmt.Errorf("//export %s: %v", exp.name, err)
+			}
+			fmt.Fprintf(&b, "\t%s r%d;\n", t, i)
+		}
+		b.WriteString("};\n")
+	}
+	params := sig.Params()
+	args := make([]string, params.Len())
+	for i := range args {
+		t, err := cTypeOf(params.At(i).Type())
+		if err != nil {
+			return "", fmt.Errorf("//export %s: %v", exp.name, err)
+		}
+		name := params.At(i).Name()
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("p%d", i)
+		}
+		args[i] = t + " " + name
+	}
+	if len(args) == 0 {
+		args = []string{"void"}
+	}
+	fmt.Fprintf(&b, "extern %s %s(%s);\n", ret, exp.name, strings.Join(args, ", "))
+	return b.String(), nil
+}
+
+var cgoCTypes = map[string]string{
+	"schar":     "signed char",
+	"uchar":     "unsigned char",
+	"ushort":    "unsigned short",
+	"uint":      "unsigned int",
+	"ulong":     "unsigned long",
+	"longlong":  "long long",
+	"ulonglong": "unsigned long long",
+}
+
+// cTypeOf returns the C type of a Go type in function signatures exported to C.
+func cTypeOf(t types.Type) (string, error) {
+	if named, ok := t.(*types.Named); ok {
+		// C types of cgo, e.g. _Ctype_int, _Ctype_struct_foo
+		if name := named.Obj().Name(); strings.HasPrefix(name, "_Ctype_") {
+			name = name[len("_Ctype_"):]
+			if ctype, ok := cgoCTypes[name]; ok {
+				return ctype, nil
+			}
+			if strings.HasPrefix(name, "struct_") {
+				return "struct " + name[len("struct_"):], nil
+			}
+			return name, nil
+		}
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			return "GoBool", nil // i1 in the IR, as _Bool of C
+		case types.Int:
+			return "GoInt", nil
+		case types.Int8:
+			return "GoInt8", nil
+		case types.Int16:
+			return "GoInt16", nil
+		case types.Int32:
+			return "GoInt32", nil
+		case types.Int64:
+			return "GoInt64", nil
+		case types.Uint:
+			return "GoUint", nil
+		case types.Uint8:
+			return "GoUint8", nil
+		case types.Uint16:
+			return "GoUint16", nil
+		case types.Uint32:
+			return "GoUint32", nil
+		case types.Uint64:
+			return "GoUint64", nil
+		case types.Uintptr:
+			return "GoUintptr", nil
+		case types.Float32:
+			return "GoFloat32", nil
+		case types.Float64:
+			return "GoFloat64", nil
+		case types.Complex64:
+			return "GoComplex64", nil
+		case types.Complex128:
+			return "GoComplex128", nil
+		case types.String:
+			return "GoString", nil
+		case types.UnsafePointer:
+			return "void*", nil
+		}
+	case *types.Pointer:
+		if elem, ok := t.Elem().(*types.Named); ok && strings.HasPrefix(elem.Obj().Name(), "_Ctype_") {
+			ctype, err := cTypeOf(elem)
+			return ctype + "*", err
+		}
+		return "void*", nil
+	case *types.Slice:
+		return "GoSlice", nil
+	case *types.Map:
+		return "GoMap", nil
+	case *types.Chan:
+		return "GoChan", nil
+	case *types.Interface:
+		return "GoInterface", nil
+	case *types.Signature:
+		// func values are closures of two words, which C can't call. cgo
+		// rejects them too.
+	}
+	return "", fmt.Errorf("Go type not supported in export: %v", t)
+}
@@ -0,0 +1,157 @@
+//go:build !llgo
+// +build !llgo
+
+package build
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llgo/internal/packages"
+)
+
+const cExportSrc = `package main
+
+type _Ctype_int int32
+
+type _Ctype_struct_point struct{ x, y _Ctype_int }
+
+//export Add
+func Add(a, b int) int { return a + b }
+
+//export Hello
+func Hello(name string, flag bool) {}
+
+//export Point
+func Point(p *_Ctype_struct_point, n _Ctype_int) (*_Ctype_struct_point, error) { return p, nil }
+
+// NotExported is a comment.
+func NotExported() {}
+
+func main() {}
+`
+
+func loadTestPkg(t *testing.T, src string) *packages.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	pkg, err := new(types.Config).Check("main", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &packages.Package{PkgPath: "main", Name: "main", Fset: fset,
+		Syntax: []*ast.File{f}, Types: pkg, TypesInfo: info}
+}
+
+func TestCExports(t *testing.T) {
+	pkg := loadTestPkg(t, cExportSrc)
+	exports, _, err := cExports(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Add":   "extern GoInt Add(GoInt a, GoInt b);\n",
+		"Hello": "extern void Hello(GoString name, GoBool flag);\n",
+		"Point": "struct Point_return {\n\tstruct point* r0;\n\tGoInterface r1;\n};\n" +
+			"extern struct Point_return Point(struct point* p, int n);\n",
+	}
+	if len(exports) != len(want) {
+		t.Fatalf("cExports: got %d exports, want %d", len(exports), len(want))
+	}
+	for _, exp := range exports {
+		decl, err := cFuncDecl(exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decl != want[exp.name] {
+			t.Errorf("cFuncDecl(%s):\ngot  %q\nwant %q", exp.name, decl, want[exp.name])
+		}
+	}
+}
+
+func TestCExportUnsupported(t *testing.T) {
+	pkg := loadTestPkg(t, `package main
+
+//export Sum
+func Sum(v [4]int) int { return 0 }
+`)
+	exports, _, err := cExports(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cFuncDecl(exports[0]); err == nil {
+		t.Error("cFuncDecl: expected error for array parameter")
+	}
+
+	pkg = loadTestPkg(t, `package main
+
+//export Call
+func Call(fn func(int) int) int { return fn(0) }
+`)
+	if exports, _, err = cExports(pkg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cFuncDecl(exports[0]); err == nil {
+		t.Error("cFuncDecl: expected error for func parameter")
+	}
+}
+
+func TestGenCHeader(t *testing.T) {
+	pkg := loadTestPkg(t, cExportSrc)
+	file := filepath.Join(t.TempDir(), "main.h")
+	if err := genCHeader(pkg, file, "arm64", []string{"-lgc"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := string(data)
+	for _, s := range []string{
+		"/* Link with: -lgc */",
+		"typedef long long GoInt;",
+		"extern GoInt Add(GoInt a, GoInt b);",
+		`extern "C" {`,
+	} {
+		if !strings.Contains(header, s) {
+			t.Errorf("genCHeader: %q not found in:\n%s", s, header)
+		}
+	}
+}
+
+func TestCheckBuildMode(t *testing.T) {
+	tests := []struct {
+		conf Config
+		ok   bool
+	}{
+		{Config{Mode: ModeBuild}, true},
+		{Config{Mode: ModeRun, BuildMode: BuildModeExe}, true},
+		{Config{Mode: ModeBuild, BuildMode: BuildModeCArchive}, true},
+		{Config{Mode: ModeBuild, BuildMode: BuildModeCShared}, true},
+		{Config{Mode: ModeRun, BuildMode: BuildModeCShared}, false},
+		{Config{Mode: ModeBuild, BuildMode: BuildModeCShared, Goos: "wasip1"}, false},
+		{Config{Mode: ModeBuild, BuildMode: BuildModeCArchive, Target: "rp2040"}, false},
+		{Config{Mode: ModeBuild, BuildMode: "pie"}, false},
+	}
+	for _, tt := range tests {
+		if err := checkBuildMode(&tt.conf); (err == nil) != tt.ok {
+			t.Errorf("checkBuildMode(%+v): %v", tt.conf, err)
+		}
+	}
+	if ext := libExt(BuildModeCShared, "darwin"); ext != ".dylib" {
+		t.Errorf("libExt: got %s", ext)
+	}
+	if ext := libExt(BuildModeCArchive, "linux"); ext != ".a" {
+		t.Errorf("libExt: got %s", ext)
+	}
+}
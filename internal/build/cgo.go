@@ -120,7 +120,8 @@ func buildCgo(ctx *context, pkg *aPackage, files []*ast.File, externs []string,
 			return nil, nil, fmt.Errorf("failed to create temp file: %v", err)
 		}
 		tmpName := tmpFile.Name()
-		defer os.Remove(tmpName)
+		tmpFile.Close()
+		ctx.jobs.cleanup(func() { os.Remove(tmpName) }) // compiled by a job of clFile
 		code := cgoHeader + "\n\n" + preamble.src
 		externDecls, err := genExternDeclsByClang(pkg, code, cflags, cgoSymbols)
 		if err != nil {
@@ -66,6 +66,15 @@ func Clean(patterns []string, conf *Config) {
 	}
 }
 
+// CleanCache removes the build cache of compiled package objects.
+func CleanCache(verbose bool) {
+	dir := buildCacheDir()
+	if verbose {
+		fmt.Fprintln(os.Stderr, "Remove", dir)
+	}
+	check(os.RemoveAll(dir))
+}
+
 func cleanMainPkg(pkg *packages.Package, conf *Config, verbose bool) {
 	pkgPath := pkg.PkgPath
 	name := path.Base(pkgPath)
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/goplus/llgo/cl"
+	"github.com/goplus/llgo/internal/cabi"
+	"github.com/goplus/llgo/internal/packages"
+	llssa "github.com/goplus/llgo/ssa"
+)
+
+// pkgCompiler generates LLVM IR of packages on a worker of compilePkgs. Each
+// one has its own llssa.Program, since an LLVM context must not be used by
+// two threads at once.
+type pkgCompiler struct {
+	prog         llssa.Program
+	cTransformer *cabi.Transformer
+	imported     map[string]bool // IDs of the packages registered to prog
+}
+
+func newPkgCompiler(prog llssa.Program, mode cabi.Mode) *pkgCompiler {
+	return &pkgCompiler{
+		prog:         prog,
+		cTransformer: cabi.NewTransformer(prog, mode),
+		imported:     make(map[string]bool),
+	}
+}
+
+// compilePkgs compiles pkgs, which are in import order, on up to Parallel
+// workers. A package is compiled once the packages it imports are.
+func compilePkgs(ctx *context, pkgs []*aPackage, verbose bool) error {
+	index := make(map[string]int, len(pkgs))
+	for i, aPkg := range pkgs {
+		index[aPkg.ID] = i
+	}
+	deps := make([][]int, len(pkgs))
+	for i, aPkg := range pkgs {
+		deps[i] = nearestDeps(aPkg.Package, index)
+	}
+	n := min(cap(ctx.jobs.sem), len(pkgs))
+	if verbose || IsEscapeDebugEnabled() {
+		n = 1 // the debug output of cl and llssa is enabled per package
+	}
+	for len(ctx.compilers) < n {
+		ctx.compilers = append(ctx.compilers, newPkgCompiler(ctx.prog.Fork(), ctx.buildConf.AbiMode))
+	}
+	return runGraph(deps, n, func(w, i int) error {
+		return ctx.compilers[w].compile(ctx, pkgs[i], verbose)
+	})
+}
+
+// nearestDeps returns the indexes of the packages pkg depends on in index,
+// but not through another one in index.
+func nearestDeps(pkg *packages.Package, index map[string]int) (deps []int) {
+	seen := make(map[string]bool)
+	var visit func(p *packages.Package)
+	visit = func(p *packages.Package) {
+		for _, dep := range p.Imports {
+			if seen[dep.ID] {
+				continue
+			}
+			seen[dep.ID] = true
+			if i, ok := index[dep.ID]; ok {
+				deps = append(deps, i)
+			} else {
+				visit(dep)
+			}
+		}
+	}
+	visit(pkg)
+	slices.Sort(deps)
+	return
+}
+
+func (c *pkgCompiler) compile(ctx *context, aPkg *aPackage, verbose bool) error {
+	generics := c.importDeps(ctx, aPkg.Package)
+	c.prog.SetGenericsCompiled(generics)
+	if err := buildPkg(ctx, c, aPkg, generics, verbose); err != nil {
+		return err
+	}
+	c.imported[aPkg.ID] = true
+	return nil
+}
+
+// importDeps registers the packages pkg depends on, which may be compiled by
+// other workers, to c.prog. It returns the sorted generic instances compiled
+// in them, which are the ones pkg is compiled after, so that its object only
+// depends on its dependencies.
+func (c *pkgCompiler) importDeps(ctx *context, pkg *packages.Package) []string {
+	imports := make([]*packages.Package, 0, len(pkg.Imports))
+	for _, dep := range pkg.Imports {
+		imports = append(imports, dep)
+	}
+	var generics []string
+	packages.Visit(imports, nil, func(dep *packages.Package) {
+		aDep, ok := ctx.aPkgs[dep.ID]
+		if !ok || !aDep.Compiled {
+			return
+		}
+		generics = append(generics, aDep.Generics...)
+		if !c.imported[dep.ID] {
+			c.imported[dep.ID] = true
+			cl.ImportPackage(c.prog, ctx.patches, aDep.SSA, aDep.syntax())
+		}
+	})
+	slices.Sort(generics)
+	return slices.Compact(generics)
+}
+
+// genericsOf returns the generic instances compiled in pkg.
+func genericsOf(pkg llssa.Package) (generics []string) {
+	// FuncCompiled is only checked for generic instances
+	for _, name := range pkg.FuncsCompiled() {
+		if strings.ContainsRune(name, '[') {
+			generics = append(generics, name)
+		}
+	}
+	return
+}
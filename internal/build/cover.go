@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+
+	"github.com/goplus/llgo/internal/cover"
+	"github.com/goplus/llgo/internal/packages"
+)
+
+// noCover lists the packages that can't be annotated with coverage
+// counters, as the annotations depend on them.
+var noCover = map[string]bool{
+	"unsafe":      true,
+	"runtime":     true,
+	"sync/atomic": true,
+	"testing":     true,
+}
+
+// checkCover checks the coverage settings of conf.
+func checkCover(conf *Config) error {
+	if conf.CoverMode == "" {
+		if conf.CoverProfile != "" {
+			return fmt.Errorf("-coverprofile requires a coverage mode")
+		}
+		return nil
+	}
+	if conf.Mode != ModeTest {
+		return fmt.Errorf("coverage is only supported by llgo test")
+	}
+	if !cover.ValidMode(conf.CoverMode) {
+		return fmt.Errorf("invalid -covermode %q, must be set, count or atomic", conf.CoverMode)
+	}
+	if conf.Race && conf.CoverMode != cover.ModeAtomic {
+		return fmt.Errorf("-covermode must be \"atomic\", not %q, when -race is enabled", conf.CoverMode)
+	}
+	return nil
+}
+
+// coverPackages annotates the Go files of the packages matched by patterns
+// with coverage counters, and adds them to the overlay of cfg, like "go test
+// -cover" does for the packages it tests. Their test files and cgo files
+// are not annotated.
+func coverPackages(conf *Config, cfg *packages.Config, patterns []string) error {
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:       packages.NeedName | packages.NeedFiles,
+		BuildFlags: cfg.BuildFlags,
+		Env:        cfg.Env,
+		Dir:        cfg.Dir,
+		Overlay:    cfg.Overlay,
+	}, patterns...)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		if noCover[pkg.PkgPath] {
+			continue
+		}
+		for i, file := range pkg.GoFiles {
+			if _, ok := cfg.Overlay[file]; ok {
+				continue
+			}
+			src, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if importsC(file, src) {
+				continue
+			}
+			name := pkg.PkgPath + "/" + filepath.Base(file)
+			out, err := cover.Annotate(file, src, conf.CoverMode, cover.VarName(i, file), name)
+			if err != nil {
+				return err
+			}
+			cfg.Overlay[file] = out
+		}
+	}
+	return nil
+}
+
+// importsC reports whether the Go file imports "C".
+func importsC(file string, src []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), file, src, parser.ImportsOnly)
+	if err != nil {
+		return false
+	}
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+// appendCoverProfile appends the blocks of the cover profile written by a
+// test to the profile file of all the tests, leaving out its mode line.
+func appendCoverProfile(file, profile string) error {
+	data, err := os.ReadFile(profile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // the test failed before writing it
+		}
+		return err
+	}
+	if bytes.HasPrefix(data, []byte("mode: ")) {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goplus/llgo/cl"
+	"github.com/goplus/llgo/internal/packages"
+)
+
+// embedCfg maps the //go:embed patterns of pkg to the files they match, or
+// returns nil if it embeds no files.
+//
+// The go command resolves the patterns when it loads pkg, but only reports
+// the files matched by all of them, EmbedFiles. The files matched by each
+// pattern are picked from them with the rules of the go command: a pattern
+// matches the files it names and the files in the directories it names,
+// except the ones whose names begin with '.' or '_' in the latter case,
+// unless the pattern has the "all:" prefix.
+func embedCfg(pkg *packages.Package) *cl.EmbedCfg {
+	if len(pkg.EmbedPatterns) == 0 || len(pkg.GoFiles) == 0 {
+		return nil
+	}
+	dir := filepath.Dir(pkg.GoFiles[0])
+	cfg := &cl.EmbedCfg{
+		Patterns: make(map[string][]string),
+		Files:    make(map[string]string),
+	}
+	rels := make([]string, 0, len(pkg.EmbedFiles))
+	for _, file := range pkg.EmbedFiles {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		cfg.Files[rel] = file
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	for _, pattern := range pkg.EmbedPatterns {
+		glob, all := strings.CutPrefix(pattern, "all:")
+		files := []string{}
+		for _, rel := range rels {
+			if embedMatch(glob, rel, all) {
+				files = append(files, rel)
+			}
+		}
+		cfg.Patterns[pattern] = files
+	}
+	return cfg
+}
+
+// embedMatch reports whether the pattern glob matches the file rel, itself
+// or one of the directories containing it.
+func embedMatch(glob, rel string, all bool) bool {
+	if ok, _ := path.Match(glob, rel); ok {
+		return true
+	}
+	for i := len(rel) - 1; i > 0; i-- {
+		if rel[i] != '/' {
+			continue
+		}
+		if ok, _ := path.Match(glob, rel[:i]); ok && (all || !hasHiddenElem(rel[i+1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHiddenElem reports whether an element of the slash-separated path
+// begins with '.' or '_'.
+func hasHiddenElem(rel string) bool {
+	for _, elem := range strings.Split(rel, "/") {
+		if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+	return false
+}
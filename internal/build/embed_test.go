@@ -0,0 +1,49 @@
+//go:build !llgo
+// +build !llgo
+
+package build
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/goplus/llgo/internal/packages"
+)
+
+func TestEmbedCfg(t *testing.T) {
+	dir := filepath.FromSlash("/src/app")
+	abs := func(rel string) string {
+		return filepath.Join(dir, filepath.FromSlash(rel))
+	}
+	pkg := &packages.Package{
+		GoFiles:       []string{abs("main.go")},
+		EmbedPatterns: []string{"version.txt", "static", "all:tmpl", "static/.keep", "*.sql"},
+		EmbedFiles: []string{
+			abs("version.txt"),
+			abs("static/app.js"),
+			abs("static/css/site.css"),
+			abs("static/.keep"),
+			abs("tmpl/_base.html"),
+			abs("tmpl/index.html"),
+			abs("001_init.sql"),
+		},
+	}
+	cfg := embedCfg(pkg)
+	want := map[string][]string{
+		"version.txt":  {"version.txt"},
+		"static":       {"static/app.js", "static/css/site.css"},
+		"all:tmpl":     {"tmpl/_base.html", "tmpl/index.html"},
+		"static/.keep": {"static/.keep"},
+		"*.sql":        {"001_init.sql"},
+	}
+	if !reflect.DeepEqual(cfg.Patterns, want) {
+		t.Fatalf("Patterns = %v, want %v", cfg.Patterns, want)
+	}
+	if got := cfg.Files["static/css/site.css"]; got != abs("static/css/site.css") {
+		t.Fatalf("Files[static/css/site.css] = %q", got)
+	}
+	if embedCfg(&packages.Package{GoFiles: pkg.GoFiles}) != nil {
+		t.Fatal("embedCfg of a package without embeds is not nil")
+	}
+}
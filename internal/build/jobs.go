@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"fmt"
+	"sync"
+)
+
+// jobQueue runs jobs on a fixed number of workers.
+//
+// LLVM IR of packages is generated by compilePkgs in import order. Everything
+// after that (clang compiling IR and C files to objects) is independent
+// between packages and runs on the jobQueue.
+type jobQueue struct {
+	sem  chan none
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+
+	after    []func() // run by wait after all jobs are done
+	cleanups []func() // run by wait even if some job fails
+}
+
+func newJobQueue(n int) *jobQueue {
+	if n < 1 {
+		n = 1
+	}
+	return &jobQueue{sem: make(chan none, n)}
+}
+
+// run starts a job. It blocks while all workers are busy.
+func (q *jobQueue) run(job func() error) {
+	q.sem <- none{}
+	q.wg.Add(1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				q.fail(fmt.Errorf("%v", r))
+			}
+			<-q.sem
+			q.wg.Done()
+		}()
+		if err := job(); err != nil {
+			q.fail(err)
+		}
+	}()
+}
+
+// onDone registers fn to be called by the next wait, after all jobs are done.
+// It isn't called if some job fails.
+func (q *jobQueue) onDone(fn func()) {
+	q.mu.Lock()
+	q.after = append(q.after, fn)
+	q.mu.Unlock()
+}
+
+// cleanup registers fn to be called by the next wait, after all jobs are done,
+// e.g. to remove a temporary file read by some job. It's called even if some
+// job fails.
+func (q *jobQueue) cleanup(fn func()) {
+	q.mu.Lock()
+	q.cleanups = append(q.cleanups, fn)
+	q.mu.Unlock()
+}
+
+func (q *jobQueue) fail(err error) {
+	q.mu.Lock()
+	q.errs = append(q.errs, err)
+	q.mu.Unlock()
+}
+
+// wait waits for all jobs started so far and returns the first error.
+func (q *jobQueue) wait() error {
+	q.wg.Wait()
+	q.mu.Lock()
+	after, cleanups, errs := q.after, q.cleanups, q.errs
+	q.after, q.cleanups = nil, nil
+	q.mu.Unlock()
+	for _, fn := range cleanups {
+		fn()
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	for _, fn := range after {
+		fn()
+	}
+	return nil
+}
+
+// runGraph calls build(w, i) for the nodes i of a graph on up to n workers,
+// each one having an index 0 <= w < n, after the calls for all the nodes in
+// deps[i] have returned. Ready nodes are started in index order. After an
+// error, it starts no more nodes and returns the first error; a panic of
+// build is raised again.
+func runGraph(deps [][]int, n int, build func(w, i int) error) (err error) {
+	type result struct {
+		w, i  int
+		err   error
+		panic any
+	}
+	if n < 1 {
+		n = 1
+	}
+	users := make([][]int, len(deps))
+	waits := make([]int, len(deps))
+	for i, ds := range deps {
+		waits[i] = len(ds)
+		for _, d := range ds {
+			users[d] = append(users[d], i)
+		}
+	}
+	idle := make([]int, 0, n)
+	for w := n - 1; w >= 0; w-- {
+		idle = append(idle, w)
+	}
+	started := make([]bool, len(deps))
+	done := make(chan result)
+	running := 0
+	var perr any
+	for {
+		for i := 0; i < len(deps) && len(idle) > 0 && err == nil && perr == nil; i++ {
+			if started[i] || waits[i] > 0 {
+				continue
+			}
+			started[i] = true
+			w := idle[len(idle)-1]
+			idle = idle[:len(idle)-1]
+			running++
+			go func() {
+				r := result{w: w, i: i}
+				defer func() {
+					r.panic = recover()
+					done <- r
+				}()
+				r.err = build(w, i)
+			}()
+		}
+		if running == 0 {
+			break
+		}
+		r := <-done
+		running--
+		idle = append(idle, r.w)
+		if r.panic != nil && perr == nil {
+			perr = r.panic
+		}
+		if r.err != nil && err == nil {
+			err = r.err
+		}
+		for _, u := range users[r.i] {
+			waits[u]--
+		}
+	}
+	if perr != nil {
+		panic(perr)
+	}
+	return
+}
@@ -0,0 +1,135 @@
+//go:build !llgo
+// +build !llgo
+
+package build
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJobQueue(t *testing.T) {
+	q := newJobQueue(2)
+	var running, maxRunning, done int32
+	block := make(chan none)
+	for i := 0; i < 8; i++ {
+		q.run(func() error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			if i == 0 {
+				<-block
+			}
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&done, 1)
+			return nil
+		})
+		if i == 0 {
+			close(block)
+		}
+	}
+	var after bool
+	q.onDone(func() { after = true })
+	if err := q.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if done != 8 {
+		t.Errorf("done = %d, want 8", done)
+	}
+	if maxRunning > 2 {
+		t.Errorf("%d jobs run in parallel, want at most 2", maxRunning)
+	}
+	if !after {
+		t.Error("onDone callback isn't called")
+	}
+}
+
+func TestJobQueueError(t *testing.T) {
+	q := newJobQueue(0)
+	errFail := errors.New("fail")
+	q.run(func() error { return errFail })
+	q.run(func() error { panic("boom") })
+	var after, cleanup bool
+	q.onDone(func() { after = true })
+	q.cleanup(func() { cleanup = true })
+	if err := q.wait(); err == nil {
+		t.Fatal("wait: expected error")
+	}
+	if after {
+		t.Error("onDone callback is called after failure")
+	}
+	if !cleanup {
+		t.Error("cleanup callback isn't called after failure")
+	}
+}
+
+func TestRunGraph(t *testing.T) {
+	// 0 <- 1 <- 3, 0 <- 2 <- 3, 4
+	deps := [][]int{nil, {0}, {0}, {1, 2}, nil}
+	var mu sync.Mutex
+	var order []int
+	var running, maxRunning int
+	err := runGraph(deps, 2, func(w, i int) error {
+		if w < 0 || w >= 2 {
+			t.Errorf("worker %d out of range", w)
+		}
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		order = append(order, i)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != len(deps) {
+		t.Fatalf("order = %v, want all of %d nodes", order, len(deps))
+	}
+	pos := make(map[int]int)
+	for i, node := range order {
+		pos[node] = i
+	}
+	for i, ds := range deps {
+		for _, d := range ds {
+			if pos[d] > pos[i] {
+				t.Errorf("node %d done before its dependency %d: %v", i, d, order)
+			}
+		}
+	}
+	if maxRunning > 2 {
+		t.Errorf("%d nodes run in parallel, want at most 2", maxRunning)
+	}
+}
+
+func TestRunGraphError(t *testing.T) {
+	errFail := errors.New("fail")
+	var called []int
+	err := runGraph([][]int{nil, {0}}, 1, func(w, i int) error {
+		called = append(called, i)
+		return errFail
+	})
+	if err != errFail {
+		t.Errorf("err = %v, want %v", err, errFail)
+	}
+	if len(called) != 1 {
+		t.Errorf("called = %v, want only the first node", called)
+	}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recover() = %v, want boom", r)
+		}
+	}()
+	runGraph([][]int{nil}, 1, func(w, i int) error { panic("boom") })
+}
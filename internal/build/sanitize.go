@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package build
+
+import (
+	"fmt"
+	"slices"
+)
+
+// sanitizer describes a clang sanitizer enabled by a build flag.
+type sanitizer struct {
+	flag string // build flag enabling it
+	kind string // kind of -fsanitize
+
+	// tags are the build tags of the runtime for the sanitizer. It annotates
+	// its synchronization for "sanitize.thread", and allocates with malloc,
+	// which the sanitizers track, with "nogc". It runs goroutines on threads
+	// instead of switching stacks, which they don't follow, with "nosched".
+	tags []string
+
+	// platforms the sanitizer has a runtime for, or nil for all the native
+	// ones.
+	platforms map[string]bool
+}
+
+var (
+	sanitizeRace = &sanitizer{
+		flag: "-race",
+		kind: "thread",
+		tags: []string{"sanitize.thread", "nosched"},
+		platforms: map[string]bool{
+			"linux/amd64":   true,
+			"linux/arm64":   true,
+			"darwin/amd64":  true,
+			"darwin/arm64":  true,
+			"freebsd/amd64": true,
+			"netbsd/amd64":  true,
+		},
+	}
+	sanitizeAddress = &sanitizer{
+		flag: "-asan",
+		kind: "address",
+		tags: []string{"sanitize.address", "nogc", "nosched"},
+		platforms: map[string]bool{
+			"linux/amd64":   true,
+			"linux/arm64":   true,
+			"linux/riscv64": true,
+			"darwin/amd64":  true,
+			"darwin/arm64":  true,
+			"freebsd/amd64": true,
+		},
+	}
+	sanitizeMemory = &sanitizer{
+		flag: "-msan",
+		kind: "memory",
+		tags: []string{"sanitize.memory", "nogc", "nosched"},
+		platforms: map[string]bool{
+			"linux/amd64":   true,
+			"linux/arm64":   true,
+			"freebsd/amd64": true,
+		},
+	}
+	// UBSan checks are inserted by the C front end, so only the C code is
+	// checked, not the Go code compiled from IR.
+	sanitizeUndefined = &sanitizer{
+		flag: "-ubsan",
+		kind: "undefined",
+		tags: []string{"sanitize.undefined"},
+	}
+)
+
+// enabledSanitizers returns the sanitizers enabled by conf.
+func enabledSanitizers(conf *Config) []*sanitizer {
+	var list []*sanitizer
+	for _, s := range []struct {
+		on bool
+		*sanitizer
+	}{
+		{conf.Race, sanitizeRace},
+		{conf.ASan, sanitizeAddress},
+		{conf.MSan, sanitizeMemory},
+		{conf.UBSan, sanitizeUndefined},
+	} {
+		if s.on {
+			list = append(list, s.sanitizer)
+		}
+	}
+	return list
+}
+
+// checkSanitizers reports an error if the sanitizers of conf can't be used
+// together or are not supported on its platform.
+func checkSanitizers(conf *Config) error {
+	list := enabledSanitizers(conf)
+	for i, s := range list {
+		// Only UBSan is compatible with the others, which all shadow memory.
+		if s != sanitizeUndefined {
+			for _, other := range list[i+1:] {
+				if other != sanitizeUndefined {
+					return fmt.Errorf("%s and %s are incompatible", s.flag, other.flag)
+				}
+			}
+		}
+		if conf.Target != "" {
+			return fmt.Errorf("%s is not supported with -target", s.flag)
+		}
+		platform := conf.Goos + "/" + conf.Goarch
+		if s.platforms != nil && !s.platforms[platform] || isWasmTarget(conf.Goos) {
+			return fmt.Errorf("%s is not supported on %s", s.flag, platform)
+		}
+	}
+	return nil
+}
+
+// sanitizers returns the clang sanitizers the code of conf is instrumented
+// with, as the kinds of -fsanitize.
+func (conf *Config) sanitizers() []string {
+	var kinds []string
+	for _, s := range enabledSanitizers(conf) {
+		kinds = append(kinds, s.kind)
+	}
+	return kinds
+}
+
+// sanitizeTags returns the build tags of the sanitizers of conf.
+func sanitizeTags(conf *Config) []string {
+	var tags []string
+	for _, s := range enabledSanitizers(conf) {
+		for _, tag := range s.tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
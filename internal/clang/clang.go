@@ -34,6 +34,9 @@ type Config struct {
 	CFLAGS  []string // C-specific flags
 	LDFLAGS []string // Linker flags
 	Linker  string   // Linker to use (e.g., "ld.lld", "avr-ld")
+
+	// Sanitizers to compile and link with (e.g., "thread"), see -fsanitize.
+	Sanitizers []string
 }
 
 // NewConfig creates a new Config with the specified parameters.
@@ -112,6 +115,11 @@ func (c *Cmd) Link(args ...string) error {
 	return c.exec(allArgs...)
 }
 
+// Exec executes the command with args only, without merging any configured flags.
+func (c *Cmd) Exec(args ...string) error {
+	return c.exec(args...)
+}
+
 // mergeCompilerFlags merges environment CCFLAGS/CFLAGS with config flags.
 func (c *Cmd) mergeCompilerFlags() []string {
 	var flags []string
@@ -132,7 +140,7 @@ func (c *Cmd) mergeCompilerFlags() []string {
 	// Add config CFLAGS
 	flags = append(flags, c.config.CFLAGS...)
 
-	return flags
+	return append(flags, c.sanitizeFlags()...)
 }
 
 // mergeLinkerFlags merges environment CCFLAGS/LDFLAGS with config flags.
@@ -152,7 +160,15 @@ func (c *Cmd) mergeLinkerFlags() []string {
 	// Add config LDFLAGS
 	flags = append(flags, c.config.LDFLAGS...)
 
-	return flags
+	return append(flags, c.sanitizeFlags()...)
+}
+
+// sanitizeFlags returns the flags enabling the sanitizers of the config.
+func (c *Cmd) sanitizeFlags() []string {
+	if len(c.config.Sanitizers) == 0 {
+		return nil
+	}
+	return []string{"-fsanitize=" + strings.Join(c.config.Sanitizers, ",")}
 }
 
 // exec executes the clang command with given arguments.
@@ -204,6 +204,28 @@ func TestMergeCompilerFlags(t *testing.T) {
 			t.Errorf("Expected empty flags, got %v", flags)
 		}
 	})
+	t.Run("WithSanitizers", func(t *testing.T) {
+		os.Unsetenv("CCFLAGS")
+		os.Unsetenv("CFLAGS")
+
+		config := Config{
+			CCFLAGS:    []string{"-Wall"},
+			Sanitizers: []string{"thread"},
+		}
+		cmd := New("clang", config)
+
+		flags := cmd.mergeCompilerFlags()
+		expected := []string{"-Wall", "-fsanitize=thread"}
+
+		if !reflect.DeepEqual(flags, expected) {
+			t.Errorf("Expected flags %v, got %v", expected, flags)
+		}
+		flags = cmd.mergeLinkerFlags()
+		expected = []string{"-fsanitize=thread"}
+		if !reflect.DeepEqual(flags, expected) {
+			t.Errorf("Expected linker flags %v, got %v", expected, flags)
+		}
+	})
 }
 
 func TestMergeLinkerFlags(t *testing.T) {
@@ -0,0 +1,481 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cover annotates Go source files with statement coverage counters,
+// the way "go tool cover" does.
+//
+// Each basic block of the source gets a counter, set or incremented when the
+// block runs, and the table of the blocks and their counters is registered
+// with the testing package when the annotated package is initialized. The
+// testing package writes them as a cover profile at the end of the tests.
+package cover
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+)
+
+// Modes of coverage.
+const (
+	ModeSet    = "set"    // whether each statement ran
+	ModeCount  = "count"  // how many times each statement ran
+	ModeAtomic = "atomic" // like count, but correct in multithreaded tests
+)
+
+// ValidMode reports whether mode is a mode of coverage.
+func ValidMode(mode string) bool {
+	return mode == ModeSet || mode == ModeCount || mode == ModeAtomic
+}
+
+// RegisterFunc is the function of the testing package the annotated files
+// register their counters with:
+//
+//	func llgoRegisterCover(mode, file string, counters, pos []uint32, numStmt []uint16)
+const RegisterFunc = "testing.llgoRegisterCover"
+
+const atomicPkg = "_cover_atomic_"
+
+// VarName returns the name of the counter variable of the n-th annotated
+// file of a package, unique across its files.
+func VarName(n int, filename string) string {
+	h := sha256.Sum256([]byte(filename))
+	return fmt.Sprintf("GoCover_%d_%x", n, h[:6])
+}
+
+// Annotate returns the source src of the file filename annotated with the
+// counters of the mode of coverage. The counters are declared as the
+// variable varName, and registered for the file name, the import path of
+// the package followed by the base name of the file in cover profiles.
+func Annotate(filename string, src []byte, mode, varName, name string) ([]byte, error) {
+	if !ValidMode(mode) {
+		return nil, fmt.Errorf("invalid coverage mode %q", mode)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	f := &annotator{
+		fset:    fset,
+		src:     src,
+		mode:    mode,
+		varName: varName,
+	}
+
+	// The imports are added on the line of the package clause, so that the
+	// lines of the file don't move.
+	imports := `; import _ "unsafe"`
+	if mode == ModeAtomic {
+		imports += `; import ` + atomicPkg + ` "sync/atomic"`
+	}
+	f.insert(f.offset(file.Name.End()), imports)
+	ast.Walk(f, file)
+
+	var buf bytes.Buffer
+	f.apply(&buf)
+	f.addVariables(&buf, name)
+	return buf.Bytes(), nil
+}
+
+// block is a basic block of the source covered by a counter.
+type block struct {
+	start, end token.Position
+	numStmt    int
+}
+
+type insertion struct {
+	offset int
+	text   string
+}
+
+type annotator struct {
+	fset    *token.FileSet
+	src     []byte
+	mode    string
+	varName string
+	blocks  []block
+	edits   []insertion
+}
+
+func (f *annotator) offset(pos token.Pos) int {
+	return f.fset.Position(pos).Offset
+}
+
+func (f *annotator) insert(offset int, text string) {
+	f.edits = append(f.edits, insertion{offset, text})
+}
+
+// apply writes the source with the insertions, which keep their order at
+// the same offset.
+func (f *annotator) apply(buf *bytes.Buffer) {
+	sort.SliceStable(f.edits, func(i, j int) bool {
+		return f.edits[i].offset < f.edits[j].offset
+	})
+	last := 0
+	for _, e := range f.edits {
+		buf.Write(f.src[last:e.offset])
+		buf.WriteString(e.text)
+		last = e.offset
+	}
+	buf.Write(f.src[last:])
+}
+
+// Visit implements ast.Visitor.
+func (f *annotator) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.BlockStmt:
+		// The body of a switch or select is a list of clauses, each of which
+		// starts a block of its own.
+		if len(n.List) > 0 {
+			switch n.List[0].(type) {
+			case *ast.CaseClause:
+				for _, stmt := range n.List {
+					clause := stmt.(*ast.CaseClause)
+					f.addCounters(clause.Colon+1, clause.Colon+1, clause.End(), clause.Body, false)
+				}
+				return f
+			case *ast.CommClause:
+				for _, stmt := range n.List {
+					clause := stmt.(*ast.CommClause)
+					f.addCounters(clause.Colon+1, clause.Colon+1, clause.End(), clause.Body, false)
+				}
+				return f
+			}
+		}
+		f.addCounters(n.Lbrace, n.Lbrace+1, n.Rbrace+1, n.List, true) // +1 to step past the closing brace
+	case *ast.IfStmt:
+		if n.Init != nil {
+			ast.Walk(f, n.Init)
+		}
+		ast.Walk(f, n.Cond)
+		ast.Walk(f, n.Body)
+		if n.Else == nil {
+			return nil
+		}
+		// An else branch gets a block of its own, so that a counter can be
+		// dropped in before the condition of
+		//	} else if y {
+		// which is annotated as
+		//	} else {COUNTER; if y {
+		//	}}
+		elseOffset := f.findText(n.Body.End(), "else")
+		if elseOffset < 0 {
+			panic("cover: lost else")
+		}
+		f.insert(elseOffset+4, "{")
+		f.insert(f.offset(n.Else.End()), "}")
+		pos := f.fset.File(n.Body.End()).Pos(elseOffset + 4)
+		switch stmt := n.Else.(type) {
+		case *ast.IfStmt:
+			n.Else = &ast.BlockStmt{Lbrace: pos, List: []ast.Stmt{stmt}, Rbrace: stmt.End()}
+		case *ast.BlockStmt:
+			stmt.Lbrace = pos
+		default:
+			panic("cover: unexpected node type in if")
+		}
+		ast.Walk(f, n.Else)
+		return nil
+	case *ast.SelectStmt:
+		// Annotating an empty select would make it invalid.
+		if n.Body == nil || len(n.Body.List) == 0 {
+			return nil
+		}
+	case *ast.SwitchStmt:
+		if n.Body == nil || len(n.Body.List) == 0 {
+			if n.Init != nil {
+				ast.Walk(f, n.Init)
+			}
+			if n.Tag != nil {
+				ast.Walk(f, n.Tag)
+			}
+			return nil
+		}
+	case *ast.TypeSwitchStmt:
+		if n.Body == nil || len(n.Body.List) == 0 {
+			if n.Init != nil {
+				ast.Walk(f, n.Init)
+			}
+			ast.Walk(f, n.Assign)
+			return nil
+		}
+	case *ast.FuncDecl:
+		// Functions with blank names can't run, and those without bodies
+		// have nothing to cover.
+		if n.Name.Name == "_" || n.Body == nil {
+			return nil
+		}
+	}
+	return f
+}
+
+// addCounters adds a counter to each basic block of the statement list,
+// which begins at pos and ends at blockEnd. The counter of the first block
+// is inserted at insertPos. The last block extends to blockEnd if
+// extendToClosingBrace is set and the list has a single block.
+func (f *annotator) addCounters(pos, insertPos, blockEnd token.Pos, list []ast.Stmt, extendToClosingBrace bool) {
+	// An empty block gets a counter too, unlike the empty rest of a list
+	// after the last block, such as one following a return statement.
+	if len(list) == 0 {
+		f.insert(f.offset(insertPos), f.newCounter(insertPos, blockEnd, 0)+";")
+		return
+	}
+	// The list is changed below.
+	list = append([]ast.Stmt(nil), list...)
+	for {
+		// The first statement that affects the flow of control ends the
+		// block.
+		var last int
+		end := blockEnd
+		for last = 0; last < len(list); last++ {
+			stmt := list[last]
+			end = f.statementBoundary(stmt)
+			if f.endsBasicSourceBlock(stmt) {
+				// A labeled statement may be the target of a goto, so a block
+				// starts between the label and the statement, which
+				//	foo: stmt
+				// is annotated as
+				//	foo: COUNTER; stmt
+				// unless the statement is itself a control statement, like a
+				// labeled for.
+				if label, ok := stmt.(*ast.LabeledStmt); ok && !isControl(label.Stmt) {
+					newLabel := *label
+					newLabel.Stmt = &ast.EmptyStmt{Semicolon: label.Stmt.Pos(), Implicit: true}
+					end = label.Pos() // the previous block ends before the label
+					list[last] = &newLabel
+					list = append(list, nil)
+					copy(list[last+1:], list[last:])
+					list[last+1] = label.Stmt
+				}
+				last++
+				extendToClosingBrace = false // the list is broken up now
+				break
+			}
+		}
+		if extendToClosingBrace {
+			end = blockEnd
+		}
+		if pos != end { // blocks may abut
+			f.insert(f.offset(insertPos), f.newCounter(pos, end, last)+";")
+		}
+		list = list[last:]
+		if len(list) == 0 {
+			break
+		}
+		pos = list[0].Pos()
+		insertPos = pos
+	}
+}
+
+// statementBoundary returns where the block of the statement s ends, which
+// is before the body of a control statement or of the first function
+// literal in s.
+func (f *annotator) statementBoundary(s ast.Stmt) token.Pos {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		// Blocks are basic blocks of their own, so that counters don't
+		// overlap.
+		return s.Lbrace
+	case *ast.IfStmt:
+		if found, pos := hasFuncLiteral(s.Init); found {
+			return pos
+		}
+		if found, pos := hasFuncLiteral(s.Cond); found {
+			return pos
+		}
+		return s.Body.Lbrace
+	case *ast.ForStmt:
+		if found, pos := hasFuncLiteral(s.Init); found {
+			return pos
+		}
+		if found, pos := hasFuncLiteral(s.Cond); found {
+			return pos
+		}
+		if found, pos := hasFuncLiteral(s.Post); found {
+			return pos
+		}
+		return s.Body.Lbrace
+	case *ast.LabeledStmt:
+		return f.statementBoundary(s.Stmt)
+	case *ast.RangeStmt:
+		if found, pos := hasFuncLiteral(s.X); found {
+			return pos
+		}
+		return s.Body.Lbrace
+	case *ast.SwitchStmt:
+		if found, pos := hasFuncLiteral(s.Init); found {
+			return pos
+		}
+		if found, pos := hasFuncLiteral(s.Tag); found {
+			return pos
+		}
+		return s.Body.Lbrace
+	case *ast.SelectStmt:
+		return s.Body.Lbrace
+	case *ast.TypeSwitchStmt:
+		if found, pos := hasFuncLiteral(s.Init); found {
+			return pos
+		}
+		return s.Body.Lbrace
+	}
+	// The body of a function literal is left out of the block of the
+	// statement.
+	if found, pos := hasFuncLiteral(s); found {
+		return pos
+	}
+	return s.End()
+}
+
+// endsBasicSourceBlock reports whether s changes the flow of control and so
+// ends a basic block.
+func (f *annotator) endsBasicSourceBlock(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.BlockStmt, *ast.BranchStmt, *ast.ForStmt, *ast.IfStmt, *ast.RangeStmt,
+		*ast.SwitchStmt, *ast.SelectStmt, *ast.TypeSwitchStmt:
+		return true
+	case *ast.LabeledStmt:
+		return true // a goto may branch here, starting a block
+	case *ast.ExprStmt:
+		// A call to panic changes the flow, assuming panic is the builtin
+		// one, which can't be checked without types.
+		if call, ok := s.X.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "panic" && len(call.Args) == 1 {
+				return true
+			}
+		}
+	}
+	found, _ := hasFuncLiteral(s)
+	return found
+}
+
+func isControl(s ast.Stmt) bool {
+	switch s.(type) {
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.SelectStmt, *ast.TypeSwitchStmt:
+		return true
+	}
+	return false
+}
+
+// hasFuncLiteral reports whether n contains a function literal, and returns
+// the position of the body of the first one.
+func hasFuncLiteral(n ast.Node) (bool, token.Pos) {
+	if n == nil {
+		return false, 0
+	}
+	var lit *ast.FuncLit
+	ast.Inspect(n, func(n ast.Node) bool {
+		if lit != nil {
+			return false
+		}
+		if fn, ok := n.(*ast.FuncLit); ok {
+			lit = fn
+			return false
+		}
+		return true
+	})
+	if lit == nil {
+		return false, 0
+	}
+	return true, lit.Body.Lbrace
+}
+
+// findText returns the offset of text in the source after pos, skipping
+// comments, or -1 if it isn't found.
+func (f *annotator) findText(pos token.Pos, text string) int {
+	b := []byte(text)
+	s := f.src
+	for i := f.offset(pos); i < len(s); {
+		if bytes.HasPrefix(s[i:], b) {
+			return i
+		}
+		if i+2 <= len(s) && s[i] == '/' && s[i+1] == '/' {
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		if i+2 <= len(s) && s[i] == '/' && s[i+1] == '*' {
+			end := bytes.Index(s[i+2:], []byte("*/"))
+			if end < 0 {
+				return -1
+			}
+			i += 2 + end + 2
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+// newCounter records the block from start to end of numStmt statements,
+// and returns the statement updating its counter.
+func (f *annotator) newCounter(start, end token.Pos, numStmt int) string {
+	n := len(f.blocks)
+	f.blocks = append(f.blocks, block{f.fset.Position(start), f.fset.Position(end), numStmt})
+	counter := fmt.Sprintf("%s.Count[%d]", f.varName, n)
+	switch f.mode {
+	case ModeSet:
+		return counter + " = 1"
+	case ModeCount:
+		return counter + "++"
+	default:
+		return atomicPkg + ".AddUint32(&" + counter + ", 1)"
+	}
+}
+
+// addVariables appends the counter variable, the table of the blocks, and
+// their registration to the annotated source.
+func (f *annotator) addVariables(buf *bytes.Buffer, name string) {
+	n := len(f.blocks)
+	fmt.Fprintf(buf, "\n\nvar %s = struct {\n", f.varName)
+	fmt.Fprintf(buf, "\tCount   [%d]uint32\n", n)
+	fmt.Fprintf(buf, "\tPos     [3 * %d]uint32\n", n)
+	fmt.Fprintf(buf, "\tNumStmt [%d]uint16\n", n)
+	fmt.Fprintf(buf, "}{\n")
+
+	// Each block is the line of its start, the line of its end, and their
+	// columns packed in a word.
+	fmt.Fprintf(buf, "\tPos: [3 * %d]uint32{\n", n)
+	for i, b := range f.blocks {
+		fmt.Fprintf(buf, "\t\t%d, %d, %#x, // [%d]\n", b.start.Line, b.end.Line, (b.end.Column&0xFFFF)<<16|(b.start.Column&0xFFFF), i)
+	}
+	fmt.Fprintf(buf, "\t},\n")
+	fmt.Fprintf(buf, "\tNumStmt: [%d]uint16{\n", n)
+	for i, b := range f.blocks {
+		// A block can't have more statements than fit in the table.
+		numStmt := min(b.numStmt, 1<<16-1)
+		fmt.Fprintf(buf, "\t\t%d, // %d\n", numStmt, i)
+	}
+	fmt.Fprintf(buf, "\t},\n")
+	fmt.Fprintf(buf, "}\n")
+	if f.mode == ModeAtomic {
+		// The file may have no blocks to use the import in.
+		fmt.Fprintf(buf, "\nvar _ = %s.LoadUint32\n", atomicPkg)
+	}
+
+	register := f.varName + "_register"
+	fmt.Fprintf(buf, "\nfunc init() {\n")
+	fmt.Fprintf(buf, "\t%s(%s, %s, %s.Count[:], %s.Pos[:], %s.NumStmt[:])\n",
+		register, strconv.Quote(f.mode), strconv.Quote(name), f.varName, f.varName, f.varName)
+	fmt.Fprintf(buf, "}\n")
+	fmt.Fprintf(buf, "\n//go:linkname %s %s\n", register, RegisterFunc)
+	fmt.Fprintf(buf, "func %s(mode, file string, counters, pos []uint32, numStmt []uint16)\n", register)
+}
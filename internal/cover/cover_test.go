@@ -0,0 +1,86 @@
+//go:build !llgo
+// +build !llgo
+
+package cover
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const testSrc = `package p
+
+func f(x int) int {
+	if x > 0 {
+		return 1
+	} else if x < 0 {
+		return -1
+	}
+	switch x {
+	case 1:
+	default:
+		x++
+	}
+	return 0
+}
+
+func _() {}
+`
+
+func TestAnnotate(t *testing.T) {
+	out, err := Annotate("p.go", []byte(testSrc), ModeCount, "GoCover_0", "example.com/p/p.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "p.go", out, 0); err != nil {
+		t.Fatalf("annotated source doesn't parse: %v\n%s", err, out)
+	}
+	got := string(out)
+	for _, want := range []string{
+		"package p; import _ \"unsafe\"\n",
+		"func f(x int) int {GoCover_0.Count[0]++;\n",
+		"} else{ GoCover_0.Count[4]++;if x < 0 {GoCover_0.Count[5]++;\n",
+		"\t}}\n\tGoCover_0.Count[1]++;switch x {\n",
+		"\tcase 1:GoCover_0.Count[6]++;\n",
+		"\tGoCover_0.Count[2]++;return 0\n",
+		"func _() {}\n",
+		"\t\t3, 4, 0xb0013, // [0]\n",
+		"\t\t9, 9, 0xb0002, // [1]\n",
+		"\t\t10, 10, 0x90009, // [6]\n",
+		"GoCover_0_register(\"count\", \"example.com/p/p.go\", GoCover_0.Count[:], GoCover_0.Pos[:], GoCover_0.NumStmt[:])",
+		"//go:linkname GoCover_0_register testing.llgoRegisterCover\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("annotated source doesn't contain %q:\n%s", want, got)
+		}
+	}
+	// The lines of the source don't move.
+	body, _, _ := strings.Cut(got, "\nvar GoCover_0 ")
+	if n, want := strings.Count(body, "\n"), strings.Count(testSrc, "\n")+1; n != want {
+		t.Errorf("annotated source has %d lines before the counters, want %d", n, want)
+	}
+}
+
+func TestAnnotateModes(t *testing.T) {
+	tests := []struct {
+		mode, want string
+	}{
+		{ModeSet, "GoCover_0.Count[0] = 1;"},
+		{ModeCount, "GoCover_0.Count[0]++;"},
+		{ModeAtomic, "_cover_atomic_.AddUint32(&GoCover_0.Count[0], 1);"},
+	}
+	for _, tt := range tests {
+		out, err := Annotate("p.go", []byte(testSrc), tt.mode, "GoCover_0", "p.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(out), tt.want) {
+			t.Errorf("mode %s: annotated source doesn't contain %q", tt.mode, tt.want)
+		}
+	}
+	if _, err := Annotate("p.go", []byte(testSrc), "sometimes", "GoCover_0", "p.go"); err == nil {
+		t.Error("Annotate with an invalid mode succeeded")
+	}
+}
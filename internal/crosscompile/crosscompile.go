@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/goplus/llgo/internal/env"
@@ -32,14 +33,75 @@ type Export struct {
 
 	BinaryFormat string // Binary format (e.g., "elf", "esp", "uf2")
 	FormatDetail string // For uf2, it's uf2FamilyID
+	Emulator     string // Emulator command template (e.g., "qemu-system-arm ... -kernel {}")
+
+	// Runtime configuration of the target, see targets.Config
+	GC         string // Garbage collector ("none", "leaking", "conservative", "precise")
+	Scheduler  string // Goroutine strategy ("none", "tasks", "asyncify", "cores", "threads")
+	StackSize  uint64 // Stack size of goroutines, 0 for the default of the runtime
+	Libc       string // C library (e.g., "picolibc", "wasi-libc")
+	RTLib      string // Compiler runtime library (e.g., "compiler-rt")
+	BuildMode  string // Build mode of the target (e.g., "c-shared")
+	WITPackage string // WIT package of the WebAssembly component
+	WITWorld   string // WIT world of the WebAssembly component
+}
+
+// EmulatorArgs expands the emulator template of the target to the command
+// running app with args, or returns nil if the target has no emulator. img
+// is the firmware image of app, used by the templates that refer to {img}.
+//
+// The args are passed to the program the way the emulator does: after the
+// program for the wasm runtimes, and as the command line of the kernel for
+// QEMU, by semihosting if it is enabled. It fails if there are args and the
+// emulator has no way to pass them, as for the flash images of QEMU.
+func (e *Export) EmulatorArgs(app, img string, args []string) ([]string, error) {
+	if e.Emulator == "" {
+		return nil, nil
+	}
+	envs := buildEnvMap(env.LLGoROOT())
+	envs["img"] = img
+	ret := expandEnvSliceWithDefault(strings.Fields(e.Emulator), envs, app)
+	if len(args) == 0 {
+		return ret, nil
+	}
+	switch name := filepath.Base(ret[0]); {
+	case strings.HasPrefix(name, "qemu-system-"):
+		if i := slices.Index(ret, "-semihosting"); i >= 0 {
+			// QEMU option values escape commas by doubling them
+			config := "enable=on,arg=" + strings.ReplaceAll(app, ",", ",,")
+			for _, arg := range args {
+				config += ",arg=" + strings.ReplaceAll(arg, ",", ",,")
+			}
+			return slices.Concat(ret[:i], []string{"-semihosting-config", config}, ret[i+1:]), nil
+		}
+		if slices.Contains(ret, "-kernel") {
+			return append(ret, "-append", strings.Join(args, " ")), nil
+		}
+	case name == "wasmtime", name == "node":
+		return append(ret, args...), nil
+	}
+	return nil, fmt.Errorf("emulator %s can't pass arguments to the program", ret[0])
 }
 
 // URLs and configuration that can be overridden for testing
 var (
-	wasiSdkUrl      = "https://github.com/WebAssembly/wasi-sdk/releases/download/wasi-sdk-25/wasi-sdk-25.0-x86_64-macos.tar.gz"
-	wasiMacosSubdir = "wasi-sdk-25.0-x86_64-macos"
+	wasiSdkSubdir = wasiSdkName(runtime.GOOS, runtime.GOARCH)
+	wasiSdkUrl    = "https://github.com/WebAssembly/wasi-sdk/releases/download/wasi-sdk-25/" + wasiSdkSubdir + ".tar.gz"
 )
 
+// wasiSdkName returns the name of the WASI SDK release for the host goos and
+// goarch, which is also the top directory of its archive.
+func wasiSdkName(goos, goarch string) string {
+	arch := "x86_64"
+	if goarch == "arm64" {
+		arch = "arm64"
+	}
+	if goos == "darwin" {
+		goos = "macos"
+	}
+	return "wasi-sdk-25.0-" + arch + "-" + goos
+}
+
 var (
 	espClangBaseUrl = "https://github.com/goplus/espressif-llvm-project-prebuilt/releases/download/19.1.2_20250820"
 	espClangVersion = "19.1.2_20250820"
@@ -468,9 +530,21 @@ func useTarget(targetName string) (export Export, err error) {
 	export.ExtraFiles = config.ExtraFiles
 	export.BinaryFormat = config.BinaryFormat
 	export.FormatDetail = config.FormatDetail()
+	export.Emulator = config.Emulator
+	export.GC = config.GC
+	export.Scheduler = config.Scheduler
+	export.StackSize = stackSize(config)
+	export.Libc = config.Libc
+	export.RTLib = config.RTLib
+	export.BuildMode = config.BuildMode
 
 	// Build environment map for template variable expansion
 	envs := buildEnvMap(env.LLGoROOT())
+	export.WITPackage = expandEnv(config.WITPackage, envs)
+	export.WITWorld = config.WITWorld
+
+	// Select the runtime implementation the target asks for
+	export.BuildTags = append(runtimeTags(config), export.BuildTags...)
 
 	// Convert LLVMTarget, CPU, Features to CCFLAGS/LDFLAGS
 	var ccflags []string
@@ -481,6 +555,9 @@ func useTarget(targetName string) (export Export, err error) {
 		cflags = append(cflags, "--target="+config.LLVMTarget)
 		ccflags = append(ccflags, "--target="+config.LLVMTarget)
 	}
+	if export.StackSize != 0 {
+		cflags = append(cflags, fmt.Sprintf("-DLLGO_STACK_SIZE=%d", export.StackSize))
+	}
 	// Expand template variables in cflags
 	expandedCFlags := expandEnvSlice(config.CFlags, envs)
 	cflags = append(cflags, expandedCFlags...)
@@ -574,6 +651,23 @@ func useTarget(targetName string) (export Export, err error) {
 	}
 	ldflags = append(ldflags, "-L", env.LLGoROOT()) // search targets/*.ld
 
+	// Handle the C library and the compiler runtime library. wasi-libc may
+	// need a download, so it's set up by UseLibc when the build needs it.
+	switch config.Libc {
+	case "picolibc":
+		// picolibc is not built by llgo, use it if it comes with the toolchain
+		sysrootDir := filepath.Join(clangRoot, "picolibc", config.LLVMTarget)
+		if _, err := os.Stat(sysrootDir); err == nil {
+			cflags = append(cflags, "-isystem", filepath.Join(sysrootDir, "include"))
+			ldflags = append(ldflags, "-L", filepath.Join(sysrootDir, "lib"), "-lc")
+		}
+	}
+	if config.RTLib == "compiler-rt" {
+		if lib := compilerRTLib(export.CC, config.LLVMTarget); lib != "" {
+			ldflags = append(ldflags, lib)
+		}
+	}
+
 	// Combine with config flags and expand template variables
 	export.CFLAGS = cflags
 	export.CCFLAGS = ccflags
@@ -583,6 +677,99 @@ func useTarget(targetName string) (export Export, err error) {
 	return export, nil
 }
 
+// runtimeTags returns the build tags selecting the garbage collector, the
+// goroutine strategy and the serial output of the runtime for config. The
+// runtime has no collector to build in with "nogc", and runs goroutines on
+// threads instead of its scheduler with "nosched".
+//
+// The M:N scheduler of the runtime only runs on the threads of linux and
+// darwin and has no scheduler name, so it's kept for the targets of these
+// goos that don't ask for a scheduler. The build tags of the targets don't
+// tell it, as the bare metal ones have "linux" too.
+func runtimeTags(config *targets.Config) []string {
+	var tags []string
+	switch config.GC {
+	case "":
+	case "none", "leaking":
+		tags = append(tags, "gc."+config.GC, "nogc")
+	default:
+		tags = append(tags, "gc."+config.GC)
+	}
+	if config.Scheduler != "" {
+		tags = append(tags, "scheduler."+config.Scheduler)
+	}
+	goos := config.GOOS
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if config.Scheduler != "" || goos != "linux" && goos != "darwin" {
+		tags = append(tags, "nosched")
+	}
+	if config.Serial != "" {
+		tags = append(tags, "serial."+config.Serial)
+	}
+	return tags
+}
+
+// minStackSize is the smallest stack of goroutines. The default-stack-size of
+// TinyGo targets is sized for the code of TinyGo, which llgo doesn't match.
+const minStackSize = 16 << 10
+
+// stackSize returns the stack size of goroutines for config, or 0 for the
+// default of the runtime. The stacks don't grow, so they are at least
+// minStackSize. The default-stack-size of the targets with an
+// automatic-stack-size is only for the goroutines TinyGo can't size from the
+// call graph, which llgo doesn't do, so their stacks are the default ones.
+func stackSize(config *targets.Config) uint64 {
+	if config.DefaultStackSize == 0 || config.AutomaticStackSize != nil && *config.AutomaticStackSize {
+		return 0
+	}
+	return max(config.DefaultStackSize, minStackSize)
+}
+
+// UseLibc adds the flags of the C library of the target that llgo fetches on
+// demand, which is wasi-libc, to CFLAGS and LDFLAGS. It may download the WASI
+// SDK, so the build calls it only before running clang the first time.
+func (e *Export) UseLibc() error {
+	if e.Libc != "wasi-libc" {
+		return nil
+	}
+	sysrootDir, err := wasiSysroot()
+	if err != nil {
+		return err
+	}
+	e.CFLAGS = append(e.CFLAGS, "--sysroot="+sysrootDir, "-isystem", filepath.Join(sysrootDir, "include", "wasm32-wasip1"))
+	e.LDFLAGS = append(e.LDFLAGS, "-L", filepath.Join(sysrootDir, "lib", "wasm32-wasip1"), "-lc")
+	return nil
+}
+
+// wasiSysroot returns the sysroot of wasi-libc, from LLGoROOT or the cached
+// WASI-SDK, which is downloaded if needed.
+func wasiSysroot() (string, error) {
+	wasiSdkRoot := filepath.Join(env.LLGoROOT(), "crosscompile", "wasi-libc")
+	if _, err := os.Stat(wasiSdkRoot); err != nil {
+		sdkDir := filepath.Join(cacheDir(), llvm.GetTargetTriple("wasip1", "wasm"))
+		if wasiSdkRoot, err = checkDownloadAndExtractWasiSDK(sdkDir); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(wasiSdkRoot, "share", "wasi-sysroot"), nil
+}
+
+// compilerRTLib returns the compiler-rt builtins library of cc for target,
+// or "" if cc has none.
+func compilerRTLib(cc, target string) string {
+	out, err := exec.Command(cc, "--target="+target, "--rtlib=compiler-rt", "-print-libgcc-file-name").Output()
+	if err != nil {
+		return ""
+	}
+	lib := strings.TrimSpace(string(out))
+	if _, err := os.Stat(lib); err != nil {
+		return ""
+	}
+	return lib
+}
+
 // Use extends the original Use function to support target-based configuration
 // If targetName is provided, it takes precedence over goos/goarch
 func Use(goos, goarch string, wasiThreads bool, targetName string) (export Export, err error) {
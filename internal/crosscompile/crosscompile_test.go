@@ -5,9 +5,13 @@ package crosscompile
 
 import (
 	"os"
+	"path/filepath"
 	"runtime"
 	"slices"
 	"testing"
+
+	"github.com/goplus/llgo/internal/targets"
+	"github.com/goplus/llgo/internal/xtool/llvm"
 )
 
 const (
@@ -302,6 +306,51 @@ func TestUseWithTarget(t *testing.T) {
 	}
 }
 
+func TestUseLibc(t *testing.T) {
+	tempCacheRoot := t.TempDir()
+	originalCacheRoot := cacheRoot
+	cacheRoot = func() string { return tempCacheRoot }
+	defer func() { cacheRoot = originalCacheRoot }()
+
+	// The cached WASI SDK, so that UseLibc doesn't download it
+	sdkRoot := filepath.Join(cacheDir(), llvm.GetTargetTriple("wasip1", "wasm"), wasiSdkSubdir)
+	if err := os.MkdirAll(sdkRoot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	export := Export{Libc: "wasi-libc", LDFLAGS: []string{"-L", "/lib"}}
+	if err := export.UseLibc(); err != nil {
+		t.Fatal(err)
+	}
+	sysrootDir := filepath.Join(sdkRoot, "share", "wasi-sysroot")
+	if !slices.Contains(export.CFLAGS, sysrootPrefix+sysrootDir) {
+		t.Errorf("Expected %s in CFLAGS, got %v", sysrootPrefix+sysrootDir, export.CFLAGS)
+	}
+	if want := []string{"-L", "/lib", "-L", filepath.Join(sysrootDir, "lib", "wasm32-wasip1"), "-lc"}; !slices.Equal(export.LDFLAGS, want) {
+		t.Errorf("LDFLAGS = %v, want %v", export.LDFLAGS, want)
+	}
+
+	export = Export{Libc: "picolibc"}
+	if err := export.UseLibc(); err != nil || export.CFLAGS != nil || export.LDFLAGS != nil {
+		t.Errorf("UseLibc of picolibc: %v, CFLAGS=%v, LDFLAGS=%v", err, export.CFLAGS, export.LDFLAGS)
+	}
+}
+
+func TestWasiSdkName(t *testing.T) {
+	tests := []struct {
+		goos, goarch, want string
+	}{
+		{"linux", "amd64", "wasi-sdk-25.0-x86_64-linux"},
+		{"linux", "arm64", "wasi-sdk-25.0-arm64-linux"},
+		{"darwin", "arm64", "wasi-sdk-25.0-arm64-macos"},
+		{"windows", "amd64", "wasi-sdk-25.0-x86_64-windows"},
+	}
+	for _, tt := range tests {
+		if got := wasiSdkName(tt.goos, tt.goarch); got != tt.want {
+			t.Errorf("wasiSdkName(%s, %s) = %s, want %s", tt.goos, tt.goarch, got, tt.want)
+		}
+	}
+}
+
 func TestExpandEnv(t *testing.T) {
 	envs := map[string]string{
 		"port": "/dev/ttyUSB0",
@@ -424,3 +473,86 @@ func TestExpandEnvWithDefault(t *testing.T) {
 		}
 	}
 }
+
+func TestEmulatorArgs(t *testing.T) {
+	tmpDir := os.TempDir()
+	tests := []struct {
+		emulator string
+		args     []string
+		expected []string
+	}{
+		{"", nil, nil},
+		{"qemu-system-arm -machine lm3s6965evb -semihosting -nographic -kernel {}", nil,
+			[]string{"qemu-system-arm", "-machine", "lm3s6965evb", "-semihosting", "-nographic", "-kernel", "app.elf"}},
+		{"qemu-system-arm -machine lm3s6965evb -semihosting -nographic -kernel {}", []string{"-test.run", "A,B"},
+			[]string{"qemu-system-arm", "-machine", "lm3s6965evb", "-semihosting-config", "enable=on,arg=app.elf,arg=-test.run,arg=A,,B", "-nographic", "-kernel", "app.elf"}},
+		{"qemu-system-riscv32 -machine virt -nographic -bios none -kernel {}", []string{"-test.v"},
+			[]string{"qemu-system-riscv32", "-machine", "virt", "-nographic", "-bios", "none", "-kernel", "app.elf", "-append", "-test.v"}},
+		{"wasmtime run --dir={tmpDir}::/tmp {}", []string{"-test.v"},
+			[]string{"wasmtime", "run", "--dir=" + tmpDir + "::/tmp", "app.elf", "-test.v"}},
+		{"qemu-system-xtensa -machine esp32 -nographic -drive file={img},if=mtd,format=raw", nil,
+			[]string{"qemu-system-xtensa", "-machine", "esp32", "-nographic", "-drive", "file=app.img,if=mtd,format=raw"}},
+	}
+	for _, test := range tests {
+		export := &Export{Emulator: test.emulator}
+		result, err := export.EmulatorArgs("app.elf", "app.img", test.args)
+		if err != nil || !slices.Equal(result, test.expected) {
+			t.Errorf("EmulatorArgs for %q %q = %q, %v, want %q", test.emulator, test.args, result, err, test.expected)
+		}
+	}
+
+	for _, emulator := range []string{
+		"qemu-system-xtensa -machine esp32 -nographic -drive file={img},if=mtd,format=raw",
+		"simavr -m atmega328p -f 16000000 {}",
+	} {
+		export := &Export{Emulator: emulator}
+		if _, err := export.EmulatorArgs("app.elf", "app.img", []string{"-test.v"}); err == nil {
+			t.Errorf("EmulatorArgs for %q with args: no error", emulator)
+		}
+	}
+}
+
+func TestRuntimeTags(t *testing.T) {
+	tests := []struct {
+		config   targets.Config
+		expected []string
+	}{
+		{targets.Config{GOOS: "linux"}, nil},
+		{targets.Config{GOOS: "darwin", GC: "conservative"}, []string{"gc.conservative"}},
+		{targets.Config{GOOS: "wasip1"}, []string{"nosched"}},
+		{targets.Config{GOOS: "linux", GC: "conservative", Scheduler: "tasks", Serial: "uart"},
+			[]string{"gc.conservative", "scheduler.tasks", "nosched", "serial.uart"}},
+		{targets.Config{GOOS: "linux", GC: "leaking", Scheduler: "cores"},
+			[]string{"gc.leaking", "nogc", "scheduler.cores", "nosched"}},
+		{targets.Config{GOOS: "linux", Scheduler: "threads"},
+			[]string{"scheduler.threads", "nosched"}},
+		{targets.Config{GOOS: "linux", GC: "none", Scheduler: "none", Serial: "none"},
+			[]string{"gc.none", "nogc", "scheduler.none", "nosched", "serial.none"}},
+	}
+	for _, test := range tests {
+		result := runtimeTags(&test.config)
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("runtimeTags(goos=%q, gc=%q, scheduler=%q, serial=%q) = %q, want %q",
+				test.config.GOOS, test.config.GC, test.config.Scheduler, test.config.Serial, result, test.expected)
+		}
+	}
+}
+
+func TestStackSize(t *testing.T) {
+	automatic := true
+	tests := []struct {
+		config   targets.Config
+		expected uint64
+	}{
+		{targets.Config{}, 0},
+		{targets.Config{DefaultStackSize: 65536}, 65536},
+		{targets.Config{DefaultStackSize: 2048}, minStackSize},
+		{targets.Config{DefaultStackSize: 2048, AutomaticStackSize: &automatic}, 0},
+	}
+	for _, test := range tests {
+		if result := stackSize(&test.config); result != test.expected {
+			t.Errorf("stackSize(default=%d, automatic=%v) = %d, want %d",
+				test.config.DefaultStackSize, test.config.AutomaticStackSize != nil, result, test.expected)
+		}
+	}
+}
@@ -15,7 +15,7 @@ import (
 
 // checkDownloadAndExtractWasiSDK downloads and extracts WASI SDK
 func checkDownloadAndExtractWasiSDK(dir string) (wasiSdkRoot string, err error) {
-	wasiSdkRoot = filepath.Join(dir, wasiMacosSubdir)
+	wasiSdkRoot = filepath.Join(dir, wasiSdkSubdir)
 
 	// Check if already exists
 	if _, err := os.Stat(wasiSdkRoot); err == nil {
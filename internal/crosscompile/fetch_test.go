@@ -308,7 +308,7 @@ func TestWasiSDKExtractionLogic(t *testing.T) {
 	tempDir := t.TempDir()
 
 	// Create fake WASI SDK directory structure
-	wasiSdkDir := filepath.Join(tempDir, wasiMacosSubdir)
+	wasiSdkDir := filepath.Join(tempDir, wasiSdkSubdir)
 	binDir := filepath.Join(wasiSdkDir, "bin")
 	err := os.MkdirAll(binDir, 0755)
 	if err != nil {
@@ -328,7 +328,7 @@ func TestWasiSDKExtractionLogic(t *testing.T) {
 		t.Fatalf("checkDownloadAndExtractWasiSDK failed: %v", err)
 	}
 
-	expectedRoot := filepath.Join(tempDir, wasiMacosSubdir)
+	expectedRoot := filepath.Join(tempDir, wasiSdkSubdir)
 	if sdkRoot != expectedRoot {
 		t.Errorf("Expected SDK root %q, got %q", expectedRoot, sdkRoot)
 	}
@@ -369,9 +369,9 @@ func TestESPClangExtractionLogic(t *testing.T) {
 func TestWasiSDKDownloadWhenNotExists(t *testing.T) {
 	// Create fake WASI SDK archive with proper structure
 	files := map[string]string{
-		"wasi-sdk-25.0-x86_64-macos/bin/clang":       "fake wasi clang binary",
-		"wasi-sdk-25.0-x86_64-macos/lib/libm.a":      "fake math library",
-		"wasi-sdk-25.0-x86_64-macos/include/stdio.h": "#include <stdio.h>",
+		wasiSdkSubdir + "/bin/clang":       "fake wasi clang binary",
+		wasiSdkSubdir + "/lib/libm.a":      "fake math library",
+		wasiSdkSubdir + "/include/stdio.h": "#include <stdio.h>",
 	}
 
 	archivePath := createTestTarGz(t, files)
@@ -384,7 +384,7 @@ func TestWasiSDKDownloadWhenNotExists(t *testing.T) {
 	}
 
 	server := createTestServer(t, map[string]string{
-		"wasi-sdk-25.0-x86_64-macos.tar.gz": string(archiveContent),
+		wasiSdkSubdir + ".tar.gz": string(archiveContent),
 	})
 	defer server.Close()
 
@@ -396,7 +396,7 @@ func TestWasiSDKDownloadWhenNotExists(t *testing.T) {
 
 	// Override wasiSdkUrl to use our test server
 	originalWasiSdkUrl := wasiSdkUrl
-	wasiSdkUrl = server.URL + "/wasi-sdk-25.0-x86_64-macos.tar.gz"
+	wasiSdkUrl = server.URL + "/" + wasiSdkSubdir + ".tar.gz"
 	defer func() { wasiSdkUrl = originalWasiSdkUrl }()
 
 	// Use the cache directory structure
@@ -408,7 +408,7 @@ func TestWasiSDKDownloadWhenNotExists(t *testing.T) {
 		t.Fatalf("checkDownloadAndExtractWasiSDK failed: %v", err)
 	}
 
-	expectedRoot := filepath.Join(extractDir, wasiMacosSubdir)
+	expectedRoot := filepath.Join(extractDir, wasiSdkSubdir)
 	if sdkRoot != expectedRoot {
 		t.Errorf("Expected SDK root %q, got %q", expectedRoot, sdkRoot)
 	}
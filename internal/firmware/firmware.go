@@ -14,6 +14,8 @@ func MakeFirmwareImage(infile, outfile, format, fmtDetail string) error {
 		return convertELFFileToUF2File(infile, outfile, uf2Family)
 	} else if format == "nrf-dfu" {
 		return makeDFUFirmwareImage(infile, outfile)
+	} else if format == "bin" || format == "hex" {
+		return objcopy(infile, outfile, format)
 	}
 	return fmt.Errorf("unsupported firmware format: %s", format)
 }
@@ -4,9 +4,11 @@ package firmware
 
 import (
 	"debug/elf"
+	"encoding/hex"
 	"io"
 	"os"
 	"sort"
+	"strings"
 )
 
 // maxPadBytes is the maximum allowed bytes to be padded in a rom extraction
@@ -115,7 +117,7 @@ func objcopy(infile, outfile, binaryFormat string) error {
 	defer f.Close()
 
 	// Read the .text segment.
-	_, data, err := extractROM(infile)
+	addr, data, err := extractROM(infile)
 	if err != nil {
 		return err
 	}
@@ -127,7 +129,48 @@ func objcopy(infile, outfile, binaryFormat string) error {
 		// should use .hex files in most cases).
 		_, err := f.Write(data)
 		return err
+	case "hex":
+		_, err := f.Write(intelHex(uint32(addr), data))
+		return err
 	default:
 		panic("unreachable")
 	}
 }
+
+// intelHex encodes data loaded at addr in the Intel HEX format, with 16
+// bytes per data record and an extended linear address record each time
+// the upper 16 bits of the address change.
+func intelHex(addr uint32, data []byte) []byte {
+	var out []byte
+	record := func(typ byte, addr uint16, data []byte) {
+		rec := []byte{byte(len(data)), byte(addr >> 8), byte(addr), typ}
+		rec = append(rec, data...)
+		var sum byte
+		for _, b := range rec {
+			sum += b
+		}
+		rec = append(rec, -sum)
+		out = append(out, ':')
+		out = append(out, strings.ToUpper(hex.EncodeToString(rec))...)
+		out = append(out, '\n')
+	}
+	upper := -1
+	for len(data) > 0 {
+		if int(addr>>16) != upper {
+			upper = int(addr >> 16)
+			record(4, 0, []byte{byte(upper >> 8), byte(upper)})
+		}
+		n := 16
+		if n > len(data) {
+			n = len(data)
+		}
+		if rest := 0x10000 - int(addr&0xffff); n > rest {
+			n = rest // don't cross a 64KiB boundary
+		}
+		record(0, uint16(addr), data[:n])
+		addr += uint32(n)
+		data = data[n:]
+	}
+	record(1, 0, nil)
+	return out
+}
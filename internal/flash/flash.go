@@ -0,0 +1,368 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package flash deploys programs to devices with the flash method of their
+// target configuration.
+package flash
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/goplus/llgo/internal/env"
+	"github.com/goplus/llgo/internal/firmware"
+	"github.com/goplus/llgo/internal/targets"
+)
+
+// Flash methods of target configurations.
+const (
+	MethodMSD     = "msd"     // copy the firmware to a mass storage device
+	MethodOpenOCD = "openocd" // program the device with OpenOCD
+	MethodCommand = "command" // run the flash-command of the target
+	MethodJLink   = "jlink"   // program the device with a SEGGER J-Link
+)
+
+var (
+	// msdTimeout is how long to wait for the mass storage device to appear,
+	// which takes a while after a reset into the bootloader.
+	msdTimeout = 10 * time.Second
+
+	// resetDelay is how long the device takes to reset after a 1200 bps
+	// touch of its serial port.
+	resetDelay = 2 * time.Second
+)
+
+// Flash deploys app, the ELF file linked for the target described by config,
+// to the device with the flash method of config. port is the serial port of
+// the device, or for the msd method the mount point of its volume, and may
+// be empty if the flash method needs none.
+func Flash(config *targets.Config, app, port string, verbose bool) error {
+	method := config.FlashMethod
+	if method == "" {
+		switch {
+		case config.FlashCommand != "":
+			method = MethodCommand
+		case config.JLinkDevice != "":
+			method = MethodJLink
+		}
+	}
+	needPort := config.Flash1200BpsReset == "true" ||
+		method == MethodCommand && strings.Contains(config.FlashCommand, "{port}")
+	if port == "" && needPort && len(config.SerialPort) > 0 {
+		var err error
+		if port, err = findSerialPort(config.SerialPort); err != nil {
+			return fmt.Errorf("target %s: %w", config.Name, err)
+		}
+	}
+	if config.Flash1200BpsReset == "true" && port != "" && !isDir(port) {
+		if verbose {
+			fmt.Fprintln(os.Stderr, "reset", port, "at 1200 bps")
+		}
+		if err := touchSerialPort(port); err != nil {
+			fmt.Fprintf(os.Stderr, "llgo flash: failed to reset %s: %v\n", port, err)
+		} else {
+			time.Sleep(resetDelay)
+		}
+	}
+	switch method {
+	case MethodMSD:
+		return flashMSD(config, app, port, verbose)
+	case MethodOpenOCD:
+		return flashOpenOCD(config, app, verbose)
+	case MethodCommand:
+		return flashCommand(config, app, port, verbose)
+	case MethodJLink:
+		return flashJLink(config, app, verbose)
+	case "":
+		return fmt.Errorf("target %s does not support flashing", config.Name)
+	}
+	return fmt.Errorf("target %s: unknown flash method %q", config.Name, method)
+}
+
+// flashMSD copies the firmware to the mass storage device the bootloader of
+// the device exposes.
+func flashMSD(config *targets.Config, app, port string, verbose bool) error {
+	if len(config.MSDVolumeName) == 0 || config.MSDFirmwareName == "" {
+		return fmt.Errorf("target %s: msd-volume-name and msd-firmware-name are required by the msd flash method", config.Name)
+	}
+	volume := port
+	if volume == "" || !isDir(volume) {
+		var err error
+		if volume, err = findMSDVolume(config.MSDVolumeName); err != nil {
+			return err
+		}
+	}
+	tmpDir, err := os.MkdirTemp("", "llgo-flash")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	image, err := makeImage(config, app, filepath.Join(tmpDir, config.MSDFirmwareName))
+	if err != nil {
+		return err
+	}
+	dest := filepath.Join(volume, config.MSDFirmwareName)
+	if verbose {
+		fmt.Fprintln(os.Stderr, "copy", image, "to", dest)
+	}
+	return copyFile(dest, image)
+}
+
+// findMSDVolume waits until a volume with one of names is mounted and
+// returns its mount point.
+func findMSDVolume(names []string) (string, error) {
+	deadline := time.Now().Add(msdTimeout)
+	for {
+		for _, root := range msdRoots() {
+			for _, name := range names {
+				if dir := filepath.Join(root, name); isDir(dir) {
+					return dir, nil
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("unable to locate any volume: [%s]", strings.Join(names, ","))
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// msdRoots returns the directories where removable volumes are mounted.
+var msdRoots = func() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"/Volumes"}
+	case "linux":
+		user := os.Getenv("USER")
+		return []string{"/media/" + user, "/run/media/" + user, "/media", "/mnt"}
+	}
+	return nil
+}
+
+// flashOpenOCD programs the device with OpenOCD, which takes the ELF file
+// as is.
+func flashOpenOCD(config *targets.Config, app string, verbose bool) error {
+	if config.OpenOCDInterface == "" || config.OpenOCDTarget == "" {
+		return fmt.Errorf("target %s: openocd-interface and openocd-target are required by the openocd flash method", config.Name)
+	}
+	args := []string{"-f", "interface/" + config.OpenOCDInterface + ".cfg"}
+	if config.OpenOCDTransport != "" {
+		args = append(args, "-c", "transport select "+config.OpenOCDTransport)
+	}
+	for _, cmd := range config.OpenOCDCommands {
+		args = append(args, "-c", cmd)
+	}
+	args = append(args, "-f", "target/"+config.OpenOCDTarget+".cfg")
+	program := "program " + filepath.ToSlash(app)
+	if config.OpenOCDVerify != nil && *config.OpenOCDVerify {
+		program += " verify"
+	}
+	args = append(args, "-c", program+" reset exit")
+	return run("openocd", args, verbose)
+}
+
+// flashJLink programs the device with the J-Link commander, which loads the
+// firmware as a hex file.
+func flashJLink(config *targets.Config, app string, verbose bool) error {
+	if config.JLinkDevice == "" {
+		return fmt.Errorf("target %s: jlink-device is required by the jlink flash method", config.Name)
+	}
+	tmpDir, err := os.MkdirTemp("", "llgo-flash")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	hex, err := makeImage(config, app, filepath.Join(tmpDir, "firmware.hex"))
+	if err != nil {
+		return err
+	}
+	script := filepath.Join(tmpDir, "flash.jlink")
+	commands := "halt\nloadfile " + hex + "\nreset\ngo\nexit\n"
+	if err := os.WriteFile(script, []byte(commands), 0644); err != nil {
+		return err
+	}
+	args := []string{"-device", config.JLinkDevice, "-autoconnect", "1", "-ExitOnError", "1", "-CommanderScript", script}
+	return run(jlinkExe(), args, verbose)
+}
+
+func jlinkExe() string {
+	if runtime.GOOS == "windows" {
+		return "JLink.exe"
+	}
+	return "JLinkExe"
+}
+
+// imageKinds lists the firmware files a flash-command can refer to.
+var imageKinds = []string{"elf", "hex", "bin", "uf2", "zip"}
+
+// flashCommand runs the flash-command of the target. It is a template where
+// {port} is replaced by the serial port, {root} by the LLGo root directory,
+// {tmpDir} by the temporary directory, and {elf}, {hex}, {bin}, {uf2} and
+// {zip} by the firmware in that format.
+func flashCommand(config *targets.Config, app, port string, verbose bool) error {
+	if config.FlashCommand == "" {
+		return fmt.Errorf("target %s: flash-command is required by the command flash method", config.Name)
+	}
+	if port == "" && strings.Contains(config.FlashCommand, "{port}") {
+		return fmt.Errorf("target %s: flash-command needs the serial port of the device, specify it with -port", config.Name)
+	}
+	tmpDir, err := os.MkdirTemp("", "llgo-flash")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	envs := map[string]string{
+		"port":   port,
+		"root":   env.LLGoROOT(),
+		"tmpDir": os.TempDir(),
+	}
+	for _, kind := range imageKinds {
+		if !strings.Contains(config.FlashCommand, "{"+kind+"}") {
+			continue
+		}
+		image := app
+		if kind != "elf" {
+			if image, err = makeImage(config, app, filepath.Join(tmpDir, "firmware."+kind)); err != nil {
+				return err
+			}
+		}
+		envs[kind] = image
+	}
+	args := strings.Fields(config.FlashCommand)
+	for i, arg := range args {
+		for key, value := range envs {
+			arg = strings.ReplaceAll(arg, "{"+key+"}", value)
+		}
+		args[i] = arg
+	}
+	return run(args[0], args[1:], verbose)
+}
+
+// makeImage converts app to the firmware image file, in the format implied
+// by its extension, and returns file.
+func makeImage(config *targets.Config, app, file string) (string, error) {
+	ext := filepath.Ext(file)
+	format := config.BinaryFormat
+	if format == "" || firmware.BinaryExt(format) != ext {
+		switch ext {
+		case ".elf":
+			return app, nil
+		case ".hex", ".bin", ".uf2":
+			format = ext[1:]
+		case ".zip":
+			format = "nrf-dfu"
+		default:
+			return "", fmt.Errorf("unsupported firmware file: %s", filepath.Base(file))
+		}
+	}
+	if err := firmware.MakeFirmwareImage(app, file, format, config.FormatDetail()); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// sysTTY is the sysfs directory of the serial ports on Linux.
+var sysTTY = "/sys/class/tty"
+
+// findSerialPort returns the serial port of the USB device with one of the
+// ids, in VID:PID form as the serial-port key of target configurations.
+func findSerialPort(ids []string) (string, error) {
+	if runtime.GOOS == "linux" {
+		entries, _ := os.ReadDir(sysTTY)
+		for _, entry := range entries {
+			dev, err := filepath.EvalSymlinks(filepath.Join(sysTTY, entry.Name(), "device"))
+			if err != nil {
+				continue
+			}
+			// ttyACM devices are USB interfaces and ttyUSB devices are ports
+			// of one, so the USB device is their parent or grandparent.
+			for dir, i := filepath.Dir(dev), 0; i < 2; dir, i = filepath.Dir(dir), i+1 {
+				vid, err1 := os.ReadFile(filepath.Join(dir, "idVendor"))
+				pid, err2 := os.ReadFile(filepath.Join(dir, "idProduct"))
+				if err1 != nil || err2 != nil {
+					continue
+				}
+				id := strings.TrimSpace(string(vid)) + ":" + strings.TrimSpace(string(pid))
+				for _, want := range ids {
+					if strings.EqualFold(id, want) {
+						return "/dev/" + entry.Name(), nil
+					}
+				}
+				break
+			}
+		}
+	}
+	return "", fmt.Errorf("unable to locate a serial port of [%s], specify it with -port", strings.Join(ids, ","))
+}
+
+// touchSerialPort opens port at 1200 bps, which resets boards such as the
+// Arduino ones into their bootloader.
+func touchSerialPort(port string) error {
+	switch runtime.GOOS {
+	case "linux":
+		return exec.Command("stty", "-F", port, "1200").Run()
+	case "darwin", "freebsd", "netbsd", "openbsd":
+		return exec.Command("stty", "-f", port, "1200").Run()
+	}
+	return errors.New("not supported on " + runtime.GOOS)
+}
+
+func run(name string, args []string, verbose bool) error {
+	if verbose {
+		fmt.Fprintln(os.Stderr, name, strings.Join(args, " "))
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", name, err)
+	}
+	return nil
+}
+
+func copyFile(dest, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync() // the device may reboot as soon as the file is closed
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
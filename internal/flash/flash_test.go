@@ -0,0 +1,279 @@
+//go:build !llgo
+// +build !llgo
+
+package flash
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llgo/internal/targets"
+)
+
+// writeELF writes an ARM executable whose single segment, .text, holds
+// text loaded at addr.
+func writeELF(t *testing.T, addr uint32, text []byte) string {
+	t.Helper()
+	const (
+		ehsize = 52
+		phsize = 32
+		shsize = 40
+	)
+	shstrtab := []byte("\x00.text\x00.shstrtab\x00")
+	textOff := uint32(ehsize + phsize)
+	strOff := textOff + uint32(len(text))
+	shOff := (strOff + uint32(len(shstrtab)) + 3) &^ 3
+
+	var buf bytes.Buffer
+	hdr := elf.Header32{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_ARM),
+		Version:   uint32(elf.EV_CURRENT),
+		Entry:     addr,
+		Phoff:     ehsize,
+		Shoff:     shOff,
+		Ehsize:    ehsize,
+		Phentsize: phsize,
+		Phnum:     1,
+		Shentsize: shsize,
+		Shnum:     3,
+		Shstrndx:  2,
+	}
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS32)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	binary.Write(&buf, binary.LittleEndian, &hdr)
+	binary.Write(&buf, binary.LittleEndian, &elf.Prog32{
+		Type:   uint32(elf.PT_LOAD),
+		Off:    textOff,
+		Vaddr:  addr,
+		Paddr:  addr,
+		Filesz: uint32(len(text)),
+		Memsz:  uint32(len(text)),
+		Flags:  uint32(elf.PF_R | elf.PF_X),
+		Align:  4,
+	})
+	buf.Write(text)
+	buf.Write(shstrtab)
+	buf.Write(make([]byte, int(shOff)-buf.Len()))
+	binary.Write(&buf, binary.LittleEndian, []elf.Section32{{}, {
+		Name:      1,
+		Type:      uint32(elf.SHT_PROGBITS),
+		Flags:     uint32(elf.SHF_ALLOC | elf.SHF_EXECINSTR),
+		Addr:      addr,
+		Off:       textOff,
+		Size:      uint32(len(text)),
+		Addralign: 4,
+	}, {
+		Name:      7,
+		Type:      uint32(elf.SHT_STRTAB),
+		Off:       strOff,
+		Size:      uint32(len(shstrtab)),
+		Addralign: 1,
+	}})
+
+	app := filepath.Join(t.TempDir(), "app.elf")
+	if err := os.WriteFile(app, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestFlashMSD(t *testing.T) {
+	app := writeELF(t, 0x10000000, bytes.Repeat([]byte{0xa5}, 300))
+	config := &targets.Config{
+		Name:            "pico",
+		FlashMethod:     MethodMSD,
+		MSDVolumeName:   []string{"RPI-RP2"},
+		MSDFirmwareName: "firmware.uf2",
+		BinaryFormat:    "uf2",
+		UF2FamilyID:     "0xe48bff56",
+	}
+
+	root := t.TempDir()
+	volume := filepath.Join(root, "RPI-RP2")
+	if err := os.Mkdir(volume, 0755); err != nil {
+		t.Fatal(err)
+	}
+	saved := msdRoots
+	defer func() { msdRoots = saved }()
+	msdRoots = func() []string { return []string{filepath.Join(root, "none"), root} }
+
+	if err := Flash(config, app, "", false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(volume, "firmware.uf2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 300 bytes in 256-byte payloads of 512-byte blocks.
+	if len(data) != 2*512 || !bytes.HasPrefix(data, []byte("UF2\n")) {
+		t.Errorf("bad UF2 file of %d bytes: %q", len(data), data[:min(len(data), 16)])
+	}
+
+	// The volume may also be given as the port.
+	other := t.TempDir()
+	if err := Flash(config, app, other, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(other, "firmware.uf2")); err != nil {
+		t.Error(err)
+	}
+
+	savedTimeout := msdTimeout
+	defer func() { msdTimeout = savedTimeout }()
+	msdTimeout = 0
+	config.MSDVolumeName = []string{"NOT-THERE"}
+	if err := Flash(config, app, "", false); err == nil || !strings.Contains(err.Error(), "NOT-THERE") {
+		t.Errorf("Flash without volume: got error %v", err)
+	}
+}
+
+func TestFlashCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub command needs a POSIX shell")
+	}
+	app := writeELF(t, 0x8000, []byte{1, 2, 3, 4})
+	out := filepath.Join(t.TempDir(), "out")
+	stub := filepath.Join(t.TempDir(), "stub.sh")
+	script := "#!/bin/sh\necho \"$@\" > " + out + "\ncat \"$2\" >> " + out + "\n"
+	if err := os.WriteFile(stub, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := &targets.Config{
+		Name:         "board",
+		FlashMethod:  MethodCommand,
+		FlashCommand: stub + " -P {port} -U flash:w:{hex}:i",
+	}
+
+	if err := Flash(config, app, "", false); err == nil || !strings.Contains(err.Error(), "-port") {
+		t.Errorf("Flash without port: got error %v", err)
+	}
+	config.FlashCommand = stub + " {port} {hex}"
+	if err := Flash(config, app, "/dev/ttyFAKE", false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{":020000040000FA", ":048000000102030472", ":00000001FF"}
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "/dev/ttyFAKE ") || strings.Join(lines[1:], "\n") != strings.Join(want, "\n") {
+		t.Errorf("flash-command got:\n%s", data)
+	}
+}
+
+func TestFlashOpenOCD(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub command needs a POSIX shell")
+	}
+	app := writeELF(t, 0x8000000, []byte{1, 2, 3, 4})
+	bin := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	script := "#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\"; done > " + out + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "openocd"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	verify := true
+	config := &targets.Config{
+		Name:             "bluepill-clone",
+		FlashMethod:      MethodOpenOCD,
+		OpenOCDInterface: "stlink-v2",
+		OpenOCDTarget:    "stm32f1x",
+		OpenOCDCommands:  []string{"set CPUTAPID 0x2ba01477"},
+		OpenOCDVerify:    &verify,
+	}
+	if err := Flash(config, app, "", false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"-f", "interface/stlink-v2.cfg",
+		"-c", "set CPUTAPID 0x2ba01477",
+		"-f", "target/stm32f1x.cfg",
+		"-c", "program " + filepath.ToSlash(app) + " verify reset exit",
+	}, "\n")
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("openocd got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFindSerialPort(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("serial ports are only looked up in sysfs")
+	}
+	root := t.TempDir()
+	saved := sysTTY
+	defer func() { sysTTY = saved }()
+	sysTTY = filepath.Join(root, "class", "tty")
+
+	// ttyACM0 is an interface of 2e8a:000a, ttyUSB0 a port of an
+	// interface of 10c4:ea60.
+	usb := filepath.Join(root, "devices", "usb1")
+	for tty, dev := range map[string]string{
+		"ttyACM0": filepath.Join(usb, "1-1", "1-1:1.0"),
+		"ttyUSB0": filepath.Join(usb, "1-2", "1-2:1.0", "ttyUSB0"),
+		"ttyS0":   filepath.Join(root, "devices", "platform", "serial8250"),
+	} {
+		if err := os.MkdirAll(dev, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(sysTTY, tty), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(dev, filepath.Join(sysTTY, tty, "device")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for dir, id := range map[string][2]string{"1-1": {"2e8a", "000a"}, "1-2": {"10c4", "ea60"}} {
+		os.WriteFile(filepath.Join(usb, dir, "idVendor"), []byte(id[0]+"\n"), 0644)
+		os.WriteFile(filepath.Join(usb, dir, "idProduct"), []byte(id[1]+"\n"), 0644)
+	}
+
+	tests := []struct {
+		ids  []string
+		want string
+	}{
+		{[]string{"2e8a:000A"}, "/dev/ttyACM0"},
+		{[]string{"2341:0043", "10c4:ea60"}, "/dev/ttyUSB0"},
+		{[]string{"2341:0043"}, ""},
+	}
+	for _, tt := range tests {
+		port, err := findSerialPort(tt.ids)
+		if port != tt.want || (err == nil) != (tt.want != "") {
+			t.Errorf("findSerialPort(%v) = %q, %v, want %q", tt.ids, port, err, tt.want)
+		}
+	}
+}
+
+func TestFlashErrors(t *testing.T) {
+	tests := []struct {
+		config *targets.Config
+		want   string
+	}{
+		{&targets.Config{Name: "wasi"}, "does not support flashing"},
+		{&targets.Config{Name: "x", FlashMethod: "jtag"}, `unknown flash method "jtag"`},
+		{&targets.Config{Name: "x", FlashMethod: MethodMSD}, "msd-volume-name"},
+		{&targets.Config{Name: "x", FlashMethod: MethodOpenOCD}, "openocd-interface"},
+		{&targets.Config{Name: "x", FlashMethod: MethodJLink}, "jlink-device"},
+	}
+	for _, tt := range tests {
+		err := Flash(tt.config, "app", "", false)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Flash(%+v) = %v, want error containing %q", tt.config, err, tt.want)
+		}
+	}
+}
@@ -44,6 +44,14 @@ func genFrom(pkgPath string, abiMode build.AbiMode) (build.Package, error) {
 		os.Setenv("LLGO_DEBUG", oldDbg)
 		os.Setenv("LLGO_DEBUG_SYMBOLS", oldDbgSyms)
 	}()
+	// The PC-line tables record the paths of the source files, which would
+	// make the generated IR depend on where the repository is.
+	defer setEnv("LLGO_PCLINE", "0")()
+	// The goldens are the IR of the heap allocations, the escape analysis
+	// being tested by cl/escape_test.go.
+	defer setEnv("LLGO_ESCAPE", "0")()
+	// Same for the pointer-free and typed allocations, see ssa/ssa_test.go.
+	defer setEnv("LLGO_TYPED_ALLOC", "0")()
 
 	conf := &build.Config{
 		Mode:    build.ModeGen,
@@ -57,6 +65,20 @@ func genFrom(pkgPath string, abiMode build.AbiMode) (build.Package, error) {
 	return pkgs[0], nil
 }
 
+// setEnv sets the environment variable key to value and returns a function
+// restoring it.
+func setEnv(key, value string) func() {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
 func DoFile(fileOrPkg, outFile string) {
 	ret := GenFrom(fileOrPkg)
 	err := os.WriteFile(outFile, []byte(ret), 0644)
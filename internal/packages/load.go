@@ -737,6 +737,12 @@ func LoadEx(dedup Deduper, sizes func(sizes types.Sizes, compiler, arch string)
 	return refineEx(dedup, ld, response)
 }
 
+// Load loads the packages named by patterns, with the details of cfg.Mode,
+// without the deduplication of LoadEx.
+func Load(cfg *Config, patterns ...string) ([]*Package, error) {
+	return packages.Load(cfg, patterns...)
+}
+
 // Visit visits all the packages in the import graph whose roots are
 // pkgs, calling the optional pre function the first time each package
 // is encountered (preorder), and the optional post function after a
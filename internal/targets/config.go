@@ -26,6 +26,15 @@ type Config struct {
 	CodeModel       string `json:"code-model"`
 	TargetABI       string `json:"target-abi"`
 	RelocationModel string `json:"relocation-model"`
+	BuildMode       string `json:"buildmode"`
+
+	// Runtime configuration
+	Scheduler          string `json:"scheduler"`
+	GC                 string `json:"gc"`
+	DefaultStackSize   uint64 `json:"default-stack-size"`
+	AutomaticStackSize *bool  `json:"automatic-stack-size"`
+	Libc               string `json:"libc"`
+	RTLib              string `json:"rtlib"`
 
 	// Binary and firmware configuration
 	BinaryFormat string `json:"binary-format"`
@@ -41,6 +50,10 @@ type Config struct {
 	MSDVolumeName   []string `json:"msd-volume-name"`
 	MSDFirmwareName string   `json:"msd-firmware-name"`
 
+	// Serial configuration
+	Serial     string   `json:"serial"`
+	SerialPort []string `json:"serial-port"` // USB VID:PID of the serial port
+
 	// Device-specific configuration
 	RP2040BootPatch bool `json:"rp2040-boot-patch"`
 
@@ -49,14 +62,28 @@ type Config struct {
 	GDB      []string `json:"gdb"`
 
 	// OpenOCD configuration
-	OpenOCDInterface string `json:"openocd-interface"`
-	OpenOCDTransport string `json:"openocd-transport"`
-	OpenOCDTarget    string `json:"openocd-target"`
+	OpenOCDInterface string   `json:"openocd-interface"`
+	OpenOCDTransport string   `json:"openocd-transport"`
+	OpenOCDTarget    string   `json:"openocd-target"`
+	OpenOCDCommands  []string `json:"openocd-commands"`
+	OpenOCDVerify    *bool    `json:"openocd-verify"`
+
+	// J-Link configuration
+	JLinkDevice string `json:"jlink-device"`
+
+	// WebAssembly component configuration
+	WITPackage string `json:"wit-package"`
+	WITWorld   string `json:"wit-world"`
+
+	// unknownKeys lists the keys of the target files of this config that
+	// are not fields of Config, reported by Resolver.validateConfig.
+	unknownKeys []string
 }
 
 // RawConfig represents the raw JSON configuration before inheritance resolution
 type RawConfig struct {
 	Inherits []string `json:"inherits"`
+	Comment  string   `json:"comment"`
 	Config
 }
 
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -43,6 +45,16 @@ func (l *Loader) LoadRaw(name string) (*RawConfig, error) {
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse target config %s: %w", name, err)
 	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse target config %s: %w", name, err)
+	}
+	for key := range keys {
+		if !knownKeys[key] {
+			config.unknownKeys = append(config.unknownKeys, fmt.Sprintf("%q in %s.json", key, name))
+		}
+	}
+	sort.Strings(config.unknownKeys)
 
 	// Set the name
 	config.Name = name
@@ -53,6 +65,27 @@ func (l *Loader) LoadRaw(name string) (*RawConfig, error) {
 	return &config, nil
 }
 
+// knownKeys is the set of keys of target files, the JSON names of the fields
+// of RawConfig.
+var knownKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	var addKeys func(t reflect.Type)
+	addKeys = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous {
+				addKeys(f.Type)
+				continue
+			}
+			if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+				keys[name] = true
+			}
+		}
+	}
+	addKeys(reflect.TypeOf(RawConfig{}))
+	return keys
+}()
+
 // Load loads a target configuration with inheritance resolved
 func (l *Loader) Load(name string) (*Config, error) {
 	raw, err := l.LoadRaw(name)
@@ -149,6 +182,30 @@ func (l *Loader) mergeConfig(dst, src *Config) {
 	if src.RelocationModel != "" {
 		dst.RelocationModel = src.RelocationModel
 	}
+	if src.BuildMode != "" {
+		dst.BuildMode = src.BuildMode
+	}
+	if src.Scheduler != "" {
+		dst.Scheduler = src.Scheduler
+	}
+	if src.GC != "" {
+		dst.GC = src.GC
+	}
+	if src.DefaultStackSize != 0 {
+		dst.DefaultStackSize = src.DefaultStackSize
+	}
+	if src.AutomaticStackSize != nil {
+		dst.AutomaticStackSize = src.AutomaticStackSize
+	}
+	if src.Libc != "" {
+		dst.Libc = src.Libc
+	}
+	if src.RTLib != "" {
+		dst.RTLib = src.RTLib
+	}
+	if src.Serial != "" {
+		dst.Serial = src.Serial
+	}
 	if src.BinaryFormat != "" {
 		dst.BinaryFormat = src.BinaryFormat
 	}
@@ -182,6 +239,18 @@ func (l *Loader) mergeConfig(dst, src *Config) {
 	if src.OpenOCDTarget != "" {
 		dst.OpenOCDTarget = src.OpenOCDTarget
 	}
+	if src.OpenOCDVerify != nil {
+		dst.OpenOCDVerify = src.OpenOCDVerify
+	}
+	if src.JLinkDevice != "" {
+		dst.JLinkDevice = src.JLinkDevice
+	}
+	if src.WITPackage != "" {
+		dst.WITPackage = src.WITPackage
+	}
+	if src.WITWorld != "" {
+		dst.WITWorld = src.WITWorld
+	}
 
 	// Merge slices (append, don't replace)
 	if len(src.BuildTags) > 0 {
@@ -202,6 +271,15 @@ func (l *Loader) mergeConfig(dst, src *Config) {
 	if len(src.GDB) > 0 {
 		dst.GDB = append(dst.GDB, src.GDB...)
 	}
+	if len(src.SerialPort) > 0 {
+		dst.SerialPort = append(dst.SerialPort, src.SerialPort...)
+	}
+	if len(src.OpenOCDCommands) > 0 {
+		dst.OpenOCDCommands = append(dst.OpenOCDCommands, src.OpenOCDCommands...)
+	}
+	if len(src.unknownKeys) > 0 {
+		dst.unknownKeys = append(dst.unknownKeys, src.unknownKeys...)
+	}
 }
 
 // GetTargetsDir returns the targets directory path
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 )
 
 // Resolver provides high-level interface for target configuration resolution
@@ -53,15 +55,31 @@ func (r *Resolver) ListAvailableTargets() ([]string, error) {
 	return r.loader.ListTargets()
 }
 
-// validateConfig validates that a resolved config has required fields
+// validateConfig validates that a resolved config has required fields, only
+// known keys and valid values of the enumerated ones
 func (r *Resolver) validateConfig(config *Config) error {
 	if config.Name == "" {
 		return fmt.Errorf("target name is required")
 	}
 
-	// For now, we don't require any specific fields since different targets
-	// may have different requirements. This can be extended in the future.
-
+	if len(config.unknownKeys) > 0 {
+		return fmt.Errorf("unknown keys: %s", strings.Join(config.unknownKeys, ", "))
+	}
+	for _, field := range []struct {
+		key, value string
+		valid      []string
+	}{
+		{"scheduler", config.Scheduler, []string{"none", "tasks", "asyncify", "cores", "threads"}},
+		{"gc", config.GC, []string{"none", "leaking", "conservative", "precise"}},
+		{"serial", config.Serial, []string{"none", "uart", "usb", "rtt"}},
+		{"libc", config.Libc, []string{"picolibc", "wasi-libc", "wasmbuiltins"}}, // the ones crosscompile sets up
+		{"rtlib", config.RTLib, []string{"compiler-rt"}},
+		{"buildmode", config.BuildMode, []string{"default", "c-shared", "wasi-legacy"}},
+	} {
+		if field.value != "" && !slices.Contains(field.valid, field.value) {
+			return fmt.Errorf("invalid %s %q, must be one of %s", field.key, field.value, strings.Join(field.valid, ", "))
+		}
+	}
 	return nil
 }
 
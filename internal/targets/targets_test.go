@@ -5,6 +5,7 @@ package targets
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -331,3 +332,101 @@ func TestResolveAllRealTargets(t *testing.T) {
 	t.Logf("GOOS distribution: %v", goosCounts)
 	t.Logf("GOARCH distribution: %v", goarchCounts)
 }
+
+func TestLoaderRuntimeInheritance(t *testing.T) {
+	tempDir := t.TempDir()
+
+	parentConfig := `{
+		"llvm-target": "thumbv7em-unknown-unknown-eabi",
+		"scheduler": "tasks",
+		"gc": "conservative",
+		"default-stack-size": 2048,
+		"automatic-stack-size": true,
+		"libc": "picolibc",
+		"rtlib": "compiler-rt",
+		"serial-port": ["2341:8036"],
+		"openocd-commands": ["adapter speed 1000"],
+		"openocd-verify": true
+	}`
+	childConfig := `{
+		"inherits": ["parent"],
+		"default-stack-size": 4096,
+		"automatic-stack-size": false,
+		"serial": "usb",
+		"serial-port": ["2341:8037"],
+		"openocd-commands": ["reset_config srst_only"],
+		"jlink-device": "nrf52840_xxaa"
+	}`
+	if err := os.WriteFile(filepath.Join(tempDir, "parent.json"), []byte(parentConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "child.json"), []byte(childConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := NewResolver(tempDir).Resolve("child")
+	if err != nil {
+		t.Fatalf("Failed to resolve child config: %v", err)
+	}
+	if config.Scheduler != "tasks" || config.GC != "conservative" || config.Libc != "picolibc" || config.RTLib != "compiler-rt" {
+		t.Errorf("Expected inherited runtime config, got scheduler=%q gc=%q libc=%q rtlib=%q",
+			config.Scheduler, config.GC, config.Libc, config.RTLib)
+	}
+	if config.DefaultStackSize != 4096 {
+		t.Errorf("Expected overridden default-stack-size 4096, got %d", config.DefaultStackSize)
+	}
+	if config.AutomaticStackSize == nil || *config.AutomaticStackSize {
+		t.Errorf("Expected overridden automatic-stack-size false, got %v", config.AutomaticStackSize)
+	}
+	if config.OpenOCDVerify == nil || !*config.OpenOCDVerify {
+		t.Errorf("Expected inherited openocd-verify true, got %v", config.OpenOCDVerify)
+	}
+	if config.Serial != "usb" || config.JLinkDevice != "nrf52840_xxaa" {
+		t.Errorf("Expected serial 'usb' and jlink-device 'nrf52840_xxaa', got %q and %q", config.Serial, config.JLinkDevice)
+	}
+	if len(config.SerialPort) != 2 || config.SerialPort[1] != "2341:8037" {
+		t.Errorf("Expected merged serial-port, got %v", config.SerialPort)
+	}
+	if len(config.OpenOCDCommands) != 2 || config.OpenOCDCommands[0] != "adapter speed 1000" {
+		t.Errorf("Expected merged openocd-commands, got %v", config.OpenOCDCommands)
+	}
+}
+
+func TestResolverValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"typo", `{"llvm-target": "wasm32", "shceduler": "none"}`, `unknown keys: "shceduler" in typo.json`},
+		{"inherited", `{"inherits": ["typo"]}`, `"shceduler" in typo.json`},
+		{"badgc", `{"gc": "refcount"}`, `invalid gc "refcount"`},
+		{"badserial", `{"serial": "spi"}`, `invalid serial "spi"`},
+		{"badlibc", `{"libc": "musl"}`, `invalid libc "musl"`},
+	}
+	tempDir := t.TempDir()
+	for _, tt := range tests {
+		if err := os.WriteFile(filepath.Join(tempDir, tt.name+".json"), []byte(tt.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolver := NewResolver(tempDir)
+	for _, tt := range tests {
+		_, err := resolver.Resolve(tt.name)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Resolve(%s) = %v, want error containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	// All the real targets are valid.
+	resolver = NewDefaultResolver()
+	names, err := resolver.ListAvailableTargets()
+	if err != nil {
+		t.Fatalf("Failed to list real targets: %v", err)
+	}
+	for _, name := range names {
+		if _, err := resolver.Resolve(name); err != nil {
+			t.Error(err)
+		}
+	}
+}
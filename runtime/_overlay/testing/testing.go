@@ -1 +1,88 @@
 package testing
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// llgoCover holds the coverage counters of the files annotated by
+// "llgo test -cover", in the order they were registered.
+var llgoCover struct {
+	Cover
+	files []string
+}
+
+// llgoRegisterCover is called by the init functions of the files annotated
+// by "llgo test -cover", which may run before the testing package is
+// initialized, to register their counters and the table of the blocks
+// they count.
+func llgoRegisterCover(mode, file string, counters, pos []uint32, numStmt []uint16) {
+	if 3*len(counters) != len(pos) || len(counters) != len(numStmt) {
+		panic("coverage: mismatched sizes")
+	}
+	c := &llgoCover.Cover
+	if c.Counters == nil {
+		c.Mode = mode
+		c.Counters = make(map[string][]uint32)
+		c.Blocks = make(map[string][]CoverBlock)
+	}
+	if c.Counters[file] != nil {
+		return // already registered
+	}
+	blocks := make([]CoverBlock, len(counters))
+	for i := range counters {
+		blocks[i] = CoverBlock{
+			Line0: pos[3*i+0],
+			Col0:  uint16(pos[3*i+2]),
+			Line1: pos[3*i+1],
+			Col1:  uint16(pos[3*i+2] >> 16),
+			Stmts: numStmt[i],
+		}
+	}
+	c.Counters[file] = counters
+	c.Blocks[file] = blocks
+	llgoCover.files = append(llgoCover.files, file)
+}
+
+// llgoCoverReport reports the coverage percentage, and writes the cover
+// profile to the file of -test.coverprofile if it is set.
+func llgoCoverReport(profile string) {
+	c := &llgoCover.Cover
+	var f *os.File
+	if profile != "" {
+		var err error
+		if f, err = os.Create(profile); err != nil {
+			fmt.Fprintf(os.Stderr, "testing: %s\n", err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(f, "mode: %s\n", c.Mode)
+	}
+	var active, total int64
+	for _, file := range llgoCover.files {
+		counters, blocks := c.Counters[file], c.Blocks[file]
+		for i := range counters {
+			stmts := int64(blocks[i].Stmts)
+			count := atomic.LoadUint32(&counters[i])
+			total += stmts
+			if count > 0 {
+				active += stmts
+			}
+			if f != nil {
+				b := &blocks[i]
+				fmt.Fprintf(f, "%s:%d.%d,%d.%d %d %d\n", file, b.Line0, b.Col0, b.Line1, b.Col1, stmts, count)
+			}
+		}
+	}
+	if f != nil {
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "testing: can't write %s: %s\n", profile, err)
+			os.Exit(2)
+		}
+	}
+	if total == 0 {
+		fmt.Println("coverage: [no statements]")
+		return
+	}
+	fmt.Printf("coverage: %.1f%% of statements\n", 100*float64(active)/float64(total))
+}
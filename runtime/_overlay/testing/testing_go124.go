@@ -703,6 +703,9 @@ func Testing() bool {
 // values are "set", "count", or "atomic". The return value will be
 // empty if test coverage is not enabled.
 func CoverMode() string {
+	if llgoCover.Mode != "" {
+		return llgoCover.Mode
+	}
 	if goexperiment.CoverageRedesign {
 		return cover2.mode
 	}
@@ -2438,7 +2441,9 @@ func (m *M) writeProfiles() {
 		}
 		f.Close()
 	}
-	if CoverMode() != "" {
+	if llgoCover.Mode != "" {
+		llgoCoverReport(toOutputDir(*coverProfile))
+	} else if CoverMode() != "" {
 		coverReport()
 	}
 }
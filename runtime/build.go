@@ -43,6 +43,7 @@ var hasAltPkg = map[string]none{
 	"math/big":                 {},
 	"math/cmplx":               {},
 	"math/rand":                {},
+	"net":                      {},
 	"reflect":                  {},
 	"sync":                     {},
 	"sync/atomic":              {},
@@ -34,12 +34,30 @@ func Init()
 //go:linkname Malloc C.GC_malloc
 func Malloc(size uintptr) c.Pointer
 
+//go:linkname MallocAtomic C.GC_malloc_atomic
+func MallocAtomic(size uintptr) c.Pointer
+
 //go:linkname Realloc C.GC_realloc
 func Realloc(ptr c.Pointer, size uintptr) c.Pointer
 
 //go:linkname Free C.GC_free
 func Free(ptr c.Pointer)
 
+//go:linkname Base C.GC_base
+func Base(ptr c.Pointer) c.Pointer
+
+// -----------------------------------------------------------------------------
+
+// Descr is a GC_descr, the layout of the objects allocated by
+// MallocExplicitlyTyped.
+type Descr uintptr
+
+//go:linkname MakeDescriptor C.GC_make_descriptor
+func MakeDescriptor(bm *uintptr, len uintptr) Descr
+
+//go:linkname MallocExplicitlyTyped C.GC_malloc_explicitly_typed
+func MallocExplicitlyTyped(size uintptr, d Descr) c.Pointer
+
 // -----------------------------------------------------------------------------
 
 //go:linkname RegisterFinalizer C.GC_register_finalizer
@@ -66,6 +84,15 @@ func RegisterFinalizerUnreachable(
 	fn func(c.Pointer, c.Pointer), cd c.Pointer,
 	oldFn *func(c.Pointer, c.Pointer), oldCd *c.Pointer)
 
+//go:linkname SetFinalizeOnDemand C.GC_set_finalize_on_demand
+func SetFinalizeOnDemand(v c.Int)
+
+//go:linkname SetFinalizerNotifier C.GC_set_finalizer_notifier
+func SetFinalizerNotifier(fn func())
+
+//go:linkname InvokeFinalizers C.GC_invoke_finalizers
+func InvokeFinalizers() c.Int
+
 // -----------------------------------------------------------------------------
 
 //go:linkname Enable C.GC_enable
@@ -83,6 +110,64 @@ func Gcollect()
 //go:linkname GetMemoryUse C.GC_get_memory_use
 func GetMemoryUse() uintptr
 
+//go:linkname GcollectAndUnmap C.GC_gcollect_and_unmap
+func GcollectAndUnmap()
+
+//go:linkname SetStartCallback C.GC_set_start_callback
+func SetStartCallback(fn func())
+
+// SetOomFn sets the function called when an allocation fails, whose result
+// is returned by the allocation instead of nil.
+//
+//go:linkname SetOomFn C.GC_set_oom_fn
+func SetOomFn(fn func(size uintptr) c.Pointer)
+
+// -----------------------------------------------------------------------------
+
+//go:linkname GetHeapSize C.GC_get_heap_size
+func GetHeapSize() uintptr
+
+//go:linkname GetFreeBytes C.GC_get_free_bytes
+func GetFreeBytes() uintptr
+
+//go:linkname GetTotalBytes C.GC_get_total_bytes
+func GetTotalBytes() uintptr
+
+//go:linkname GetGcNo C.GC_get_gc_no
+func GetGcNo() uintptr
+
+// ProfStats is the GC_prof_stats_s structure filled by GetProfStats.
+type ProfStats struct {
+	HeapsizeFull           uintptr
+	FreeBytesFull          uintptr
+	UnmappedBytes          uintptr
+	BytesAllocdSinceGC     uintptr
+	AllocdBytesBeforeGC    uintptr
+	NonGCBytes             uintptr
+	GCNo                   uintptr
+	MarkersM1              uintptr
+	BytesReclaimedSinceGC  uintptr
+	ReclaimedBytesBeforeGC uintptr
+}
+
+//go:linkname GetProfStats C.GC_get_prof_stats
+func GetProfStats(stats *ProfStats, size uintptr) uintptr
+
+//go:linkname SetFreeSpaceDivisor C.GC_set_free_space_divisor
+func SetFreeSpaceDivisor(v uintptr)
+
+//go:linkname GetFreeSpaceDivisor C.GC_get_free_space_divisor
+func GetFreeSpaceDivisor() uintptr
+
+//go:linkname SetMaxHeapSize C.GC_set_max_heap_size
+func SetMaxHeapSize(n uintptr)
+
+// RegisterAltstack tells the collector about the alternate signal stack of
+// the current thread, so that it scans the stack a signal handler runs on.
+//
+//go:linkname RegisterAltstack C.GC_register_altstack
+func RegisterAltstack(normstack c.Pointer, normstackSize uintptr, altstack c.Pointer, altstackSize uintptr)
+
 // -----------------------------------------------------------------------------
 
 //go:linkname EnableIncremental C.GC_enable_incremental
@@ -38,4 +38,23 @@ void llgo_stacktrace(int skip, void *ctx, int (*fn)(void *ctx, void *pc, void *o
             }
         }
     }
-}
\ No newline at end of file
+}
+
+int llgo_backtrace(int skip, void **pcs, int max) {
+    unw_cursor_t cursor;
+    unw_context_t context;
+    unw_word_t pc;
+    unw_getcontext(&context);
+    unw_init_local(&cursor, &context);
+    int n = 0;
+    while (n < max && unw_step(&cursor) > 0) {
+        if (skip > 0) {
+            skip--;
+            continue;
+        }
+        if (unw_get_reg(&cursor, UNW_REG_IP, &pc) == 0) {
+            pcs[n++] = (void *)pc;
+        }
+    }
+    return n;
+}
@@ -22,8 +22,11 @@ type Info struct {
 //go:linkname Address C.llgo_address
 func Address() unsafe.Pointer
 
+// Addrinfo looks up the symbol and the object file containing the code
+// address addr.
+//
 //go:linkname Addrinfo C.llgo_addrinfo
-func Addrinfo(addr unsafe.Pointer, info *Info) c.Int
+func Addrinfo(addr uintptr, info *Info) c.Int
 
 //go:linkname stacktrace C.llgo_stacktrace
 func stacktrace(skip c.Int, ctx unsafe.Pointer, fn func(ctx, pc, offset, sp unsafe.Pointer, name *c.Char) c.Int)
@@ -45,10 +48,22 @@ func StackTrace(skip int, fn func(fr *Frame) bool) {
 	})
 }
 
+//go:linkname backtrace C.llgo_backtrace
+func backtrace(skip c.Int, pcs *uintptr, max c.Int) c.Int
+
+// Backtrace stores the return addresses of the calling stack into pcs and
+// returns the number of entries written. It doesn't allocate memory.
+func Backtrace(skip int, pcs []uintptr) int {
+	if len(pcs) == 0 {
+		return 0
+	}
+	return int(backtrace(c.Int(1+skip), &pcs[0], c.Int(len(pcs))))
+}
+
 func PrintStack(skip int) {
 	StackTrace(skip+1, func(fr *Frame) bool {
 		var info Info
-		Addrinfo(unsafe.Pointer(fr.PC), &info)
+		Addrinfo(fr.PC, &info)
 		c.Fprintf(c.Stderr, c.Str("[0x%08X %s+0x%x, SP = 0x%x]\n"), fr.PC, fr.Name, fr.Offset, fr.SP)
 		return true
 	})
@@ -21,7 +21,7 @@ func Address() unsafe.Pointer {
 	panic("not implemented")
 }
 
-func Addrinfo(addr unsafe.Pointer, info *Info) c.Int {
+func Addrinfo(addr uintptr, info *Info) c.Int {
 	panic("not implemented")
 }
 
@@ -36,6 +36,10 @@ func StackTrace(skip int, fn func(fr *Frame) bool) {
 	panic("not implemented")
 }
 
+func Backtrace(skip int, pcs []uintptr) int {
+	return 0
+}
+
 func PrintStack(skip int) {
 	print_stack(c.Int(skip + 4))
 }
@@ -37,6 +37,8 @@ type (
 	GidT  uint32
 	OffT  int64
 	DevT  int32
+
+	NfdsT c.Uint
 )
 
 //go:linkname Clearenv C.cliteClearenv
@@ -67,3 +67,21 @@ func Fork() PidT
 
 //go:linkname Kill C.kill
 func Kill(pid PidT, sig c.Int) c.Int
+
+// -----------------------------------------------------------------------------
+
+const (
+	POLLIN  = 0x1
+	POLLOUT = 0x4
+	POLLERR = 0x8
+	POLLHUP = 0x10
+)
+
+type Pollfd struct {
+	Fd      c.Int
+	Events  int16
+	Revents int16
+}
+
+//go:linkname Poll C.poll
+func Poll(fds *Pollfd, nfds NfdsT, timeout c.Int) c.Int
@@ -39,6 +39,8 @@ type (
 	GidT  uint32
 	OffT  int64
 	DevT  uint64
+
+	NfdsT c.Ulong
 )
 
 //go:linkname Clearenv C.clearenv
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tsan declares the annotations of the ThreadSanitizer runtime,
+// which is only linked in by -race builds.
+package tsan
+
+import (
+	_ "unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+)
+
+const (
+	LLGoPackage = "decl"
+)
+
+// Acquire makes the calling thread synchronized after the last Release of
+// addr.
+//
+//go:linkname Acquire C.__tsan_acquire
+func Acquire(addr c.Pointer)
+
+// Release makes the next Acquire of addr synchronized after the calling
+// thread.
+//
+//go:linkname Release C.__tsan_release
+func Release(addr c.Pointer)
@@ -0,0 +1,10 @@
+//go:build linux && !(amd64 || 386)
+
+package poll
+
+// epollEvent is struct epoll_event.
+type epollEvent struct {
+	events uint32
+	_      uint32
+	data   [8]byte // uintptr
+}
@@ -1,45 +1,315 @@
 package poll
 
+import (
+	"sync"
+	"time"
+
+	ctime "github.com/goplus/llgo/runtime/internal/clite/time"
+	"github.com/goplus/llgo/runtime/internal/runtime"
+)
+
+// -----------------------------------------------------------------------------
+
 func runtime_Semacquire(sema *uint32) {
-	panic("todo: poll.runtime_Semacquire")
+	runtime.Semacquire(sema)
 }
 
 func runtime_Semrelease(sema *uint32) {
-	panic("todo: poll.runtime_Semrelease")
+	runtime.Semrelease(sema)
+}
+
+// -----------------------------------------------------------------------------
+
+func runtimeNano() int64 {
+	var ts ctime.Timespec
+	ctime.ClockGettime(ctime.CLOCK_MONOTONIC, &ts)
+	return int64(ts.Sec)*1e9 + int64(ts.Nsec)
+}
+
+// -----------------------------------------------------------------------------
+
+// rtPollDesc is the runtime side of a pollable file descriptor. The poller
+// thread (see netpoll) marks it ready on I/O events, and goroutines waiting
+// in runtime_pollWait park on the semaphore of their mode until it's ready,
+// closing or their deadline expires.
+type rtPollDesc struct {
+	id      uintptr
+	fd      uintptr
+	mu      sync.Mutex
+	closing bool
+	r, w    pollWaiters
+}
+
+// pollWaiters are the goroutines waiting for a mode of a rtPollDesc.
+type pollWaiters struct {
+	ready bool
+	d     int64       // deadline: 0 means none, <0 means expired
+	timer *time.Timer // expires d
+	sema  uint32
+	n     uint32 // number of goroutines parked on sema
+}
+
+// wake wakes up the waiters to recheck pd. It's called with pd.mu held.
+func (pw *pollWaiters) wake() {
+	for ; pw.n > 0; pw.n-- {
+		runtime.Semrelease(&pw.sema)
+	}
+}
+
+// pds maps the ids of the open rtPollDescs, which internal/poll, package os
+// and the kernel refer to, to them. The id of a closed one isn't reused, so
+// that its late events are ignored and its late waiters see it closing.
+var (
+	pdsMu  sync.Mutex
+	pds    = make(map[uintptr]*rtPollDesc)
+	lastID uintptr
+)
+
+func pollDesc(id uintptr) *rtPollDesc {
+	pdsMu.Lock()
+	pd := pds[id]
+	pdsMu.Unlock()
+	return pd
+}
+
+const (
+	pollNoError        = 0 // no error
+	pollErrClosing     = 1 // descriptor is closed
+	pollErrTimeout     = 2 // I/O timeout
+	pollErrNotPollable = 3 // general error polling descriptor
+)
+
+// netpollready marks the rtPollDesc of id ready for read and/or write. It's
+// called by the poller thread, which may see events of closed ones.
+func netpollready(id uintptr, read, write bool) {
+	pd := pollDesc(id)
+	if pd == nil {
+		return
+	}
+	pd.mu.Lock()
+	if read {
+		pd.r.ready = true
+		pd.r.wake()
+	}
+	if write {
+		pd.w.ready = true
+		pd.w.wake()
+	}
+	pd.mu.Unlock()
+}
+
+func expired(d int64) bool {
+	return d < 0 || (d > 0 && d <= runtimeNano())
 }
 
+// check returns the error of waiting for mode. It's called with pd.mu held.
+func (pd *rtPollDesc) check(mode int) int {
+	if pd.closing {
+		return pollErrClosing
+	}
+	if (mode == 'r' || mode == 'r'+'w') && expired(pd.r.d) {
+		return pollErrTimeout
+	}
+	if (mode == 'w' || mode == 'r'+'w') && expired(pd.w.d) {
+		return pollErrTimeout
+	}
+	return pollNoError
+}
+
+// netpollInited makes netpollinit run once, as both FD and package os
+// start the poller.
+var netpollInited sync.Once
+
 func runtime_pollServerInit() {
-	panic("todo: poll.runtime_pollServerInit")
+	netpollInited.Do(netpollinit)
 }
 
 func runtime_pollOpen(fd uintptr) (uintptr, int) {
-	panic("todo: poll.runtime_pollOpen")
+	pdsMu.Lock()
+	lastID++
+	pd := &rtPollDesc{id: lastID, fd: fd}
+	pds[pd.id] = pd
+	pdsMu.Unlock()
+	if errno := netpollopen(fd, pd.id); errno != 0 {
+		runtime_pollClose(pd.id)
+		return 0, errno
+	}
+	return pd.id, 0
 }
 
 func runtime_pollClose(ctx uintptr) {
-	panic("todo: poll.runtime_pollClose")
+	pd := pollDesc(ctx)
+	if pd == nil {
+		return
+	}
+	netpollclose(pd.fd)
+	pdsMu.Lock()
+	delete(pds, ctx)
+	pdsMu.Unlock()
+	pd.mu.Lock()
+	pd.setDeadline(&pd.r, 0)
+	pd.setDeadline(&pd.w, 0)
+	pd.mu.Unlock()
 }
 
 func runtime_pollWait(ctx uintptr, mode int) int {
-	panic("todo: poll.runtime_pollWait")
+	pd := pollDesc(ctx)
+	if pd == nil {
+		return pollErrClosing
+	}
+	pw := &pd.r
+	if mode == 'w' {
+		pw = &pd.w
+	}
+	pd.mu.Lock()
+	for {
+		if res := pd.check(mode); res != pollNoError {
+			pd.mu.Unlock()
+			return res
+		}
+		if pw.ready {
+			pw.ready = false
+			pd.mu.Unlock()
+			return pollNoError
+		}
+		pw.n++
+		pd.mu.Unlock()
+		runtime.Semacquire(&pw.sema)
+		pd.mu.Lock()
+	}
 }
 
 func runtime_pollWaitCanceled(ctx uintptr, mode int) {
-	panic("todo: poll.runtime_pollWaitCanceled")
 }
 
 func runtime_pollReset(ctx uintptr, mode int) int {
-	panic("todo: poll.runtime_pollReset")
+	pd := pollDesc(ctx)
+	if pd == nil {
+		return pollErrClosing
+	}
+	pd.mu.Lock()
+	res := pd.check(mode)
+	if res == pollNoError {
+		if mode == 'r' {
+			pd.r.ready = false
+		} else if mode == 'w' {
+			pd.w.ready = false
+		}
+	}
+	pd.mu.Unlock()
+	return res
+}
+
+// setDeadline sets the deadline of pw, which is pd.r or pd.w, to d and
+// wakes up its waiters to recheck it. It's called with pd.mu held.
+func (pd *rtPollDesc) setDeadline(pw *pollWaiters, d int64) {
+	if pw.timer != nil {
+		pw.timer.Stop()
+		pw.timer = nil
+	}
+	pw.d = d
+	if d > 0 {
+		pw.timer = time.AfterFunc(time.Duration(d-runtimeNano()), func() {
+			pd.expire(pw)
+		})
+	}
+	pw.wake()
+}
+
+// expire wakes up the waiters of pw once its deadline is due. A timer
+// stopped too late may call it for a later deadline, which is ignored.
+func (pd *rtPollDesc) expire(pw *pollWaiters) {
+	pd.mu.Lock()
+	if pw.d > 0 && pw.d <= runtimeNano() {
+		pw.d = -1
+		pw.timer = nil
+		pw.wake()
+	}
+	pd.mu.Unlock()
 }
 
 func runtime_pollSetDeadline(ctx uintptr, d int64, mode int) {
-	panic("todo: poll.runtime_pollSetDeadline")
+	pd := pollDesc(ctx)
+	if pd == nil {
+		return
+	}
+	if d > 0 {
+		d += runtimeNano()
+		if d <= 0 {
+			// overflow
+			d = 1<<63 - 1
+		}
+	}
+	pd.mu.Lock()
+	if mode == 'r' || mode == 'r'+'w' {
+		pd.setDeadline(&pd.r, d)
+	}
+	if mode == 'w' || mode == 'r'+'w' {
+		pd.setDeadline(&pd.w, d)
+	}
+	pd.mu.Unlock()
 }
 
 func runtime_pollUnblock(ctx uintptr) {
-	panic("todo: poll.runtime_pollUnblock")
+	pd := pollDesc(ctx)
+	if pd == nil {
+		return
+	}
+	pd.mu.Lock()
+	pd.closing = true
+	pd.r.wake()
+	pd.w.wake()
+	pd.mu.Unlock()
 }
 
 func runtime_isPollServerDescriptor(fd uintptr) bool {
-	panic("todo: poll.runtime_isPollServerDescriptor")
+	return isPollServerDescriptor(fd)
+}
+
+// -----------------------------------------------------------------------------
+
+// Package os uses the poller for the deadlines of pollable files, such as
+// pipes and FIFOs, through the functions below: it's compiled without the
+// files of this package which define FD.
+
+// The results of FileReset and FileWait.
+const (
+	FileNoError    = pollNoError
+	FileErrClosing = pollErrClosing
+	FileErrTimeout = pollErrTimeout
+)
+
+// FileOpen registers fd to the poller. It returns the context of fd, or 0
+// if fd can't be polled.
+func FileOpen(fd uintptr) uintptr {
+	runtime_pollServerInit()
+	ctx, errno := runtime_pollOpen(fd)
+	if errno != 0 {
+		return 0
+	}
+	return ctx
+}
+
+// FileClose wakes up the waiters of ctx and unregisters it. fd must be
+// closed after.
+func FileClose(ctx uintptr) {
+	runtime_pollUnblock(ctx)
+	runtime_pollClose(ctx)
+}
+
+// FileSetDeadline sets the deadline of mode, which is 'r', 'w' or 'r'+'w',
+// d nanoseconds from now: 0 means none and <0 means expired.
+func FileSetDeadline(ctx uintptr, d int64, mode int) {
+	runtime_pollSetDeadline(ctx, d, mode)
+}
+
+// FileReset prepares ctx for I/O of mode, which is 'r' or 'w'.
+func FileReset(ctx uintptr, mode int) int {
+	return runtime_pollReset(ctx, mode)
+}
+
+// FileWait waits until ctx is ready for mode, which is 'r' or 'w', its
+// deadline expires or it's closed.
+func FileWait(ctx uintptr, mode int) int {
+	return runtime_pollWait(ctx, mode)
 }
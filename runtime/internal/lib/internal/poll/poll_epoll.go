@@ -0,0 +1,90 @@
+//go:build linux
+
+package poll
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/os"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	"github.com/goplus/llgo/runtime/internal/clite/syscall"
+)
+
+const (
+	_EPOLLIN       = 0x1
+	_EPOLLOUT      = 0x4
+	_EPOLLERR      = 0x8
+	_EPOLLHUP      = 0x10
+	_EPOLLRDHUP    = 0x2000
+	_EPOLLET       = 0x80000000
+	_EPOLL_CLOEXEC = 0x80000
+	_EPOLL_CTL_ADD = 0x1
+	_EPOLL_CTL_DEL = 0x2
+)
+
+//go:linkname epoll_create1 C.epoll_create1
+func epoll_create1(flags c.Int) c.Int
+
+//go:linkname epoll_ctl C.epoll_ctl
+func epoll_ctl(epfd, op, fd c.Int, ev *epollEvent) c.Int
+
+//go:linkname epoll_wait C.epoll_wait
+func epoll_wait(epfd c.Int, evs *epollEvent, maxevents, timeout c.Int) c.Int
+
+var epfd c.Int = -1
+
+func netpollinit() {
+	epfd = epoll_create1(_EPOLL_CLOEXEC)
+	if epfd < 0 {
+		c.Fprintf(c.Stderr, c.Str("netpollinit: failed to create epoll descriptor (%d)\n"), os.Errno())
+		panic("netpollinit: failed to create epoll descriptor")
+	}
+	var th pthread.Thread
+	pthread.Create(&th, nil, netpoll, nil)
+}
+
+func isPollServerDescriptor(fd uintptr) bool {
+	return c.Int(fd) == epfd
+}
+
+func netpollopen(fd uintptr, id uintptr) int {
+	var ev epollEvent
+	ev.events = _EPOLLIN | _EPOLLOUT | _EPOLLRDHUP | _EPOLLET
+	*(*uintptr)(unsafe.Pointer(&ev.data)) = id
+	if epoll_ctl(epfd, _EPOLL_CTL_ADD, c.Int(fd), &ev) != 0 {
+		return int(os.Errno())
+	}
+	return 0
+}
+
+func netpollclose(fd uintptr) {
+	var ev epollEvent
+	epoll_ctl(epfd, _EPOLL_CTL_DEL, c.Int(fd), &ev)
+}
+
+// netpoll runs on its own thread, waiting for I/O events forever. It's
+// not a goroutine, so that it doesn't hold a worker of the scheduler.
+func netpoll(arg c.Pointer) c.Pointer {
+	var events [128]epollEvent
+	for {
+		n := epoll_wait(epfd, &events[0], c.Int(len(events)), -1)
+		if n < 0 {
+			if errno := os.Errno(); errno != c.Int(syscall.EINTR) {
+				c.Fprintf(c.Stderr, c.Str("netpoll: epoll_wait failed (%d)\n"), errno)
+				panic("netpoll: epoll_wait failed")
+			}
+			continue
+		}
+		for i := c.Int(0); i < n; i++ {
+			ev := &events[i]
+			if ev.events == 0 {
+				continue
+			}
+			read := ev.events&(_EPOLLIN|_EPOLLRDHUP|_EPOLLHUP|_EPOLLERR) != 0
+			write := ev.events&(_EPOLLOUT|_EPOLLHUP|_EPOLLERR) != 0
+			id := *(*uintptr)(unsafe.Pointer(&ev.data))
+			netpollready(id, read, write)
+		}
+	}
+}
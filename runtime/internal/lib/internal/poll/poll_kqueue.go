@@ -0,0 +1,101 @@
+//go:build darwin
+
+package poll
+
+import (
+	_ "unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/os"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	"github.com/goplus/llgo/runtime/internal/clite/syscall"
+	"github.com/goplus/llgo/runtime/internal/clite/time"
+)
+
+const (
+	_EVFILT_READ  = -0x1
+	_EVFILT_WRITE = -0x2
+	_EV_ADD       = 0x1
+	_EV_DELETE    = 0x2
+	_EV_CLEAR     = 0x20
+	_EV_EOF       = 0x8000
+	_EV_ERROR     = 0x4000
+)
+
+// keventT is struct kevent.
+type keventT struct {
+	ident  uint64
+	filter int16
+	flags  uint16
+	fflags uint32
+	data   int64
+	udata  uintptr // id of the rtPollDesc
+}
+
+//go:linkname kqueue C.kqueue
+func kqueue() c.Int
+
+//go:linkname kevent C.kevent
+func kevent(kq c.Int, changes *keventT, nchanges c.Int, events *keventT, nevents c.Int, timeout *time.Timespec) c.Int
+
+var kq c.Int = -1
+
+func netpollinit() {
+	kq = kqueue()
+	if kq < 0 {
+		c.Fprintf(c.Stderr, c.Str("netpollinit: failed to create kqueue (%d)\n"), os.Errno())
+		panic("netpollinit: failed to create kqueue")
+	}
+	os.Fcntl(kq, syscall.F_SETFD, syscall.FD_CLOEXEC)
+	var th pthread.Thread
+	pthread.Create(&th, nil, netpoll, nil)
+}
+
+func isPollServerDescriptor(fd uintptr) bool {
+	return c.Int(fd) == kq
+}
+
+func netpollopen(fd uintptr, id uintptr) int {
+	var ev [2]keventT
+	ev[0] = keventT{
+		ident:  uint64(fd),
+		filter: _EVFILT_READ,
+		flags:  _EV_ADD | _EV_CLEAR,
+		udata:  id,
+	}
+	ev[1] = ev[0]
+	ev[1].filter = _EVFILT_WRITE
+	if kevent(kq, &ev[0], 2, nil, 0, nil) < 0 {
+		return int(os.Errno())
+	}
+	return 0
+}
+
+func netpollclose(fd uintptr) {
+	// Closing the fd removes it from kqueue.
+}
+
+// netpoll runs on its own thread, waiting for I/O events forever. It's
+// not a goroutine, so that it doesn't hold a worker of the scheduler.
+func netpoll(arg c.Pointer) c.Pointer {
+	var events [64]keventT
+	for {
+		n := kevent(kq, nil, 0, &events[0], c.Int(len(events)), nil)
+		if n < 0 {
+			if errno := os.Errno(); errno != c.Int(syscall.EINTR) {
+				c.Fprintf(c.Stderr, c.Str("netpoll: kevent failed (%d)\n"), errno)
+				panic("netpoll: kevent failed")
+			}
+			continue
+		}
+		for i := c.Int(0); i < n; i++ {
+			ev := &events[i]
+			switch ev.filter {
+			case _EVFILT_READ:
+				netpollready(ev.udata, true, ev.flags&_EV_EOF != 0)
+			case _EVFILT_WRITE:
+				netpollready(ev.udata, false, true)
+			}
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+
+package poll
+
+func netpollinit() {}
+
+func isPollServerDescriptor(fd uintptr) bool {
+	return false
+}
+
+// netpollopen reports every descriptor as not pollable, so internal/poll
+// falls back to blocking I/O.
+func netpollopen(fd uintptr, id uintptr) int {
+	return -1
+}
+
+func netpollclose(fd uintptr) {}
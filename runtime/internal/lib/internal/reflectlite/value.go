@@ -5,6 +5,7 @@
 package reflectlite
 
 import (
+	"runtime"
 	"unsafe"
 
 	"github.com/goplus/llgo/runtime/abi"
@@ -172,15 +173,12 @@ func (e *ValueError) Error() string {
 // methodName returns the name of the calling method,
 // assumed to be two stack frames above.
 func methodName() string {
-	/* TODO(xsw):
 	pc, _, _, _ := runtime.Caller(2)
 	f := runtime.FuncForPC(pc)
 	if f == nil {
 		return "unknown method"
 	}
 	return f.Name()
-	*/
-	return "unknown method"
 }
 
 // emptyInterface is the header for an interface{} value.
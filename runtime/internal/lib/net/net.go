@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package net
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/goplus/llgo/runtime/internal/clite/math/rand"
+)
+
+func runtime_rand() uint64 {
+	v1 := uint64(rand.Random())
+	v2 := uint64(rand.Random())
+	return v1 ^ (v2 << 32)
+}
+
+// newUnixFile returns a File for a network connection fd. As in Go, the
+// descriptor is switched back to blocking mode, since existing code expects
+// conn.File().Fd() to be blocking.
+func newUnixFile(fd int, name string) *os.File {
+	if fd < 0 {
+		panic("invalid FD")
+	}
+	syscall.SetNonblock(fd, false)
+	return os.NewFile(uintptr(fd), name)
+}
@@ -144,6 +144,7 @@ func (f *File) ReadDir(n int) (dirents []DirEntry, err error) {
 		return nil, err
 	}
 	defer closedir(dir)
+	f.dirread = true
 
 	// Match Readdir and Readdirnames: don't return nil slices.
 	dirents = []DirEntry{}
@@ -7,6 +7,7 @@ package os
 import (
 	"io/fs"
 	"syscall"
+	_ "unsafe"
 )
 
 // Portable analogs of some common system call errors.
@@ -23,12 +24,11 @@ var (
 	ErrNotExist   = fs.ErrNotExist   // "file does not exist"
 	ErrClosed     = fs.ErrClosed     // "file already closed"
 
-	// TODO(xsw):
-	// ErrNoDeadline       = errNoDeadline()       // "file type does not support deadline"
-	// ErrDeadlineExceeded = errDeadlineExceeded() // "i/o timeout"
+	ErrNoDeadline       = errNoDeadline()       // "file type does not support deadline"
+	ErrDeadlineExceeded = errDeadlineExceeded() // "i/o timeout"
 )
 
-// func errNoDeadline() error { return poll.ErrNoDeadline }
+func errNoDeadline() error { return pollErrNoDeadline }
 
 // errDeadlineExceeded returns the value for os.ErrDeadlineExceeded.
 // This error comes from the internal/poll package, which is also
@@ -37,7 +37,16 @@ var (
 // as documented by net.Conn.SetDeadline, without requiring any extra
 // work in the net package and without requiring the internal/poll
 // package to import os (which it can't, because that would be circular).
-// func errDeadlineExceeded() error { return poll.ErrDeadlineExceeded }
+func errDeadlineExceeded() error { return pollErrDeadlineExceeded }
+
+// The errors are linked, since internal/poll is imported through its patch,
+// which doesn't declare them.
+
+//go:linkname pollErrNoDeadline internal/poll.ErrNoDeadline
+var pollErrNoDeadline error
+
+//go:linkname pollErrDeadlineExceeded internal/poll.ErrDeadlineExceeded
+var pollErrDeadlineExceeded error
 
 type timeout interface {
 	Timeout() bool
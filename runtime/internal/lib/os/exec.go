@@ -6,11 +6,13 @@ package os
 
 import (
 	"errors"
-	"runtime"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
+	"unsafe"
+
+	rt "github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 // ErrProcessDone indicates a Process has finished.
@@ -26,7 +28,9 @@ type Process struct {
 
 func newProcess(pid int, handle uintptr) *Process {
 	p := &Process{Pid: pid, handle: handle}
-	runtime.SetFinalizer(p, (*Process).Release)
+	rt.TrySetFinalizer(unsafe.Pointer(p), func(p unsafe.Pointer) {
+		(*Process)(p).Release()
+	})
 	return p
 }
 
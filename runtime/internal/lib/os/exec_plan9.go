@@ -6,9 +6,11 @@ package os
 
 import (
 	"internal/itoa"
-	"runtime"
 	"syscall"
 	"time"
+	"unsafe"
+
+	rt "github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 // The only signal values guaranteed to be present in the os package
@@ -87,7 +89,7 @@ func (p *Process) release() error {
 	// NOOP for Plan 9.
 	p.Pid = -1
 	// no need for a finalizer anymore
-	runtime.SetFinalizer(p, nil)
+	rt.TrySetFinalizer(unsafe.Pointer(p), nil)
 	return nil
 }
 
@@ -8,9 +8,11 @@ package os
 
 import (
 	"errors"
-	"runtime"
 	"syscall"
 	"time"
+	"unsafe"
+
+	rt "github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 func (p *Process) wait() (ps *ProcessState, err error) {
@@ -88,7 +90,7 @@ func (p *Process) release() error {
 	// NOOP for unix.
 	p.Pid = -1
 	// no need for a finalizer anymore
-	runtime.SetFinalizer(p, nil)
+	rt.TrySetFinalizer(unsafe.Pointer(p), nil)
 	return nil
 }
 
@@ -10,6 +10,9 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
+	"unsafe"
+
+	rt "github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 func (p *Process) wait() (ps *ProcessState, err error) {
@@ -80,7 +83,7 @@ func (p *Process) release() error {
 		return NewSyscallError("CloseHandle", e)
 	}
 	// no need for a finalizer anymore
-	runtime.SetFinalizer(p, nil)
+	rt.TrySetFinalizer(unsafe.Pointer(p), nil)
 	return nil
 }
 
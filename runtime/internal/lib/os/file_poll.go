@@ -0,0 +1,105 @@
+package os
+
+import (
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/goplus/llgo/runtime/internal/lib/internal/poll"
+)
+
+// pollDesc implements the deadlines of a pollable File, such as a pipe or a
+// FIFO, on the poller of internal/poll. The descriptor is left in blocking
+// mode until a deadline is first set. From then on it's non-blocking, and
+// I/O that would block parks the goroutine on the poller until the
+// descriptor is ready, the deadline expires or the File is closed. Setting
+// a deadline doesn't interrupt a Read or Write already blocked in blocking
+// mode.
+type pollDesc struct {
+	ctx      uintptr    // context of the descriptor in the poller
+	mu       sync.Mutex // guards the following
+	nonblock bool       // whether the descriptor is in non-blocking mode
+	ownmode  bool       // whether nonblock was set by setDeadline
+	closing  bool
+}
+
+// newPollDesc returns the pollDesc of fd, or nil if it doesn't support
+// deadlines.
+func newPollDesc(fd int, kind newFileKind) *pollDesc {
+	if !pollable(fd, kind) {
+		return nil
+	}
+	ctx := poll.FileOpen(uintptr(fd))
+	if ctx == 0 {
+		return nil
+	}
+	return &pollDesc{ctx: ctx, nonblock: kind == kindNonBlock}
+}
+
+// pollErr returns the error of a result of the poller.
+func pollErr(res int) error {
+	switch res {
+	case poll.FileErrClosing:
+		return ErrClosed
+	case poll.FileErrTimeout:
+		return ErrDeadlineExceeded
+	}
+	return nil
+}
+
+// setDeadline sets the deadline of mode, which is 'r', 'w' or 'r'+'w'.
+func (pd *pollDesc) setDeadline(fd uintptr, t time.Time, mode int) error {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	if pd.closing {
+		return ErrClosed
+	}
+	if !pd.nonblock {
+		if err := syscall.SetNonblock(int(fd), true); err != nil {
+			return err
+		}
+		pd.nonblock = true
+		pd.ownmode = true
+	}
+	var d int64
+	if !t.IsZero() {
+		d = int64(time.Until(t))
+		if d == 0 {
+			d = -1 // don't confuse deadline right now with no deadline
+		}
+	}
+	poll.FileSetDeadline(pd.ctx, d, mode)
+	return nil
+}
+
+// prepare returns the error of doing I/O for mode: ErrClosed if the File
+// is being closed and ErrDeadlineExceeded if the deadline has passed.
+func (pd *pollDesc) prepare(mode int) error {
+	return pollErr(poll.FileReset(pd.ctx, mode))
+}
+
+// wait waits until the descriptor is ready for mode, the deadline of mode
+// expires or the File is closed.
+func (pd *pollDesc) wait(mode int) error {
+	return pollErr(poll.FileWait(pd.ctx, mode))
+}
+
+// setBlocking puts fd back into blocking mode if setDeadline put it into
+// non-blocking mode, so that Fd returns a blocking descriptor.
+func (pd *pollDesc) setBlocking(fd uintptr) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	if pd.ownmode && syscall.SetNonblock(int(fd), false) == nil {
+		pd.nonblock = false
+		pd.ownmode = false
+	}
+}
+
+// close makes current and future waiters return ErrClosed, and removes the
+// descriptor from the poller. It's called before the descriptor is closed.
+func (pd *pollDesc) close() {
+	pd.mu.Lock()
+	pd.closing = true
+	pd.mu.Unlock()
+	poll.FileClose(pd.ctx)
+}
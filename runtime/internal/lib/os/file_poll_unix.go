@@ -0,0 +1,41 @@
+//go:build unix
+
+package os
+
+import (
+	"syscall"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/os"
+)
+
+// hasNonblockFlag reports whether the file status flags of a descriptor
+// include O_NONBLOCK.
+func hasNonblockFlag(flag int) bool {
+	return flag&syscall.O_NONBLOCK != 0
+}
+
+// pollable reports whether fd supports deadlines, that is, whether the
+// poller reports its readiness: pipes, FIFOs, sockets and character devices.
+func pollable(fd int, kind newFileKind) bool {
+	switch kind {
+	case kindPipe, kindNonBlock:
+		return true
+	case kindOpenFile:
+		var st os.StatT
+		err := ignoringEINTR(func() error {
+			if os.Fstat(c.Int(fd), &st) < 0 {
+				return syscall.Errno(os.Errno())
+			}
+			return nil
+		})
+		if err != nil {
+			return false
+		}
+		switch st.Mode & syscall.S_IFMT {
+		case syscall.S_IFIFO, syscall.S_IFSOCK, syscall.S_IFCHR:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+package os
+
+func hasNonblockFlag(flag int) bool {
+	return false
+}
+
+// pollable reports whether fd supports deadlines, which it doesn't here.
+func pollable(fd int, kind newFileKind) bool {
+	return false
+}
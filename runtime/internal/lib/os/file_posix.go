@@ -7,8 +7,13 @@
 package os
 
 import (
+	"io"
 	"syscall"
 	"time"
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/os"
 )
 
 // Close closes the File, rendering it unusable for I/O.
@@ -24,25 +29,34 @@ func (f *File) Close() error {
 
 // pread reads len(b) bytes from the File starting at byte offset off.
 // It returns the number of bytes read and the error, if any.
-// EOF is signaled by a zero count with err set to nil.
+// EOF is signaled by a zero count with err set to io.EOF.
 func (f *File) pread(b []byte, off int64) (n int, err error) {
-	/*
-		n, err = f.pfd.Pread(b, off)
-		runtime.KeepAlive(f)
-		return n, err
-	*/
-	panic("todo: os.(*File).pread")
+	for {
+		ret := os.Pread(c.Int(f.fd), unsafe.Pointer(unsafe.SliceData(b)), uintptr(len(b)), os.OffT(off))
+		if ret > 0 || (ret == 0 && len(b) == 0) {
+			return ret, nil
+		}
+		if ret == 0 {
+			return 0, io.EOF
+		}
+		if e := syscall.Errno(os.Errno()); e != syscall.EINTR {
+			return 0, e
+		}
+	}
 }
 
 // pwrite writes len(b) bytes to the File starting at byte offset off.
 // It returns the number of bytes written and an error, if any.
 func (f *File) pwrite(b []byte, off int64) (n int, err error) {
-	/*
-		n, err = f.pfd.Pwrite(b, off)
-		runtime.KeepAlive(f)
-		return n, err
-	*/
-	panic("todo: os.(*File).pwrite")
+	for {
+		ret := os.Pwrite(c.Int(f.fd), unsafe.Pointer(unsafe.SliceData(b)), uintptr(len(b)), os.OffT(off))
+		if ret >= 0 {
+			return ret, nil
+		}
+		if e := syscall.Errno(os.Errno()); e != syscall.EINTR {
+			return 0, e
+		}
+	}
 }
 
 // syscallMode returns the syscall-specific mode bits from Go's portable mode bits.
@@ -77,16 +91,19 @@ func chmod(name string, mode FileMode) error {
 
 // See docs in file.go:(*File).Chmod.
 func (f *File) chmod(mode FileMode) error {
-	/*
-		if err := f.checkValid("chmod"); err != nil {
-			return err
-		}
-		if e := f.pfd.Fchmod(syscallMode(mode)); e != nil {
-			return f.wrapErr("chmod", e)
+	if err := f.checkValid("chmod"); err != nil {
+		return err
+	}
+	e := ignoringEINTR(func() error {
+		if os.Fchmod(c.Int(f.fd), os.ModeT(syscallMode(mode))) < 0 {
+			return syscall.Errno(os.Errno())
 		}
 		return nil
-	*/
-	panic("todo: os.(*File).chmod")
+	})
+	if e != nil {
+		return f.wrapErr("chmod", e)
+	}
+	return nil
 }
 
 // Chown changes the numeric uid and gid of the named file.
@@ -95,48 +112,57 @@ func (f *File) chmod(mode FileMode) error {
 // On Windows, it always returns the syscall.EWINDOWS error, wrapped
 // in *PathError.
 func (f *File) Chown(uid, gid int) error {
-	/*
-		if err := f.checkValid("chown"); err != nil {
-			return err
-		}
-		if e := f.pfd.Fchown(uid, gid); e != nil {
-			return f.wrapErr("chown", e)
+	if err := f.checkValid("chown"); err != nil {
+		return err
+	}
+	e := ignoringEINTR(func() error {
+		if os.Fchown(c.Int(f.fd), os.UidT(uid), os.GidT(gid)) < 0 {
+			return syscall.Errno(os.Errno())
 		}
 		return nil
-	*/
-	panic("todo: os.(*File).Chown")
+	})
+	if e != nil {
+		return f.wrapErr("chown", e)
+	}
+	return nil
 }
 
 // Truncate changes the size of the file.
 // It does not change the I/O offset.
 // If there is an error, it will be of type *PathError.
 func (f *File) Truncate(size int64) error {
-	/*
-		if err := f.checkValid("truncate"); err != nil {
-			return err
-		}
-		if e := f.pfd.Ftruncate(size); e != nil {
-			return f.wrapErr("truncate", e)
+	if err := f.checkValid("truncate"); err != nil {
+		return err
+	}
+	e := ignoringEINTR(func() error {
+		if os.Ftruncate(c.Int(f.fd), os.OffT(size)) < 0 {
+			return syscall.Errno(os.Errno())
 		}
 		return nil
-	*/
-	panic("todo: os.(*File).Truncate")
+	})
+	if e != nil {
+		return f.wrapErr("truncate", e)
+	}
+	return nil
 }
 
 // Sync commits the current contents of the file to stable storage.
 // Typically, this means flushing the file system's in-memory copy
 // of recently written data to disk.
 func (f *File) Sync() error {
-	/*
-		if err := f.checkValid("sync"); err != nil {
-			return err
-		}
-		if e := f.pfd.Fsync(); e != nil {
-			return f.wrapErr("sync", e)
+	if err := f.checkValid("sync"); err != nil {
+		return err
+	}
+	e := ignoringEINTR(func() error {
+		if os.Fsync(c.Int(f.fd)) < 0 {
+			return syscall.Errno(os.Errno())
 		}
 		return nil
-	*/
-	panic("todo: os.(*File).Sync")
+	})
+	if e != nil {
+		return f.wrapErr("sync", e)
+	}
+	return nil
 }
 
 /*
@@ -169,49 +195,52 @@ func Chtimes(name string, atime time.Time, mtime time.Time) error {
 // which must be a directory.
 // If there is an error, it will be of type *PathError.
 func (f *File) Chdir() error {
-	/*
-		if err := f.checkValid("chdir"); err != nil {
-			return err
-		}
-		if e := f.pfd.Fchdir(); e != nil {
-			return f.wrapErr("chdir", e)
+	if err := f.checkValid("chdir"); err != nil {
+		return err
+	}
+	e := ignoringEINTR(func() error {
+		if os.Fchdir(c.Int(f.fd)) < 0 {
+			return syscall.Errno(os.Errno())
 		}
 		return nil
-	*/
-	panic("todo: os.(*File).Chdir")
+	})
+	if e != nil {
+		return f.wrapErr("chdir", e)
+	}
+	return nil
 }
 
 // setDeadline sets the read and write deadline.
 func (f *File) setDeadline(t time.Time) error {
-	/*
-		if err := f.checkValid("SetDeadline"); err != nil {
-			return err
-		}
-		return f.pfd.SetDeadline(t)
-	*/
-	panic("todo: os.(*File).setDeadline")
+	if err := f.checkValid("SetDeadline"); err != nil {
+		return err
+	}
+	if f.pd == nil {
+		return ErrNoDeadline
+	}
+	return f.pd.setDeadline(f.fd, t, 'r'+'w')
 }
 
 // setReadDeadline sets the read deadline.
 func (f *File) setReadDeadline(t time.Time) error {
-	/*
-		if err := f.checkValid("SetReadDeadline"); err != nil {
-			return err
-		}
-		return f.pfd.SetReadDeadline(t)
-	*/
-	panic("todo: os.(*File).setReadDeadline")
+	if err := f.checkValid("SetReadDeadline"); err != nil {
+		return err
+	}
+	if f.pd == nil {
+		return ErrNoDeadline
+	}
+	return f.pd.setDeadline(f.fd, t, 'r')
 }
 
 // setWriteDeadline sets the write deadline.
 func (f *File) setWriteDeadline(t time.Time) error {
-	/*
-		if err := f.checkValid("SetWriteDeadline"); err != nil {
-			return err
-		}
-		return f.pfd.SetWriteDeadline(t)
-	*/
-	panic("todo: os.(*File).setWriteDeadline")
+	if err := f.checkValid("SetWriteDeadline"); err != nil {
+		return err
+	}
+	if f.pd == nil {
+		return ErrNoDeadline
+	}
+	return f.pd.setDeadline(f.fd, t, 'w')
 }
 
 // checkValid checks whether f is valid for use.
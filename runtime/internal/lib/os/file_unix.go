@@ -7,9 +7,12 @@
 package os
 
 import (
+	"io/fs"
 	"runtime"
 	"syscall"
 
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/os"
 	"github.com/goplus/llgo/runtime/internal/lib/internal/syscall/unix"
 )
 
@@ -30,14 +33,18 @@ func (f *File) Fd() uintptr {
 		return ^(uintptr(0))
 	}
 
-	return f.fd
-}
+	// If we put the file descriptor into nonblocking mode,
+	// then set it to blocking mode before we return it,
+	// because historically we have always returned a descriptor
+	// opened in blocking mode. The File will continue to work,
+	// but any blocking operation will tie up a thread.
+	if f.pd != nil {
+		f.pd.setBlocking(f.fd)
+	}
 
-func NewFile(fd uintptr, name string) *File {
-	return &File{fd: fd, name: name}
+	return f.fd
 }
 
-/* TODO(xsw):
 // NewFile returns a new File with the given file descriptor and
 // name. The returned value will be nil if fd is not a valid file
 // descriptor. On Unix systems, if the file descriptor is in
@@ -56,7 +63,7 @@ func NewFile(fd uintptr, name string) *File {
 	kind := kindNewFile
 	appendMode := false
 	if flags, err := unix.Fcntl(fdi, syscall.F_GETFL, 0); err == nil {
-		if unix.HasNonblockFlag(flags) {
+		if hasNonblockFlag(flags) {
 			kind = kindNonBlock
 		}
 		appendMode = flags&syscall.O_APPEND != 0
@@ -66,6 +73,7 @@ func NewFile(fd uintptr, name string) *File {
 	return f
 }
 
+/* TODO(xsw):
 // net_newUnixFile is a hidden entry point called by net.conn.File.
 // This is used so that a nonblocking network connection will become
 // blocking if code calls the Fd method. We don't want that for direct
@@ -115,6 +123,7 @@ func newFile(fd int, name string, kind newFileKind) *File {
 		fd:          uintptr(fd),
 		name:        name,
 		stdoutOrErr: fd == 1 || fd == 2,
+		pd:          newPollDesc(fd, kind),
 	}
 
 	/* TODO(xsw):
@@ -241,9 +250,8 @@ func openFileNolog(name string, flag int, perm FileMode) (*File, error) {
 	}
 
 	kind := kindOpenFile
-	if unix.HasNonblockFlag(flag) {
+	if hasNonblockFlag(flag) {
 		kind = kindNonBlock
-		panic("todo: os.openFileNolog: unix.HasNonblockFlag")
 	}
 
 	f := newFile(r, name, kind)
@@ -251,6 +259,9 @@ func openFileNolog(name string, flag int, perm FileMode) (*File, error) {
 }
 
 func (file *File) close() error {
+	if file.pd != nil {
+		file.pd.close()
+	}
 	return syscall.Close(int(file.fd))
 	/* TODO(xsw):
 	if file.dirinfo != nil {
@@ -271,6 +282,18 @@ func (file *File) close() error {
 	*/
 }
 
+// seek sets the offset for the next Read or Write on file to offset, interpreted
+// according to whence: 0 means relative to the origin of the file, 1 means
+// relative to the current offset, and 2 means relative to the end.
+// It returns the new offset and an error, if any.
+func (f *File) seek(offset int64, whence int) (ret int64, err error) {
+	ret = int64(os.Lseek(c.Int(f.fd), os.OffT(offset), c.Int(whence)))
+	if ret < 0 {
+		return 0, syscall.Errno(os.Errno())
+	}
+	return ret, nil
+}
+
 func tempDir() string {
 	dir := Getenv("TMPDIR")
 	if dir == "" {
@@ -295,20 +318,19 @@ func (d *unixDirent) IsDir() bool    { return d.typ.IsDir() }
 func (d *unixDirent) Type() FileMode { return d.typ }
 
 func (d *unixDirent) Info() (FileInfo, error) {
-	/* TODO(xsw):
 	if d.info != nil {
 		return d.info, nil
 	}
-	return lstat(d.parent + "/" + d.name)
-	*/
-	panic("todo: os.unixDirent.Info")
+	info, err := Lstat(d.parent + "/" + d.name)
+	if err != nil {
+		return nil, err
+	}
+	d.info = info
+	return info, nil
 }
 
 func (d *unixDirent) String() string {
-	/* TODO(xsw):
 	return fs.FormatDirEntry(d)
-	*/
-	panic("todo: os.unixDirent.String")
 }
 
 /* TODO(xsw):
@@ -0,0 +1,183 @@
+#if defined(__wasm__)
+
+#include <errno.h>
+
+void llgo_signal_enable(int sig) {}
+
+void llgo_signal_disable(int sig) {}
+
+void llgo_signal_ignore(int sig) {}
+
+int llgo_signal_ignored(int sig) {
+    return 0;
+}
+
+int llgo_signal_pending(void) {
+    return 0;
+}
+
+void llgo_signal_delivered(void) {}
+
+int llgo_signal_wait(void) {
+    errno = ENOSYS;
+    return -1;
+}
+
+#else
+
+#include <errno.h>
+#include <fcntl.h>
+#include <pthread.h>
+#include <signal.h>
+#include <string.h>
+#include <unistd.h>
+
+#define NSIG_WORDS 3 // signals 0..64, see numSig in os/signal
+
+// Signals are received by sig_handler, which marks them pending and wakes
+// up llgo_signal_wait with a byte written to a pipe.
+static unsigned int sig_pending[NSIG_WORDS];
+static unsigned int sig_enabled[NSIG_WORDS];
+static unsigned int sig_ignored[NSIG_WORDS];
+static unsigned int sig_saved[NSIG_WORDS]; // sig_oldact is valid
+static struct sigaction sig_oldact[NSIG_WORDS * 32];
+static unsigned int sig_delivering; // returned by llgo_signal_wait, not delivered yet
+static int sig_pipe[2] = {-1, -1};
+static pthread_once_t sig_once = PTHREAD_ONCE_INIT;
+
+#define SIG_BIT(set, sig) ((set)[(sig) / 32] & (1u << ((sig) % 32)))
+#define SIG_SET(set, sig) ((set)[(sig) / 32] |= 1u << ((sig) % 32))
+#define SIG_CLR(set, sig) ((set)[(sig) / 32] &= ~(1u << ((sig) % 32)))
+
+static void sig_init(void) {
+    if (pipe(sig_pipe) == 0) {
+        fcntl(sig_pipe[0], F_SETFD, FD_CLOEXEC);
+        fcntl(sig_pipe[1], F_SETFD, FD_CLOEXEC);
+        fcntl(sig_pipe[1], F_SETFL, O_NONBLOCK);
+    }
+}
+
+static void sig_handler(int sig) {
+    int saved = errno;
+    __atomic_fetch_or(&sig_pending[sig / 32], 1u << (sig % 32), __ATOMIC_SEQ_CST);
+    char b = 0;
+    (void)!write(sig_pipe[1], &b, 1);
+    errno = saved;
+}
+
+// sig_catchable reports whether sig can be forwarded: the signals raised by
+// faults are left to the default handlers, and SIGPROF to the profiler.
+static int sig_catchable(int sig) {
+    switch (sig) {
+    case SIGKILL:
+    case SIGSTOP:
+    case SIGSEGV:
+    case SIGBUS:
+    case SIGFPE:
+    case SIGILL:
+    case SIGPROF:
+        return 0;
+    }
+    return sig > 0 && sig < NSIG && sig < NSIG_WORDS * 32;
+}
+
+static void sig_setaction(int sig, void (*handler)(int)) {
+    struct sigaction act, old;
+    memset(&act, 0, sizeof(act));
+    act.sa_handler = handler;
+    act.sa_flags = SA_RESTART | SA_ONSTACK;
+    sigfillset(&act.sa_mask);
+    if (sigaction(sig, &act, &old) == 0 && !SIG_BIT(sig_saved, sig)) {
+        sig_oldact[sig] = old;
+        SIG_SET(sig_saved, sig);
+    }
+}
+
+void llgo_signal_enable(int sig) {
+    pthread_once(&sig_once, sig_init);
+    if (!sig_catchable(sig)) {
+        return;
+    }
+    SIG_SET(sig_enabled, sig);
+    SIG_CLR(sig_ignored, sig);
+    sig_setaction(sig, sig_handler);
+}
+
+void llgo_signal_disable(int sig) {
+    if (!sig_catchable(sig) || !SIG_BIT(sig_enabled, sig)) {
+        return;
+    }
+    SIG_CLR(sig_enabled, sig);
+    if (SIG_BIT(sig_saved, sig)) {
+        sigaction(sig, &sig_oldact[sig], NULL);
+    } else {
+        sig_setaction(sig, SIG_DFL);
+    }
+}
+
+void llgo_signal_ignore(int sig) {
+    if (!sig_catchable(sig)) {
+        return;
+    }
+    SIG_CLR(sig_enabled, sig);
+    SIG_SET(sig_ignored, sig);
+    sig_setaction(sig, SIG_IGN);
+}
+
+int llgo_signal_ignored(int sig) {
+    if (!sig_catchable(sig)) {
+        return 0;
+    }
+    if (SIG_BIT(sig_ignored, sig)) {
+        return 1;
+    }
+    if (SIG_BIT(sig_enabled, sig)) {
+        return 0;
+    }
+    // ignored since the program started
+    struct sigaction old;
+    return sigaction(sig, NULL, &old) == 0 && old.sa_handler == SIG_IGN;
+}
+
+// llgo_signal_pending reports whether a received signal hasn't been
+// delivered yet: it's pending, or it has been returned by llgo_signal_wait
+// and llgo_signal_delivered hasn't been called for it. The pending signals
+// are checked first, as a signal is counted as delivering before it stops
+// being pending.
+int llgo_signal_pending(void) {
+    for (int i = 0; i < NSIG_WORDS; i++) {
+        if (__atomic_load_n(&sig_pending[i], __ATOMIC_SEQ_CST) != 0) {
+            return 1;
+        }
+    }
+    return __atomic_load_n(&sig_delivering, __ATOMIC_SEQ_CST) != 0;
+}
+
+// llgo_signal_delivered reports that a signal returned by llgo_signal_wait
+// has been delivered.
+void llgo_signal_delivered(void) {
+    __atomic_fetch_sub(&sig_delivering, 1, __ATOMIC_SEQ_CST);
+}
+
+// llgo_signal_wait blocks until a signal is received and returns it. The
+// signal is delivering until llgo_signal_delivered is called.
+int llgo_signal_wait(void) {
+    pthread_once(&sig_once, sig_init);
+    for (;;) {
+        for (int i = 0; i < NSIG_WORDS; i++) {
+            unsigned int set = __atomic_load_n(&sig_pending[i], __ATOMIC_SEQ_CST);
+            if (set != 0) {
+                int bit = __builtin_ctz(set);
+                __atomic_fetch_add(&sig_delivering, 1, __ATOMIC_SEQ_CST);
+                __atomic_fetch_and(&sig_pending[i], ~(1u << bit), __ATOMIC_SEQ_CST);
+                return i * 32 + bit;
+            }
+        }
+        char buf[64];
+        if (read(sig_pipe[0], buf, sizeof(buf)) < 0 && errno != EINTR) {
+            return -1;
+        }
+    }
+}
+
+#endif
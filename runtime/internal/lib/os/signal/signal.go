@@ -1,25 +1,106 @@
 package signal
 
-func signal_disable(uint32) {
-	panic("signal_disable not implemented")
+import (
+	"sync"
+	_ "unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	psync "github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+)
+
+const (
+	LLGoFiles = "_wrap/signal.c"
+)
+
+//go:linkname c_signal_enable C.llgo_signal_enable
+func c_signal_enable(sig c.Int)
+
+//go:linkname c_signal_disable C.llgo_signal_disable
+func c_signal_disable(sig c.Int)
+
+//go:linkname c_signal_ignore C.llgo_signal_ignore
+func c_signal_ignore(sig c.Int)
+
+//go:linkname c_signal_ignored C.llgo_signal_ignored
+func c_signal_ignored(sig c.Int) c.Int
+
+//go:linkname c_signal_pending C.llgo_signal_pending
+func c_signal_pending() c.Int
+
+//go:linkname c_signal_delivered C.llgo_signal_delivered
+func c_signal_delivered()
+
+//go:linkname c_signal_wait C.llgo_signal_wait
+func c_signal_wait() c.Int
+
+// Received signals are forwarded by a dedicated thread from the C handler
+// to sigq, which signal_recv drains. Signals received while sigq is full
+// stay pending in C, where repeated signals are coalesced.
+var (
+	sigq      = make(chan uint32, 65) // numSig
+	sigqStart psync.Once
+)
+
+func startSigq() {
+	var th pthread.Thread
+	pthread.Create(&th, nil, sigForward, nil)
+}
+
+func sigForward(arg c.Pointer) c.Pointer {
+	for {
+		sig := c_signal_wait()
+		if sig < 0 {
+			return nil
+		}
+		sigq <- uint32(sig)
+	}
+}
+
+func signal_disable(sig uint32) {
+	c_signal_disable(c.Int(sig))
 }
 
-func signal_enable(uint32) {
-	panic("signal_enable not implemented")
+func signal_enable(sig uint32) {
+	sigqStart.Do(startSigq)
+	c_signal_enable(c.Int(sig))
 }
 
-func signal_ignore(uint32) {
-	panic("signal_ignore not implemented")
+func signal_ignore(sig uint32) {
+	c_signal_ignore(c.Int(sig))
 }
 
-func signal_ignored(uint32) bool {
-	panic("signal_ignored not implemented")
+func signal_ignored(sig uint32) bool {
+	return c_signal_ignored(c.Int(sig)) != 0
 }
 
+// A signal taken by sigForward is delivered once the loop of os/signal,
+// the only caller of signal_recv, has processed it, that is, when it calls
+// signal_recv again. signalWaitUntilIdle waits for it on sigIdle.
+var (
+	sigIdleMu sync.Mutex
+	sigIdle   = sync.NewCond(&sigIdleMu)
+	sigRecvd  bool // signal_recv returned a signal, which is being processed
+)
+
 func signal_recv() uint32 {
-	panic("signal_recv not implemented")
+	if sigRecvd {
+		sigIdleMu.Lock()
+		c_signal_delivered()
+		sigIdle.Broadcast()
+		sigIdleMu.Unlock()
+	}
+	sig := <-sigq
+	sigRecvd = true
+	return sig
 }
 
+// signalWaitUntilIdle waits until the signals received so far have been
+// processed by the caller of signal_recv.
 func signalWaitUntilIdle() {
-	panic("signalWaitUntilIdle not implemented")
+	sigIdleMu.Lock()
+	for c_signal_pending() != 0 {
+		sigIdle.Wait()
+	}
+	sigIdleMu.Unlock()
 }
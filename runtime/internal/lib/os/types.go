@@ -24,16 +24,37 @@ type File struct {
 	appendMode  bool
 	nonblock    bool
 	stdoutOrErr bool
+	pd          *pollDesc // nil if the file doesn't support deadlines
+	dirread     bool      // whether the file was read as a directory
 }
 
 // write writes len(b) bytes to the File.
 // It returns the number of bytes written and an error, if any.
 func (f *File) write(b []byte) (int, error) {
-	ret := os.Write(c.Int(f.fd), unsafe.Pointer(unsafe.SliceData(b)), uintptr(len(b)))
-	if ret >= 0 {
-		return int(ret), nil
+	if f.pd != nil {
+		if err := f.pd.prepare('w'); err != nil {
+			return 0, err
+		}
+	}
+	n := 0
+	for {
+		ret := os.Write(c.Int(f.fd), unsafe.Pointer(unsafe.SliceData(b[n:])), uintptr(len(b)-n))
+		if ret >= 0 {
+			n += int(ret)
+			if n == len(b) || f.pd == nil {
+				return n, nil
+			}
+			continue
+		}
+		e := syscall.Errno(os.Errno())
+		if e == syscall.EAGAIN && f.pd != nil {
+			if err := f.pd.wait('w'); err != nil {
+				return n, err
+			}
+			continue
+		}
+		return n, e
 	}
-	return 0, syscall.Errno(os.Errno())
 }
 
 /* TODO(xsw):
@@ -49,14 +70,28 @@ func (f *File) write(b []byte) (n int, err error) {
 // read reads up to len(b) bytes from the File.
 // It returns the number of bytes read and an error, if any.
 func (f *File) read(b []byte) (int, error) {
-	ret := os.Read(c.Int(f.fd), unsafe.Pointer(unsafe.SliceData(b)), uintptr(len(b)))
-	if ret > 0 {
-		return int(ret), nil
+	if f.pd != nil {
+		if err := f.pd.prepare('r'); err != nil {
+			return 0, err
+		}
 	}
-	if ret == 0 {
-		return 0, io.EOF
+	for {
+		ret := os.Read(c.Int(f.fd), unsafe.Pointer(unsafe.SliceData(b)), uintptr(len(b)))
+		if ret > 0 {
+			return int(ret), nil
+		}
+		if ret == 0 {
+			return 0, io.EOF
+		}
+		e := syscall.Errno(os.Errno())
+		if e == syscall.EAGAIN && f.pd != nil {
+			if err := f.pd.wait('r'); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		return 0, e
 	}
-	return 0, syscall.Errno(os.Errno())
 }
 
 /* TODO(xsw):
@@ -22,6 +22,8 @@ package reflect
 
 import (
 	"strconv"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
 
 	"github.com/goplus/llgo/runtime/abi"
@@ -1108,6 +1110,137 @@ func SliceOf(t Type) Type {
 	return ti.(Type)
 }
 
+// isLetter reports whether a given 'rune' is classified as a Letter.
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
+}
+
+// isValidFieldName checks if a string is a valid (struct) field name or not.
+//
+// According to the language spec, a field name should be an identifier.
+//
+// identifier = letter { letter | unicode_digit } .
+// letter = unicode_letter | "_" .
+func isValidFieldName(fieldName string) bool {
+	for i, c := range fieldName {
+		if i == 0 && !isLetter(c) {
+			return false
+		}
+
+		if !(isLetter(c) || unicode.IsDigit(c)) {
+			return false
+		}
+	}
+
+	return len(fieldName) > 0
+}
+
+// StructOf returns the struct type containing fields.
+// The Offset and Index fields are ignored and computed as they would be
+// by the compiler.
+//
+// StructOf currently does not generate wrapper methods for embedded
+// fields, so the methods of embedded fields are not promoted.
+// Passing unexported StructFields panics.
+func StructOf(fields []StructField) Type {
+	var (
+		size     uintptr
+		typalign uint8
+		pkgpath  string
+		fs       = make([]abi.StructField, len(fields))
+		seen     = make(map[string]struct{}, len(fields))
+	)
+	for i, field := range fields {
+		if field.Name == "" {
+			panic("reflect.StructOf: field " + strconv.Itoa(i) + " has no name")
+		}
+		if !isValidFieldName(field.Name) {
+			panic("reflect.StructOf: field " + strconv.Itoa(i) + " has invalid name")
+		}
+		if field.Type == nil {
+			panic("reflect.StructOf: field " + strconv.Itoa(i) + " has no type")
+		}
+		if field.Anonymous && field.PkgPath != "" {
+			panic("reflect.StructOf: field \"" + field.Name + "\" is anonymous but has PkgPath set")
+		}
+		if field.IsExported() {
+			// Best-effort check for misuse.
+			// Since this field will be treated as exported, not much harm done if Unicode lowercase slips through.
+			if c := field.Name[0]; 'a' <= c && c <= 'z' || c == '_' {
+				panic("reflect.StructOf: field \"" + field.Name + "\" is unexported but missing PkgPath")
+			}
+		} else {
+			if pkgpath == "" {
+				pkgpath = field.PkgPath
+			} else if pkgpath != field.PkgPath {
+				panic("reflect.Struct: fields with different PkgPath " + pkgpath + " and " + field.PkgPath)
+			}
+		}
+		if _, dup := seen[field.Name]; dup {
+			panic("reflect.StructOf: duplicate field " + field.Name)
+		}
+		seen[field.Name] = struct{}{}
+
+		ft := field.Type.common()
+		fa := uintptr(ft.Align_)
+		if fa == 0 {
+			fa = 1
+		}
+		offset := align(size, fa)
+		if offset < size {
+			panic("reflect.StructOf: struct size would exceed virtual address space")
+		}
+		if ft.Align_ > typalign {
+			typalign = ft.Align_
+		}
+		size = offset + ft.Size_
+		if size < offset {
+			panic("reflect.StructOf: struct size would exceed virtual address space")
+		}
+		fs[i] = runtime.StructField(field.Name, ft, offset, string(field.Tag), field.Anonymous)
+	}
+
+	if size > 0 && typalign > 0 {
+		size = align(size, uintptr(typalign))
+	}
+	return toType(runtime.Struct(pkgpath, size, fs...))
+}
+
+// ArrayOf returns the array type with the given length and element type.
+// For example, if t represents int, ArrayOf(5, t) represents [5]int.
+//
+// If the resulting type would be larger than the available address space,
+// ArrayOf panics.
+func ArrayOf(length int, elem Type) Type {
+	typ := elem.common()
+
+	// Look in cache.
+	ckey := cacheKey{Array, typ, nil, uintptr(length)}
+	if array, ok := lookupCache.Load(ckey); ok {
+		return array.(Type)
+	}
+
+	if length < 0 {
+		panic("reflect: negative length passed to ArrayOf")
+	}
+	if typ.Size_ > 0 {
+		max := ^uintptr(0) / typ.Size_
+		if uintptr(length) > max {
+			panic("reflect.ArrayOf: array size would exceed virtual address space")
+		}
+	}
+
+	array := runtime.ArrayOf(uintptr(length), typ)
+	ti, _ := lookupCache.LoadOrStore(ckey, toRType(array))
+	return ti.(Type)
+}
+
+// align returns the result of rounding x up to a multiple of n.
+// n must be a power of two.
+func align(x, n uintptr) uintptr {
+	return (x + n - 1) &^ (n - 1)
+}
+
 // toType converts from a *rtype to a Type that can be returned
 // to the client of package reflect. In gc, the only concern is that
 // a nil *rtype must be replaced by a nil Type, but in gccgo this
@@ -1263,6 +1396,41 @@ func funcStr(ft *funcType) string {
 	return string(repr)
 }
 
+// ChanOf returns the channel type with the given direction and element type.
+// For example, if t represents int, ChanOf(RecvDir, t) represents <-chan int.
+//
+// The gc runtime imposes a limit of 64 kB on channel element types.
+// If t's size is equal to or exceeds this limit, ChanOf panics.
+func ChanOf(dir ChanDir, t Type) Type {
+	typ := t.common()
+
+	// Look in cache.
+	ckey := cacheKey{Chan, typ, nil, uintptr(dir)}
+	if ch, ok := lookupCache.Load(ckey); ok {
+		return ch.(*rtype)
+	}
+
+	// This restriction is imposed by the gc compiler and the runtime.
+	if typ.Size_ >= 1<<16 {
+		panic("reflect.ChanOf: element size too large")
+	}
+
+	var s string
+	switch dir {
+	default:
+		panic("reflect.ChanOf: invalid dir")
+	case SendDir:
+		s = "chan<-"
+	case RecvDir:
+		s = "<-chan"
+	case BothDir:
+		s = "chan"
+	}
+	ch := runtime.ChanOf(int(dir), s, typ)
+	ti, _ := lookupCache.LoadOrStore(ckey, toRType(ch))
+	return ti.(Type)
+}
+
 // MapOf returns the map type with the given key and element types.
 // For example, if k represents int and e represents string,
 // MapOf(k, e) represents map[int]string.
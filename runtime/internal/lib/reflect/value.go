@@ -498,12 +498,17 @@ func (v Value) capNonSlice() int {
 // Close closes the channel v.
 // It panics if v's Kind is not Chan.
 func (v Value) Close() {
-	/* TODO(xsw):
 	v.mustBe(Chan)
 	v.mustBeExported()
-	chanclose(v.pointer())
-	*/
-	panic("todo: reflect.Value.Close")
+	tt := (*chanType)(unsafe.Pointer(v.typ()))
+	if ChanDir(tt.Dir)&SendDir == 0 {
+		panic("reflect: close of receive-only channel")
+	}
+	ch := v.pointer()
+	if ch == nil {
+		panic("close of nil channel")
+	}
+	chanclose(ch)
 }
 
 // CanComplex reports whether Complex can be used without panicking.
@@ -1145,7 +1150,6 @@ func (v Value) Recv() (x Value, ok bool) {
 // internal recv, possibly non-blocking (nb).
 // v is known to be a channel.
 func (v Value) recv(nb bool) (val Value, ok bool) {
-	/* TODO(xsw):
 	tt := (*chanType)(unsafe.Pointer(v.typ()))
 	if ChanDir(tt.Dir)&RecvDir == 0 {
 		panic("reflect: recv on send-only channel")
@@ -1160,13 +1164,11 @@ func (v Value) recv(nb bool) (val Value, ok bool) {
 	} else {
 		p = unsafe.Pointer(&val.ptr)
 	}
-	selected, ok := chanrecv(v.pointer(), nb, p)
+	selected, ok := chanrecv(v.pointer(), nb, p, int(t.Size_))
 	if !selected {
 		val = Value{}
 	}
 	return
-	*/
-	panic("todo: reflect.Value.recv")
 }
 
 // Send sends x on the channel v.
@@ -1181,7 +1183,6 @@ func (v Value) Send(x Value) {
 // internal send, possibly non-blocking.
 // v is known to be a channel.
 func (v Value) send(x Value, nb bool) (selected bool) {
-	/* TODO(xsw):
 	tt := (*chanType)(unsafe.Pointer(v.typ()))
 	if ChanDir(tt.Dir)&SendDir == 0 {
 		panic("reflect: send on recv-only channel")
@@ -1194,9 +1195,7 @@ func (v Value) send(x Value, nb bool) (selected bool) {
 	} else {
 		p = unsafe.Pointer(&x.ptr)
 	}
-	return chansend(v.pointer(), p, nb)
-	*/
-	panic("todo: reflect.Value.send")
+	return chansend(v.pointer(), p, nb, int(tt.Elem.Size_))
 }
 
 // Set assigns x to the value v.
@@ -1559,12 +1558,9 @@ func (v Value) stringNonString() string {
 // If the receive cannot finish without blocking, x is the zero Value and ok is false.
 // If the channel is closed, x is the zero value for the channel's element type and ok is false.
 func (v Value) TryRecv() (x Value, ok bool) {
-	/* TODO(xsw):
 	v.mustBe(Chan)
 	v.mustBeExported()
 	return v.recv(true)
-	*/
-	panic("todo: reflect.Value.TryRecv")
 }
 
 // TrySend attempts to send x on the channel v but will not block.
@@ -1572,12 +1568,9 @@ func (v Value) TryRecv() (x Value, ok bool) {
 // It reports whether the value was sent.
 // As in Go, x's value must be assignable to the channel's element type.
 func (v Value) TrySend(x Value) bool {
-	/* TODO(xsw):
 	v.mustBe(Chan)
 	v.mustBeExported()
 	return v.send(x, true)
-	*/
-	panic("todo: reflect.Value.TrySend")
 }
 
 // Type returns v's type.
@@ -1738,6 +1731,149 @@ func (v Value) UnsafePointer() unsafe.Pointer {
 	panic(&ValueError{"reflect.Value.UnsafePointer", v.kind()})
 }
 
+// A SelectDir describes the communication direction of a select case.
+type SelectDir int
+
+const (
+	_             SelectDir = iota
+	SelectSend              // case Chan <- Send
+	SelectRecv              // case <-Chan:
+	SelectDefault           // default
+)
+
+// A SelectCase describes a single case in a select operation.
+// The kind of case depends on Dir, the communication direction.
+//
+// If Dir is SelectDefault, the case represents a default case.
+// Chan and Send must be zero Values.
+//
+// If Dir is SelectSend, the case represents a send operation.
+// Normally Chan's underlying value must be a channel, and Send's underlying value must be
+// assignable to the channel's element type. As a special case, if Chan is a zero Value,
+// then the case is ignored, and the field Send will also be ignored and may be either zero
+// or non-zero.
+//
+// If Dir is SelectRecv, the case represents a receive operation.
+// Normally Chan's underlying value must be a channel and Send must be a zero Value.
+// If Chan is a zero Value, then the case is ignored, but Send must still be a zero Value.
+// When a receive operation is selected, the received Value is returned by Select.
+type SelectCase struct {
+	Dir  SelectDir // direction of case
+	Chan Value     // channel to use (for send or receive)
+	Send Value     // value to send (for send)
+}
+
+// Select executes a select operation described by the list of cases.
+// Like the Go select statement, it blocks until at least one of the cases
+// can proceed, makes a uniform pseudo-random choice,
+// and then executes that case. It returns the index of the chosen case
+// and, if that case was a receive operation, the value received and a
+// boolean indicating whether the value corresponds to a send on the channel
+// (as opposed to a zero value received because the channel is closed).
+// Select supports a maximum of 65536 cases.
+func Select(cases []SelectCase) (chosen int, recv Value, recvOK bool) {
+	if len(cases) > 65536 {
+		panic("reflect.Select: too many cases (max 65536)")
+	}
+	// Cases with a nil channel never proceed, so they are left out of ops.
+	// index maps ops back to cases.
+	ops := make([]runtime.ChanOp, 0, len(cases))
+	index := make([]int, 0, len(cases))
+	elems := make([]*abi.Type, 0, len(cases))
+	idefault := -1
+	for i, c := range cases {
+		switch c.Dir {
+		default:
+			panic("reflect.Select: invalid Dir")
+
+		case SelectDefault: // default
+			if idefault >= 0 {
+				panic("reflect.Select: multiple default cases")
+			}
+			idefault = i
+			if c.Chan.IsValid() {
+				panic("reflect.Select: default case has Chan value")
+			}
+			if c.Send.IsValid() {
+				panic("reflect.Select: default case has Send value")
+			}
+
+		case SelectSend:
+			ch := c.Chan
+			if !ch.IsValid() {
+				break
+			}
+			ch.mustBe(Chan)
+			ch.mustBeExported()
+			tt := (*chanType)(unsafe.Pointer(ch.typ()))
+			if ChanDir(tt.Dir)&SendDir == 0 {
+				panic("reflect.Select: SendDir case using recv-only channel")
+			}
+			v := c.Send
+			if !v.IsValid() {
+				panic("reflect.Select: SendDir case missing Send value")
+			}
+			v.mustBeExported()
+			v = v.assignTo("reflect.Select", tt.Elem, nil)
+			var p unsafe.Pointer
+			if v.flag&flagIndir != 0 {
+				p = v.ptr
+			} else {
+				p = unsafe.Pointer(&v.ptr)
+			}
+			if chp := ch.pointer(); chp != nil {
+				ops = append(ops, runtime.ChanOp{C: (*runtime.Chan)(chp), Val: p, Size: int32(tt.Elem.Size_), Send: true})
+				index = append(index, i)
+				elems = append(elems, tt.Elem)
+			}
+
+		case SelectRecv:
+			if c.Send.IsValid() {
+				panic("reflect.Select: RecvDir case has Send value")
+			}
+			ch := c.Chan
+			if !ch.IsValid() {
+				break
+			}
+			ch.mustBe(Chan)
+			ch.mustBeExported()
+			tt := (*chanType)(unsafe.Pointer(ch.typ()))
+			if ChanDir(tt.Dir)&RecvDir == 0 {
+				panic("reflect.Select: RecvDir case using send-only channel")
+			}
+			if chp := ch.pointer(); chp != nil {
+				ops = append(ops, runtime.ChanOp{C: (*runtime.Chan)(chp), Val: unsafe_New(tt.Elem), Size: int32(tt.Elem.Size_)})
+				index = append(index, i)
+				elems = append(elems, tt.Elem)
+			}
+		}
+	}
+
+	var isel int
+	if idefault >= 0 {
+		var tryOK bool
+		if isel, recvOK, tryOK = runtime.TrySelect(ops...); !tryOK {
+			return idefault, Value{}, false
+		}
+	} else {
+		// With no ops left, this blocks forever like an empty select.
+		isel, recvOK = runtime.Select(ops...)
+	}
+	chosen = index[isel]
+	if op := ops[isel]; !op.Send {
+		t := elems[isel]
+		fl := flag(t.Kind())
+		if ifaceIndir(t) {
+			recv = Value{t, op.Val, fl | flagIndir}
+		} else {
+			recv = Value{t, *(*unsafe.Pointer)(op.Val), fl}
+		}
+	} else {
+		recvOK = false
+	}
+	return chosen, recv, recvOK
+}
+
 //go:linkname unsafe_New github.com/goplus/llgo/runtime/internal/runtime.New
 func unsafe_New(*abi.Type) unsafe.Pointer
 
@@ -1829,17 +1965,80 @@ func Append(s Value, x ...Value) Value {
 // AppendSlice appends a slice t to a slice s and returns the resulting slice.
 // The slices s and t must have the same element type.
 func AppendSlice(s, t Value) Value {
-	/*
-		s.mustBe(Slice)
-		t.mustBe(Slice)
-		typesMustMatch("reflect.AppendSlice", s.Type().Elem(), t.Type().Elem())
-		ns := s.Len()
-		nt := t.Len()
-		s = s.extendSlice(nt)
-		Copy(s.Slice(ns, ns+nt), t)
-		return s
-	*/
-	panic("todo: reflect.AppendSlice")
+	s.mustBe(Slice)
+	t.mustBe(Slice)
+	typesMustMatch("reflect.AppendSlice", s.Type().Elem(), t.Type().Elem())
+	ns := s.Len()
+	nt := t.Len()
+	s = s.extendSlice(nt)
+	Copy(s.Slice(ns, ns+nt), t)
+	return s
+}
+
+// Copy copies the contents of src into dst until either
+// dst has been filled or src has been exhausted.
+// It returns the number of elements copied.
+// Dst and src each must have kind Slice or Array, and
+// dst and src must have the same element type.
+//
+// As a special case, src can be a String if the element type of dst is kind Uint8.
+func Copy(dst, src Value) int {
+	dk := dst.kind()
+	if dk != Array && dk != Slice {
+		panic(&ValueError{"reflect.Copy", dk})
+	}
+	if dk == Array {
+		dst.mustBeAssignable()
+	}
+	dst.mustBeExported()
+
+	sk := src.kind()
+	var stringCopy bool
+	if sk != Array && sk != Slice {
+		stringCopy = sk == String && dst.typ().Elem().Kind() == abi.Uint8
+		if !stringCopy {
+			panic(&ValueError{"reflect.Copy", sk})
+		}
+	}
+	src.mustBeExported()
+
+	de := dst.typ().Elem()
+	if !stringCopy {
+		se := src.typ().Elem()
+		typesMustMatch("reflect.Copy", toType(de), toType(se))
+	}
+
+	var ds, ss unsafeheaderSlice
+	if dk == Array {
+		ds.Data = dst.ptr
+		ds.Len = dst.Len()
+		ds.Cap = ds.Len
+	} else {
+		ds = *(*unsafeheaderSlice)(dst.ptr)
+	}
+	if sk == Array {
+		ss.Data = src.ptr
+		if src.flag&flagIndir == 0 {
+			ss.Data = unsafe.Pointer(&src.ptr)
+		}
+		ss.Len = src.Len()
+		ss.Cap = ss.Len
+	} else if sk == Slice {
+		ss = *(*unsafeheaderSlice)(src.ptr)
+	} else {
+		sh := *(*unsafeheaderString)(src.ptr)
+		ss.Data = sh.Data
+		ss.Len = sh.Len
+		ss.Cap = sh.Len
+	}
+
+	return typedslicecopy(de.Common(), ds, ss)
+}
+
+func typesMustMatch(what string, t1, t2 Type) {
+	if t1 != t2 {
+		panic(what + ": " + t1.String() + " != " + t2.String())
+	}
 }
 
 // Zero returns a Value representing the zero value for the specified type.
@@ -1995,6 +2194,19 @@ func typedmemmove(t *abi.Type, dst, src unsafe.Pointer)
 //go:linkname typedmemclr github.com/goplus/llgo/runtime/internal/runtime.Typedmemclr
 func typedmemclr(t *abi.Type, ptr unsafe.Pointer)
 
+// typedslicecopy copies a slice of elemType values from src to dst,
+// returning the number of elements copied.
+func typedslicecopy(t *abi.Type, dst, src unsafeheaderSlice) int {
+	n := dst.Len
+	if n > src.Len {
+		n = src.Len
+	}
+	if n > 0 {
+		memmove(dst.Data, src.Data, uintptr(n)*t.Size_)
+	}
+	return n
+}
+
 /*
 	TODO(xsw):
 
@@ -2004,12 +2216,6 @@ func typedmemclr(t *abi.Type, ptr unsafe.Pointer)
 //go:noescape
 func typedmemclrpartial(t *abi.Type, ptr unsafe.Pointer, off, size uintptr)
 
-// typedslicecopy copies a slice of elemType values from src to dst,
-// returning the number of elements copied.
-//
-//go:noescape
-func typedslicecopy(t *abi.Type, dst, src unsafeheaderSlice) int
-
 // typedarrayclear zeroes the value at ptr of an array of elemType,
 // only clears len elem.
 //
@@ -3057,6 +3263,44 @@ func cvtI2I(v Value, typ Type) Value {
 //go:linkname chancap github.com/goplus/llgo/runtime/internal/runtime.ChanCap
 func chancap(ch unsafe.Pointer) int
 
+//go:linkname chanclose github.com/goplus/llgo/runtime/internal/runtime.ChanClose
+func chanclose(ch unsafe.Pointer)
+
+//go:linkname makechan github.com/goplus/llgo/runtime/internal/runtime.NewChan
+func makechan(eltSize, cap int) unsafe.Pointer
+
+// chanrecv receives a value of size bytes from ch into val. If nb is set,
+// it doesn't block and reports whether a value was received or ch is closed.
+func chanrecv(ch unsafe.Pointer, nb bool, val unsafe.Pointer, size int) (selected, received bool) {
+	if ch == nil {
+		if nb {
+			return false, false
+		}
+		select {} // receive from nil channel blocks forever
+	}
+	if nb {
+		received, selected = runtime.ChanTryRecv((*runtime.Chan)(ch), val, size)
+		return
+	}
+	return true, runtime.ChanRecv((*runtime.Chan)(ch), val, size)
+}
+
+// chansend sends the value of size bytes at val on ch. If nb is set,
+// it doesn't block and reports whether the value was sent.
+func chansend(ch unsafe.Pointer, val unsafe.Pointer, nb bool, size int) bool {
+	if ch == nil {
+		if nb {
+			return false
+		}
+		select {} // send on nil channel blocks forever
+	}
+	if nb {
+		return runtime.ChanTrySend((*runtime.Chan)(ch), val, size)
+	}
+	runtime.ChanSend((*runtime.Chan)(ch), val, size)
+	return true
+}
+
 //go:linkname chanlen github.com/goplus/llgo/runtime/internal/runtime.ChanLen
 func chanlen(ch unsafe.Pointer) int
 
@@ -3134,6 +3378,22 @@ func MakeSlice(typ Type, len, cap int) Value {
 	return Value{&typ.(*rtype).t, unsafe.Pointer(&s), flagIndir | flag(Slice)}
 }
 
+// MakeChan creates a new channel with the specified type and buffer size.
+func MakeChan(typ Type, buffer int) Value {
+	if typ.Kind() != Chan {
+		panic("reflect.MakeChan of non-chan type")
+	}
+	if buffer < 0 {
+		panic("reflect.MakeChan: negative buffer size")
+	}
+	if typ.ChanDir() != BothDir {
+		panic("reflect.MakeChan: unidirectional channel type")
+	}
+	t := typ.common()
+	ch := makechan(int(t.Elem().Size_), buffer)
+	return Value{t, ch, flag(Chan)}
+}
+
 // MakeMap creates a new map with the specified type.
 func MakeMap(typ Type) Value {
 	return MakeMapWithSize(typ, 0)
@@ -1,5 +1,16 @@
 package debug
 
+import "github.com/goplus/llgo/runtime/internal/runtime"
+
+// SetTraceback sets the amount of detail printed by the runtime in
+// the traceback it prints before exiting due to an unrecovered panic
+// or an internal runtime error.
+// The level argument takes the same values as the GOTRACEBACK
+// environment variable. For example, SetTraceback("all") ensure
+// that the program prints all goroutines when it crashes.
+// See the package runtime documentation for details.
+// If SetTraceback is called with a level lower than that of the
+// environment variable, the call is ignored.
 func SetTraceback(level string) {
-	panic("todo: runtime/debug.SetTraceback")
+	runtime.SetTraceback(level)
 }
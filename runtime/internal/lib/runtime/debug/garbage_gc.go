@@ -0,0 +1,131 @@
+//go:build !nogc
+
+package debug
+
+import (
+	"math"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/bdwgc"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+)
+
+// The GC settings are mapped onto bdwgc: the heap grows by about
+// heapsize/divisor bytes between collections, so the GC percentage is
+// 100/divisor, and the memory limit is the maximum heap size.
+var gcConf struct {
+	mu       sync.Mutex
+	once     sync.Once
+	percent  int // as set, which the divisor only approximates
+	memLimit int64
+	maxHeap  uintptr // the maximum heap size of bdwgc, 0 if unlimited
+}
+
+func gcConfInit() {
+	gcConf.mu.Init(nil)
+	gcConf.percent = 100
+	gcConf.memLimit = math.MaxInt64
+}
+
+// ReadGCStats reads statistics about garbage collection into stats.
+// bdwgc doesn't measure pauses, so only NumGC and LastGC are set.
+func ReadGCStats(stats *debug.GCStats) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	stats.NumGC = int64(m.NumGC)
+	stats.LastGC = time.Unix(0, int64(m.LastGC))
+	stats.PauseTotal = 0
+	stats.Pause = stats.Pause[:0]
+	stats.PauseEnd = stats.PauseEnd[:0]
+	stats.PauseQuantiles = stats.PauseQuantiles[:0]
+}
+
+// SetGCPercent sets the garbage collection target percentage and returns
+// the previous setting. A negative percentage disables garbage collection.
+//
+// The percentage is mapped onto the free space divisor of bdwgc, which
+// is 100/percent, so settings above 100 behave like 100.
+func SetGCPercent(percent int) int {
+	gcConf.once.Do(gcConfInit)
+	gcConf.mu.Lock()
+	defer gcConf.mu.Unlock()
+	old := gcConf.percent
+	if percent < 0 {
+		if old >= 0 {
+			bdwgc.Disable()
+		}
+		gcConf.percent = -1
+		return old
+	}
+	if old < 0 {
+		bdwgc.Enable()
+	}
+	gcConf.percent = percent
+	divisor := 100
+	if percent > 0 {
+		divisor = (100 + percent/2) / percent
+		if divisor < 1 {
+			divisor = 1
+		}
+	}
+	bdwgc.SetFreeSpaceDivisor(uintptr(divisor))
+	return old
+}
+
+// FreeOSMemory forces a garbage collection followed by an
+// attempt to return as much memory to the operating system
+// as possible.
+func FreeOSMemory() {
+	bdwgc.GcollectAndUnmap()
+}
+
+// SetMemoryLimit sets a soft limit on the heap size and returns the
+// previously set limit. A negative input does not adjust the limit.
+//
+// The limit is the maximum heap size of bdwgc, so that it collects garbage
+// rather than growing the heap past the limit. Like in Go, the limit is
+// soft: if an allocation still doesn't fit, the heap grows past the limit
+// instead of the allocation failing. math.MaxInt64 removes the limit.
+func SetMemoryLimit(limit int64) int64 {
+	gcConf.once.Do(gcConfInit)
+	gcConf.mu.Lock()
+	defer gcConf.mu.Unlock()
+	old := gcConf.memLimit
+	if limit < 0 {
+		return old
+	}
+	gcConf.memLimit = limit
+	if limit == math.MaxInt64 || uint64(limit) > uint64(^uintptr(0)) {
+		setMaxHeap(0) // no limit
+	} else {
+		bdwgc.SetOomFn(growHeap)
+		setMaxHeap(uintptr(limit))
+	}
+	return old
+}
+
+func setMaxHeap(n uintptr) {
+	atomic.StoreUintptr(&gcConf.maxHeap, n)
+	bdwgc.SetMaxHeapSize(n)
+}
+
+// growHeap is called by bdwgc when an allocation fails. If the heap is at
+// the memory limit, it raises the maximum heap size of bdwgc to fit the
+// allocation and retries it.
+func growHeap(size uintptr) c.Pointer {
+	max := atomic.LoadUintptr(&gcConf.maxHeap)
+	need := bdwgc.GetHeapSize() + size
+	need += need / 8
+	if max == 0 || need <= max {
+		return nil // out of memory, not at the limit
+	}
+	if !atomic.CompareAndSwapUintptr(&gcConf.maxHeap, max, need) {
+		return bdwgc.Malloc(size) // raised by another thread
+	}
+	bdwgc.SetMaxHeapSize(need)
+	return bdwgc.Malloc(size)
+}
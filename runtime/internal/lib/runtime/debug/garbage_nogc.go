@@ -0,0 +1,45 @@
+//go:build nogc
+
+package debug
+
+import (
+	"math"
+	"runtime/debug"
+)
+
+// Without GC there is nothing to tune: the settings are recorded and
+// returned but have no effect.
+var (
+	gcPercent = 100
+	memLimit  = int64(math.MaxInt64)
+)
+
+// ReadGCStats reads statistics about garbage collection into stats.
+// No collection ever runs without GC.
+func ReadGCStats(stats *debug.GCStats) {
+	*stats = debug.GCStats{}
+}
+
+// SetGCPercent records percent and returns the previous setting.
+func SetGCPercent(percent int) int {
+	old := gcPercent
+	gcPercent = percent
+	if gcPercent < 0 {
+		gcPercent = -1
+	}
+	return old
+}
+
+// FreeOSMemory does nothing without GC.
+func FreeOSMemory() {
+}
+
+// SetMemoryLimit records limit and returns the previous setting. A negative
+// input does not adjust the limit.
+func SetMemoryLimit(limit int64) int64 {
+	old := memLimit
+	if limit >= 0 {
+		memLimit = limit
+	}
+	return old
+}
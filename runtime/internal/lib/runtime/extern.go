@@ -8,22 +8,33 @@ import (
 	"github.com/goplus/llgo/runtime/internal/clite/debug"
 )
 
+// Caller reports file and line number information about function invocations on
+// the calling goroutine's stack. The argument skip is the number of stack frames
+// to ascend, with 0 identifying the caller of Caller. The return values report
+// the program counter, the file name, and the line number within the file of the
+// corresponding call. The boolean ok is false if it was not possible to recover
+// the information.
 func Caller(skip int) (pc uintptr, file string, line int, ok bool) {
-	panic("todo: runtime.Caller")
+	// Inlined functions have no physical frames, so skip+1 return addresses
+	// are enough for skip+1 logical frames.
+	rpc := make([]uintptr, skip+1)
+	n := debug.Backtrace(1, rpc)
+	frames := CallersFrames(rpc[:n])
+	for more := n > 0; more; skip-- {
+		var frame Frame
+		frame, more = frames.Next()
+		if skip == 0 {
+			return frame.PC, frame.File, frame.Line, frame.PC != 0
+		}
+	}
+	return
 }
 
+// Callers fills the slice pc with the return program counters of function invocations
+// on the calling goroutine's stack. The argument skip is the number of stack frames
+// to skip before recording in pc, with 0 identifying the frame for Callers itself and
+// 1 identifying the caller of Callers.
+// It returns the number of entries written to pc.
 func Callers(skip int, pc []uintptr) int {
-	if len(pc) == 0 {
-		return 0
-	}
-	n := 0
-	debug.StackTrace(skip, func(fr *debug.Frame) bool {
-		if n >= len(pc) {
-			return false
-		}
-		pc[n] = fr.PC
-		n++
-		return true
-	})
-	return n
+	return debug.Backtrace(skip, pc)
 }
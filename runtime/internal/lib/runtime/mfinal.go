@@ -1,3 +1,5 @@
+//go:build !nogc
+
 // Copyright 2009 The Go Authors. All rights reserved.
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
@@ -6,7 +8,127 @@
 
 package runtime
 
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/runtime/abi"
+	"github.com/goplus/llgo/runtime/internal/runtime"
+)
+
+type eface struct {
+	_type *abi.Type
+	data  unsafe.Pointer
+}
+
+type iface struct {
+	tab  *runtime.Itab
+	data unsafe.Pointer
+}
+
+// SetFinalizer sets the finalizer associated with obj to the provided
+// finalizer function. When the garbage collector finds an unreachable block
+// with an associated finalizer, it clears the association and runs
+// finalizer(obj) in a separate goroutine. This makes obj reachable again,
+// but now without an associated finalizer. Assuming that SetFinalizer
+// is not called again, the next time the garbage collector sees
+// that obj is unreachable, it will free obj.
+//
+// SetFinalizer(obj, nil) clears any finalizer associated with obj.
+//
+// The argument obj must be a pointer to an object allocated by calling
+// new, by taking the address of a composite literal, or by taking the
+// address of a local variable.
+// The argument finalizer must be a function that takes a single argument
+// to which obj's type can be assigned, and can have arbitrary ignored return
+// values. If either of these is not true, SetFinalizer may abort the
+// program.
+//
+// If a cyclic structure includes a block with a finalizer, that
+// cycle is not guaranteed to be garbage collected and the finalizer
+// is not guaranteed to run, because there is no ordering that
+// respects the dependencies.
+//
+// Finalizers are run by bdwgc, in dependency order: if A points at B,
+// both have finalizers, and they are otherwise unreachable, only the
+// finalizer for A runs; once A is freed, the finalizer for B can run.
 func SetFinalizer(obj any, finalizer any) {
-	// TODO(xsw):
-	// panic("todo: runtime.SetFinalizer")
+	e := (*eface)(unsafe.Pointer(&obj))
+	etyp := e._type
+	if etyp == nil {
+		panic("runtime.SetFinalizer: first argument is nil")
+	}
+	if etyp.Kind() != abi.Pointer {
+		panic("runtime.SetFinalizer: first argument is " + etyp.String() + ", not pointer")
+	}
+	p := e.data
+	if p == nil {
+		panic("runtime.SetFinalizer: pointer not in allocated block")
+	}
+	switch base := runtime.HeapBase(p); base {
+	case nil:
+		// Global variables are never freed, and zero-sized objects may
+		// share their address: ignore them as Go does.
+		return
+	case p:
+	default:
+		panic("runtime.SetFinalizer: pointer not at beginning of allocated block")
+	}
+
+	f := (*eface)(unsafe.Pointer(&finalizer))
+	ftyp := f._type
+	if ftyp == nil {
+		runtime.SetFinalizer(p, nil)
+		return
+	}
+	if !ftyp.IsClosure() {
+		panic("runtime.SetFinalizer: second argument is " + ftyp.String() + ", not a function")
+	}
+	ft := ftyp.StructType().Fields[0].Typ.FuncType()
+	if ft.Variadic() {
+		panic("runtime.SetFinalizer: cannot pass " + etyp.String() + " to finalizer " + ftyp.String() + " because dotdotdot")
+	}
+	if len(ft.In) != 1 {
+		panic("runtime.SetFinalizer: cannot pass " + etyp.String() + " to finalizer " + ftyp.String())
+	}
+	var nret uintptr
+	for _, t := range ft.Out {
+		nret += t.Size()
+	}
+	if nret > 2*unsafe.Sizeof(uintptr(0)) {
+		// the results would be returned in memory, which the calls below
+		// don't provide
+		panic("runtime.SetFinalizer: results of finalizer " + ftyp.String() + " are too large")
+	}
+
+	// The finalizer is called with the closure of f.data reinterpreted as
+	// a function of the same calling convention.
+	var fn func(unsafe.Pointer)
+	fint := ft.In[0]
+	switch {
+	case fint == etyp:
+		// ok - same type
+		fn = *(*func(unsafe.Pointer))(f.data)
+	case fint.Kind() == abi.Pointer && (fint.Uncommon() == nil || etyp.Uncommon() == nil) && fint.Elem() == etyp.Elem():
+		// ok - not same type, but both pointers,
+		// one or the other is unnamed, and same element type, so assignable.
+		fn = *(*func(unsafe.Pointer))(f.data)
+	case fint.Kind() == abi.Interface && len(fint.InterfaceType().Methods) == 0:
+		// ok - satisfies empty interface
+		call := *(*func(eface))(f.data)
+		fn = func(p unsafe.Pointer) {
+			call(eface{etyp, p})
+		}
+	case fint.Kind() == abi.Interface && runtime.Implements(fint, etyp):
+		// ok - satisfies non-empty interface
+		call := *(*func(iface))(f.data)
+		tab := runtime.NewItab(fint.InterfaceType(), etyp)
+		fn = func(p unsafe.Pointer) {
+			call(iface{tab, p})
+		}
+	default:
+		panic("runtime.SetFinalizer: cannot pass " + etyp.String() + " to finalizer " + ftyp.String())
+	}
+	if !runtime.SetFinalizer(p, fn) {
+		panic("runtime.SetFinalizer: finalizer already set")
+	}
 }
@@ -0,0 +1,8 @@
+//go:build nogc
+
+package runtime
+
+// SetFinalizer does nothing: without a garbage collector objects are never
+// freed, so their finalizers would never run.
+func SetFinalizer(obj any, finalizer any) {
+}
@@ -0,0 +1,128 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Malloc profiling.
+
+package runtime
+
+import (
+	"github.com/goplus/llgo/runtime/internal/runtime"
+)
+
+func init() {
+	runtime.SetMemProfileRate(&MemProfileRate)
+}
+
+// A StackRecord describes a single execution stack.
+type StackRecord struct {
+	Stack0 [32]uintptr // stack trace for this record; ends at first 0 entry
+}
+
+// Stack returns the stack trace associated with the record,
+// a prefix of r.Stack0.
+func (r *StackRecord) Stack() []uintptr {
+	for i, v := range r.Stack0 {
+		if v == 0 {
+			return r.Stack0[0:i]
+		}
+	}
+	return r.Stack0[0:]
+}
+
+// A MemProfileRecord describes the live objects allocated
+// by a particular call sequence (stack trace).
+type MemProfileRecord struct {
+	AllocBytes, FreeBytes     int64       // number of bytes allocated, freed
+	AllocObjects, FreeObjects int64       // number of objects allocated, freed
+	Stack0                    [32]uintptr // stack trace for this record; ends at first 0 entry
+}
+
+// InUseBytes returns the number of bytes in use (AllocBytes - FreeBytes).
+func (r *MemProfileRecord) InUseBytes() int64 { return r.AllocBytes - r.FreeBytes }
+
+// InUseObjects returns the number of objects in use (AllocObjects - FreeObjects).
+func (r *MemProfileRecord) InUseObjects() int64 {
+	return r.AllocObjects - r.FreeObjects
+}
+
+// Stack returns the stack trace associated with the record,
+// a prefix of r.Stack0.
+func (r *MemProfileRecord) Stack() []uintptr {
+	for i, v := range r.Stack0 {
+		if v == 0 {
+			return r.Stack0[0:i]
+		}
+	}
+	return r.Stack0[0:]
+}
+
+// MemProfile returns a profile of memory allocated and freed per allocation
+// site.
+//
+// MemProfile returns n, the number of records in the current memory profile.
+// If len(p) >= n, MemProfile copies the profile into p and returns n, true.
+// If len(p) < n, MemProfile does not change p and returns n, false.
+//
+// If inuseZero is true, the profile includes allocation records
+// where r.AllocBytes > 0 but r.AllocBytes == r.FreeBytes.
+// These are sites where memory was allocated, but it has all
+// been released back to the runtime.
+func MemProfile(p []MemProfileRecord, inuseZero bool) (n int, ok bool) {
+	var recs []MemProfileRecord
+	runtime.MemProfile(func(b *runtime.MemBucket) {
+		if inuseZero || b.AllocBytes != b.FreeBytes {
+			r := MemProfileRecord{
+				AllocBytes:   b.AllocBytes,
+				FreeBytes:    b.FreeBytes,
+				AllocObjects: b.Allocs,
+				FreeObjects:  b.Frees,
+			}
+			copy(r.Stack0[:], b.Stk[:b.NStk])
+			recs = append(recs, r)
+		}
+	})
+	n = len(recs)
+	if n <= len(p) {
+		ok = true
+		copy(p, recs)
+	}
+	return
+}
+
+// ThreadCreateProfile returns n, the number of records in the thread creation profile.
+// If len(p) >= n, ThreadCreateProfile copies the profile into p and returns n, true.
+// If len(p) < n, ThreadCreateProfile does not change p and returns n, false.
+func ThreadCreateProfile(p []StackRecord) (n int, ok bool) {
+	return stackProfile(p, runtime.ThreadCreateProfile)
+}
+
+// GoroutineProfile returns n, the number of records in the active goroutine stack profile.
+// If len(p) >= n, GoroutineProfile copies the profile into p and returns n, true.
+// If len(p) < n, GoroutineProfile does not change p and returns n, false.
+//
+// A goroutine running on another thread can't be unwound: its record holds
+// the function it started with.
+func GoroutineProfile(p []StackRecord) (n int, ok bool) {
+	return stackProfile(p, runtime.GoroutineProfile)
+}
+
+func stackProfile(p []StackRecord, profile func(fn func(stk []uintptr))) (n int, ok bool) {
+	var recs []StackRecord
+	profile(func(stk []uintptr) {
+		var r StackRecord
+		copy(r.Stack0[:], stk)
+		recs = append(recs, r)
+	})
+	n = len(recs)
+	if n <= len(p) {
+		ok = true
+		copy(p, recs)
+	}
+	return
+}
+
+// NumGoroutine returns the number of goroutines that currently exist.
+func NumGoroutine() int {
+	return runtime.NumGoroutine()
+}
@@ -0,0 +1,103 @@
+#include <errno.h>
+#include <string.h>
+
+#if defined(__wasm__)
+
+int llgo_cpuprof_start(int hz) {
+    errno = ENOSYS;
+    return -1;
+}
+
+void llgo_cpuprof_stop() {}
+
+int llgo_cpuprof_read(void **pcs, int max) {
+    return -1;
+}
+
+#else
+
+#include <signal.h>
+#include <sys/time.h>
+
+#define CPUPROF_MAXSTACK 64
+#define CPUPROF_BUFSIZE 1024
+
+int llgo_backtrace(int skip, void **pcs, int max);
+
+// Samples are written by the SIGPROF handler and read by the profile
+// writer. The handler drops a sample instead of waiting when the buffer is
+// full or busy.
+static struct {
+    int depth;
+    void *pcs[CPUPROF_MAXSTACK];
+} cpuprof_buf[CPUPROF_BUFSIZE];
+
+static int cpuprof_head, cpuprof_tail;
+static volatile char cpuprof_busy;
+static struct sigaction cpuprof_oldact;
+
+static int cpuprof_lock() {
+    return !__atomic_test_and_set(&cpuprof_busy, __ATOMIC_ACQUIRE);
+}
+
+static void cpuprof_unlock() {
+    __atomic_clear(&cpuprof_busy, __ATOMIC_RELEASE);
+}
+
+static void cpuprof_handler(int sig) {
+    int saved = errno;
+    if (cpuprof_lock()) {
+        if (cpuprof_tail - cpuprof_head < CPUPROF_BUFSIZE) {
+            int i = cpuprof_tail % CPUPROF_BUFSIZE;
+            // skip the handler and the signal trampoline
+            cpuprof_buf[i].depth = llgo_backtrace(2, cpuprof_buf[i].pcs, CPUPROF_MAXSTACK);
+            cpuprof_tail++;
+        }
+        cpuprof_unlock();
+    }
+    errno = saved;
+}
+
+int llgo_cpuprof_start(int hz) {
+    struct sigaction act;
+    memset(&act, 0, sizeof(act));
+    act.sa_handler = cpuprof_handler;
+    act.sa_flags = SA_RESTART;
+    sigemptyset(&act.sa_mask);
+    if (sigaction(SIGPROF, &act, &cpuprof_oldact) != 0) {
+        return -1;
+    }
+    struct itimerval it;
+    it.it_interval.tv_sec = 0;
+    it.it_interval.tv_usec = 1000000 / hz;
+    it.it_value = it.it_interval;
+    return setitimer(ITIMER_PROF, &it, NULL);
+}
+
+void llgo_cpuprof_stop() {
+    struct itimerval it;
+    memset(&it, 0, sizeof(it));
+    setitimer(ITIMER_PROF, &it, NULL);
+    sigaction(SIGPROF, &cpuprof_oldact, NULL);
+}
+
+// llgo_cpuprof_read copies the oldest sample into pcs and returns its depth,
+// or -1 if there is none.
+int llgo_cpuprof_read(void **pcs, int max) {
+    int n = -1;
+    while (!cpuprof_lock()) {
+    }
+    if (cpuprof_head != cpuprof_tail) {
+        int i = cpuprof_head % CPUPROF_BUFSIZE;
+        n = cpuprof_buf[i].depth;
+        if (n > max) {
+            n = max;
+        }
+        memcpy(pcs, cpuprof_buf[i].pcs, n * sizeof(void *));
+        cpuprof_head++;
+    }
+    cpuprof_unlock();
+    return n;
+}
+
+#endif
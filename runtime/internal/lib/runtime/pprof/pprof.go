@@ -1,24 +1,609 @@
+// Copyright 2010 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pprof writes runtime profiling data in the format expected
+// by the pprof visualization tool.
 package pprof
 
 import (
+	"bufio"
+	"fmt"
 	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/os"
 )
 
 // llgo:skipall
-type Profile struct{}
+type _pprof struct{}
+
+const (
+	LLGoFiles = "_wrap/cpuprof.c"
+)
 
-func (p *Profile) WriteTo(w io.Writer, verbose bool) (int, error) {
-	panic("WriteTo not implemented")
+// A Profile is a collection of stack traces showing the call sequences
+// that led to instances of a particular event, such as allocation.
+// Packages can create and maintain their own profiles; the most common
+// use is for tracking resources that must be explicitly closed, such as files
+// or network connections.
+//
+// A Profile's methods can be called from multiple goroutines simultaneously.
+type Profile struct {
+	name  string
+	mu    sync.Mutex
+	m     map[any][]uintptr
+	count func() int
+	write func(io.Writer, int) error
 }
 
-func StartCPUProfile(w io.Writer) error {
-	panic("StartCPUProfile not implemented")
+// profiles records all registered profiles.
+var profiles struct {
+	mu sync.Mutex
+	m  map[string]*Profile
 }
 
-func StopCPUProfile() {
-	panic("StopCPUProfile not implemented")
+var goroutineProfile = &Profile{
+	name:  "goroutine",
+	count: countGoroutine,
+	write: writeGoroutine,
+}
+
+var threadcreateProfile = &Profile{
+	name:  "threadcreate",
+	count: countThreadCreate,
+	write: writeThreadCreate,
+}
+
+var heapProfile = &Profile{
+	name:  "heap",
+	count: countHeap,
+	write: writeHeap,
 }
 
+var allocsProfile = &Profile{
+	name:  "allocs",
+	count: countHeap, // identical to heap profile
+	write: writeAlloc,
+}
+
+// Block and mutex profiles are not recorded: they are always empty.
+
+var blockProfile = &Profile{
+	name:  "block",
+	count: countNone,
+	write: writeNone("contentions"),
+}
+
+var mutexProfile = &Profile{
+	name:  "mutex",
+	count: countNone,
+	write: writeNone("contentions"),
+}
+
+func lockProfiles() {
+	profiles.mu.Lock()
+	if profiles.m == nil {
+		// Initial built-in profiles.
+		profiles.m = map[string]*Profile{
+			"goroutine":    goroutineProfile,
+			"threadcreate": threadcreateProfile,
+			"heap":         heapProfile,
+			"allocs":       allocsProfile,
+			"block":        blockProfile,
+			"mutex":        mutexProfile,
+		}
+	}
+}
+
+func unlockProfiles() {
+	profiles.mu.Unlock()
+}
+
+// NewProfile creates a new profile with the given name.
+// If a profile with that name already exists, NewProfile panics.
+// The convention is to use a 'import/path.' prefix to create
+// separate name spaces for each package.
+func NewProfile(name string) *Profile {
+	lockProfiles()
+	defer unlockProfiles()
+	if name == "" {
+		panic("pprof: NewProfile with empty name")
+	}
+	if profiles.m[name] != nil {
+		panic("pprof: NewProfile name already in use: " + name)
+	}
+	p := &Profile{
+		name: name,
+		m:    map[any][]uintptr{},
+	}
+	profiles.m[name] = p
+	return p
+}
+
+// Lookup returns the profile with the given name, or nil if no such profile exists.
 func Lookup(name string) *Profile {
-	panic("Lookup not implemented")
+	lockProfiles()
+	defer unlockProfiles()
+	return profiles.m[name]
+}
+
+// Profiles returns a slice of all the known profiles, sorted by name.
+func Profiles() []*Profile {
+	lockProfiles()
+	defer unlockProfiles()
+
+	all := make([]*Profile, 0, len(profiles.m))
+	for _, p := range profiles.m {
+		all = append(all, p)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	return all
+}
+
+// Name returns this profile's name, which can be passed to Lookup to reobtain the profile.
+func (p *Profile) Name() string {
+	return p.name
+}
+
+// Count returns the number of execution stacks currently in the profile.
+func (p *Profile) Count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.count != nil {
+		return p.count()
+	}
+	return len(p.m)
+}
+
+// Add adds the current execution stack to the profile, associated with value.
+// Add stores value in an internal map, so value must be suitable for use as
+// a map key and will not be garbage collected until the corresponding
+// call to Remove. Add panics if the profile already contains a stack for value.
+//
+// The skip parameter has the same meaning as runtime.Caller's skip
+// and controls where the stack trace begins. Passing skip=0 begins the
+// trace in the function calling Add.
+func (p *Profile) Add(value any, skip int) {
+	if p.name == "" {
+		panic("pprof: use of uninitialized Profile")
+	}
+	if p.write != nil {
+		panic("pprof: Add called on built-in Profile " + p.name)
+	}
+
+	stk := make([]uintptr, 32)
+	n := runtime.Callers(skip+1, stk[:])
+	stk = stk[:n]
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.m[value] != nil {
+		panic("pprof: Profile.Add of duplicate value")
+	}
+	p.m[value] = stk
+}
+
+// Remove removes the execution stack associated with value from the profile.
+// It is a no-op if the value is not in the profile.
+func (p *Profile) Remove(value any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.m, value)
+}
+
+// WriteTo writes a pprof-formatted snapshot of the profile to w.
+// If a write to w returns an error, WriteTo returns that error.
+// Otherwise, WriteTo returns nil.
+//
+// The debug parameter enables additional output.
+// Passing debug=0 writes the gzip-compressed protocol buffer described
+// in https://github.com/google/pprof/tree/master/proto#overview.
+// Passing debug=1 writes the legacy text format with comments
+// translating addresses to function names.
+func (p *Profile) WriteTo(w io.Writer, debug int) error {
+	if p.name == "" {
+		panic("pprof: use of zero Profile")
+	}
+	if p.write != nil {
+		return p.write(w, debug)
+	}
+
+	// Obtain consistent snapshot under lock; then process without lock.
+	p.mu.Lock()
+	all := make([][]uintptr, 0, len(p.m))
+	for _, stk := range p.m {
+		all = append(all, stk)
+	}
+	p.mu.Unlock()
+
+	return printCountProfile(w, debug, p.name, all)
+}
+
+// printCountProfile prints a countProfile at the specified debug level.
+func printCountProfile(w io.Writer, debug int, name string, stks [][]uintptr) error {
+	// Group stacks by their textual key.
+	var keys []string
+	counts := make(map[string]int)
+	index := make(map[string]int)
+	for i, stk := range stks {
+		var buf strings.Builder
+		buf.WriteString("@")
+		for _, pc := range stk {
+			fmt.Fprintf(&buf, " %#x", pc)
+		}
+		k := buf.String()
+		if counts[k] == 0 {
+			index[k] = i
+			keys = append(keys, k)
+		}
+		counts[k]++
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		ki, kj := keys[i], keys[j]
+		if counts[ki] != counts[kj] {
+			return counts[ki] > counts[kj]
+		}
+		return ki < kj
+	})
+
+	if debug > 0 {
+		// Print debug profile in legacy format
+		tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+		fmt.Fprintf(tw, "%s profile: total %d\n", name, len(stks))
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%d %s\n", counts[k], k)
+			printStackRecord(tw, stks[index[k]])
+		}
+		return tw.Flush()
+	}
+
+	// Output profile in protobuf form.
+	b := newProfileBuilder(w)
+	b.pbValueType(tagProfile_PeriodType, name, "count")
+	b.pb.int64Opt(tagProfile_Period, 1)
+	b.pbValueType(tagProfile_SampleType, name, "count")
+
+	for _, k := range keys {
+		b.addSample(stks[index[k]], []int64{int64(counts[k])}, false)
+	}
+	return b.build()
+}
+
+// printStackRecord prints the function + source line information
+// for a single stack trace.
+func printStackRecord(w io.Writer, stk []uintptr) {
+	for _, pc := range stk {
+		name, off := funcName(pc - 1)
+		if name == "" {
+			fmt.Fprintf(w, "#\t%#x\n", pc)
+		} else {
+			fmt.Fprintf(w, "#\t%#x\t%s+%#x\n", pc, name, off+1)
+		}
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// -----------------------------------------------------------------------------
+
+func countNone() int {
+	return 0
+}
+
+func writeNone(unit string) func(io.Writer, int) error {
+	return func(w io.Writer, debug int) error {
+		if debug > 0 {
+			_, err := fmt.Fprintf(w, "--- %s:\ncycles/second=%v\n", unit, 1)
+			return err
+		}
+		b := newProfileBuilder(w)
+		b.pbValueType(tagProfile_PeriodType, unit, "count")
+		b.pb.int64Opt(tagProfile_Period, 1)
+		b.pbValueType(tagProfile_SampleType, unit, "count")
+		b.pbValueType(tagProfile_SampleType, "delay", "nanoseconds")
+		return b.build()
+	}
+}
+
+// countGoroutine returns the number of goroutines.
+func countGoroutine() int {
+	return runtime.NumGoroutine()
+}
+
+// writeGoroutine writes the current runtime GoroutineProfile to w.
+func writeGoroutine(w io.Writer, debug int) error {
+	return writeRuntimeProfile(w, debug, "goroutine", runtime.GoroutineProfile)
+}
+
+// countThreadCreate returns the size of the current ThreadCreateProfile.
+func countThreadCreate() int {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return n
+}
+
+// writeThreadCreate writes the current runtime ThreadCreateProfile to w.
+func writeThreadCreate(w io.Writer, debug int) error {
+	return writeRuntimeProfile(w, debug, "threadcreate", runtime.ThreadCreateProfile)
+}
+
+func writeRuntimeProfile(w io.Writer, debug int, name string, fetch func([]runtime.StackRecord) (int, bool)) error {
+	// Find out how many records there are (fetch(nil)),
+	// allocate that many records, and get the data.
+	// There's a race—more records might be added between
+	// the two calls—so allocate a few extra records for safety
+	// and also try again if we're very unlucky.
+	// The loop should only execute one iteration in the common case.
+	var p []runtime.StackRecord
+	n, ok := fetch(nil)
+	for {
+		p = make([]runtime.StackRecord, n+10)
+		n, ok = fetch(p)
+		if ok {
+			p = p[0:n]
+			break
+		}
+		// Profile grew; try again.
+	}
+
+	stks := make([][]uintptr, len(p))
+	for i := range p {
+		stks[i] = p[i].Stack()
+	}
+	return printCountProfile(w, debug, name, stks)
+}
+
+// countHeap returns the number of records in the heap profile.
+func countHeap() int {
+	n, _ := runtime.MemProfile(nil, true)
+	return n
+}
+
+// writeHeap writes the current runtime heap profile to w.
+func writeHeap(w io.Writer, debug int) error {
+	return writeHeapInternal(w, debug, "")
+}
+
+// writeAlloc writes the current runtime heap profile to w
+// with the total allocation space as the default sample type.
+func writeAlloc(w io.Writer, debug int) error {
+	return writeHeapInternal(w, debug, "alloc_space")
+}
+
+func writeHeapInternal(w io.Writer, debug int, defaultSampleType string) error {
+	var p []runtime.MemProfileRecord
+	n, ok := runtime.MemProfile(nil, true)
+	for {
+		// Allocate room for a slightly bigger profile,
+		// in case a few more entries have been added
+		// since the call to MemProfile.
+		p = make([]runtime.MemProfileRecord, n+50)
+		n, ok = runtime.MemProfile(p, true)
+		if ok {
+			p = p[0:n]
+			break
+		}
+		// Profile grew; try again.
+	}
+
+	rate := int64(runtime.MemProfileRate)
+	if debug == 0 {
+		return writeHeapProto(w, p, rate, defaultSampleType)
+	}
+
+	sort.Slice(p, func(i, j int) bool { return p[i].InUseBytes() > p[j].InUseBytes() })
+
+	b := bufio.NewWriter(w)
+	tw := tabwriter.NewWriter(b, 1, 8, 1, '\t', 0)
+	w = tw
+
+	var total runtime.MemProfileRecord
+	for i := range p {
+		r := &p[i]
+		total.AllocBytes += r.AllocBytes
+		total.AllocObjects += r.AllocObjects
+		total.FreeBytes += r.FreeBytes
+		total.FreeObjects += r.FreeObjects
+	}
+
+	// Technically the rate is MemProfileRate not 2*MemProfileRate,
+	// but early versions of the C++ heap profiler reported 2*MemProfileRate,
+	// so that's what pprof has come to expect.
+	fmt.Fprintf(w, "heap profile: %d: %d [%d: %d] @ heap/%d\n",
+		total.InUseObjects(), total.InUseBytes(),
+		total.AllocObjects, total.AllocBytes,
+		2*rate)
+
+	for i := range p {
+		r := &p[i]
+		fmt.Fprintf(w, "%d: %d [%d: %d] @",
+			r.InUseObjects(), r.InUseBytes(),
+			r.AllocObjects, r.AllocBytes)
+		for _, pc := range r.Stack() {
+			fmt.Fprintf(w, " %#x", pc)
+		}
+		fmt.Fprintf(w, "\n")
+		printStackRecord(w, r.Stack())
+	}
+
+	tw.Flush()
+	return b.Flush()
+}
+
+// writeHeapProto writes the current heap profile in protobuf format to w.
+func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, rate int64, defaultSampleType string) error {
+	b := newProfileBuilder(w)
+	b.pbValueType(tagProfile_PeriodType, "space", "bytes")
+	b.pb.int64Opt(tagProfile_Period, rate)
+	b.pbValueType(tagProfile_SampleType, "alloc_objects", "count")
+	b.pbValueType(tagProfile_SampleType, "alloc_space", "bytes")
+	b.pbValueType(tagProfile_SampleType, "inuse_objects", "count")
+	b.pbValueType(tagProfile_SampleType, "inuse_space", "bytes")
+	if defaultSampleType != "" {
+		b.pb.int64Opt(tagProfile_DefaultSampleType, b.stringIndex(defaultSampleType))
+	}
+
+	values := []int64{0, 0, 0, 0}
+	for _, r := range p {
+		values[0], values[1] = scaleHeapSample(r.AllocObjects, r.AllocBytes, rate)
+		values[2], values[3] = scaleHeapSample(r.InUseObjects(), r.InUseBytes(), rate)
+		b.addSample(r.Stack(), values, false)
+	}
+	return b.build()
+}
+
+//go:linkname cExp C.exp
+func cExp(x float64) float64
+
+// scaleHeapSample adjusts the data from a heap Sample to
+// account for its probability of appearing in the collected
+// data. heap profiles are a sampling of the memory allocations
+// requests in a program. We estimate the unsampled value by dividing
+// each collected sample by its probability of appearing in the
+// profile. heap profiles rely on a poisson process to determine
+// which samples to collect, based on the desired average collection
+// rate R. The probability of a sample of size S to appear in that
+// profile is 1-exp(-S/R).
+func scaleHeapSample(count, size, rate int64) (int64, int64) {
+	if count == 0 || size == 0 {
+		return 0, 0
+	}
+
+	if rate <= 1 {
+		// if rate==1 all samples were collected so no adjustment is needed.
+		// if rate<1 treat as unknown and skip scaling.
+		return count, size
+	}
+
+	avgSize := float64(size) / float64(count)
+	scale := 1 / (1 - cExp(-avgSize/float64(rate)))
+
+	return int64(float64(count) * scale), int64(float64(size) * scale)
+}
+
+// WriteHeapProfile is shorthand for Lookup("heap").WriteTo(w, 0).
+// It is preserved for backwards compatibility.
+func WriteHeapProfile(w io.Writer) error {
+	return writeHeap(w, 0)
+}
+
+// -----------------------------------------------------------------------------
+
+//go:linkname cpuprofStart C.llgo_cpuprof_start
+func cpuprofStart(hz c.Int) c.Int
+
+//go:linkname cpuprofStop C.llgo_cpuprof_stop
+func cpuprofStop()
+
+//go:linkname cpuprofRead C.llgo_cpuprof_read
+func cpuprofRead(pcs *uintptr, max c.Int) c.Int
+
+// cpuHz is the sampling rate of the CPU profiler.
+const cpuHz = 100
+
+var cpu struct {
+	sync.Mutex
+	profiling bool
+	done      chan bool
+	stop      chan bool
+}
+
+// StartCPUProfile enables CPU profiling for the current process.
+// While profiling, the profile will be buffered and written to w.
+// StartCPUProfile returns an error if profiling is already enabled.
+//
+// CPU samples are taken on SIGPROF, which the process receives at a
+// rate of 100Hz of CPU time consumed.
+func StartCPUProfile(w io.Writer) error {
+	cpu.Lock()
+	defer cpu.Unlock()
+	if cpu.done == nil {
+		cpu.done = make(chan bool)
+		cpu.stop = make(chan bool)
+	}
+	// Double-check.
+	if cpu.profiling {
+		return fmt.Errorf("cpu profiling already in use")
+	}
+	if cpuprofStart(cpuHz) != 0 {
+		return fmt.Errorf("cpu profiling: %w", syscall.Errno(os.Errno()))
+	}
+	cpu.profiling = true
+	go profileWriter(w)
+	return nil
+}
+
+// profileWriter drains the samples of the CPU profiler every 100ms, and
+// writes the profile to w when profiling stops.
+func profileWriter(w io.Writer) {
+	counts := make(map[string]int64)
+	var keys []string
+	stks := make(map[string][]uintptr)
+	start := time.Now()
+	drain := func() {
+		var buf [64]uintptr
+		for {
+			n := cpuprofRead(&buf[0], c.Int(len(buf)))
+			if n < 0 {
+				return
+			}
+			stk := buf[:n]
+			k := string(unsafe.Slice((*byte)(unsafe.Pointer(&buf[0])), uintptr(n)*unsafe.Sizeof(buf[0])))
+			if _, ok := counts[k]; !ok {
+				keys = append(keys, k)
+				stks[k] = append([]uintptr(nil), stk...)
+			}
+			counts[k]++
+		}
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	for running := true; running; {
+		select {
+		case <-ticker.C:
+		case <-cpu.stop:
+			running = false
+		}
+		drain()
+	}
+	ticker.Stop()
+
+	b := newProfileBuilder(w)
+	b.pb.int64Opt(tagProfile_TimeNanos, start.UnixNano())
+	b.pb.int64Opt(tagProfile_DurationNanos, int64(time.Since(start)))
+	b.pbValueType(tagProfile_PeriodType, "cpu", "nanoseconds")
+	b.pb.int64Opt(tagProfile_Period, 1e9/cpuHz)
+	b.pbValueType(tagProfile_SampleType, "samples", "count")
+	b.pbValueType(tagProfile_SampleType, "cpu", "nanoseconds")
+	for _, k := range keys {
+		b.addSample(stks[k], []int64{counts[k], counts[k] * (1e9 / cpuHz)}, true)
+	}
+	if err := b.build(); err != nil {
+		// The file has been written partially: there is nobody to report
+		// the error to, so we follow Go's profileWriter and panic.
+		panic("runtime/pprof: converting profile: " + err.Error())
+	}
+	cpu.done <- true
+}
+
+// StopCPUProfile stops the current CPU profile, if any.
+// StopCPUProfile only returns after all the writes for the
+// profile have completed.
+func StopCPUProfile() {
+	cpu.Lock()
+	defer cpu.Unlock()
+
+	if !cpu.profiling {
+		return
+	}
+	cpu.profiling = false
+	cpuprofStop()
+	cpu.stop <- true
+	<-cpu.done
 }
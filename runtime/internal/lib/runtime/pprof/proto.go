@@ -0,0 +1,250 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pprof
+
+import (
+	"compress/gzip"
+	"io"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/debug"
+)
+
+// A profileBuilder writes a profile incrementally from a
+// sequence of stacks, symbolizing addresses with dladdr.
+type profileBuilder struct {
+	zw *gzip.Writer
+
+	// encoding state
+	pb        protobuf
+	strings   []string
+	stringMap map[string]int
+	locs      map[uintptr]uint64 // address -> location id
+	funcs     map[string]uint64  // function name -> function id
+	mem       []memMap
+}
+
+// memMap is a shared object, executable or library, containing
+// symbolized addresses.
+type memMap struct {
+	start, end uintptr // end is one past the highest address seen
+	file       string
+}
+
+const (
+	// message Profile
+	tagProfile_SampleType        = 1  // repeated ValueType
+	tagProfile_Sample            = 2  // repeated Sample
+	tagProfile_Mapping           = 3  // repeated Mapping
+	tagProfile_Location          = 4  // repeated Location
+	tagProfile_Function          = 5  // repeated Function
+	tagProfile_StringTable       = 6  // repeated string
+	tagProfile_DropFrames        = 7  // int64 (string table index)
+	tagProfile_KeepFrames        = 8  // int64 (string table index)
+	tagProfile_TimeNanos         = 9  // int64
+	tagProfile_DurationNanos     = 10 // int64
+	tagProfile_PeriodType        = 11 // ValueType (really optional string???)
+	tagProfile_Period            = 12 // int64
+	tagProfile_Comment           = 13 // repeated int64
+	tagProfile_DefaultSampleType = 14 // int64
+
+	// message ValueType
+	tagValueType_Type = 1 // int64 (string table index)
+	tagValueType_Unit = 2 // int64 (string table index)
+
+	// message Sample
+	tagSample_Location = 1 // repeated uint64
+	tagSample_Value    = 2 // repeated int64
+	tagSample_Label    = 3 // repeated Label
+
+	// message Label
+	tagLabel_Key = 1 // int64 (string table index)
+	tagLabel_Str = 2 // int64 (string table index)
+	tagLabel_Num = 3 // int64
+
+	// message Mapping
+	tagMapping_ID              = 1  // uint64
+	tagMapping_Start           = 2  // uint64
+	tagMapping_Limit           = 3  // uint64
+	tagMapping_Offset          = 4  // uint64
+	tagMapping_Filename        = 5  // int64 (string table index)
+	tagMapping_BuildID         = 6  // int64 (string table index)
+	tagMapping_HasFunctions    = 7  // bool
+	tagMapping_HasFilenames    = 8  // bool
+	tagMapping_HasLineNumbers  = 9  // bool
+	tagMapping_HasInlineFrames = 10 // bool
+
+	// message Location
+	tagLocation_ID        = 1 // uint64
+	tagLocation_MappingID = 2 // uint64
+	tagLocation_Address   = 3 // uint64
+	tagLocation_Line      = 4 // repeated Line
+
+	// message Line
+	tagLine_FunctionID = 1 // uint64
+	tagLine_Line       = 2 // int64
+
+	// message Function
+	tagFunction_ID         = 1 // uint64
+	tagFunction_Name       = 2 // int64 (string table index)
+	tagFunction_SystemName = 3 // int64 (string table index)
+	tagFunction_Filename   = 4 // int64 (string table index)
+	tagFunction_StartLine  = 5 // int64
+)
+
+// stringIndex adds s to the string table if not already present
+// and returns the index of s in the string table.
+func (b *profileBuilder) stringIndex(s string) int64 {
+	id, ok := b.stringMap[s]
+	if !ok {
+		id = len(b.strings)
+		b.strings = append(b.strings, s)
+		b.stringMap[s] = id
+	}
+	return int64(id)
+}
+
+// pbValueType encodes a ValueType message to b.pb.
+func (b *profileBuilder) pbValueType(tag int, typ, unit string) {
+	start := b.pb.startMessage()
+	b.pb.int64(tagValueType_Type, b.stringIndex(typ))
+	b.pb.int64(tagValueType_Unit, b.stringIndex(unit))
+	b.pb.endMessage(tag, start)
+}
+
+// newProfileBuilder returns a new profileBuilder.
+// CPU profiling data obtained from the runtime can be added
+// by calling b.addSample and then the eventual profile
+// can be obtained by calling b.build.
+func newProfileBuilder(w io.Writer) *profileBuilder {
+	zw, _ := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	return &profileBuilder{
+		zw:        zw,
+		strings:   []string{""},
+		stringMap: map[string]int{"": 0},
+		locs:      map[uintptr]uint64{},
+		funcs:     map[string]uint64{},
+	}
+}
+
+// addSample adds a sample of stk, leaf first, with values. If exact is
+// true, the leaf address is the instruction being executed instead of a
+// return address, as in CPU samples.
+func (b *profileBuilder) addSample(stk []uintptr, values []int64, exact bool) {
+	locs := make([]uint64, 0, len(stk))
+	for i, pc := range stk {
+		addr := pc
+		if i > 0 || !exact {
+			// Use the address of the call instruction instead of the
+			// return address, so it's attributed to the right line.
+			addr--
+		}
+		locs = append(locs, b.locForAddr(addr))
+	}
+	start := b.pb.startMessage()
+	b.pb.uint64s(tagSample_Location, locs)
+	b.pb.int64s(tagSample_Value, values)
+	b.pb.endMessage(tagProfile_Sample, start)
+}
+
+// locForAddr returns the id of the location of addr, emitting the
+// Location and Function messages the first time addr is seen.
+func (b *profileBuilder) locForAddr(addr uintptr) uint64 {
+	if id, ok := b.locs[addr]; ok {
+		return id
+	}
+	id := uint64(len(b.locs)) + 1
+	b.locs[addr] = id
+
+	name, _ := funcName(addr)
+	var info debug.Info
+	var mapID uint64
+	if debug.Addrinfo(addr, &info) != 0 && info.Fbase != nil {
+		mapID = b.mapForAddr(addr, uintptr(info.Fbase), c.GoString(info.Fname))
+	}
+
+	// The Function message must be emitted before the Location one starts.
+	var funcID uint64
+	if name != "" {
+		funcID = b.funcID(name)
+	}
+
+	start := b.pb.startMessage()
+	b.pb.uint64(tagLocation_ID, id)
+	b.pb.uint64Opt(tagLocation_MappingID, mapID)
+	b.pb.uint64(tagLocation_Address, uint64(addr))
+	if funcID != 0 {
+		lineStart := b.pb.startMessage()
+		b.pb.uint64(tagLine_FunctionID, funcID)
+		b.pb.endMessage(tagLocation_Line, lineStart)
+	}
+	b.pb.endMessage(tagProfile_Location, start)
+	return id
+}
+
+// funcID returns the id of the function name, emitting its Function
+// message the first time it's seen.
+func (b *profileBuilder) funcID(name string) uint64 {
+	if id, ok := b.funcs[name]; ok {
+		return id
+	}
+	id := uint64(len(b.funcs)) + 1
+	b.funcs[name] = id
+	start := b.pb.startMessage()
+	b.pb.uint64(tagFunction_ID, id)
+	b.pb.int64(tagFunction_Name, b.stringIndex(name))
+	b.pb.int64(tagFunction_SystemName, b.stringIndex(name))
+	b.pb.endMessage(tagProfile_Function, start)
+	return id
+}
+
+// mapForAddr returns the id of the mapping of the object loaded at base
+// from file, extending it to contain addr.
+func (b *profileBuilder) mapForAddr(addr, base uintptr, file string) uint64 {
+	for i := range b.mem {
+		m := &b.mem[i]
+		if m.start == base {
+			if addr >= m.end {
+				m.end = addr + 1
+			}
+			return uint64(i + 1)
+		}
+	}
+	b.mem = append(b.mem, memMap{start: base, end: addr + 1, file: file})
+	return uint64(len(b.mem))
+}
+
+// build completes and writes the profile, and returns the first error
+// encountered.
+func (b *profileBuilder) build() error {
+	for i, m := range b.mem {
+		start := b.pb.startMessage()
+		b.pb.uint64Opt(tagMapping_ID, uint64(i+1))
+		b.pb.uint64Opt(tagMapping_Start, uint64(m.start))
+		b.pb.uint64Opt(tagMapping_Limit, uint64(m.end))
+		b.pb.int64Opt(tagMapping_Filename, b.stringIndex(m.file))
+		b.pb.boolOpt(tagMapping_HasFunctions, true)
+		b.pb.endMessage(tagProfile_Mapping, start)
+	}
+
+	// TODO: Anything for tagProfile_DropFrames?
+	// TODO: Anything for tagProfile_KeepFrames?
+
+	b.pb.strings(tagProfile_StringTable, b.strings)
+	if _, err := b.zw.Write(b.pb.data); err != nil {
+		return err
+	}
+	return b.zw.Close()
+}
+
+// funcName returns the name of the function containing addr and the
+// offset of addr in it, or "" if it's unknown.
+func funcName(addr uintptr) (name string, off uintptr) {
+	var info debug.Info
+	if debug.Addrinfo(addr, &info) == 0 || info.Sname == nil {
+		return "", 0
+	}
+	return c.GoString(info.Sname), addr - uintptr(info.Saddr)
+}
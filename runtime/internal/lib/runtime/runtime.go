@@ -54,6 +54,10 @@ func Goexit() {
 	runtime.Goexit()
 }
 
+func Gosched() {
+	runtime.Gosched()
+}
+
 func KeepAlive(x any) {
 }
 
@@ -4,7 +4,7 @@
 
 package runtime
 
-import "runtime"
+import "github.com/goplus/llgo/runtime/internal/runtime"
 
 // Layout of in-memory per-function information prepared by linker
 // See https://golang.org/s/go12symtab.
@@ -14,8 +14,15 @@ type _func struct {
 	unused [8]byte
 }
 
+// Stack formats a stack trace of the calling goroutine into buf
+// and returns the number of bytes written to buf.
+// If all is true, Stack formats stack traces of all other goroutines
+// into buf after the trace for the current goroutine.
+//
+// The stacks of the other goroutines can't be unwound in llgo, so only
+// their headers and the go statements which created them are formatted.
 func Stack(buf []byte, all bool) int {
-	panic("todo: runtime.Stack")
+	return copy(buf, runtime.Stack(nil, all))
 }
 
 func StartTrace() error {
@@ -30,10 +37,6 @@ func StopTrace() {
 	panic("todo: runtime.StopTrace")
 }
 
-func ReadMemStats(m *runtime.MemStats) {
-	panic("todo: runtime.ReadMemStats")
-}
-
 func SetMutexProfileFraction(rate int) int {
 	panic("todo: runtime.SetMutexProfileFraction")
 }
@@ -2,8 +2,66 @@
 
 package runtime
 
-import "github.com/goplus/llgo/runtime/internal/clite/bdwgc"
+import (
+	"runtime"
+	"unsafe"
 
+	"github.com/goplus/llgo/runtime/internal/clite/bdwgc"
+	"github.com/goplus/llgo/runtime/internal/clite/sync/atomic"
+	"github.com/goplus/llgo/runtime/internal/clite/time"
+)
+
+var (
+	numForcedGC uint32
+	lastGC      int64 // time of the last collection start, in Unix nanoseconds
+)
+
+func init() {
+	bdwgc.SetStartCallback(gcStarted)
+}
+
+// gcStarted is called by bdwgc, with its lock held, when a collection
+// starts. It must not allocate.
+func gcStarted() {
+	var ts time.Timespec
+	time.ClockGettime(time.CLOCK_REALTIME, &ts)
+	atomic.Store(&lastGC, int64(ts.Sec)*1e9+int64(ts.Nsec))
+}
+
+// GC runs a full garbage collection.
 func GC() {
+	atomic.Add(&numForcedGC, 1)
 	bdwgc.Gcollect()
 }
+
+// ReadMemStats populates m with memory allocator statistics.
+//
+// The statistics come from bdwgc, which neither counts objects nor measures
+// pauses: Mallocs, Frees, HeapObjects and the pause statistics are always
+// zero. HeapAlloc is the part of the heap not in free blocks, so it also
+// counts unreachable objects until they are collected, and NextGC is an
+// estimate based on the free space divisor (see debug.SetGCPercent).
+func ReadMemStats(m *runtime.MemStats) {
+	var stats bdwgc.ProfStats
+	bdwgc.GetProfStats(&stats, unsafe.Sizeof(stats))
+	*m = runtime.MemStats{}
+	m.HeapSys = uint64(stats.HeapsizeFull)
+	m.HeapIdle = uint64(stats.FreeBytesFull)
+	m.HeapReleased = uint64(stats.UnmappedBytes)
+	m.HeapInuse = m.HeapSys - m.HeapIdle
+	m.HeapAlloc = m.HeapInuse
+	m.Alloc = m.HeapAlloc
+	m.TotalAlloc = uint64(stats.AllocdBytesBeforeGC + stats.BytesAllocdSinceGC)
+	m.Sys = m.HeapSys
+	// a collection is triggered after allocating about HeapSys/divisor
+	// bytes since the previous one
+	live := m.HeapAlloc
+	if since := uint64(stats.BytesAllocdSinceGC); since < live {
+		live -= since
+	}
+	m.NextGC = live + m.HeapSys/uint64(bdwgc.GetFreeSpaceDivisor())
+	m.LastGC = uint64(atomic.Load(&lastGC))
+	m.NumGC = uint32(stats.GCNo)
+	m.NumForcedGC = atomic.Load(&numForcedGC)
+	m.EnableGC = bdwgc.IsDisabled() == 0
+}
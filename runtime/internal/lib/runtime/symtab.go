@@ -9,6 +9,7 @@ import (
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
 	"github.com/goplus/llgo/runtime/internal/clite/debug"
+	"github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 // Frames may be used to get function/file/line information for a
@@ -17,8 +18,6 @@ type Frames struct {
 	// callers is a slice of PCs that have not yet been expanded to frames.
 	callers []uintptr
 
-	nextPC uintptr
-
 	// frames is a slice of Frames that have yet to be returned.
 	frames     []Frame
 	frameStore [2]Frame
@@ -71,13 +70,6 @@ type Frame struct {
 	funcInfo funcInfo
 }
 
-func safeGoString(s *c.Char, defaultStr string) string {
-	if s == nil {
-		return defaultStr
-	}
-	return c.GoString(s)
-}
-
 func (ci *Frames) Next() (frame Frame, more bool) {
 	for len(ci.frames) < 2 {
 		// Find the next frame.
@@ -86,24 +78,10 @@ func (ci *Frames) Next() (frame Frame, more bool) {
 		if len(ci.callers) == 0 {
 			break
 		}
-		var pc uintptr
-		if ci.nextPC != 0 {
-			pc, ci.nextPC = ci.nextPC, 0
-		} else {
-			pc, ci.callers = ci.callers[0], ci.callers[1:]
-		}
-		info := &debug.Info{}
-		if debug.Addrinfo(unsafe.Pointer(pc), info) == 0 {
-			break
-		}
-		ci.frames = append(ci.frames, Frame{
-			PC:        pc,
-			Function:  safeGoString(info.Fname, "<unknown function>"),
-			File:      safeGoString(info.Sname, "<unknown file>"),
-			Line:      0,
-			startLine: 0,
-			Entry:     uintptr(info.Saddr),
-		})
+		pc := ci.callers[0]
+		ci.callers = ci.callers[1:]
+		// pc is a return address, look up the call before it.
+		ci.frames = appendFrames(ci.frames, pc-1)
 	}
 
 	// Pop one frame from the frame list. Keep the rest.
@@ -135,11 +113,83 @@ func CallersFrames(callers []uintptr) *Frames {
 	return f
 }
 
+// appendFrames appends the frames at pc to frames: those of the functions
+// inlined at pc, innermost first, and that of the function containing pc.
+// Non-Go functions get a frame with their symbol name, if known.
+func appendFrames(frames []Frame, pc uintptr) []Frame {
+	f := runtime.FindFunc(pc)
+	if f == nil {
+		var info debug.Info
+		if debug.Addrinfo(pc, &info) == 0 || info.Sname == nil {
+			return frames
+		}
+		return append(frames, Frame{
+			PC:       pc,
+			Function: c.GoString(info.Sname),
+			Entry:    uintptr(info.Saddr),
+		})
+	}
+	var buf [8]runtime.PCFrame
+	pcfs := runtime.PCFrames(buf[:0], f, pc)
+	for i, pcf := range pcfs {
+		frame := Frame{
+			PC:        pc,
+			Function:  pcf.Func.Name(),
+			File:      pcf.Func.File(),
+			Line:      pcf.Line,
+			startLine: pcf.Func.StartLine(),
+			Entry:     f.Entry(),
+		}
+		if i == len(pcfs)-1 {
+			frame.Func = (*Func)(unsafe.Pointer(f))
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
 // A Func represents a Go function in the running binary.
 type Func struct {
 	opaque struct{} // unexported field to disallow conversions
 }
 
+func (f *Func) raw() *runtime.FuncInfo {
+	return (*runtime.FuncInfo)(unsafe.Pointer(f))
+}
+
+// FuncForPC returns a *Func describing the function that contains the
+// given program counter address, or else nil.
+func FuncForPC(pc uintptr) *Func {
+	f := runtime.FindFunc(pc)
+	if f == nil {
+		return nil
+	}
+	return (*Func)(unsafe.Pointer(f))
+}
+
+// Name returns the name of the function.
+func (f *Func) Name() string {
+	if f == nil {
+		return ""
+	}
+	return f.raw().Name()
+}
+
+// Entry returns the entry address of the function.
+func (f *Func) Entry() uintptr {
+	return f.raw().Entry()
+}
+
+// FileLine returns the file name and line number of the
+// source code corresponding to the program counter pc.
+// The result will not be accurate if pc is not a program
+// counter within f.
+func (f *Func) FileLine(pc uintptr) (file string, line int) {
+	var buf [8]runtime.PCFrame
+	pcfs := runtime.PCFrames(buf[:0], f.raw(), pc)
+	return pcfs[0].Func.File(), pcfs[0].Line
+}
+
 // moduledata records information about the layout of the executable
 // image. It is written by the linker. Any changes here must be
 // matched changes to the code in cmd/link/internal/ld/symtab.go:symtab.
@@ -0,0 +1,22 @@
+//go:build sanitize.thread
+
+package sync
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/runtime/internal/clite/tsan"
+)
+
+// -race builds instrument the code with ThreadSanitizer, which is told the
+// happens-before edges of the locks.
+
+const raceEnabled = true
+
+func raceAcquire(addr unsafe.Pointer) {
+	tsan.Acquire(addr)
+}
+
+func raceRelease(addr unsafe.Pointer) {
+	tsan.Release(addr)
+}
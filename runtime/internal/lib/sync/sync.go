@@ -17,197 +17,291 @@
 package sync
 
 import (
-	"runtime"
 	gosync "sync"
-	_ "unsafe"
+	"unsafe"
 
-	c "github.com/goplus/llgo/runtime/internal/clite"
-	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
 	"github.com/goplus/llgo/runtime/internal/lib/sync/atomic"
+	"github.com/goplus/llgo/runtime/internal/runtime"
 )
 
 // llgo:skipall
 type _sync struct{}
 
-const (
-	uninited     = 0
-	initializing = 1
-	inited       = 2
-)
+// The locks park the goroutines waiting for them on the semaphores of the
+// runtime, instead of blocking the threads of the scheduler. Their zero
+// values are ready to use, and they hold no resources.
 
 // -----------------------------------------------------------------------------
 
+const (
+	mutexUnlocked  = 0
+	mutexLocked    = 1
+	mutexContended = 2 // locked, and goroutines may be waiting
+)
+
 type Mutex struct {
-	sync.Mutex
-	init int32
-}
-
-func (m *Mutex) ensureInit() {
-	for atomic.LoadInt32(&m.init) != inited {
-		if atomic.CompareAndSwapInt32(&m.init, uninited, initializing) {
-			(*sync.Mutex)(&m.Mutex).Init(nil)
-			atomic.StoreInt32(&m.init, inited)
-			runtime.SetFinalizer(m, func(m *Mutex) {
-				m.Mutex.Destroy()
-			})
-		}
-	}
+	state int32
+	sema  uint32
 }
 
 func (m *Mutex) Lock() {
-	m.ensureInit()
-	(*sync.Mutex)(&m.Mutex).Lock()
+	if !atomic.CompareAndSwapInt32(&m.state, mutexUnlocked, mutexLocked) {
+		m.lockSlow()
+	}
+	if raceEnabled {
+		raceAcquire(unsafe.Pointer(m))
+	}
 }
 
-func (m *Mutex) TryLock() bool {
-	m.ensureInit()
-	return (*sync.Mutex)(&m.Mutex).TryLock() == 0
+func (m *Mutex) lockSlow() {
+	// The woken up goroutine locks m as contended, as others may wait.
+	for atomic.SwapInt32(&m.state, mutexContended) != mutexUnlocked {
+		runtime.Semacquire(&m.sema)
+	}
 }
 
-//go:linkname c_pthread_mutex_unlock C.pthread_mutex_unlock
-func c_pthread_mutex_unlock(m *Mutex) c.Int
+func (m *Mutex) TryLock() bool {
+	if !atomic.CompareAndSwapInt32(&m.state, mutexUnlocked, mutexLocked) {
+		return false
+	}
+	if raceEnabled {
+		raceAcquire(unsafe.Pointer(m))
+	}
+	return true
+}
 
 func (m *Mutex) Unlock() {
-	c_pthread_mutex_unlock(m)
+	if raceEnabled {
+		raceRelease(unsafe.Pointer(m))
+	}
+	switch atomic.AddInt32(&m.state, -1) {
+	case mutexUnlocked:
+		return
+	case mutexUnlocked - 1:
+		panic("sync: unlock of unlocked mutex")
+	}
+	atomic.StoreInt32(&m.state, mutexUnlocked)
+	runtime.Semrelease(&m.sema)
 }
 
 // -----------------------------------------------------------------------------
 
+// RWMutex is the one of gc: writers lock w and wait for the active readers
+// to leave, while the new readers wait for the writer.
 type RWMutex struct {
-	sync.RWLock
-	init int32
-}
-
-func (m *RWMutex) ensureInit() {
-	for atomic.LoadInt32(&m.init) != inited {
-		if atomic.CompareAndSwapInt32(&m.init, uninited, initializing) {
-			(*sync.RWLock)(&m.RWLock).Init(nil)
-			atomic.StoreInt32(&m.init, inited)
-			runtime.SetFinalizer(m, func(m *RWMutex) {
-				m.RWLock.Destroy()
-			})
-		}
-	}
+	w           Mutex
+	writerSem   uint32 // semaphore for writers to wait for completing readers
+	readerSem   uint32 // semaphore for readers to wait for completing writers
+	readerCount int32  // number of pending readers, minus rwmutexMaxReaders if a writer is pending
+	readerWait  int32  // number of departing readers
 }
 
+const rwmutexMaxReaders = 1 << 30
+
+// Writers release rw and readers release &rw.readerCount, so that readers
+// are not synchronized with each other under -race.
+
 func (rw *RWMutex) RLock() {
-	rw.ensureInit()
-	(*sync.RWLock)(&rw.RWLock).RLock()
+	if atomic.AddInt32(&rw.readerCount, 1) < 0 {
+		// A writer is pending, wait for it.
+		runtime.Semacquire(&rw.readerSem)
+	}
+	if raceEnabled {
+		raceAcquire(unsafe.Pointer(rw))
+	}
 }
 
 func (rw *RWMutex) TryRLock() bool {
-	rw.ensureInit()
-	return (*sync.RWLock)(&rw.RWLock).TryRLock() == 0
+	for {
+		c := atomic.LoadInt32(&rw.readerCount)
+		if c < 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&rw.readerCount, c, c+1) {
+			if raceEnabled {
+				raceAcquire(unsafe.Pointer(rw))
+			}
+			return true
+		}
+	}
 }
 
-//go:linkname c_pthread_rwlock_unlock C.pthread_rwlock_unlock
-func c_pthread_rwlock_unlock(rw *RWMutex) c.Int
-
 func (rw *RWMutex) RUnlock() {
-	c_pthread_rwlock_unlock(rw)
+	if raceEnabled {
+		raceRelease(unsafe.Pointer(&rw.readerCount))
+	}
+	if r := atomic.AddInt32(&rw.readerCount, -1); r < 0 {
+		if r+1 == 0 || r+1 == -rwmutexMaxReaders {
+			panic("sync: RUnlock of unlocked RWMutex")
+		}
+		// A writer is pending, the last departing reader wakes it up.
+		if atomic.AddInt32(&rw.readerWait, -1) == 0 {
+			runtime.Semrelease(&rw.writerSem)
+		}
+	}
 }
 
 func (rw *RWMutex) Lock() {
-	rw.ensureInit()
-	(*sync.RWLock)(&rw.RWLock).Lock()
+	rw.w.Lock()
+	// Announce to readers there is a pending writer.
+	r := atomic.AddInt32(&rw.readerCount, -rwmutexMaxReaders) + rwmutexMaxReaders
+	// Wait for active readers.
+	if r != 0 && atomic.AddInt32(&rw.readerWait, r) != 0 {
+		runtime.Semacquire(&rw.writerSem)
+	}
+	if raceEnabled {
+		rw.raceLock()
+	}
 }
 
 func (rw *RWMutex) TryLock() bool {
-	rw.ensureInit()
-	return (*sync.RWLock)(&rw.RWLock).TryLock() == 0
+	if !rw.w.TryLock() {
+		return false
+	}
+	if !atomic.CompareAndSwapInt32(&rw.readerCount, 0, -rwmutexMaxReaders) {
+		rw.w.Unlock()
+		return false
+	}
+	if raceEnabled {
+		rw.raceLock()
+	}
+	return true
+}
+
+func (rw *RWMutex) raceLock() {
+	raceAcquire(unsafe.Pointer(rw))
+	raceAcquire(unsafe.Pointer(&rw.readerCount))
 }
 
 func (rw *RWMutex) Unlock() {
-	c_pthread_rwlock_unlock(rw)
+	if raceEnabled {
+		raceRelease(unsafe.Pointer(rw))
+	}
+	// Announce to readers there is no active writer.
+	r := atomic.AddInt32(&rw.readerCount, rwmutexMaxReaders)
+	if r >= rwmutexMaxReaders {
+		panic("sync: Unlock of unlocked RWMutex")
+	}
+	// Unblock blocked readers, if any.
+	for i := 0; i < int(r); i++ {
+		runtime.Semrelease(&rw.readerSem)
+	}
+	rw.w.Unlock()
 }
 
 // -----------------------------------------------------------------------------
 
 type Once struct {
 	m    Mutex
-	done bool
+	done int32
 }
 
 func (o *Once) Do(f func()) {
-	if !o.done {
-		o.m.Lock()
-		if !o.done {
-			o.done = true
-			f()
-		}
-		o.m.Unlock()
+	if atomic.LoadInt32(&o.done) == 0 {
+		o.doSlow(f)
+	}
+}
+
+func (o *Once) doSlow(f func()) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	if o.done == 0 {
+		defer atomic.StoreInt32(&o.done, 1)
+		f()
 	}
 }
 
 // -----------------------------------------------------------------------------
 
+// Cond wakes up its waiters by generations: Signal wakes up one of the old
+// generation, which the new one becomes once it's empty, so that a
+// goroutine isn't woken up by a Signal before its Wait.
 type Cond struct {
-	cond sync.Cond
-	m    *sync.Mutex
+	L gosync.Locker
+
+	m          Mutex // guards the following
+	oldWaiters int
+	oldSema    *uint32
+	newWaiters int
+	newSema    *uint32
 }
 
 func NewCond(l gosync.Locker) *Cond {
-	ret := &Cond{m: l.(*sync.Mutex)}
-	ret.cond.Init(nil)
-	runtime.SetFinalizer(ret, func(ret *Cond) {
-		ret.cond.Destroy()
-	})
-	return ret
+	return &Cond{L: l}
 }
 
-//go:linkname c_pthread_cond_signal C.pthread_cond_signal
-func c_pthread_cond_signal(c *Cond) c.Int
-
-//go:linkname c_pthread_cond_broadcast C.pthread_cond_broadcast
-func c_pthread_cond_broadcast(c *Cond) c.Int
+func (c *Cond) Wait() {
+	c.m.Lock()
+	if c.newSema == nil {
+		c.newSema = new(uint32)
+	}
+	s := c.newSema
+	c.newWaiters++
+	c.m.Unlock()
+	c.L.Unlock()
+	runtime.Semacquire(s)
+	c.L.Lock()
+}
 
 func (c *Cond) Signal() {
-	c_pthread_cond_signal(c)
+	c.m.Lock()
+	if c.oldWaiters == 0 && c.newWaiters > 0 {
+		// Retire the old generation, the new one becomes the old one.
+		c.oldWaiters, c.oldSema = c.newWaiters, c.newSema
+		c.newWaiters, c.newSema = 0, nil
+	}
+	if c.oldWaiters > 0 {
+		c.oldWaiters--
+		runtime.Semrelease(c.oldSema)
+	}
+	c.m.Unlock()
 }
 
 func (c *Cond) Broadcast() {
-	c_pthread_cond_broadcast(c)
-}
-
-func (c *Cond) Wait() {
-	c.cond.Wait(c.m)
+	c.m.Lock()
+	for ; c.oldWaiters > 0; c.oldWaiters-- {
+		runtime.Semrelease(c.oldSema)
+	}
+	for ; c.newWaiters > 0; c.newWaiters-- {
+		runtime.Semrelease(c.newSema)
+	}
+	c.newSema = nil
+	c.m.Unlock()
 }
 
 // -----------------------------------------------------------------------------
 
+// WaitGroup is the one of gc: the high 32 bits of state are the counter,
+// and the low 32 bits the number of waiters.
 type WaitGroup struct {
-	mutex sync.Mutex
-	cond  sync.Cond
-	count int
-	init  int32
-}
-
-func (wg *WaitGroup) ensureInit() {
-	for atomic.LoadInt32(&wg.init) != inited {
-		if atomic.CompareAndSwapInt32(&wg.init, uninited, initializing) {
-			wg.doInit()
-			atomic.StoreInt32(&wg.init, inited)
-		}
-	}
-}
-
-func (wg *WaitGroup) doInit() {
-	wg.mutex.Init(nil)
-	wg.cond.Init(nil)
-	runtime.SetFinalizer(wg, func(wg *WaitGroup) {
-		wg.cond.Destroy()
-		wg.mutex.Destroy()
-	})
+	state atomic.Uint64
+	sema  uint32
 }
 
 func (wg *WaitGroup) Add(delta int) {
-	wg.ensureInit()
-	wg.mutex.Lock()
-	wg.count += delta
-	if wg.count <= 0 {
-		wg.cond.Broadcast()
+	if raceEnabled && delta < 0 {
+		raceRelease(unsafe.Pointer(wg))
+	}
+	state := wg.state.Add(uint64(delta) << 32)
+	v := int32(state >> 32)
+	w := uint32(state)
+	if v < 0 {
+		panic("sync: negative WaitGroup counter")
+	}
+	if w != 0 && delta > 0 && v == int32(delta) {
+		panic("sync: WaitGroup misuse: Add called concurrently with Wait")
+	}
+	if v > 0 || w == 0 {
+		return
+	}
+	// The counter is 0 with waiters: wake them up and reset the state.
+	if wg.state.Load() != state {
+		panic("sync: WaitGroup misuse: Add called concurrently with Wait")
+	}
+	wg.state.Store(0)
+	for ; w != 0; w-- {
+		runtime.Semrelease(&wg.sema)
 	}
-	wg.mutex.Unlock()
 }
 
 func (wg *WaitGroup) Done() {
@@ -215,12 +309,23 @@ func (wg *WaitGroup) Done() {
 }
 
 func (wg *WaitGroup) Wait() {
-	wg.ensureInit()
-	wg.mutex.Lock()
-	for wg.count > 0 {
-		wg.cond.Wait(&wg.mutex)
+	for {
+		state := wg.state.Load()
+		if int32(state>>32) == 0 {
+			break
+		}
+		// Increment the waiters count.
+		if wg.state.CompareAndSwap(state, state+1) {
+			runtime.Semacquire(&wg.sema)
+			if wg.state.Load() != 0 {
+				panic("sync: WaitGroup is reused before previous Wait has returned")
+			}
+			break
+		}
+	}
+	if raceEnabled {
+		raceAcquire(unsafe.Pointer(wg))
 	}
-	wg.mutex.Unlock()
 }
 
 // -----------------------------------------------------------------------------
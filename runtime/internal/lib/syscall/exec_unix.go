@@ -72,20 +72,19 @@ func CloseOnExec(fd int) {
 }
 
 func SetNonblock(fd int, nonblocking bool) (err error) {
-	/* TODO(xsw):
-	flag, err := fcntl(fd, F_GETFL, 0)
-	if err != nil {
-		return err
+	flag := os.Fcntl(c.Int(fd), syscall.F_GETFL)
+	if flag < 0 {
+		return Errno(os.Errno())
 	}
 	if nonblocking {
-		flag |= O_NONBLOCK
+		flag |= syscall.O_NONBLOCK
 	} else {
-		flag &^= O_NONBLOCK
+		flag &^= syscall.O_NONBLOCK
 	}
-	_, err = fcntl(fd, F_SETFL, flag)
-	return err
-	*/
-	panic("todo: syscall.SetNonblock")
+	if os.Fcntl(c.Int(fd), syscall.F_SETFL, flag) < 0 {
+		return Errno(os.Errno())
+	}
+	return nil
 }
 
 // Credential holds user and group identities to be assumed
@@ -0,0 +1,103 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package syscall
+
+import (
+	origSyscall "syscall"
+	"unsafe"
+
+	"github.com/goplus/llgo/runtime/internal/clite/syscall"
+)
+
+func sockaddr(sa origSyscall.Sockaddr) (unsafe.Pointer, _Socklen, error) {
+	switch sa := sa.(type) {
+	case *origSyscall.SockaddrInet4:
+		if sa.Port < 0 || sa.Port > 0xFFFF {
+			return nil, 0, Errno(syscall.EINVAL)
+		}
+		raw := new(syscall.RawSockaddrInet4)
+		raw.Len = syscall.SizeofSockaddrInet4
+		raw.Family = syscall.AF_INET
+		p := (*[2]byte)(unsafe.Pointer(&raw.Port))
+		p[0] = byte(sa.Port >> 8)
+		p[1] = byte(sa.Port)
+		raw.Addr = sa.Addr
+		return unsafe.Pointer(raw), _Socklen(raw.Len), nil
+
+	case *origSyscall.SockaddrInet6:
+		if sa.Port < 0 || sa.Port > 0xFFFF {
+			return nil, 0, Errno(syscall.EINVAL)
+		}
+		raw := new(syscall.RawSockaddrInet6)
+		raw.Len = syscall.SizeofSockaddrInet6
+		raw.Family = syscall.AF_INET6
+		p := (*[2]byte)(unsafe.Pointer(&raw.Port))
+		p[0] = byte(sa.Port >> 8)
+		p[1] = byte(sa.Port)
+		raw.Scope_id = sa.ZoneId
+		raw.Addr = sa.Addr
+		return unsafe.Pointer(raw), _Socklen(raw.Len), nil
+
+	case *origSyscall.SockaddrUnix:
+		name := sa.Name
+		n := len(name)
+		raw := new(syscall.RawSockaddrUnix)
+		if n >= len(raw.Path) || n == 0 {
+			return nil, 0, Errno(syscall.EINVAL)
+		}
+		raw.Len = byte(3 + n) // 2 for Family, Len; 1 for NUL
+		raw.Family = syscall.AF_UNIX
+		for i := 0; i < n; i++ {
+			raw.Path[i] = int8(name[i])
+		}
+		return unsafe.Pointer(raw), _Socklen(raw.Len), nil
+	}
+	return nil, 0, Errno(syscall.EAFNOSUPPORT)
+}
+
+func anyToSockaddr(rsa *syscall.RawSockaddrAny) (origSyscall.Sockaddr, error) {
+	switch rsa.Addr.Family {
+	case syscall.AF_UNIX:
+		pp := (*syscall.RawSockaddrUnix)(unsafe.Pointer(rsa))
+		if pp.Len < 2 || pp.Len > syscall.SizeofSockaddrUnix {
+			return nil, Errno(syscall.EINVAL)
+		}
+		sa := new(origSyscall.SockaddrUnix)
+
+		// Some BSDs include the trailing NUL in the length, whereas
+		// others do not. Work around this by subtracting the leading
+		// family and len. The path is then scanned to see if a NUL
+		// terminator still exists within the length.
+		n := int(pp.Len) - 2 // subtract leading Family, Len
+		for i := 0; i < n; i++ {
+			if pp.Path[i] == 0 {
+				// found early NUL; assume Len included the NUL
+				// or was overestimating.
+				n = i
+				break
+			}
+		}
+		sa.Name = string(unsafe.Slice((*byte)(unsafe.Pointer(&pp.Path[0])), n))
+		return sa, nil
+
+	case syscall.AF_INET:
+		pp := (*syscall.RawSockaddrInet4)(unsafe.Pointer(rsa))
+		sa := new(origSyscall.SockaddrInet4)
+		p := (*[2]byte)(unsafe.Pointer(&pp.Port))
+		sa.Port = int(p[0])<<8 + int(p[1])
+		sa.Addr = pp.Addr
+		return sa, nil
+
+	case syscall.AF_INET6:
+		pp := (*syscall.RawSockaddrInet6)(unsafe.Pointer(rsa))
+		sa := new(origSyscall.SockaddrInet6)
+		p := (*[2]byte)(unsafe.Pointer(&pp.Port))
+		sa.Port = int(p[0])<<8 + int(p[1])
+		sa.ZoneId = pp.Scope_id
+		sa.Addr = pp.Addr
+		return sa, nil
+	}
+	return nil, Errno(syscall.EAFNOSUPPORT)
+}
@@ -0,0 +1,119 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package syscall
+
+import (
+	origSyscall "syscall"
+	"unsafe"
+
+	"github.com/goplus/llgo/runtime/internal/clite/syscall"
+)
+
+func sockaddr(sa origSyscall.Sockaddr) (unsafe.Pointer, _Socklen, error) {
+	switch sa := sa.(type) {
+	case *origSyscall.SockaddrInet4:
+		if sa.Port < 0 || sa.Port > 0xFFFF {
+			return nil, 0, Errno(syscall.EINVAL)
+		}
+		raw := new(syscall.RawSockaddrInet4)
+		raw.Family = syscall.AF_INET
+		p := (*[2]byte)(unsafe.Pointer(&raw.Port))
+		p[0] = byte(sa.Port >> 8)
+		p[1] = byte(sa.Port)
+		raw.Addr = sa.Addr
+		return unsafe.Pointer(raw), syscall.SizeofSockaddrInet4, nil
+
+	case *origSyscall.SockaddrInet6:
+		if sa.Port < 0 || sa.Port > 0xFFFF {
+			return nil, 0, Errno(syscall.EINVAL)
+		}
+		raw := new(syscall.RawSockaddrInet6)
+		raw.Family = syscall.AF_INET6
+		p := (*[2]byte)(unsafe.Pointer(&raw.Port))
+		p[0] = byte(sa.Port >> 8)
+		p[1] = byte(sa.Port)
+		raw.Scope_id = sa.ZoneId
+		raw.Addr = sa.Addr
+		return unsafe.Pointer(raw), syscall.SizeofSockaddrInet6, nil
+
+	case *origSyscall.SockaddrUnix:
+		name := sa.Name
+		n := len(name)
+		raw := new(syscall.RawSockaddrUnix)
+		if n > len(raw.Path) {
+			return nil, 0, Errno(syscall.EINVAL)
+		}
+		if n == len(raw.Path) && name[0] != '@' {
+			return nil, 0, Errno(syscall.EINVAL)
+		}
+		raw.Family = syscall.AF_UNIX
+		for i := 0; i < n; i++ {
+			raw.Path[i] = int8(name[i])
+		}
+		// length is family (uint16), name, NUL.
+		sl := _Socklen(2)
+		if n > 0 {
+			sl += _Socklen(n) + 1
+		}
+		if raw.Path[0] == '@' || (raw.Path[0] == 0 && sl > 3) {
+			// Check sl > 3 so we don't change unnamed socket behavior.
+			raw.Path[0] = 0
+			// Don't count trailing NUL for abstract address.
+			sl--
+		}
+		return unsafe.Pointer(raw), sl, nil
+	}
+	return nil, 0, Errno(syscall.EAFNOSUPPORT)
+}
+
+func anyToSockaddr(rsa *syscall.RawSockaddrAny) (origSyscall.Sockaddr, error) {
+	switch rsa.Addr.Family {
+	case syscall.AF_UNIX:
+		pp := (*syscall.RawSockaddrUnix)(unsafe.Pointer(rsa))
+		sa := new(origSyscall.SockaddrUnix)
+		if pp.Path[0] == 0 {
+			// "Abstract" Unix domain socket.
+			// Rewrite leading NUL as @ for textual display.
+			// (This is the standard convention.)
+			// Not friendly to overwrite in place,
+			// but the callers below don't care.
+			pp.Path[0] = '@'
+		}
+
+		// Assume path ends at NUL.
+		// This is not technically the Linux semantics for
+		// abstract Unix domain sockets--they are supposed
+		// to be uninterpreted fixed-size binary blobs--but
+		// everyone uses this convention.
+		n := 0
+		for n < len(pp.Path) && pp.Path[n] != 0 {
+			n++
+		}
+		sa.Name = string(unsafe.Slice((*byte)(unsafe.Pointer(&pp.Path[0])), n))
+		return sa, nil
+
+	case syscall.AF_INET:
+		pp := (*syscall.RawSockaddrInet4)(unsafe.Pointer(rsa))
+		sa := new(origSyscall.SockaddrInet4)
+		p := (*[2]byte)(unsafe.Pointer(&pp.Port))
+		sa.Port = int(p[0])<<8 + int(p[1])
+		sa.Addr = pp.Addr
+		return sa, nil
+
+	case syscall.AF_INET6:
+		pp := (*syscall.RawSockaddrInet6)(unsafe.Pointer(rsa))
+		sa := new(origSyscall.SockaddrInet6)
+		p := (*[2]byte)(unsafe.Pointer(&pp.Port))
+		sa.Port = int(p[0])<<8 + int(p[1])
+		sa.ZoneId = pp.Scope_id
+		sa.Addr = pp.Addr
+		return sa, nil
+	}
+	return nil, Errno(syscall.EAFNOSUPPORT)
+}
+
+func SetsockoptIPMreqn(fd, level, opt int, mreq *origSyscall.IPMreqn) (err error) {
+	return setsockopt(fd, level, opt, unsafe.Pointer(mreq), unsafe.Sizeof(*mreq))
+}
@@ -0,0 +1,358 @@
+//go:build darwin || linux
+
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syscall
+
+import (
+	origSyscall "syscall"
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/os"
+	"github.com/goplus/llgo/runtime/internal/clite/syscall"
+)
+
+type _Socklen uint32
+
+// The sockaddr types of package syscall implement Sockaddr by their sockaddr
+// methods. These types give them the methods, since package syscall is
+// replaced as a whole.
+
+type SockaddrInet4 origSyscall.SockaddrInet4
+
+func (sa *SockaddrInet4) sockaddr() (unsafe.Pointer, _Socklen, error) {
+	return sockaddr((*origSyscall.SockaddrInet4)(sa))
+}
+
+type SockaddrInet6 origSyscall.SockaddrInet6
+
+func (sa *SockaddrInet6) sockaddr() (unsafe.Pointer, _Socklen, error) {
+	return sockaddr((*origSyscall.SockaddrInet6)(sa))
+}
+
+type SockaddrUnix origSyscall.SockaddrUnix
+
+func (sa *SockaddrUnix) sockaddr() (unsafe.Pointer, _Socklen, error) {
+	return sockaddr((*origSyscall.SockaddrUnix)(sa))
+}
+
+// -----------------------------------------------------------------------------
+
+//go:linkname c_socket C.socket
+func c_socket(domain, typ, proto c.Int) c.Int
+
+//go:linkname c_socketpair C.socketpair
+func c_socketpair(domain, typ, proto c.Int, fds *[2]c.Int) c.Int
+
+//go:linkname c_bind C.bind
+func c_bind(fd c.Int, addr unsafe.Pointer, addrlen _Socklen) c.Int
+
+//go:linkname c_connect C.connect
+func c_connect(fd c.Int, addr unsafe.Pointer, addrlen _Socklen) c.Int
+
+//go:linkname c_listen C.listen
+func c_listen(fd c.Int, backlog c.Int) c.Int
+
+//go:linkname c_accept C.accept
+func c_accept(fd c.Int, addr unsafe.Pointer, addrlen *_Socklen) c.Int
+
+//go:linkname c_getsockname C.getsockname
+func c_getsockname(fd c.Int, addr unsafe.Pointer, addrlen *_Socklen) c.Int
+
+//go:linkname c_getpeername C.getpeername
+func c_getpeername(fd c.Int, addr unsafe.Pointer, addrlen *_Socklen) c.Int
+
+//go:linkname c_getsockopt C.getsockopt
+func c_getsockopt(fd, level, opt c.Int, val unsafe.Pointer, vallen *_Socklen) c.Int
+
+//go:linkname c_setsockopt C.setsockopt
+func c_setsockopt(fd, level, opt c.Int, val unsafe.Pointer, vallen _Socklen) c.Int
+
+//go:linkname c_shutdown C.shutdown
+func c_shutdown(fd, how c.Int) c.Int
+
+//go:linkname c_recvfrom C.recvfrom
+func c_recvfrom(fd c.Int, buf unsafe.Pointer, n uintptr, flags c.Int, from unsafe.Pointer, fromlen *_Socklen) int
+
+//go:linkname c_sendto C.sendto
+func c_sendto(fd c.Int, buf unsafe.Pointer, n uintptr, flags c.Int, to unsafe.Pointer, tolen _Socklen) int
+
+//go:linkname c_recvmsg C.recvmsg
+func c_recvmsg(fd c.Int, msg *syscall.Msghdr, flags c.Int) int
+
+//go:linkname c_sendmsg C.sendmsg
+func c_sendmsg(fd c.Int, msg *syscall.Msghdr, flags c.Int) int
+
+// -----------------------------------------------------------------------------
+
+func Socket(domain, typ, proto int) (fd int, err error) {
+	ret := c_socket(c.Int(domain), c.Int(typ), c.Int(proto))
+	if ret >= 0 {
+		return int(ret), nil
+	}
+	return -1, Errno(os.Errno())
+}
+
+func Socketpair(domain, typ, proto int) (fd [2]int, err error) {
+	var fds [2]c.Int
+	if c_socketpair(c.Int(domain), c.Int(typ), c.Int(proto), &fds) != 0 {
+		return fd, Errno(os.Errno())
+	}
+	return [2]int{int(fds[0]), int(fds[1])}, nil
+}
+
+func Bind(fd int, sa origSyscall.Sockaddr) error {
+	ptr, n, err := sockaddr(sa)
+	if err != nil {
+		return err
+	}
+	if c_bind(c.Int(fd), ptr, n) != 0 {
+		return Errno(os.Errno())
+	}
+	return nil
+}
+
+func Connect(fd int, sa origSyscall.Sockaddr) error {
+	ptr, n, err := sockaddr(sa)
+	if err != nil {
+		return err
+	}
+	if c_connect(c.Int(fd), ptr, n) != 0 {
+		return Errno(os.Errno())
+	}
+	return nil
+}
+
+func Listen(fd int, backlog int) error {
+	if c_listen(c.Int(fd), c.Int(backlog)) != 0 {
+		return Errno(os.Errno())
+	}
+	return nil
+}
+
+func Accept(fd int) (nfd int, sa origSyscall.Sockaddr, err error) {
+	var rsa syscall.RawSockaddrAny
+	var n _Socklen = syscall.SizeofSockaddrAny
+	ret := c_accept(c.Int(fd), unsafe.Pointer(&rsa), &n)
+	if ret < 0 {
+		return -1, nil, Errno(os.Errno())
+	}
+	nfd = int(ret)
+	if sa, err = anyToSockaddr(&rsa); err != nil {
+		Close(nfd)
+		nfd = 0
+	}
+	return
+}
+
+func Getsockname(fd int) (sa origSyscall.Sockaddr, err error) {
+	var rsa syscall.RawSockaddrAny
+	var n _Socklen = syscall.SizeofSockaddrAny
+	if c_getsockname(c.Int(fd), unsafe.Pointer(&rsa), &n) != 0 {
+		return nil, Errno(os.Errno())
+	}
+	return anyToSockaddr(&rsa)
+}
+
+func Getpeername(fd int) (sa origSyscall.Sockaddr, err error) {
+	var rsa syscall.RawSockaddrAny
+	var n _Socklen = syscall.SizeofSockaddrAny
+	if c_getpeername(c.Int(fd), unsafe.Pointer(&rsa), &n) != 0 {
+		return nil, Errno(os.Errno())
+	}
+	return anyToSockaddr(&rsa)
+}
+
+func Shutdown(fd, how int) error {
+	if c_shutdown(c.Int(fd), c.Int(how)) != 0 {
+		return Errno(os.Errno())
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func getsockopt(fd, level, opt int, val unsafe.Pointer, vallen *_Socklen) error {
+	if c_getsockopt(c.Int(fd), c.Int(level), c.Int(opt), val, vallen) != 0 {
+		return Errno(os.Errno())
+	}
+	return nil
+}
+
+func setsockopt(fd, level, opt int, val unsafe.Pointer, vallen uintptr) error {
+	if c_setsockopt(c.Int(fd), c.Int(level), c.Int(opt), val, _Socklen(vallen)) != 0 {
+		return Errno(os.Errno())
+	}
+	return nil
+}
+
+func GetsockoptInt(fd, level, opt int) (value int, err error) {
+	var n int32
+	vallen := _Socklen(4)
+	err = getsockopt(fd, level, opt, unsafe.Pointer(&n), &vallen)
+	return int(n), err
+}
+
+func SetsockoptByte(fd, level, opt int, value byte) (err error) {
+	return setsockopt(fd, level, opt, unsafe.Pointer(&value), 1)
+}
+
+func SetsockoptInt(fd, level, opt int, value int) (err error) {
+	n := int32(value)
+	return setsockopt(fd, level, opt, unsafe.Pointer(&n), 4)
+}
+
+func SetsockoptInet4Addr(fd, level, opt int, value [4]byte) (err error) {
+	return setsockopt(fd, level, opt, unsafe.Pointer(&value[0]), 4)
+}
+
+func SetsockoptIPMreq(fd, level, opt int, mreq *origSyscall.IPMreq) (err error) {
+	return setsockopt(fd, level, opt, unsafe.Pointer(mreq), unsafe.Sizeof(*mreq))
+}
+
+func SetsockoptIPv6Mreq(fd, level, opt int, mreq *origSyscall.IPv6Mreq) (err error) {
+	return setsockopt(fd, level, opt, unsafe.Pointer(mreq), unsafe.Sizeof(*mreq))
+}
+
+func SetsockoptLinger(fd, level, opt int, l *origSyscall.Linger) (err error) {
+	return setsockopt(fd, level, opt, unsafe.Pointer(l), unsafe.Sizeof(*l))
+}
+
+func SetsockoptTimeval(fd, level, opt int, tv *Timeval) (err error) {
+	return setsockopt(fd, level, opt, unsafe.Pointer(tv), unsafe.Sizeof(*tv))
+}
+
+// -----------------------------------------------------------------------------
+
+func Write(fd int, p []byte) (n int, err error) {
+	ret := os.Write(c.Int(fd), unsafe.Pointer(unsafe.SliceData(p)), uintptr(len(p)))
+	if ret >= 0 {
+		return ret, nil
+	}
+	return 0, Errno(os.Errno())
+}
+
+func Recvfrom(fd int, p []byte, flags int) (n int, from origSyscall.Sockaddr, err error) {
+	var rsa syscall.RawSockaddrAny
+	var salen _Socklen = syscall.SizeofSockaddrAny
+	ret := c_recvfrom(c.Int(fd), unsafe.Pointer(unsafe.SliceData(p)), uintptr(len(p)), c.Int(flags), unsafe.Pointer(&rsa), &salen)
+	if ret < 0 {
+		return 0, nil, Errno(os.Errno())
+	}
+	if rsa.Addr.Family != syscall.AF_UNSPEC {
+		from, err = anyToSockaddr(&rsa)
+	}
+	return ret, from, err
+}
+
+func Sendto(fd int, p []byte, flags int, to origSyscall.Sockaddr) (err error) {
+	var ptr unsafe.Pointer
+	var n _Socklen
+	if to != nil {
+		if ptr, n, err = sockaddr(to); err != nil {
+			return
+		}
+	}
+	if c_sendto(c.Int(fd), unsafe.Pointer(unsafe.SliceData(p)), uintptr(len(p)), c.Int(flags), ptr, n) < 0 {
+		return Errno(os.Errno())
+	}
+	return nil
+}
+
+// setLen sets a length field of a C struct, whose type depends on the platform.
+func setLen[T ~int32 | ~uint32 | ~uint64](p *T, n int) {
+	*p = T(n)
+}
+
+func Recvmsg(fd int, p, oob []byte, flags int) (n, oobn int, recvflags int, from origSyscall.Sockaddr, err error) {
+	var msg syscall.Msghdr
+	var rsa syscall.RawSockaddrAny
+	msg.Name = (*byte)(unsafe.Pointer(&rsa))
+	msg.Namelen = syscall.SizeofSockaddrAny
+	var iov syscall.Iovec
+	if len(p) > 0 {
+		iov.Base = &p[0]
+		setLen(&iov.Len, len(p))
+	}
+	var dummy byte
+	if len(oob) > 0 {
+		// receive at least one normal byte
+		if len(p) == 0 {
+			iov.Base = &dummy
+			setLen(&iov.Len, 1)
+		}
+		msg.Control = &oob[0]
+		setLen(&msg.Controllen, len(oob))
+	}
+	msg.Iov = &iov
+	msg.Iovlen = 1
+	ret := c_recvmsg(c.Int(fd), &msg, c.Int(flags))
+	if ret < 0 {
+		err = Errno(os.Errno())
+		return
+	}
+	n = ret
+	oobn = int(msg.Controllen)
+	recvflags = int(msg.Flags)
+	if rsa.Addr.Family != syscall.AF_UNSPEC {
+		from, err = anyToSockaddr(&rsa)
+	}
+	return
+}
+
+func Sendmsg(fd int, p, oob []byte, to origSyscall.Sockaddr, flags int) (err error) {
+	_, err = SendmsgN(fd, p, oob, to, flags)
+	return
+}
+
+func SendmsgN(fd int, p, oob []byte, to origSyscall.Sockaddr, flags int) (n int, err error) {
+	var msg syscall.Msghdr
+	if to != nil {
+		ptr, salen, err := sockaddr(to)
+		if err != nil {
+			return 0, err
+		}
+		msg.Name = (*byte)(ptr)
+		msg.Namelen = uint32(salen)
+	}
+	var iov syscall.Iovec
+	if len(p) > 0 {
+		iov.Base = &p[0]
+		setLen(&iov.Len, len(p))
+	}
+	var dummy byte
+	if len(oob) > 0 {
+		// send at least one normal byte
+		if len(p) == 0 {
+			iov.Base = &dummy
+			setLen(&iov.Len, 1)
+		}
+		msg.Control = &oob[0]
+		setLen(&msg.Controllen, len(oob))
+	}
+	msg.Iov = &iov
+	msg.Iovlen = 1
+	ret := c_sendmsg(c.Int(fd), &msg, c.Int(flags))
+	if ret < 0 {
+		return 0, Errno(os.Errno())
+	}
+	if len(oob) > 0 && len(p) == 0 {
+		ret = 0
+	}
+	return ret, nil
+}
@@ -17,7 +17,6 @@
 package syscall
 
 import (
-	origSyscall "syscall"
 	"unsafe"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
@@ -158,10 +157,6 @@ func ByteSliceFromString(s string) ([]byte, error) {
 	return a, nil
 }
 
-func Accept(fd int) (nfd int, sa origSyscall.Sockaddr, err error) {
-	panic("todo: syscall.Accept")
-}
-
 func Kill(pid int, signum Signal) error {
 	return syscall.Kill(pid, syscall.Signal(signum))
 }
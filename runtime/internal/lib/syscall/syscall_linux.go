@@ -13,7 +13,7 @@ package syscall
 
 import (
 	origSyscall "syscall"
-	_ "unsafe"
+	"unsafe"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
 	"github.com/goplus/llgo/runtime/internal/clite/os"
@@ -128,8 +128,25 @@ func faccessat(dirfd c.Int, path *c.Char, mode c.Int, flags c.Int) c.Int
 
 // -----------------------------------------------------------------------------
 
+//go:linkname c_accept4 C.accept4
+func c_accept4(fd c.Int, addr unsafe.Pointer, addrlen *_Socklen, flags c.Int) c.Int
+
 func Accept4(fd int, flags int) (nfd int, sa origSyscall.Sockaddr, err error) {
-	panic("todo: syscall.Accept4")
+	var rsa syscall.RawSockaddrAny
+	var n _Socklen = syscall.SizeofSockaddrAny
+	ret := c_accept4(c.Int(fd), unsafe.Pointer(&rsa), &n, c.Int(flags))
+	if ret < 0 {
+		return -1, nil, Errno(os.Errno())
+	}
+	nfd = int(ret)
+	if n > syscall.SizeofSockaddrAny {
+		panic("RawSockaddrAny too small")
+	}
+	if sa, err = anyToSockaddr(&rsa); err != nil {
+		Close(nfd)
+		nfd = 0
+	}
+	return
 }
 
 func Uname(buf *origSyscall.Utsname) (err error) {
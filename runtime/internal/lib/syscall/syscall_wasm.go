@@ -8,6 +8,7 @@ package syscall
 
 import (
 	"strconv"
+	origSyscall "syscall"
 
 	"github.com/goplus/llgo/runtime/internal/clite/syscall"
 )
@@ -364,3 +365,7 @@ func StartProcess(argv0 string, argv []string, attr *ProcAttr) (pid int, handle
 func Wait4(pid int, wstatus *WaitStatus, options int, rusage *Rusage) (wpid int, err error) {
 	panic("not implemented")
 }
+
+func Accept(fd int) (nfd int, sa origSyscall.Sockaddr, err error) {
+	return 0, nil, Errno(syscall.ENOSYS)
+}
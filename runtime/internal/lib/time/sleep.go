@@ -8,21 +8,31 @@ import (
 	"sync"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	psync "github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+	"github.com/goplus/llgo/runtime/internal/clite/time"
 )
 
 // Sleep pauses the current goroutine for at least the duration d.
 // A negative or zero duration causes Sleep to return immediately.
 func Sleep(d Duration) {
-	c.Usleep(c.Uint(d.Microseconds()))
+	if d <= 0 {
+		return
+	}
+	// the goroutine parks on the channel instead of blocking its worker
+	t := NewTimer(d)
+	<-t.C
 }
 
 // Interface to timers implemented in package runtime.
 // Must be in sync with ../runtime/time.go:/^type timer
 type runtimeTimer struct {
-	// libuv.Timer
-	when int64
-	f    func(any, uintptr)
-	arg  any
+	when   int64
+	period int64
+	f      func(any, uintptr)
+	arg    any
+	seq    uintptr
+	i      int // 1-based index in timerHeap, 0 if the timer isn't active
 }
 
 // when is a helper function for setting the 'when' field of a runtimeTimer.
@@ -42,12 +52,6 @@ func when(d Duration) int64 {
 	return t
 }
 
-/* TODO(xsw):
-func modTimer(t *runtimeTimer, when, period int64, f func(any, uintptr), arg any, seq uintptr) {
-	panic("todo: time.modTimer")
-}
-*/
-
 // The Timer type represents a single event.
 // When the Timer expires, the current time will be sent on C,
 // unless the Timer was created by AfterFunc.
@@ -175,66 +179,169 @@ func goFunc(arg any, seq uintptr) {
 	go arg.(func())()
 }
 
+// -----------------------------------------------------------------------------
+
+// Timers are kept in a min-heap ordered by when, and fired by a dedicated
+// timer thread which sleeps on a condition variable until the earliest
+// timer is due or the heap is changed. It's not a goroutine, so that it
+// doesn't hold a worker of the scheduler while sleeping.
 var (
-	// timerLoop *libuv.Loop
+	timerMu   psync.Mutex
+	timerCond psync.Cond
+	timerHeap []*runtimeTimer
 	timerOnce sync.Once
 )
 
-// func init() {
-// 	timerOnce.Do(func() {
-// 		timerLoop = libuv.LoopNew()
-// 	})
-// 	go func() {
-// 		timerLoop.Run(libuv.RUN_DEFAULT)
-// 	}()
-// }
-
-// cross thread
-// func timerEvent(async *libuv.Async) {
-// 	a := (*asyncTimerEvent)(unsafe.Pointer(async))
-// 	a.cb()
-// 	a.Close(nil)
-// }
-
-type asyncTimerEvent struct {
-	// libuv.Async
-	cb func()
-}
-
-// func timerCallback(t *libuv.Timer) {
-// }
-
-func startTimer(r *runtimeTimer) {
-	panic("not implemented")
-	// asyncTimer := &asyncTimerEvent{
-	// 	cb: func() {
-	// 		libuv.InitTimer(timerLoop, &r.Timer)
-	// 		r.Start(timerCallback, uint64(r.when), 0)
-	// 	},
-	// }
-	// timerLoop.Async(&asyncTimer.Async, timerEvent)
-	// asyncTimer.Send()
-}
-
-func stopTimer(r *runtimeTimer) bool {
-	panic("not implemented")
-	// asyncTimer := &asyncTimerEvent{
-	// 	cb: func() {
-	// 		r.Stop()
-	// 	},
-	// }
-	// timerLoop.Async(&asyncTimer.Async, timerEvent)
-	// return asyncTimer.Send() == 0
-}
-
-func resetTimer(r *runtimeTimer, when int64) bool {
-	panic("not implemented")
-	// asyncTimer := &asyncTimerEvent{
-	// 	cb: func() {
-	// 		r.Stop()
-	// 		r.Start(timerCallback, uint64(when), 0)
-	// 	},
-	// }
-	// timerLoop.Async(&asyncTimer.Async, timerEvent)
-	// return asyncTimer.Send() == 0
+func lockTimers() {
+	timerOnce.Do(func() {
+		timerMu.Init(nil)
+		timerCond.Init(nil)
+		var th pthread.Thread
+		pthread.Create(&th, nil, timerproc, nil)
+	})
+	timerMu.Lock()
+}
+
+func timerproc(arg c.Pointer) c.Pointer {
+	timerMu.Lock()
+	for {
+		if len(timerHeap) == 0 {
+			timerCond.Wait(&timerMu)
+			continue
+		}
+		t := timerHeap[0]
+		delta := t.when - runtimeNano()
+		if delta > 0 {
+			timerCond.TimedWait(&timerMu, deadline(delta))
+			continue
+		}
+		if t.period > 0 {
+			// skip ticks missed while the timer was late
+			t.when += t.period * (1 + -delta/t.period)
+			if t.when < 0 {
+				t.when = 1<<63 - 1
+			}
+			siftdownTimer(0)
+		} else {
+			delTimer(t)
+		}
+		f, arg, seq := t.f, t.arg, t.seq
+		timerMu.Unlock()
+		f(arg, seq)
+		timerMu.Lock()
+	}
+}
+
+// deadline returns the absolute CLOCK_REALTIME time, as expected by
+// pthread_cond_timedwait, delta nanoseconds from now.
+func deadline(delta int64) *time.Timespec {
+	ts := new(time.Timespec)
+	time.ClockGettime(time.CLOCK_REALTIME, ts)
+	nsec := int64(ts.Nsec) + delta%1e9
+	ts.Sec += time.TimeT(delta/1e9 + nsec/1e9)
+	ts.Nsec = c.Long(nsec % 1e9)
+	return ts
+}
+
+// addTimer adds t to the heap. It's called with timerMu held.
+func addTimer(t *runtimeTimer) {
+	t.i = len(timerHeap) + 1
+	timerHeap = append(timerHeap, t)
+	siftupTimer(t.i - 1)
+	if timerHeap[0] == t {
+		timerCond.Signal()
+	}
+}
+
+// delTimer removes t from the heap and reports whether it was there. It's
+// called with timerMu held.
+func delTimer(t *runtimeTimer) bool {
+	if t.i == 0 {
+		return false
+	}
+	i, last := t.i-1, len(timerHeap)-1
+	if i != last {
+		timerHeap[i] = timerHeap[last]
+		timerHeap[i].i = i + 1
+	}
+	timerHeap[last] = nil
+	timerHeap = timerHeap[:last]
+	if i != last {
+		siftupTimer(i)
+		siftdownTimer(i)
+	}
+	t.i = 0
+	return true
+}
+
+func siftupTimer(i int) {
+	t := timerHeap[i]
+	for i > 0 {
+		p := (i - 1) / 2
+		if t.when >= timerHeap[p].when {
+			break
+		}
+		timerHeap[i] = timerHeap[p]
+		timerHeap[i].i = i + 1
+		i = p
+	}
+	timerHeap[i] = t
+	t.i = i + 1
+}
+
+func siftdownTimer(i int) {
+	n := len(timerHeap)
+	t := timerHeap[i]
+	for {
+		c := 2*i + 1
+		if c >= n {
+			break
+		}
+		if c+1 < n && timerHeap[c+1].when < timerHeap[c].when {
+			c++
+		}
+		if t.when <= timerHeap[c].when {
+			break
+		}
+		timerHeap[i] = timerHeap[c]
+		timerHeap[i].i = i + 1
+		i = c
+	}
+	timerHeap[i] = t
+	t.i = i + 1
+}
+
+func startTimer(t *runtimeTimer) {
+	lockTimers()
+	delTimer(t)
+	addTimer(t)
+	timerMu.Unlock()
+}
+
+func stopTimer(t *runtimeTimer) bool {
+	lockTimers()
+	active := delTimer(t)
+	timerMu.Unlock()
+	return active
+}
+
+func resetTimer(t *runtimeTimer, when int64) bool {
+	lockTimers()
+	active := delTimer(t)
+	t.when = when
+	addTimer(t)
+	timerMu.Unlock()
+	return active
+}
+
+func modTimer(t *runtimeTimer, when, period int64, f func(any, uintptr), arg any, seq uintptr) {
+	lockTimers()
+	delTimer(t)
+	t.when = when
+	t.period = period
+	t.f = f
+	t.arg = arg
+	t.seq = seq
+	addTimer(t)
+	timerMu.Unlock()
 }
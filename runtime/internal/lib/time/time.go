@@ -17,6 +17,7 @@
 package time
 
 import (
+	"errors"
 	"unsafe"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
@@ -191,6 +192,22 @@ func (t Time) UnixNano() int64 {
 	return (t.unixSec())*1e9 + int64(t.nsec())
 }
 
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// The time must be a quoted string in the RFC 3339 format.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	// TODO(https://go.dev/issue/47353): Properly unescape a JSON string.
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return errors.New("Time.UnmarshalJSON: input is not a JSON string")
+	}
+	data = data[len(`"`) : len(data)-len(`"`)]
+	var err error
+	*t, err = parseStrictRFC3339(data)
+	return err
+}
+
 // Equal reports whether t and u represent the same time instant.
 // Two times can be equal even if they are in different locations.
 // For example, 6:00 +0200 and 4:00 UTC are Equal.
@@ -412,7 +429,7 @@ func now() (sec int64, nsec int32, mono int64) {
 func runtimeNano() int64 {
 	tv := (*time.Timespec)(c.Alloca(unsafe.Sizeof(time.Timespec{})))
 	time.ClockGettime(time.CLOCK_MONOTONIC, tv)
-	return int64(tv.Sec)<<nsecShift | int64(tv.Nsec)
+	return int64(tv.Sec)*1e9 + int64(tv.Nsec)
 }
 
 // Monotonic times are reported as offsets from startNano.
@@ -449,6 +466,28 @@ func (t Time) Local() Time {
 	return t
 }
 
+// In returns a copy of t representing the same time instant, but
+// with the copy's location information set to loc for display
+// purposes.
+//
+// In panics if loc is nil.
+func (t Time) In(loc *Location) Time {
+	if loc == nil {
+		panic("time: missing Location in call to Time.In")
+	}
+	t.setLoc(loc)
+	return t
+}
+
+// Location returns the time zone information associated with t.
+func (t Time) Location() *Location {
+	l := t.loc
+	if l == nil {
+		l = UTC
+	}
+	return l
+}
+
 const (
 	// The unsigned zero year for internal calculations.
 	// Must be 1 mod 400, and times before it will not compute correctly,
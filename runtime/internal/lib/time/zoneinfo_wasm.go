@@ -4,9 +4,25 @@
 
 package time
 
+import (
+	"syscall"
+)
+
 // in wasip1 zoneinfo is managed by the runtime.
 var platformZoneSources = []string{}
 
+// initLocal uses $TZ if the time zone database is embedded, by importing
+// time/tzdata or building with the timetzdata tag, and UTC otherwise.
 func initLocal() {
+	tz, _ := syscall.Getenv("TZ")
+	if tz != "" && tz[0] == ':' {
+		tz = tz[1:]
+	}
+	if tz != "" && tz != "UTC" {
+		if z, err := loadLocation(tz, platformZoneSources); err == nil {
+			localLoc = *z
+			return
+		}
+	}
 	localLoc.name = "Local"
 }
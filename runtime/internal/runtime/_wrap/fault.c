@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Handler of SIGSEGV and SIGBUS, which calls sigpanic (see z_traceback.go)
+// with the faulting address and instruction.
+
+#if defined(__wasm__) || defined(_WIN32)
+
+void llgo_fault_init(void (*fn)(int sig, int code, void *addr, void *pc)) {}
+
+void *llgo_fault_altstack(unsigned long size) {
+    return 0;
+}
+
+#else
+
+#if defined(__linux__) && !defined(_GNU_SOURCE)
+#define _GNU_SOURCE // REG_RIP and friends
+#endif
+
+#include <pthread.h>
+#include <signal.h>
+#include <stddef.h>
+#include <stdlib.h>
+#include <string.h>
+#include <ucontext.h>
+
+static void (*fault_fn)(int sig, int code, void *addr, void *pc);
+
+static void *fault_pc(void *uctx) {
+    ucontext_t *uc = (ucontext_t *)uctx;
+#if defined(__APPLE__) && defined(__aarch64__)
+    return (void *)uc->uc_mcontext->__ss.__pc;
+#elif defined(__APPLE__) && defined(__x86_64__)
+    return (void *)uc->uc_mcontext->__ss.__rip;
+#elif defined(__linux__) && defined(__x86_64__)
+    return (void *)uc->uc_mcontext.gregs[REG_RIP];
+#elif defined(__linux__) && defined(__i386__)
+    return (void *)uc->uc_mcontext.gregs[REG_EIP];
+#elif defined(__linux__) && defined(__aarch64__)
+    return (void *)uc->uc_mcontext.pc;
+#elif defined(__linux__) && defined(__arm__)
+    return (void *)uc->uc_mcontext.arm_pc;
+#elif defined(__linux__) && defined(__riscv)
+    return (void *)uc->uc_mcontext.__gregs[REG_PC];
+#else
+    (void)uc;
+    return NULL;
+#endif
+}
+
+static void fault_handler(int sig, siginfo_t *info, void *uctx) {
+    fault_fn(sig, info->si_code, info->si_addr, fault_pc(uctx));
+}
+
+// llgo_fault_init installs the handler unless the signals are handled
+// already, by the program embedding a c-archive for example. SA_NODEFER
+// keeps the signal unblocked once a panic jumps out of the handler, and
+// SA_ONSTACK runs it on the stack of llgo_fault_altstack.
+void llgo_fault_init(void (*fn)(int sig, int code, void *addr, void *pc)) {
+    static const int sigs[] = {SIGSEGV, SIGBUS};
+    fault_fn = fn;
+    for (size_t i = 0; i < sizeof(sigs) / sizeof(sigs[0]); i++) {
+        struct sigaction act, old;
+        if (sigaction(sigs[i], NULL, &old) != 0 || old.sa_handler != SIG_DFL) {
+            continue;
+        }
+        memset(&act, 0, sizeof(act));
+        act.sa_sigaction = fault_handler;
+        act.sa_flags = SA_SIGINFO | SA_NODEFER | SA_ONSTACK;
+        sigemptyset(&act.sa_mask);
+        sigaction(sigs[i], &act, NULL);
+    }
+}
+
+static pthread_key_t fault_altstack_key;
+static pthread_once_t fault_altstack_once = PTHREAD_ONCE_INIT;
+
+// fault_altstack_free disables and frees the alternate signal stack of an
+// exiting thread.
+static void fault_altstack_free(void *stk) {
+    stack_t ss;
+    memset(&ss, 0, sizeof(ss));
+    ss.ss_flags = SS_DISABLE;
+    sigaltstack(&ss, NULL);
+    free(stk);
+}
+
+static void fault_altstack_init(void) {
+    pthread_key_create(&fault_altstack_key, fault_altstack_free);
+}
+
+// llgo_fault_altstack installs an alternate signal stack of size bytes for
+// the current thread, so that the handler runs when a goroutine overflows
+// its stack. The stack is freed when the thread exits. It returns the
+// stack, or NULL if the thread has one already or it can't be installed.
+void *llgo_fault_altstack(unsigned long size) {
+    stack_t ss;
+    if (sigaltstack(NULL, &ss) != 0 || !(ss.ss_flags & SS_DISABLE)) {
+        return NULL;
+    }
+    pthread_once(&fault_altstack_once, fault_altstack_init);
+    ss.ss_sp = malloc(size);
+    if (ss.ss_sp == NULL) {
+        return NULL;
+    }
+    ss.ss_size = size;
+    ss.ss_flags = 0;
+    if (sigaltstack(&ss, NULL) != 0) {
+        free(ss.ss_sp);
+        return NULL;
+    }
+    pthread_setspecific(fault_altstack_key, ss.ss_sp);
+    return ss.ss_sp;
+}
+
+#endif
@@ -0,0 +1,483 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Execution contexts of the goroutine scheduler (see z_sched.go).
+//
+// A context is either the native stack of a worker thread or a goroutine
+// stack allocated here. Switching contexts saves the callee-saved registers
+// on the current stack and resumes another one, with a hand-written switch
+// on amd64/arm64 and ucontext elsewhere.
+//
+// The collector only knows about thread stacks, so every switch tells it
+// which stack the thread is now running on, and the stacks of suspended
+// contexts are pushed as extra roots (see sched_gc.c).
+
+#if defined(__linux__)
+#define UNW_LOCAL_ONLY
+#ifndef _GNU_SOURCE
+#define _GNU_SOURCE
+#endif
+#endif
+
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/mman.h>
+#include <unistd.h>
+#include <pthread.h>
+
+#if defined(__x86_64__) || defined(__aarch64__) || defined(__arm64__)
+#define LLGO_CTX_ASM 1
+#include <libunwind.h>
+#if defined(__linux__)
+#include <ucontext.h>
+#endif
+#else
+#include <ucontext.h>
+#endif
+
+// The pthread key of defer chains, shared with code generated by llgo
+// (see ssa/eh.go). Defined here too in case no package uses defer.
+__attribute__((weak)) int __llgo_defer;
+
+void *llgo_defer_get(void) { return pthread_getspecific((pthread_key_t)__llgo_defer); }
+void llgo_defer_set(void *v) { pthread_setspecific((pthread_key_t)__llgo_defer, v); }
+
+// Hooks of the garbage collector, see sched_gc.c and sched_nogc.c.
+void llgo_gc_lock(void);
+void llgo_gc_unlock(void);
+void *llgo_gc_thread(void **base);
+void llgo_gc_set_stack(void *thread, void *base);
+void llgo_gc_init(void (*push)(void));
+void llgo_gc_push(void *lo, void *hi);
+
+typedef void *(*llgo_ctx_fn)(void *);
+
+typedef struct llgo_ctx {
+    void *sp;        // saved stack pointer while suspended
+    char *lo, *hi;   // stack bounds (lo is NULL for thread stacks)
+    void *thread;    // GC handle of the thread owning a native stack
+    llgo_ctx_fn fn;
+    void *arg;
+    int running;
+    struct llgo_ctx *prev, *next;
+#ifndef LLGO_CTX_ASM
+    ucontext_t uc;
+#endif
+} llgo_ctx;
+
+// All contexts, guarded by the GC lock (llgo_gc_lock).
+static llgo_ctx *ctx_all;
+
+static void ctx_link(llgo_ctx *ctx) {
+    ctx->next = ctx_all;
+    if (ctx_all) {
+        ctx_all->prev = ctx;
+    }
+    ctx_all = ctx;
+}
+
+static void ctx_unlink(llgo_ctx *ctx) {
+    if (ctx->prev) {
+        ctx->prev->next = ctx->next;
+    } else {
+        ctx_all = ctx->next;
+    }
+    if (ctx->next) {
+        ctx->next->prev = ctx->prev;
+    }
+}
+
+// ctx_push_roots pushes the live part of suspended stacks. It's called by
+// the collector, which holds the GC lock while the world is stopped.
+static void ctx_push_roots(void) {
+    for (llgo_ctx *ctx = ctx_all; ctx; ctx = ctx->next) {
+        if (ctx->running || ctx->sp == NULL) {
+            continue;
+        }
+        llgo_gc_push(ctx->sp, ctx->hi);
+#ifndef LLGO_CTX_ASM
+        llgo_gc_push(&ctx->uc, &ctx->uc + 1);
+#endif
+    }
+}
+
+static pthread_once_t ctx_once = PTHREAD_ONCE_INIT;
+
+static void stack_size_init(void);
+
+static void ctx_init(void) {
+    stack_size_init();
+    llgo_gc_init(ctx_push_roots);
+}
+
+// -----------------------------------------------------------------------------
+
+#if defined(__APPLE__)
+#define ASM_SYM(name) "_" #name
+#else
+#define ASM_SYM(name) #name
+#endif
+
+#if defined(__x86_64__)
+
+// void llgo_swapctx(void **from_sp, void *to_sp)
+__asm__(
+    ".text\n"
+    ".globl " ASM_SYM(llgo_swapctx) "\n"
+    ".p2align 4\n"
+    ASM_SYM(llgo_swapctx) ":\n"
+    "    pushq %rbp\n"
+    "    pushq %rbx\n"
+    "    pushq %r12\n"
+    "    pushq %r13\n"
+    "    pushq %r14\n"
+    "    pushq %r15\n"
+    "    subq $8, %rsp\n"
+    "    stmxcsr (%rsp)\n"
+    "    fnstcw 4(%rsp)\n"
+    "    movq %rsp, (%rdi)\n"
+    "    movq %rsi, %rsp\n"
+    "    ldmxcsr (%rsp)\n"
+    "    fldcw 4(%rsp)\n"
+    "    addq $8, %rsp\n"
+    "    popq %r15\n"
+    "    popq %r14\n"
+    "    popq %r13\n"
+    "    popq %r12\n"
+    "    popq %rbx\n"
+    "    popq %rbp\n"
+    "    ret\n"
+    ".globl " ASM_SYM(llgo_ctx_trampoline) "\n"
+    ".p2align 4\n"
+    ASM_SYM(llgo_ctx_trampoline) ":\n"
+    "    movq %r13, %rdi\n"
+    "    callq *%r12\n"
+    "    ud2\n"
+);
+
+#elif defined(__aarch64__) || defined(__arm64__)
+
+// void llgo_swapctx(void **from_sp, void *to_sp)
+__asm__(
+    ".text\n"
+    ".globl " ASM_SYM(llgo_swapctx) "\n"
+    ".p2align 4\n"
+    ASM_SYM(llgo_swapctx) ":\n"
+    "    sub sp, sp, #160\n"
+    "    stp x19, x20, [sp, #0]\n"
+    "    stp x21, x22, [sp, #16]\n"
+    "    stp x23, x24, [sp, #32]\n"
+    "    stp x25, x26, [sp, #48]\n"
+    "    stp x27, x28, [sp, #64]\n"
+    "    stp x29, x30, [sp, #80]\n"
+    "    stp d8, d9, [sp, #96]\n"
+    "    stp d10, d11, [sp, #112]\n"
+    "    stp d12, d13, [sp, #128]\n"
+    "    stp d14, d15, [sp, #144]\n"
+    "    mov x9, sp\n"
+    "    str x9, [x0]\n"
+    "    mov sp, x1\n"
+    "    ldp x19, x20, [sp, #0]\n"
+    "    ldp x21, x22, [sp, #16]\n"
+    "    ldp x23, x24, [sp, #32]\n"
+    "    ldp x25, x26, [sp, #48]\n"
+    "    ldp x27, x28, [sp, #64]\n"
+    "    ldp x29, x30, [sp, #80]\n"
+    "    ldp d8, d9, [sp, #96]\n"
+    "    ldp d10, d11, [sp, #112]\n"
+    "    ldp d12, d13, [sp, #128]\n"
+    "    ldp d14, d15, [sp, #144]\n"
+    "    add sp, sp, #160\n"
+    "    ret\n"
+    ".globl " ASM_SYM(llgo_ctx_trampoline) "\n"
+    ".p2align 4\n"
+    ASM_SYM(llgo_ctx_trampoline) ":\n"
+    "    mov x0, x20\n"
+    "    blr x19\n"
+    "    brk #0\n"
+);
+
+#endif
+
+#ifdef LLGO_CTX_ASM
+void llgo_swapctx(void **from_sp, void *to_sp);
+void llgo_ctx_trampoline(void);
+#else
+static __thread llgo_ctx *ctx_starting;
+#endif
+
+// ctx_main is the first function run on a goroutine stack. It's entered
+// from llgo_ctx_switch, so the GC lock is still held.
+static void ctx_main(llgo_ctx *ctx) {
+    llgo_gc_unlock();
+    ctx->fn(ctx->arg);
+    abort(); // fn never returns
+}
+
+#ifndef LLGO_CTX_ASM
+static void ctx_main_uc(void) {
+    ctx_main(ctx_starting);
+}
+#endif
+
+// -----------------------------------------------------------------------------
+
+#define STACK_CACHE 64
+
+// Goroutine stacks don't grow: a goroutine going deeper than STACK_SIZE
+// crashes on the guard page. The size is the LLGO_STACK_SIZE environment
+// variable (in bytes) if set, else the default-stack-size of the target,
+// else the default stack size of threads (RLIMIT_STACK, usually 8MB, on
+// Linux and 512KB on macOS), which goroutines had when each one ran on its
+// own thread.
+#define STACK_SIZE stack_size
+
+static size_t stack_size;
+
+static void stack_size_init(void) {
+    const char *s = getenv("LLGO_STACK_SIZE");
+    if (s && strtoull(s, NULL, 0) > 0) {
+        stack_size = strtoull(s, NULL, 0);
+    } else {
+#if defined(LLGO_STACK_SIZE) && LLGO_STACK_SIZE > 0
+        stack_size = LLGO_STACK_SIZE;
+#else
+        pthread_attr_t attr;
+        if (pthread_attr_init(&attr) == 0) {
+            pthread_attr_getstacksize(&attr, &stack_size);
+            pthread_attr_destroy(&attr);
+        }
+#endif
+    }
+    size_t page = getpagesize();
+    if (stack_size < 16 * page) {
+        stack_size = 16 * page; // the guard page and some room for C code
+    }
+    stack_size = (stack_size + page - 1) & ~(page - 1);
+}
+
+// Stacks are reserved but only committed when touched, so a goroutine only
+// uses the memory its stack grows into. The lowest page is a guard page.
+static char *stack_cache[STACK_CACHE];
+static int stack_ncache;
+static pthread_mutex_t stack_mu = PTHREAD_MUTEX_INITIALIZER;
+
+static char *stack_alloc(void) {
+    char *lo = NULL;
+    pthread_mutex_lock(&stack_mu);
+    if (stack_ncache > 0) {
+        lo = stack_cache[--stack_ncache];
+    }
+    pthread_mutex_unlock(&stack_mu);
+    if (lo) {
+        return lo;
+    }
+    lo = mmap(NULL, STACK_SIZE, PROT_READ | PROT_WRITE, MAP_PRIVATE | MAP_ANON, -1, 0);
+    if (lo == MAP_FAILED) {
+        return NULL;
+    }
+    mprotect(lo, getpagesize(), PROT_NONE);
+    return lo;
+}
+
+static void stack_free(char *lo) {
+    pthread_mutex_lock(&stack_mu);
+    if (stack_ncache < STACK_CACHE) {
+        stack_cache[stack_ncache++] = lo;
+        lo = NULL;
+    }
+    pthread_mutex_unlock(&stack_mu);
+    if (lo) {
+        munmap(lo, STACK_SIZE);
+    }
+}
+
+// llgo_ctx_self returns the context of the calling thread's native stack.
+llgo_ctx *llgo_ctx_self(void) {
+    pthread_once(&ctx_once, ctx_init);
+    llgo_ctx *ctx = calloc(1, sizeof(llgo_ctx));
+    ctx->thread = llgo_gc_thread((void **)&ctx->hi);
+    ctx->running = 1;
+    llgo_gc_lock();
+    ctx_link(ctx);
+    llgo_gc_unlock();
+    return ctx;
+}
+
+// llgo_ctx_new returns a context running fn(arg) on a new stack. fn must
+// never return: it switches to another context when done.
+llgo_ctx *llgo_ctx_new(llgo_ctx_fn fn, void *arg) {
+    pthread_once(&ctx_once, ctx_init);
+    llgo_ctx *ctx = calloc(1, sizeof(llgo_ctx));
+    if (ctx == NULL || (ctx->lo = stack_alloc()) == NULL) {
+        abort();
+    }
+    ctx->hi = ctx->lo + STACK_SIZE;
+    ctx->fn = fn;
+    ctx->arg = arg;
+#if defined(__x86_64__)
+    uintptr_t *sp = (uintptr_t *)((uintptr_t)ctx->hi & ~(uintptr_t)15);
+    *--sp = (uintptr_t)llgo_ctx_trampoline; // return address
+    *--sp = 0;                              // rbp
+    *--sp = 0;                              // rbx
+    *--sp = (uintptr_t)ctx_main;            // r12
+    *--sp = (uintptr_t)ctx;                 // r13
+    *--sp = 0;                              // r14
+    *--sp = 0;                              // r15
+    *--sp = 0x1F80 | (0x037FULL << 32);     // mxcsr, x87 control word
+    ctx->sp = sp;
+#elif defined(__aarch64__) || defined(__arm64__)
+    uintptr_t *sp = (uintptr_t *)((uintptr_t)ctx->hi & ~(uintptr_t)15) - 20;
+    memset(sp, 0, 20 * sizeof(uintptr_t));
+    sp[0] = (uintptr_t)ctx_main;             // x19
+    sp[1] = (uintptr_t)ctx;                  // x20
+    sp[11] = (uintptr_t)llgo_ctx_trampoline; // x30
+    ctx->sp = sp;
+#else
+    getcontext(&ctx->uc);
+    ctx->uc.uc_stack.ss_sp = ctx->lo;
+    ctx->uc.uc_stack.ss_size = STACK_SIZE;
+    ctx->uc.uc_link = NULL;
+    makecontext(&ctx->uc, ctx_main_uc, 0);
+    ctx->sp = ctx->hi - 4096;
+#endif
+    llgo_gc_lock();
+    ctx_link(ctx);
+    llgo_gc_unlock();
+    return ctx;
+}
+
+// llgo_ctx_free releases a goroutine context, which must not be running.
+void llgo_ctx_free(llgo_ctx *ctx) {
+    llgo_gc_lock();
+    ctx_unlink(ctx);
+    llgo_gc_unlock();
+    stack_free(ctx->lo);
+    free(ctx);
+}
+
+// llgo_ctx_switch suspends the current context from and resumes to. One
+// of them is the native stack of the calling thread.
+void llgo_ctx_switch(llgo_ctx *from, llgo_ctx *to) {
+    void *thread = from->thread ? from->thread : to->thread;
+    llgo_gc_lock();
+    from->running = 0;
+    to->running = 1;
+    llgo_gc_set_stack(thread, to->hi);
+#ifdef LLGO_CTX_ASM
+    llgo_swapctx(&from->sp, to->sp);
+#else
+    char here;
+    from->sp = (void *)((uintptr_t)&here - 256); // below the red zone
+    ctx_starting = to;
+    swapcontext(&from->uc, &to->uc);
+#endif
+    llgo_gc_unlock();
+}
+
+// -----------------------------------------------------------------------------
+
+#ifdef LLGO_CTX_ASM
+
+// ctx_unwind_init points cursor at the frame of llgo_ctx_switch in which
+// ctx is suspended, restoring the registers llgo_swapctx saved. uc is the
+// context of the calling thread, whose registers are replaced.
+static int ctx_unwind_init(llgo_ctx *ctx, unw_context_t *uc, unw_cursor_t *cursor) {
+    uintptr_t *sp = ctx->sp;
+    unw_getcontext(uc);
+#if defined(__linux__) && defined(__x86_64__)
+    greg_t *gregs = ((ucontext_t *)uc)->uc_mcontext.gregs;
+    gregs[REG_R15] = sp[1];
+    gregs[REG_R14] = sp[2];
+    gregs[REG_R13] = sp[3];
+    gregs[REG_R12] = sp[4];
+    gregs[REG_RBX] = sp[5];
+    gregs[REG_RBP] = sp[6];
+    gregs[REG_RIP] = sp[7];
+    gregs[REG_RSP] = (uintptr_t)(sp + 8);
+    return unw_init_local(cursor, uc);
+#elif defined(__linux__)
+    mcontext_t *mc = &((ucontext_t *)uc)->uc_mcontext;
+    for (int i = 0; i < 12; i++) {
+        mc->regs[19 + i] = sp[i];
+    }
+    mc->pc = sp[11];
+    mc->sp = (uintptr_t)(sp + 20);
+    return unw_init_local(cursor, uc);
+#else
+    // libunwind of LLVM, which re-reads the unwind info when IP is set
+    int ret = unw_init_local(cursor, uc);
+    if (ret != 0) {
+        return ret;
+    }
+#if defined(__x86_64__)
+    static const int regs[] = {UNW_X86_64_R15, UNW_X86_64_R14, UNW_X86_64_R13,
+                               UNW_X86_64_R12, UNW_X86_64_RBX, UNW_X86_64_RBP};
+    for (int i = 0; i < 6; i++) {
+        unw_set_reg(cursor, regs[i], sp[1 + i]);
+    }
+    unw_set_reg(cursor, UNW_REG_SP, (uintptr_t)(sp + 8));
+    return unw_set_reg(cursor, UNW_REG_IP, sp[7]);
+#else
+    for (int i = 0; i < 12; i++) {
+        unw_set_reg(cursor, UNW_ARM64_X19 + i, sp[i]);
+    }
+    unw_set_reg(cursor, UNW_REG_SP, (uintptr_t)(sp + 20));
+    return unw_set_reg(cursor, UNW_REG_IP, sp[11]);
+#endif
+#endif
+}
+
+#endif
+
+// llgo_ctx_backtrace stores the return addresses of the goroutine context
+// ctx into pcs and returns the number of entries written. It's 0 if ctx is
+// running or can't be unwound. The GC lock is held meanwhile, so that no
+// context is switched and the stack of ctx stays as it is.
+int llgo_ctx_backtrace(llgo_ctx *ctx, void **pcs, int max) {
+    int n = 0;
+#ifdef LLGO_CTX_ASM
+    unw_context_t uc;
+    unw_cursor_t cursor;
+    unw_word_t pc;
+    llgo_gc_lock();
+    if (!ctx->running && ctx_unwind_init(ctx, &uc, &cursor) == 0) {
+        while (n < max && unw_step(&cursor) > 0) {
+            if (unw_get_reg(&cursor, UNW_REG_IP, &pc) == 0) {
+                pcs[n++] = (void *)pc;
+            }
+        }
+    }
+    llgo_gc_unlock();
+#endif
+    return n;
+}
+
+// llgo_sched_nprocs returns the number of worker threads to start.
+int llgo_sched_nprocs(void) {
+    const char *s = getenv("GOMAXPROCS");
+    if (s && atoi(s) > 0) {
+        return atoi(s);
+    }
+#ifdef _SC_NPROCESSORS_ONLN
+    long n = sysconf(_SC_NPROCESSORS_ONLN);
+    if (n > 0) {
+        return (int)n;
+    }
+#endif
+    return 1;
+}
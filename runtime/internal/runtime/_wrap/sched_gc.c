@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// GC hooks of the goroutine scheduler for builds with the Boehm GC.
+
+#include <stddef.h>
+
+struct GC_stack_base {
+    void *mem_base;
+};
+
+typedef void (*GC_push_other_roots_proc)(void);
+
+extern void GC_alloc_lock(void);
+extern void GC_alloc_unlock(void);
+extern void *GC_get_my_stackbottom(struct GC_stack_base *);
+extern void GC_set_stackbottom(void *, const struct GC_stack_base *);
+extern void GC_push_all(void *, void *);
+extern void GC_set_push_other_roots(GC_push_other_roots_proc);
+extern GC_push_other_roots_proc GC_get_push_other_roots(void);
+
+static GC_push_other_roots_proc old_push_other_roots;
+static void (*push_stacks)(void);
+
+static void push_other_roots(void) {
+    if (old_push_other_roots) {
+        old_push_other_roots();
+    }
+    push_stacks();
+}
+
+void llgo_gc_init(void (*push)(void)) {
+    push_stacks = push;
+    GC_alloc_lock();
+    old_push_other_roots = GC_get_push_other_roots();
+    GC_set_push_other_roots(push_other_roots);
+    GC_alloc_unlock();
+}
+
+void llgo_gc_lock(void) { GC_alloc_lock(); }
+
+void llgo_gc_unlock(void) { GC_alloc_unlock(); }
+
+void *llgo_gc_thread(void **base) {
+    struct GC_stack_base sb;
+    void *thread = GC_get_my_stackbottom(&sb);
+    *base = sb.mem_base;
+    return thread;
+}
+
+// llgo_gc_set_stack tells the collector the stack base of a thread, which
+// is called with the GC lock held.
+void llgo_gc_set_stack(void *thread, void *base) {
+    struct GC_stack_base sb = {base};
+    GC_set_stackbottom(thread, &sb);
+}
+
+void llgo_gc_push(void *lo, void *hi) { GC_push_all(lo, hi); }
@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// GC hooks of the goroutine scheduler for builds without GC.
+
+#include <stddef.h>
+#include <pthread.h>
+
+// The lock still keeps contexts from being switched while a suspended
+// stack is unwound (see llgo_ctx_backtrace).
+static pthread_mutex_t gc_mu = PTHREAD_MUTEX_INITIALIZER;
+
+void llgo_gc_init(void (*push)(void)) {}
+
+void llgo_gc_lock(void) { pthread_mutex_lock(&gc_mu); }
+
+void llgo_gc_unlock(void) { pthread_mutex_unlock(&gc_mu); }
+
+void *llgo_gc_thread(void **base) {
+    *base = NULL;
+    return NULL;
+}
+
+void llgo_gc_set_stack(void *thread, void *base) {}
+
+void llgo_gc_push(void *lo, void *hi) {}
@@ -0,0 +1,28 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Stack size of goroutine threads, the default-stack-size of the target
+// configuration passed as LLGO_STACK_SIZE, or 0 for the system default.
+
+#include <stddef.h>
+
+#ifndef LLGO_STACK_SIZE
+#define LLGO_STACK_SIZE 0
+#endif
+
+size_t llgo_default_stack_size(void) {
+    return LLGO_STACK_SIZE;
+}
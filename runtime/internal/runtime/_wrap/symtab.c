@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Bounds of the PC-line tables emitted by the compiler (see z_symtab.go).
+//
+// The ELF linkers define __start_ and __stop_ symbols for the sections,
+// weak so that a program without tables links too. ld64 defines the
+// section$start$ and section$end$ ones.
+
+#include <stddef.h>
+
+#if defined(__APPLE__)
+
+extern char llgo_functab_start[] __asm("section$start$__DATA$__llgo_functab");
+extern char llgo_functab_end[] __asm("section$end$__DATA$__llgo_functab");
+extern char llgo_pcln_start[] __asm("section$start$__DATA$__llgo_pcln");
+extern char llgo_pcln_end[] __asm("section$end$__DATA$__llgo_pcln");
+
+#define LLGO_SYMTAB 1
+#define FUNCTAB_START llgo_functab_start
+#define FUNCTAB_END llgo_functab_end
+#define PCLN_START llgo_pcln_start
+#define PCLN_END llgo_pcln_end
+
+#elif defined(__ELF__) && (defined(__linux__) || defined(__FreeBSD__) || \
+    defined(__NetBSD__) || defined(__OpenBSD__))
+
+extern char __start___llgo_functab[] __attribute__((weak, visibility("hidden")));
+extern char __stop___llgo_functab[] __attribute__((weak, visibility("hidden")));
+extern char __start___llgo_pcln[] __attribute__((weak, visibility("hidden")));
+extern char __stop___llgo_pcln[] __attribute__((weak, visibility("hidden")));
+
+#define LLGO_SYMTAB 1
+#define FUNCTAB_START __start___llgo_functab
+#define FUNCTAB_END __stop___llgo_functab
+#define PCLN_START __start___llgo_pcln
+#define PCLN_END __stop___llgo_pcln
+
+#endif
+
+void llgo_symtab(void **functab, void **efunctab, void **pcln, void **epcln) {
+#ifdef LLGO_SYMTAB
+    *functab = FUNCTAB_START;
+    *efunctab = FUNCTAB_END;
+    *pcln = PCLN_START;
+    *epcln = PCLN_END;
+#else
+    *functab = *efunctab = *pcln = *epcln = NULL;
+#endif
+}
+
+#ifdef LLGO_SYMTAB
+
+// Provided by the unwinder, libunwind or libgcc_s.
+extern void *_Unwind_FindEnclosingFunction(void *pc);
+
+void *llgo_func_entry(void *pc) {
+    return _Unwind_FindEnclosingFunction(pc);
+}
+
+#else
+
+void *llgo_func_entry(void *pc) {
+    return NULL;
+}
+
+#endif
@@ -223,6 +223,14 @@ var overflowError error
 
 var shiftError = error(errorString("negative shift amount"))
 
+var memoryError = error(errorString("invalid memory address or nil pointer dereference"))
+
+// Panicmem panics with a nil pointer dereference. The SIGSEGV handler
+// raises the same panic, see sigpanic.
+func Panicmem() {
+	panic(memoryError)
+}
+
 /*
 func Panicshift() {
 	panic(shiftError)
@@ -246,12 +254,6 @@ func Panicfloat() {
 	panic(floatError)
 }
 
-var memoryError = error(errorString("invalid memory address or nil pointer dereference"))
-
-func Panicmem() {
-	panic(memoryError)
-}
-
 /*
 func PanicmemAddr(addr uintptr) {
 	panic(errorAddressString{msg: "invalid memory address or nil pointer dereference", addr: addr})
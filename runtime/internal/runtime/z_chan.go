@@ -32,7 +32,7 @@ const (
 
 type Chan struct {
 	mutex sync.Mutex
-	cond  sync.Cond
+	cond  cond
 	data  unsafe.Pointer
 	getp  int
 	len   int
@@ -76,8 +76,24 @@ func notifyOps(p *Chan) {
 	}
 }
 
+// raceNotify records that the operation on the ith element of the buffer
+// of p is synchronized after the previous one on it: the kth receive after
+// the kth send, and the kth send after the (k-cap)th receive. Unbuffered
+// channels use p itself.
+func raceNotify(p *Chan, i, eltSize int) {
+	addr := unsafe.Pointer(p)
+	if p.cap > 0 && eltSize > 0 {
+		addr = c.Advance(p.data, i*eltSize)
+	}
+	raceacquire(addr)
+	racerelease(addr)
+}
+
 func ChanClose(p *Chan) {
 	p.mutex.Lock()
+	if raceenabled {
+		racerelease(unsafe.Pointer(p))
+	}
 	p.close = true
 	notifyOps(p)
 	p.mutex.Unlock()
@@ -95,6 +111,9 @@ func ChanTrySend(p *Chan, v unsafe.Pointer, eltSize int) bool {
 		if p.data != nil {
 			c.Memcpy(p.data, v, uintptr(eltSize))
 		}
+		if raceenabled {
+			raceNotify(p, 0, eltSize)
+		}
 		p.getp = chanNoSendRecv
 	} else {
 		if p.len == n || p.close {
@@ -103,6 +122,9 @@ func ChanTrySend(p *Chan, v unsafe.Pointer, eltSize int) bool {
 		}
 		off := (p.getp + p.len) % n
 		c.Memcpy(c.Advance(p.data, off*eltSize), v, uintptr(eltSize))
+		if raceenabled {
+			raceNotify(p, off, eltSize)
+		}
 		p.len++
 	}
 	notifyOps(p)
@@ -127,6 +149,9 @@ func ChanSend(p *Chan, v unsafe.Pointer, eltSize int) bool {
 		if p.data != nil {
 			c.Memcpy(p.data, v, uintptr(eltSize))
 		}
+		if raceenabled {
+			raceNotify(p, 0, eltSize)
+		}
 		p.getp = chanNoSendRecv
 	} else {
 		for p.len == n {
@@ -138,6 +163,9 @@ func ChanSend(p *Chan, v unsafe.Pointer, eltSize int) bool {
 		}
 		off := (p.getp + p.len) % n
 		c.Memcpy(c.Advance(p.data, off*eltSize), v, uintptr(eltSize))
+		if raceenabled {
+			raceNotify(p, off, eltSize)
+		}
 		p.len++
 	}
 	notifyOps(p)
@@ -152,6 +180,9 @@ func ChanTryRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool, tryOK boo
 	if n == 0 {
 		if p.sends == 0 || p.getp == chanHasRecv || p.close {
 			tryOK = p.close
+			if raceenabled && tryOK {
+				raceacquire(unsafe.Pointer(p))
+			}
 			p.mutex.Unlock()
 			return
 		}
@@ -160,12 +191,18 @@ func ChanTryRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool, tryOK boo
 	} else {
 		if p.len == 0 {
 			tryOK = p.close
+			if raceenabled && tryOK {
+				raceacquire(unsafe.Pointer(p))
+			}
 			p.mutex.Unlock()
 			return
 		}
 		if v != nil {
 			c.Memcpy(v, c.Advance(p.data, p.getp*eltSize), uintptr(eltSize))
 		}
+		if raceenabled {
+			raceNotify(p, p.getp, eltSize)
+		}
 		p.getp = (p.getp + 1) % n
 		p.len--
 	}
@@ -179,6 +216,9 @@ func ChanTryRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool, tryOK boo
 		}
 		recvOK = !p.close
 		tryOK = recvOK
+		if raceenabled {
+			raceRecvUnbuffered(p, recvOK, eltSize)
+		}
 		p.mutex.Unlock()
 	} else {
 		recvOK, tryOK = true, true
@@ -194,6 +234,9 @@ func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
 			p.cond.Wait(&p.mutex)
 		}
 		if p.close {
+			if raceenabled {
+				raceacquire(unsafe.Pointer(p))
+			}
 			p.mutex.Unlock()
 			return false
 		}
@@ -202,6 +245,9 @@ func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
 	} else {
 		for p.len == 0 {
 			if p.close {
+				if raceenabled {
+					raceacquire(unsafe.Pointer(p))
+				}
 				p.mutex.Unlock()
 				return false
 			}
@@ -210,6 +256,9 @@ func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
 		if v != nil {
 			c.Memcpy(v, c.Advance(p.data, p.getp*eltSize), uintptr(eltSize))
 		}
+		if raceenabled {
+			raceNotify(p, p.getp, eltSize)
+		}
 		p.getp = (p.getp + 1) % n
 		p.len--
 	}
@@ -222,6 +271,9 @@ func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
 			p.cond.Wait(&p.mutex)
 		}
 		recvOK = !p.close
+		if raceenabled {
+			raceRecvUnbuffered(p, recvOK, eltSize)
+		}
 		p.mutex.Unlock()
 	} else {
 		recvOK = true
@@ -229,11 +281,22 @@ func ChanRecv(p *Chan, v unsafe.Pointer, eltSize int) (recvOK bool) {
 	return
 }
 
+// raceRecvUnbuffered records that a receive from the unbuffered channel p
+// is synchronized after the send it got the value from, or after the close
+// of p if it failed.
+func raceRecvUnbuffered(p *Chan, recvOK bool, eltSize int) {
+	if recvOK {
+		raceNotify(p, 0, eltSize)
+	} else {
+		raceacquire(unsafe.Pointer(p))
+	}
+}
+
 // -----------------------------------------------------------------------------
 
 type selectOp struct {
 	mutex sync.Mutex
-	cond  sync.Cond
+	cond  cond
 	sem   bool
 }
 
@@ -24,15 +24,200 @@ import (
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
 	"github.com/goplus/llgo/runtime/internal/clite/bdwgc"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+	"github.com/goplus/llgo/runtime/internal/clite/sync/atomic"
 )
 
 // AllocU allocates uninitialized memory.
 func AllocU(size uintptr) unsafe.Pointer {
-	return bdwgc.Malloc(size)
+	ret := bdwgc.Malloc(size)
+	profileAlloc(ret, size)
+	return ret
 }
 
 // AllocZ allocates zero-initialized memory.
 func AllocZ(size uintptr) unsafe.Pointer {
 	ret := bdwgc.Malloc(size)
+	profileAlloc(ret, size)
+	return c.Memset(ret, 0, size)
+}
+
+// AllocUNoscan allocates uninitialized memory that doesn't hold pointers,
+// which the GC doesn't scan.
+func AllocUNoscan(size uintptr) unsafe.Pointer {
+	ret := bdwgc.MallocAtomic(size)
+	profileAlloc(ret, size)
+	return ret
+}
+
+// AllocZNoscan allocates zero-initialized memory that doesn't hold pointers,
+// which the GC doesn't scan.
+func AllocZNoscan(size uintptr) unsafe.Pointer {
+	ret := bdwgc.MallocAtomic(size)
+	profileAlloc(ret, size)
 	return c.Memset(ret, 0, size)
 }
+
+// bitmapBits is the number of words described by a bitmap descriptor of
+// bdwgc, whose tag takes GC_DS_TAG_BITS bits of a word.
+const bitmapBits = unsafe.Sizeof(uintptr(0))*8 - 2
+
+// AllocZTyped allocates zero-initialized memory whose words may only hold
+// pointers where the bits of ptrmask are set, so that the GC scans only
+// these words.
+func AllocZTyped(size, ptrmask uintptr) unsafe.Pointer {
+	if ptrmask>>bitmapBits != 0 {
+		// GC_make_descriptor would register an extended descriptor on
+		// every call.
+		return AllocZ(size)
+	}
+	ret := bdwgc.MallocExplicitlyTyped(size, descrOf(ptrmask)) // cleared by bdwgc
+	profileAlloc(ret, size)
+	return ret
+}
+
+// typedDescr is a bitmap descriptor of bdwgc cached by descrOf.
+type typedDescr struct {
+	mask uintptr
+	d    bdwgc.Descr
+}
+
+// typedDescrs caches the descriptors of the ptrmasks of AllocZTyped, as
+// GC_make_descriptor is too slow to be called on every allocation. The
+// entries point to immutable typedDescr, so that they are read without a
+// lock.
+var typedDescrs [251]unsafe.Pointer
+
+func descrOf(ptrmask uintptr) bdwgc.Descr {
+	slot := &typedDescrs[ptrmask%uintptr(len(typedDescrs))]
+	if e := (*typedDescr)(atomic.Load(slot)); e != nil && e.mask == ptrmask {
+		return e.d
+	}
+	e := (*typedDescr)(bdwgc.Malloc(unsafe.Sizeof(typedDescr{}))) // not profiled
+	e.mask = ptrmask
+	e.d = bdwgc.MakeDescriptor(&ptrmask, bitmapBits)
+	atomic.Store(slot, unsafe.Pointer(e))
+	return e.d
+}
+
+// -----------------------------------------------------------------------------
+
+// finalizer is the client data of the finalizers registered by the runtime,
+// for SetFinalizer and for objects sampled by the heap profiler. bdwgc
+// traces the client data of finalizers, so it's allocated in the GC heap.
+type finalizer struct {
+	fn   func(unsafe.Pointer) // set by SetFinalizer
+	b    *MemBucket           // set by the heap profiler
+	size uintptr
+}
+
+func newFinalizer() *finalizer {
+	f := bdwgc.Malloc(unsafe.Sizeof(finalizer{})) // not profiled
+	return (*finalizer)(c.Memset(f, 0, unsafe.Sizeof(finalizer{})))
+}
+
+func setMemProfFinalizer(p unsafe.Pointer, b *MemBucket, size uintptr) {
+	f := newFinalizer()
+	f.b, f.size = b, size
+	finqStart.Do(startFinq)
+	bdwgc.RegisterFinalizerNoOrder(p, runFinalizer, unsafe.Pointer(f), nil, nil)
+}
+
+// registerSigStack tells the collector about the alternate signal stack of
+// the current thread, as the fault handler may allocate on it.
+func registerSigStack(stk unsafe.Pointer, size uintptr) {
+	bdwgc.RegisterAltstack(nil, 0, stk, size)
+}
+
+// HeapBase returns the start of the heap object containing p, or nil if p
+// doesn't point into the heap.
+func HeapBase(p unsafe.Pointer) unsafe.Pointer {
+	return bdwgc.Base(p)
+}
+
+// SetFinalizer sets fn to be called with p, which must be the start of a
+// heap object, once p becomes unreachable. A nil fn removes the finalizer.
+// It returns false if a finalizer is already set and fn isn't nil.
+func SetFinalizer(p unsafe.Pointer, fn func(unsafe.Pointer)) bool {
+	finqStart.Do(startFinq)
+	var old unsafe.Pointer
+	bdwgc.RegisterFinalizer(p, nil, nil, nil, &old)
+	of := (*finalizer)(old)
+	if of != nil && of.fn != nil && fn != nil {
+		bdwgc.RegisterFinalizer(p, runFinalizer, old, nil, nil)
+		return false
+	}
+	// keep counting the frees of a sampled object
+	f := newFinalizer()
+	f.fn = fn
+	if of != nil {
+		f.b, f.size = of.b, of.size
+	}
+	if fn != nil {
+		bdwgc.RegisterFinalizer(p, runFinalizer, unsafe.Pointer(f), nil, nil)
+	} else if f.b != nil {
+		bdwgc.RegisterFinalizerNoOrder(p, runFinalizer, unsafe.Pointer(f), nil, nil)
+	}
+	return true
+}
+
+// TrySetFinalizer is SetFinalizer for the library packages. It returns
+// false if p isn't the start of a heap object or already has a finalizer.
+func TrySetFinalizer(p unsafe.Pointer, fn func(unsafe.Pointer)) bool {
+	if p == nil || HeapBase(p) != p {
+		return false
+	}
+	return SetFinalizer(p, fn)
+}
+
+func runFinalizer(p, cd unsafe.Pointer) {
+	f := (*finalizer)(cd)
+	if f.b != nil {
+		memProfFreed(f.b, f.size)
+	}
+	if f.fn != nil {
+		f.fn(p)
+	}
+}
+
+// Finalizers of unreachable objects are queued by bdwgc and run by the
+// finalizer goroutine. It runs on its own thread, so that it doesn't hold
+// a worker while waiting for finalizers.
+var finq struct {
+	lock    sync.Mutex
+	cond    sync.Cond
+	pending bool
+}
+
+var finqStart sync.Once
+
+func startFinq() {
+	finq.lock.Init(nil)
+	finq.cond.Init(nil)
+	bdwgc.SetFinalizeOnDemand(1)
+	bdwgc.SetFinalizerNotifier(finqNotify)
+	var th pthread.Thread
+	pthread.Create(&th, nil, runfinq, nil)
+}
+
+// finqNotify is called by bdwgc when finalizers are queued. It may be
+// called while allocating, so it must neither allocate nor take other locks.
+func finqNotify() {
+	finq.lock.Lock()
+	finq.pending = true
+	finq.cond.Signal()
+	finq.lock.Unlock()
+}
+
+func runfinq(arg c.Pointer) c.Pointer {
+	for {
+		finq.lock.Lock()
+		for !finq.pending {
+			finq.cond.Wait(&finq.lock)
+		}
+		finq.pending = false
+		finq.lock.Unlock()
+		bdwgc.InvokeFinalizers()
+	}
+}
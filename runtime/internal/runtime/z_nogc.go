@@ -27,11 +27,42 @@ import (
 
 // AllocU allocates uninitialized memory.
 func AllocU(size uintptr) unsafe.Pointer {
-	return c.Malloc(size)
+	ret := c.Malloc(size)
+	profileAlloc(ret, size)
+	return ret
 }
 
 // AllocZ allocates zero-initialized memory.
 func AllocZ(size uintptr) unsafe.Pointer {
 	ret := c.Malloc(size)
+	profileAlloc(ret, size)
 	return c.Memset(ret, 0, size)
 }
+
+// AllocUNoscan allocates uninitialized memory that doesn't hold pointers.
+func AllocUNoscan(size uintptr) unsafe.Pointer {
+	return AllocU(size)
+}
+
+// AllocZNoscan allocates zero-initialized memory that doesn't hold pointers.
+func AllocZNoscan(size uintptr) unsafe.Pointer {
+	return AllocZ(size)
+}
+
+// AllocZTyped allocates zero-initialized memory whose words may only hold
+// pointers where the bits of ptrmask are set.
+func AllocZTyped(size, ptrmask uintptr) unsafe.Pointer {
+	return AllocZ(size)
+}
+
+// setMemProfFinalizer does nothing as memory is never freed without GC.
+func setMemProfFinalizer(p unsafe.Pointer, b *MemBucket, size uintptr) {
+}
+
+func registerSigStack(stk unsafe.Pointer, size uintptr) {
+}
+
+// TrySetFinalizer does nothing as memory is never freed without GC.
+func TrySetFinalizer(p unsafe.Pointer, fn func(unsafe.Pointer)) bool {
+	return false
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/debug"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+	"github.com/goplus/llgo/runtime/internal/clite/sync/atomic"
+)
+
+// -----------------------------------------------------------------------------
+// Profiling support for package runtime and runtime/pprof
+
+// MaxStack is the depth of stacks recorded in profiles.
+const MaxStack = 32
+
+// MemBucket holds the allocations sampled at the same stack. Buckets are
+// allocated outside of the GC heap and are never freed.
+type MemBucket struct {
+	next       *MemBucket
+	NStk       int
+	Stk        [MaxStack]uintptr
+	Allocs     int64
+	Frees      int64
+	AllocBytes int64
+	FreeBytes  int64
+}
+
+const memBucketsSize = 1 << 10
+
+var memProf struct {
+	lock    sync.Mutex
+	buckets [memBucketsSize]*MemBucket
+	rate    *int  // runtime.MemProfileRate
+	next    int64 // bytes to allocate until the next sample
+}
+
+//go:linkname cLog C.log
+func cLog(x float64) float64
+
+func init() {
+	memProf.lock.Init(nil)
+	allgs.lock.Init(nil)
+	threadCreates.lock.Init(nil)
+}
+
+// SetMemProfileRate makes the allocator sample an average of one allocation
+// per *rate bytes. *rate is read on each allocation, so it can be changed
+// later, <= 0 disables sampling.
+func SetMemProfileRate(rate *int) {
+	atomic.Store(&memProf.next, nextSample(*rate))
+	memProf.rate = rate
+}
+
+// nextSample returns an exponentially distributed number of bytes with a
+// mean of rate, so the sampled allocations form a Poisson process.
+func nextSample(rate int) int64 {
+	if rate <= 1 {
+		return int64(rate)
+	}
+	u := (float64(fastrand()%(1<<24)) + 1) / (1 << 24)
+	return int64(-cLog(u)*float64(rate)) + 1
+}
+
+// profileAlloc is called on each allocation of size bytes at p.
+func profileAlloc(p unsafe.Pointer, size uintptr) {
+	rate := memProf.rate
+	if rate == nil || *rate <= 0 {
+		return
+	}
+	if *rate > 1 {
+		old := atomic.Add(&memProf.next, -int64(size))
+		if old <= 0 || old > int64(size) {
+			return
+		}
+		atomic.Store(&memProf.next, nextSample(*rate))
+	}
+	var stk [MaxStack]uintptr
+	n := debug.Backtrace(2, stk[:])
+	memProf.lock.Lock()
+	b := memBucketOf(stk[:n])
+	b.Allocs++
+	b.AllocBytes += int64(size)
+	memProf.lock.Unlock()
+	setMemProfFinalizer(p, b, size)
+}
+
+// memBucketOf returns the bucket of stk. It's called with memProf.lock held.
+func memBucketOf(stk []uintptr) *MemBucket {
+	h := uintptr(len(stk))
+	for _, pc := range stk {
+		h = h*31 + pc
+	}
+	i := h % memBucketsSize
+	for b := memProf.buckets[i]; b != nil; b = b.next {
+		if b.NStk == len(stk) && equalStk(b.Stk[:b.NStk], stk) {
+			return b
+		}
+	}
+	b := (*MemBucket)(c.Malloc(unsafe.Sizeof(MemBucket{})))
+	c.Memset(unsafe.Pointer(b), 0, unsafe.Sizeof(MemBucket{}))
+	b.NStk = copy(b.Stk[:], stk)
+	b.next = memProf.buckets[i]
+	memProf.buckets[i] = b
+	return b
+}
+
+func equalStk(a, b []uintptr) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// memProfFreed records that an object of size bytes sampled in b was freed.
+func memProfFreed(b *MemBucket, size uintptr) {
+	memProf.lock.Lock()
+	b.Frees++
+	b.FreeBytes += int64(size)
+	memProf.lock.Unlock()
+}
+
+// MemProfile calls fn with a snapshot of each heap profile bucket.
+func MemProfile(fn func(b *MemBucket)) {
+	// bs is allocated without holding memProf.lock, as the allocation may
+	// be sampled.
+	var bs []MemBucket
+	for {
+		memProf.lock.Lock()
+		n := 0
+		for _, b := range memProf.buckets {
+			for ; b != nil; b = b.next {
+				if n < len(bs) {
+					bs[n] = *b
+				}
+				n++
+			}
+		}
+		memProf.lock.Unlock()
+		if n <= len(bs) {
+			bs = bs[:n]
+			break
+		}
+		bs = make([]MemBucket, n+10)
+	}
+	for i := range bs {
+		fn(&bs[i])
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// allgs maps a key of each goroutine, except the main one, to its record.
+var allgs struct {
+	lock sync.Mutex
+	m    map[unsafe.Pointer]gRecord
+}
+
+// gRecord describes a goroutine for profiles and tracebacks.
+type gRecord struct {
+	id        int64
+	parent    int64   // id of the goroutine which created it
+	entry     uintptr // address of the function it started with
+	createdBy uintptr // return address of the go statement, 0 if unknown
+}
+
+var goidgen int64 = 1 // the main goroutine is 1
+
+// gcreated records a goroutine created by a go statement in the caller of
+// the caller of gcreated.
+func gcreated(key unsafe.Pointer, entry uintptr) {
+	var pc [1]uintptr
+	debug.Backtrace(2, pc[:])
+	r := gRecord{atomic.Add(&goidgen, 1) + 1, goroutineOf(curgKey()).id, entry, pc[0]}
+	allgs.lock.Lock()
+	if allgs.m == nil {
+		allgs.m = make(map[unsafe.Pointer]gRecord)
+	}
+	allgs.m[key] = r
+	allgs.lock.Unlock()
+}
+
+func gexited(key unsafe.Pointer) {
+	allgs.lock.Lock()
+	delete(allgs.m, key)
+	allgs.lock.Unlock()
+}
+
+// goroutineOf returns the record of the goroutine of key. The main
+// goroutine, whose key is nil, and threads not started by go statements
+// are goroutine 1.
+func goroutineOf(key unsafe.Pointer) gRecord {
+	if key != nil {
+		allgs.lock.Lock()
+		r, ok := allgs.m[key]
+		allgs.lock.Unlock()
+		if ok {
+			return r
+		}
+	}
+	return gRecord{id: 1}
+}
+
+// NumGoroutine returns the number of goroutines that currently exist.
+func NumGoroutine() int {
+	allgs.lock.Lock()
+	n := len(allgs.m)
+	allgs.lock.Unlock()
+	return n + 1
+}
+
+// GoroutineProfile calls fn with the stack of each goroutine. Goroutines
+// are unwound where they are suspended; the stack of one running on another
+// thread is the function it started with.
+func GoroutineProfile(fn func(stk []uintptr)) {
+	var stk [MaxStack]uintptr
+	n := debug.Backtrace(1, stk[:])
+	fn(stk[:n])
+	var stks [][]uintptr
+	self := curgKey()
+	allgs.lock.Lock()
+	for key, r := range allgs.m {
+		if key == self {
+			continue
+		}
+		n := gstack(key, stk[:])
+		if n == 0 {
+			stk[0] = r.entry
+			n = 1
+		}
+		stks = append(stks, append([]uintptr(nil), stk[:n]...))
+	}
+	allgs.lock.Unlock()
+	for _, stk := range stks {
+		fn(stk)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+// threadCreates records the stacks which created threads.
+var threadCreates struct {
+	lock sync.Mutex
+	stks [][]uintptr
+}
+
+func recordThreadCreate() {
+	var stk [MaxStack]uintptr
+	n := debug.Backtrace(2, stk[:])
+	threadCreates.lock.Lock()
+	threadCreates.stks = append(threadCreates.stks, append([]uintptr(nil), stk[:n]...))
+	threadCreates.lock.Unlock()
+}
+
+// ThreadCreateProfile calls fn with the stack which created each thread.
+func ThreadCreateProfile(fn func(stk []uintptr)) {
+	threadCreates.lock.Lock()
+	stks := threadCreates.stks
+	threadCreates.lock.Unlock()
+	for _, stk := range stks {
+		fn(stk)
+	}
+}
+
+// -----------------------------------------------------------------------------
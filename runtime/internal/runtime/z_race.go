@@ -0,0 +1,39 @@
+//go:build sanitize.thread
+// +build sanitize.thread
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/runtime/internal/clite/tsan"
+)
+
+// -race builds instrument the code with ThreadSanitizer. The runtime tells
+// it the happens-before edges of channels and goroutine creation.
+
+const raceenabled = true
+
+func raceacquire(addr unsafe.Pointer) {
+	tsan.Acquire(addr)
+}
+
+func racerelease(addr unsafe.Pointer) {
+	tsan.Release(addr)
+}
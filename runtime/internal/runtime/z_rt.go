@@ -19,8 +19,8 @@ package runtime
 import (
 	"unsafe"
 
+	"github.com/goplus/llgo/runtime/abi"
 	c "github.com/goplus/llgo/runtime/internal/clite"
-	"github.com/goplus/llgo/runtime/internal/clite/debug"
 	"github.com/goplus/llgo/runtime/internal/clite/pthread"
 	"github.com/goplus/llgo/runtime/internal/clite/setjmp"
 )
@@ -42,7 +42,7 @@ func Recover() (ret any) {
 	ptr := excepKey.Get()
 	if ptr != nil {
 		excepKey.Set(nil)
-		ret = *(*any)(ptr)
+		ret = (*panicRecord)(ptr).v
 		c.Free(ptr)
 	}
 	return
@@ -50,10 +50,7 @@ func Recover() (ret any) {
 
 // Panic panics with a value.
 func Panic(v any) {
-	ptr := c.Malloc(unsafe.Sizeof(v))
-	*(*any)(ptr) = v
-	excepKey.Set(ptr)
-
+	excepKey.Set(unsafe.Pointer(newPanic(v)))
 	Rethrow((*Defer)(c.GoDeferData()))
 }
 
@@ -61,19 +58,12 @@ func Panic(v any) {
 func Rethrow(link *Defer) {
 	if ptr := excepKey.Get(); ptr != nil {
 		if link == nil {
-			TracePanic(*(*any)(ptr))
-			debug.PrintStack(2)
-			c.Free(ptr)
-			c.Exit(2)
+			fatalpanic((*panicRecord)(ptr))
 		} else {
 			c.Siglongjmp(link.Addr, 1)
 		}
 	} else if link == nil && goexitKey.Get() != nil {
-		if pthread.Equal(mainThread, pthread.Self()) != 0 {
-			fatal("no goroutines (main called runtime.Goexit) - deadlock!")
-			c.Exit(2)
-		}
-		pthread.Exit(nil)
+		goexit0()
 	}
 }
 
@@ -83,6 +73,9 @@ var (
 	mainThread pthread.Thread
 )
 
+//go:linkname schedYield C.sched_yield
+func schedYield() c.Int
+
 func Goexit() {
 	goexitKey.Set(unsafe.Pointer(&goexitKey))
 	Rethrow((*Defer)(c.GoDeferData()))
@@ -96,13 +89,6 @@ func init() {
 
 // -----------------------------------------------------------------------------
 
-// TracePanic prints panic message.
-func TracePanic(v any) {
-	print("panic: ")
-	printany(v)
-	println("\n")
-}
-
 /*
 func stringTracef(fp c.FilePtr, format *c.Char, s String) {
 	cs := c.Alloca(uintptr(s.len) + 1)
@@ -114,12 +100,50 @@ func stringTracef(fp c.FilePtr, format *c.Char, s String) {
 
 // New allocates memory and initializes it to zero.
 func New(t *Type) unsafe.Pointer {
-	return AllocZ(t.Size_)
+	return allocZOf(t, t.Size_)
 }
 
 // NewArray allocates memory for an array and initializes it to zero.
 func NewArray(t *Type, n int) unsafe.Pointer {
-	return AllocZ(uintptr(n) * t.Size_)
+	return allocZOf(t, uintptr(n)*t.Size_)
+}
+
+// allocZOf allocates zero-initialized memory of size bytes for values of
+// type t, which the GC doesn't scan if t has no pointers.
+func allocZOf(t *Type, size uintptr) unsafe.Pointer {
+	if t.PtrBytes == 0 && noPointers(t) {
+		return AllocZNoscan(size)
+	}
+	return AllocZ(size)
+}
+
+// noPointers reports whether the values of type t hold no pointers. Unlike
+// PtrBytes, it doesn't depend on the order the types are initialized in:
+// the PtrBytes of a struct or an array is computed when it is created, and
+// is 0 if it holds a value of a named type which isn't initialized yet, as
+// for mutually recursive types. Types hold the values of their fields, so
+// the walk ends.
+func noPointers(t *Type) bool {
+	if t.TFlag&abi.TFlagUninited != 0 {
+		return false
+	}
+	switch t.Kind() {
+	case abi.Bool, abi.Int, abi.Int8, abi.Int16, abi.Int32, abi.Int64,
+		abi.Uint, abi.Uint8, abi.Uint16, abi.Uint32, abi.Uint64, abi.Uintptr,
+		abi.Float32, abi.Float64, abi.Complex64, abi.Complex128:
+		return true
+	case abi.Array:
+		at := t.ArrayType()
+		return at.Len == 0 || noPointers(at.Elem)
+	case abi.Struct:
+		for _, f := range t.StructType().Fields {
+			if !noPointers(f.Typ) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
 }
 
 // -----------------------------------------------------------------------------
@@ -130,18 +154,6 @@ const MaxZero = 1024
 
 var ZeroVal [MaxZero]byte
 
-// func init() {
-// 	signal.Signal(c.Int(syscall.SIGSEGV), func(v c.Int) {
-// 		switch syscall.Signal(v) {
-// 		case syscall.SIGSEGV:
-// 			panic(errorString("invalid memory address or nil pointer dereference"))
-// 		default:
-// 			var buf [20]byte
-// 			panic(errorString("unexpected signal value: " + string(itoa(buf[:], uint64(v)))))
-// 		}
-// 	})
-// }
-
 // -----------------------------------------------------------------------------
 
 type SigjmpBuf struct {
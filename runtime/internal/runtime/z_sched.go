@@ -0,0 +1,433 @@
+//go:build !nosched && (linux || darwin)
+// +build !nosched
+// +build linux darwin
+
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+)
+
+// -----------------------------------------------------------------------------
+// M:N scheduler
+//
+// Goroutines run on their own stacks (see _wrap/sched.c), multiplexed on a
+// pool of worker threads, GOMAXPROCS of them by default. A goroutine runs
+// until it returns, yields or parks, in which case the worker switches back
+// to its scheduler context and picks the next runnable goroutine.
+//
+// A goroutine blocked in a system call or a C function also blocks its
+// worker. sysmon starts another worker when runnable goroutines have not
+// been picked for a while, so they are not starved. Build with -tags
+// nosched to run each goroutine on its own thread instead.
+
+// _wrap/sched.c is listed in LLGoFiles of z_sched_gc.go and z_sched_nogc.go.
+
+type ctx struct {
+	Unused [8]byte
+}
+
+//go:linkname ctxSelf C.llgo_ctx_self
+func ctxSelf() *ctx
+
+//go:linkname ctxNew C.llgo_ctx_new
+func ctxNew(fn pthread.RoutineFunc, arg c.Pointer) *ctx
+
+//go:linkname ctxFree C.llgo_ctx_free
+func ctxFree(ctx *ctx)
+
+//go:linkname ctxSwitch C.llgo_ctx_switch
+func ctxSwitch(from, to *ctx)
+
+//go:linkname ctxBacktrace C.llgo_ctx_backtrace
+func ctxBacktrace(ctx *ctx, pcs *uintptr, max c.Int) c.Int
+
+//go:linkname deferGet C.llgo_defer_get
+func deferGet() c.Pointer
+
+//go:linkname deferSet C.llgo_defer_set
+func deferSet(v c.Pointer)
+
+//go:linkname schedNprocs C.llgo_sched_nprocs
+func schedNprocs() c.Int
+
+// maxWorkers limits the workers started by sysmon.
+const maxWorkers = 10000
+
+const (
+	gRunnable = iota
+	gRunning
+	gWaiting
+	gDead
+)
+
+// g is a goroutine, or a thread parking in mutex/cond of the runtime.
+type g struct {
+	ctx       *ctx // nil for threads
+	fn        pthread.RoutineFunc
+	arg       c.Pointer
+	status    int
+	schedlink *g          // next in the run queue
+	waitlink  *g          // next in a cond waiter list
+	waitlk    *sync.Mutex // unlocked once the goroutine is switched out
+
+	// thread local states of a goroutine: defer chain, panic and goexit flag.
+	tls [3]c.Pointer
+
+	// threads park on their own condition variable.
+	mu    sync.Mutex
+	cond  sync.Cond
+	ready bool
+}
+
+// m is a worker thread.
+type m struct {
+	g0   *ctx // scheduler context
+	curg *g
+	link *m
+}
+
+var sched struct {
+	lock     sync.Mutex
+	idle     sync.Cond // idle workers wait here
+	runqhead *g
+	runqtail *g
+	nidle    int
+	nm       int
+	ticks    uint // number of goroutines picked by workers
+	allm     *m
+	threadgs []*g
+}
+
+var (
+	mKey       pthread.Key // *m of worker threads
+	tgKey      pthread.Key // *g of other threads
+	schedStart sync.Once
+)
+
+func init() {
+	mKey.Create(nil)
+	tgKey.Create(nil)
+	sched.lock.Init(nil)
+	sched.idle.Init(nil)
+}
+
+// CreateThread starts a goroutine running routine(arg). It's called for
+// each go statement.
+func CreateThread(th *pthread.Thread, attr *pthread.Attr, routine pthread.RoutineFunc, arg c.Pointer) c.Int {
+	schedStart.Do(startWorkers)
+	gp := new(g)
+	gp.fn, gp.arg = routine, arg
+	gp.ctx = ctxNew(gostart, c.Pointer(gp))
+	gp.status = gRunnable
+	gcreated(unsafe.Pointer(gp), *(*uintptr)(unsafe.Pointer(&routine)))
+	runqput(gp)
+	return 0
+}
+
+func startWorkers() {
+	n := int(schedNprocs())
+	sched.lock.Lock()
+	for i := 0; i < n; i++ {
+		newm()
+	}
+	sched.lock.Unlock()
+	var th pthread.Thread
+	pthread.Create(&th, nil, sysmon, nil)
+}
+
+// newm starts a worker thread. It's called with sched.lock held.
+func newm() {
+	mp := new(m)
+	mp.link = sched.allm
+	sched.allm = mp
+	sched.nm++
+	recordThreadCreate()
+	var th pthread.Thread
+	pthread.Create(&th, nil, mstart, c.Pointer(mp))
+}
+
+func mstart(arg c.Pointer) c.Pointer {
+	mp := (*m)(arg)
+	mKey.Set(arg)
+	mp.g0 = ctxSelf()
+	minitSigStack()
+	for {
+		execute(mp, findRunnable())
+	}
+}
+
+// sysmon starts a new worker when no goroutine has been picked from a
+// non-empty run queue for 10ms, as all workers are then blocked or busy.
+func sysmon(arg c.Pointer) c.Pointer {
+	ticks := sched.ticks
+	for {
+		c.Usleep(10000)
+		sched.lock.Lock()
+		if sched.runqhead != nil && sched.nidle == 0 && sched.ticks == ticks && sched.nm < maxWorkers {
+			newm()
+		}
+		ticks = sched.ticks
+		sched.lock.Unlock()
+	}
+}
+
+func runqput(gp *g) {
+	sched.lock.Lock()
+	if sched.runqtail != nil {
+		sched.runqtail.schedlink = gp
+	} else {
+		sched.runqhead = gp
+	}
+	sched.runqtail = gp
+	if sched.nidle > 0 {
+		sched.idle.Signal()
+	}
+	sched.lock.Unlock()
+}
+
+func findRunnable() *g {
+	sched.lock.Lock()
+	for sched.runqhead == nil {
+		sched.nidle++
+		sched.idle.Wait(&sched.lock)
+		sched.nidle--
+	}
+	gp := sched.runqhead
+	sched.runqhead = gp.schedlink
+	if sched.runqhead == nil {
+		sched.runqtail = nil
+	}
+	gp.schedlink = nil
+	sched.ticks++
+	sched.lock.Unlock()
+	return gp
+}
+
+// execute runs gp on the current worker until it yields, parks or exits.
+func execute(mp *m, gp *g) {
+	mp.curg = gp
+	gp.status = gRunning
+	deferSet(gp.tls[0])
+	excepKey.Set(gp.tls[1])
+	goexitKey.Set(gp.tls[2])
+	ctxSwitch(mp.g0, gp.ctx)
+	gp.tls[0] = deferGet()
+	gp.tls[1] = excepKey.Get()
+	gp.tls[2] = goexitKey.Get()
+	mp.curg = nil
+	switch gp.status {
+	case gRunnable:
+		runqput(gp)
+	case gWaiting:
+		lk := gp.waitlk
+		gp.waitlk = nil
+		lk.Unlock() // gp may be running on another worker from now on
+	case gDead:
+		gexited(unsafe.Pointer(gp))
+		ctxFree(gp.ctx)
+		gp.ctx = nil
+	}
+}
+
+func gostart(arg c.Pointer) c.Pointer {
+	gp := (*g)(arg)
+	gp.fn(gp.arg)
+	gp.yield(gDead)
+	return nil
+}
+
+// yield switches the current goroutine back to the scheduler of its
+// worker, setting its status first.
+func (gp *g) yield(status int) {
+	mp := (*m)(mKey.Get())
+	gp.status = status
+	ctxSwitch(gp.ctx, mp.g0)
+}
+
+// curgKey returns the key of the current goroutine in allgs.
+func curgKey() unsafe.Pointer {
+	if mp := (*m)(mKey.Get()); mp != nil {
+		return unsafe.Pointer(mp.curg)
+	}
+	return nil
+}
+
+// gstack stores the stack of the goroutine of key into pcs and returns the
+// number of entries written, or 0 if it's running. It's called with
+// allgs.lock held, so the goroutine can't exit and free its context.
+func gstack(key unsafe.Pointer, pcs []uintptr) int {
+	return int(ctxBacktrace((*g)(key).ctx, &pcs[0], c.Int(len(pcs))))
+}
+
+// gstatus returns the status of the goroutine of key for tracebacks.
+func gstatus(key unsafe.Pointer) string {
+	switch (*g)(key).status {
+	case gRunnable:
+		return "runnable"
+	case gRunning:
+		return "running"
+	case gWaiting:
+		return "waiting"
+	}
+	return "dead"
+}
+
+// getg returns the current goroutine, or a g for the current thread if
+// it's not running a goroutine.
+func getg() *g {
+	if mp := (*m)(mKey.Get()); mp != nil && mp.curg != nil {
+		return mp.curg
+	}
+	if tg := (*g)(tgKey.Get()); tg != nil {
+		return tg
+	}
+	tg := new(g)
+	tg.mu.Init(nil)
+	tg.cond.Init(nil)
+	tgKey.Set(unsafe.Pointer(tg))
+	sched.lock.Lock()
+	sched.threadgs = append(sched.threadgs, tg) // keep it alive
+	sched.lock.Unlock()
+	return tg
+}
+
+// park blocks gp, the current goroutine or thread, until ready(gp) is
+// called. lk, which guards the list gp is waiting in, is unlocked once gp
+// can be made ready.
+func park(gp *g, lk *sync.Mutex) {
+	if gp.ctx == nil {
+		gp.mu.Lock()
+		lk.Unlock()
+		for !gp.ready {
+			gp.cond.Wait(&gp.mu)
+		}
+		gp.ready = false
+		gp.mu.Unlock()
+		return
+	}
+	gp.waitlk = lk
+	gp.yield(gWaiting)
+}
+
+func ready(gp *g) {
+	if gp.ctx == nil {
+		gp.mu.Lock()
+		gp.ready = true
+		gp.cond.Signal()
+		gp.mu.Unlock()
+		return
+	}
+	gp.status = gRunnable
+	runqput(gp)
+}
+
+// Gosched yields the processor, allowing other goroutines to run.
+func Gosched() {
+	if mp := (*m)(mKey.Get()); mp != nil && mp.curg != nil {
+		mp.curg.yield(gRunnable)
+	} else {
+		schedYield()
+	}
+}
+
+// goexit0 terminates the current goroutine after Goexit ran its deferred
+// calls.
+func goexit0() {
+	if mp := (*m)(mKey.Get()); mp != nil && mp.curg != nil {
+		mp.curg.yield(gDead)
+	}
+	if pthread.Equal(mainThread, pthread.Self()) != 0 {
+		fatal("no goroutines (main called runtime.Goexit) - deadlock!")
+		c.Exit(2)
+	}
+	pthread.Exit(nil)
+}
+
+// -----------------------------------------------------------------------------
+
+// cond is a condition variable which parks waiting goroutines instead of
+// blocking their workers. Its mutex guards short critical sections, so it
+// remains a thread mutex.
+type cond struct {
+	lk       sync.Mutex
+	waithead *g
+	waittail *g
+}
+
+func (p *cond) Init(attr *sync.CondAttr) c.Int {
+	p.lk.Init(nil)
+	return 0
+}
+
+func (p *cond) Destroy() {
+	p.lk.Destroy()
+}
+
+func (p *cond) Wait(m *sync.Mutex) c.Int {
+	gp := getg()
+	p.lk.Lock()
+	if p.waittail != nil {
+		p.waittail.waitlink = gp
+	} else {
+		p.waithead = gp
+	}
+	p.waittail = gp
+	m.Unlock()
+	park(gp, &p.lk)
+	m.Lock()
+	return 0
+}
+
+func (p *cond) Signal() c.Int {
+	p.lk.Lock()
+	gp := p.waithead
+	if gp != nil {
+		p.waithead = gp.waitlink
+		if p.waithead == nil {
+			p.waittail = nil
+		}
+		gp.waitlink = nil
+	}
+	p.lk.Unlock()
+	if gp != nil {
+		ready(gp)
+	}
+	return 0
+}
+
+func (p *cond) Broadcast() c.Int {
+	p.lk.Lock()
+	gp := p.waithead
+	p.waithead, p.waittail = nil, nil
+	p.lk.Unlock()
+	for gp != nil {
+		next := gp.waitlink
+		gp.waitlink = nil
+		ready(gp)
+		gp = next
+	}
+	return 0
+}
+
+// -----------------------------------------------------------------------------
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2025 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+	"github.com/goplus/llgo/runtime/internal/clite/sync/atomic"
+)
+
+// -----------------------------------------------------------------------------
+// Semaphores
+//
+// Semaphores are the blocking primitive of the sync and internal/poll
+// packages, like those of gc. A goroutine waiting for a semaphore parks
+// on cond, so it doesn't block its worker. The semaphores are hashed by
+// address to semaRoots, whose waiters are all woken up to recheck theirs.
+
+type semaRoot struct {
+	lock  sync.Mutex
+	cond  cond
+	nwait uint32 // number of waiters, read without lock
+}
+
+const semTabSize = 251
+
+var semtable [semTabSize]semaRoot
+
+func init() {
+	for i := range semtable {
+		semtable[i].lock.Init(nil)
+		semtable[i].cond.Init(nil)
+	}
+}
+
+func semroot(addr *uint32) *semaRoot {
+	return &semtable[(uintptr(unsafe.Pointer(addr))>>3)%semTabSize]
+}
+
+func cansemacquire(addr *uint32) bool {
+	for {
+		v := atomic.Load(addr)
+		if v == 0 {
+			return false
+		}
+		if _, ok := atomic.CompareAndExchange(addr, v, v-1); ok {
+			return true
+		}
+	}
+}
+
+// Semacquire waits until *addr > 0 and then decrements it.
+func Semacquire(addr *uint32) {
+	if cansemacquire(addr) {
+		return
+	}
+	root := semroot(addr)
+	root.lock.Lock()
+	atomic.Add(&root.nwait, 1)
+	for !cansemacquire(addr) {
+		root.cond.Wait(&root.lock)
+	}
+	atomic.Sub(&root.nwait, 1)
+	root.lock.Unlock()
+}
+
+// Semrelease increments *addr and wakes up the goroutines waiting for it.
+func Semrelease(addr *uint32) {
+	atomic.Add(addr, 1)
+	root := semroot(addr)
+	if atomic.Load(&root.nwait) == 0 {
+		return
+	}
+	root.lock.Lock()
+	root.cond.Broadcast()
+	root.lock.Unlock()
+}
+
+// -----------------------------------------------------------------------------
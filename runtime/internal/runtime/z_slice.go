@@ -49,22 +49,41 @@ func NewSlice3(base unsafe.Pointer, eltSize, cap, i, j, k int) (s Slice) {
 
 // SliceAppend append elem data and returns a slice.
 func SliceAppend(src Slice, data unsafe.Pointer, num, etSize int) Slice {
+	return sliceAppend(src, data, num, etSize, false)
+}
+
+// SliceAppendNoscan is like SliceAppend, for elements that don't hold
+// pointers.
+func SliceAppendNoscan(src Slice, data unsafe.Pointer, num, etSize int) Slice {
+	return sliceAppend(src, data, num, etSize, true)
+}
+
+func sliceAppend(src Slice, data unsafe.Pointer, num, etSize int, noscan bool) Slice {
 	if etSize == 0 {
 		return src
 	}
 	oldLen := src.len
-	src = GrowSlice(src, num, etSize)
+	src = growSlice(src, num, etSize, noscan)
 	c.Memcpy(c.Advance(src.data, oldLen*etSize), data, uintptr(num*etSize))
 	return src
 }
 
 // GrowSlice grows slice and returns the grown slice.
 func GrowSlice(src Slice, num, etSize int) Slice {
+	return growSlice(src, num, etSize, false)
+}
+
+func growSlice(src Slice, num, etSize int, noscan bool) Slice {
 	oldLen := src.len
 	newLen := oldLen + num
 	if newLen > src.cap {
 		newCap := nextslicecap(newLen, src.cap)
-		p := AllocZ(uintptr(newCap * etSize))
+		var p unsafe.Pointer
+		if noscan {
+			p = AllocZNoscan(uintptr(newCap * etSize))
+		} else {
+			p = AllocZ(uintptr(newCap * etSize))
+		}
 		if oldLen != 0 {
 			c.Memcpy(p, src.data, uintptr(oldLen*etSize))
 		}
@@ -123,6 +142,15 @@ func SliceCopy(dst Slice, data unsafe.Pointer, num int, etSize int) int {
 }
 
 func MakeSlice(len, cap int, etSize int) Slice {
+	return Slice{AllocZ(makeSliceSize(len, cap, etSize)), len, cap}
+}
+
+// MakeSliceNoscan is like MakeSlice, for elements that don't hold pointers.
+func MakeSliceNoscan(len, cap int, etSize int) Slice {
+	return Slice{AllocZNoscan(makeSliceSize(len, cap, etSize)), len, cap}
+}
+
+func makeSliceSize(len, cap int, etSize int) uintptr {
 	mem, overflow := math.MulUintptr(uintptr(etSize), uintptr(cap))
 	if overflow || mem > maxAlloc || len < 0 || len > cap {
 		mem, overflow := math.MulUintptr(uintptr(etSize), uintptr(len))
@@ -131,7 +159,7 @@ func MakeSlice(len, cap int, etSize int) Slice {
 		}
 		panicmakeslicecap()
 	}
-	return Slice{AllocZ(mem), len, cap}
+	return mem
 }
 
 func panicmakeslicelen() {
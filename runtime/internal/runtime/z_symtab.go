@@ -0,0 +1,226 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
+)
+
+// -----------------------------------------------------------------------------
+// Go symbol table
+//
+// The compiler emits a FuncInfo for each Go function and a pcRecord before
+// each call in it (see ssa/pcln.go), which the linker gathers in the
+// __llgo_functab and __llgo_pcln sections (see _wrap/symtab.c). They are
+// sorted the first time they are used.
+
+// _wrap/symtab.c is listed in LLGoFiles of z_sched_gc.go, z_sched_nogc.go
+// and z_thread.go.
+
+// FuncInfo describes a Go function. Its layout matches the func infos
+// emitted by the compiler.
+type FuncInfo struct {
+	name      *c.Char
+	file      *c.Char
+	startLine int32
+	entry     uintptr
+}
+
+// Name returns the package path-qualified name of the function.
+func (f *FuncInfo) Name() string {
+	return c.GoString(f.name)
+}
+
+// File returns the file name of the function.
+func (f *FuncInfo) File() string {
+	return c.GoString(f.file)
+}
+
+// StartLine returns the line of the func keyword of the function.
+func (f *FuncInfo) StartLine() int {
+	return int(f.startLine)
+}
+
+// Entry returns the entry address of the function.
+func (f *FuncInfo) Entry() uintptr {
+	return f.entry
+}
+
+// pcRecord records that the call at pc is at line of fn. fn is not the
+// function containing pc if the call was inlined into it.
+type pcRecord struct {
+	pc   uintptr
+	fn   *FuncInfo
+	line uintptr
+}
+
+//go:linkname c_symtab C.llgo_symtab
+func c_symtab(functab, efunctab, pcln, epcln *unsafe.Pointer)
+
+//go:linkname c_funcEntry C.llgo_func_entry
+func c_funcEntry(pc uintptr) uintptr
+
+var (
+	symtabOnce sync.Once
+	funcs      []*FuncInfo // sorted by entry
+	pclns      []pcRecord  // sorted by pc
+)
+
+func initSymtab() {
+	var functab, efunctab, pcln, epcln unsafe.Pointer
+	c_symtab(&functab, &efunctab, &pcln, &epcln)
+	if functab == nil || pcln == nil {
+		return
+	}
+	nfunc := (uintptr(efunctab) - uintptr(functab)) / unsafe.Sizeof(FuncInfo{})
+	ftab := unsafe.Slice((*FuncInfo)(functab), nfunc)
+	funcs = make([]*FuncInfo, nfunc)
+	for i := range ftab {
+		funcs[i] = &ftab[i]
+	}
+	sortIfNeeded(len(funcs), func(i, j int) bool {
+		return funcs[i].entry < funcs[j].entry
+	}, func(i, j int) {
+		funcs[i], funcs[j] = funcs[j], funcs[i]
+	})
+
+	// The records are sorted in place. They are in a writable section, and
+	// nothing else refers to them.
+	npcln := (uintptr(epcln) - uintptr(pcln)) / unsafe.Sizeof(pcRecord{})
+	pclns = unsafe.Slice((*pcRecord)(pcln), npcln)
+	sortIfNeeded(len(pclns), func(i, j int) bool {
+		return pclns[i].pc < pclns[j].pc
+	}, func(i, j int) {
+		pclns[i], pclns[j] = pclns[j], pclns[i]
+	})
+}
+
+// sortIfNeeded heapsorts n elements unless they are sorted already, as
+// they usually are since the linker lays out the tables in code order.
+func sortIfNeeded(n int, less func(i, j int) bool, swap func(i, j int)) {
+	sorted := true
+	for i := 1; i < n; i++ {
+		if less(i, i-1) {
+			sorted = false
+			break
+		}
+	}
+	if sorted {
+		return
+	}
+	siftDown := func(root, hi int) {
+		for {
+			child := 2*root + 1
+			if child >= hi {
+				return
+			}
+			if child+1 < hi && less(child, child+1) {
+				child++
+			}
+			if !less(root, child) {
+				return
+			}
+			swap(root, child)
+			root = child
+		}
+	}
+	for i := (n - 1) / 2; i >= 0; i-- {
+		siftDown(i, n)
+	}
+	for i := n - 1; i >= 0; i-- {
+		swap(0, i)
+		siftDown(0, i)
+	}
+}
+
+// searchPC returns the index of the last element whose address, as
+// returned by addr, is at most pc, or -1 if there is none.
+func searchPC(n int, addr func(i int) uintptr, pc uintptr) int {
+	lo, hi := 0, n
+	for lo < hi {
+		h := int(uint(lo+hi) >> 1)
+		if addr(h) <= pc {
+			lo = h + 1
+		} else {
+			hi = h
+		}
+	}
+	return lo - 1
+}
+
+// FindFunc returns the Go function containing pc, or nil if pc is not in
+// Go code.
+func FindFunc(pc uintptr) *FuncInfo {
+	symtabOnce.Do(initSymtab)
+	i := searchPC(len(funcs), func(i int) uintptr { return funcs[i].entry }, pc)
+	if i < 0 {
+		return nil
+	}
+	f := funcs[i]
+	// pc follows f, but it may be in C code laid out after f. The unwinder
+	// knows where functions start, if they have unwind information.
+	if entry := c_funcEntry(pc); entry != 0 && entry != f.entry {
+		return nil
+	}
+	return f
+}
+
+// PCFrame is a frame of a Go function at a pc.
+type PCFrame struct {
+	Func *FuncInfo
+	Line int
+}
+
+// PCFrames appends the frames at pc, the address of a call, to frames and
+// returns them. They are the functions inlined at pc, innermost first,
+// followed by the function f containing pc.
+func PCFrames(frames []PCFrame, f *FuncInfo, pc uintptr) []PCFrame {
+	addr := func(i int) uintptr { return pclns[i].pc }
+	last := searchPC(len(pclns), addr, pc)
+	first := searchPC(len(pclns), addr, f.entry-1) + 1
+
+	// The record of a call inlined into f is preceded by the records of the
+	// calls it was inlined at. Replay the records of f up to pc to find the
+	// callers of the innermost function.
+	start := len(frames)
+	frames = append(frames, PCFrame{f, f.StartLine()})
+	for i := first; i <= last; i++ {
+		r := &pclns[i]
+		k := len(frames) - 1
+		for k > start && frames[k].Func != r.fn {
+			k--
+		}
+		if frames[k].Func == r.fn {
+			frames = frames[:k+1] // returned from the inlined functions above
+		} else {
+			frames = append(frames, PCFrame{r.fn, 0}) // entered an inlined function
+			k = len(frames) - 1
+		}
+		frames[k].Line = int(r.line)
+	}
+
+	// Innermost first.
+	for i, j := start, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return frames
+}
+
+// -----------------------------------------------------------------------------
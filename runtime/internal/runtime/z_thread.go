@@ -1,3 +1,6 @@
+//go:build nosched || !(linux || darwin)
+// +build nosched !linux,!darwin
+
 /*
  * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
  *
@@ -17,12 +20,96 @@
 package runtime
 
 import (
-	_ "unsafe"
+	"unsafe"
 
 	c "github.com/goplus/llgo/runtime/internal/clite"
 	"github.com/goplus/llgo/runtime/internal/clite/pthread"
+	"github.com/goplus/llgo/runtime/internal/clite/pthread/sync"
 )
 
+// Each goroutine runs on its own thread, see z_sched.go for the scheduler.
+
+const LLGoFiles = "_wrap/symtab.c; _wrap/fault.c; _wrap/stack.c; _wrap/sanitize.c"
+
+//go:linkname defaultStackSize C.llgo_default_stack_size
+func defaultStackSize() uintptr
+
+// threadg is a goroutine running on its own thread.
+type threadg struct {
+	fn  pthread.RoutineFunc
+	arg c.Pointer
+}
+
+var curgTLS pthread.Key // *threadg of the current thread
+
+func init() {
+	curgTLS.Create(nil)
+}
+
 func CreateThread(th *pthread.Thread, attr *pthread.Attr, routine pthread.RoutineFunc, arg c.Pointer) c.Int {
-	return pthread.Create(th, attr, routine, arg)
+	gp := &threadg{routine, arg}
+	recordThreadCreate()
+	gcreated(unsafe.Pointer(gp), *(*uintptr)(unsafe.Pointer(&routine)))
+	if size := defaultStackSize(); attr == nil && size != 0 {
+		var stackAttr pthread.Attr
+		stackAttr.Init()
+		stackAttr.SetStackSize(size)
+		defer stackAttr.Destroy()
+		attr = &stackAttr
+	}
+	if raceenabled {
+		racerelease(unsafe.Pointer(gp))
+	}
+	ret := pthread.Create(th, attr, threadStart, unsafe.Pointer(gp))
+	if ret != 0 {
+		gexited(unsafe.Pointer(gp))
+	}
+	return ret
+}
+
+func threadStart(arg c.Pointer) c.Pointer {
+	gp := (*threadg)(arg)
+	if raceenabled {
+		raceacquire(arg)
+	}
+	curgTLS.Set(arg)
+	minitSigStack()
+	ret := gp.fn(gp.arg)
+	gexited(arg)
+	return ret
+}
+
+// curgKey returns the key of the current goroutine in allgs.
+func curgKey() unsafe.Pointer {
+	return curgTLS.Get()
+}
+
+// gstack returns 0 as the goroutine of key is running on its own thread,
+// whose stack can't be unwound from another one.
+func gstack(key unsafe.Pointer, pcs []uintptr) int {
+	return 0
+}
+
+// gstatus returns the status of the goroutine of key for tracebacks. It's
+// running on its own thread, as far as the runtime knows.
+func gstatus(key unsafe.Pointer) string {
+	return "running"
+}
+
+type cond = sync.Cond
+
+// Gosched yields the processor, allowing other goroutines to run.
+func Gosched() {
+	schedYield()
+}
+
+// goexit0 terminates the current goroutine after Goexit ran its deferred
+// calls.
+func goexit0() {
+	if pthread.Equal(mainThread, pthread.Self()) != 0 {
+		fatal("no goroutines (main called runtime.Goexit) - deadlock!")
+		c.Exit(2)
+	}
+	gexited(curgTLS.Get())
+	pthread.Exit(nil)
 }
@@ -0,0 +1,444 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"unsafe"
+
+	c "github.com/goplus/llgo/runtime/internal/clite"
+	"github.com/goplus/llgo/runtime/internal/clite/debug"
+)
+
+// -----------------------------------------------------------------------------
+// Tracebacks of fatal panics and signals
+//
+// The stack of a panic is only unwound once it turns fatal, as most panics
+// are recovered: it's then the stack where the panic was raised if no
+// deferred calls ran for it, or else the one of the last function whose
+// deferred calls ran, as the frames above it are gone. It's printed with
+// the Go symbol table (see z_symtab.go), as GOTRACEBACK or
+// debug.SetTraceback tells:
+//
+//	none    no goroutine tracebacks
+//	single  the panicking goroutine (the default)
+//	all     all goroutines
+//	system  all goroutines, including the frames of the runtime
+//	crash   like system, then abort to dump core
+//
+// Only the current goroutine can be unwound: for the others the header and
+// the go statement which created them are printed.
+
+// _wrap/fault.c is listed in LLGoFiles of z_sched_gc.go, z_sched_nogc.go
+// and z_thread.go.
+
+// maxPanicStack is the depth of the stacks printed by tracebacks.
+const maxPanicStack = 100
+
+// sigStackSize is the size of the alternate signal stacks, on which the
+// fault handler runs and may print tracebacks.
+const sigStackSize = 64 << 10
+
+// panicRecord is a panic in progress, stored in excepKey. It's allocated
+// with c.Malloc and v comes first, so it's read as an any.
+type panicRecord struct {
+	v   any
+	sig c.Int // signal raising the panic, or 0
+	sigInfo
+}
+
+// sigInfo describes a fault signal.
+type sigInfo struct {
+	code c.Int
+	addr uintptr // faulting address
+	pc   uintptr // faulting instruction
+}
+
+// newPanic returns a panic of v.
+func newPanic(v any) *panicRecord {
+	p := (*panicRecord)(c.Malloc(unsafe.Sizeof(panicRecord{})))
+	c.Memset(unsafe.Pointer(p), 0, unsafe.Sizeof(panicRecord{}))
+	p.v = v
+	return p
+}
+
+const (
+	tracebackCrash = 1 << iota
+	tracebackAll
+	tracebackShift = iota
+)
+
+// tracebackCache holds the traceback settings, as in gc: the level shifted
+// by tracebackShift, or'ed with tracebackAll and tracebackCrash.
+// tracebackEnv is the setting of GOTRACEBACK, which debug.SetTraceback
+// can't go below.
+var tracebackCache, tracebackEnv uint32 = 1 << tracebackShift, 1 << tracebackShift
+
+//go:linkname cGetenv C.getenv
+func cGetenv(name *c.Char) *c.Char
+
+//go:linkname cAbort C.abort
+func cAbort()
+
+func init() {
+	var level string
+	if s := cGetenv(c.Str("GOTRACEBACK")); s != nil {
+		level = c.GoString(s)
+	}
+	SetTraceback(level)
+	tracebackEnv = tracebackCache
+	faultInit(sigpanic)
+	minitSigStack()
+}
+
+// SetTraceback sets the amount of detail printed by the traceback of a
+// fatal panic or signal, see runtime/debug.SetTraceback.
+func SetTraceback(level string) {
+	var t uint32
+	switch level {
+	case "none":
+		t = 0
+	case "single", "":
+		t = 1 << tracebackShift
+	case "all":
+		t = 1<<tracebackShift | tracebackAll
+	case "system":
+		t = 2<<tracebackShift | tracebackAll
+	case "crash":
+		t = 2<<tracebackShift | tracebackAll | tracebackCrash
+	default:
+		t = tracebackAll
+		if n, ok := atoi(level); ok && n == int(uint32(n)) {
+			t |= uint32(n) << tracebackShift
+		}
+	}
+	tracebackCache = t | tracebackEnv
+}
+
+// gotraceback returns the current traceback settings: the level, whether
+// to print all goroutines and whether to crash after printing.
+func gotraceback() (level int32, all, crash bool) {
+	t := tracebackCache
+	return int32(t >> tracebackShift), t&tracebackAll != 0, t&tracebackCrash != 0
+}
+
+func atoi(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' || n > 1<<30 {
+			return 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+	}
+	return n, true
+}
+
+// fatalpanic prints p, which wasn't recovered, and the goroutine tracebacks,
+// then exits. A panic raised by a fault may still be in its handler.
+func fatalpanic(p *panicRecord) {
+	print("panic: ")
+	printany(p.v)
+	if p.sig != 0 {
+		print("\n")
+		printSignal(p.sig, &p.sigInfo)
+	}
+	print("\n")
+	var pcs [maxPanicStack]uintptr
+	n := debug.Backtrace(1, pcs[:])
+	dotraceback(pcs[:n], false, p.sig != 0)
+}
+
+// dotraceback prints the tracebacks of the current goroutine, whose stack is
+// pcs, and of the others, as gotraceback tells, then exits. They are all
+// printed for a fatal error. insig tells it may run in the handler of a
+// fault.
+func dotraceback(pcs []uintptr, fatal, insig bool) {
+	level, all, crash := gotraceback()
+	if fatal && level < 1 {
+		level = 1
+	}
+	if level > 0 {
+		self, others, ok := snapshotGoroutines(all || fatal, insig)
+		var b []byte
+		b = append(b, '\n')
+		b = appendGoroutine(b, self, "running", pcs, level >= 2)
+		if !ok {
+			b = append(b, "\n...other goroutines unavailable, the runtime is locked\n"...)
+		}
+		b = appendOtherGoroutines(b, others)
+		print(string(b))
+	}
+	if crash {
+		cAbort()
+	}
+	c.Exit(2)
+}
+
+// Stack appends the traceback of the current goroutine, from the caller of
+// the caller of Stack on, to b and returns it. If all is true, the other
+// goroutines follow.
+func Stack(b []byte, all bool) []byte {
+	var pcs [maxPanicStack]uintptr
+	n := debug.Backtrace(2, pcs[:])
+	level, _, _ := gotraceback()
+	self, others, _ := snapshotGoroutines(all, false)
+	b = appendGoroutine(b, self, "running", pcs[:n], level >= 2)
+	return appendOtherGoroutines(b, others)
+}
+
+// appendGoroutine appends the traceback of the goroutine of r to b. Its
+// stack is pcs, or unknown if pcs is nil.
+func appendGoroutine(b []byte, r gRecord, status string, pcs []uintptr, system bool) []byte {
+	b = append(b, "goroutine "...)
+	b = appendIntStr(b, r.id, false)
+	b = append(b, " ["...)
+	b = append(b, status...)
+	b = append(b, "]:\n"...)
+	if pcs == nil {
+		if status == "running" {
+			b = append(b, "\tgoroutine running on other thread; stack unavailable\n"...)
+		}
+	} else {
+		b = appendTraceback(b, pcs, system)
+		if len(pcs) == maxPanicStack {
+			b = append(b, "...additional frames elided...\n"...)
+		}
+	}
+	if r.createdBy == 0 {
+		return b
+	}
+	var buf [8]PCFrame
+	pc := r.createdBy - 1
+	if f := FindFunc(pc); f != nil {
+		frames := PCFrames(buf[:0], f, pc)
+		fr := frames[0]
+		b = append(b, "created by "...)
+		b = append(b, fr.Func.Name()...)
+		b = append(b, " in goroutine "...)
+		b = appendIntStr(b, r.parent, false)
+		b = append(b, "\n\t"...)
+		b = append(b, fr.Func.File()...)
+		b = append(b, ':')
+		b = appendIntStr(b, int64(fr.Line), false)
+		b = append(b, " +0x"...)
+		b = appendHex(b, uint64(r.createdBy-f.entry))
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// otherg is a goroutine but the current one, for tracebacks.
+type otherg struct {
+	key unsafe.Pointer // nil for the main goroutine
+	r   gRecord
+}
+
+// snapshotGoroutines returns the record of the current goroutine and, if
+// all is true, of the others in the order they were created. If insig is
+// true, it may run in the handler of a fault, which may have interrupted
+// the thread holding allgs.lock: the lock is only tried then, and ok is
+// false if it's held.
+func snapshotGoroutines(all, insig bool) (self gRecord, others []otherg, ok bool) {
+	self = gRecord{id: 1}
+	key := curgKey()
+	if insig {
+		if allgs.lock.TryLock() != 0 {
+			return
+		}
+	} else {
+		allgs.lock.Lock()
+	}
+	if r, found := allgs.m[key]; found && key != nil {
+		self = r
+	}
+	if all {
+		if key != nil {
+			others = append(others, otherg{nil, gRecord{id: 1}})
+		}
+		for k, r := range allgs.m {
+			if k != key {
+				others = append(others, otherg{k, r})
+			}
+		}
+	}
+	allgs.lock.Unlock()
+	for i := 1; i < len(others); i++ {
+		for j := i; j > 0 && others[j].r.id < others[j-1].r.id; j-- {
+			others[j], others[j-1] = others[j-1], others[j]
+		}
+	}
+	return self, others, true
+}
+
+// appendOtherGoroutines appends the headers of others to b.
+func appendOtherGoroutines(b []byte, others []otherg) []byte {
+	for _, o := range others {
+		status := "running"
+		if o.key != nil {
+			status = gstatus(o.key)
+		}
+		b = append(b, '\n')
+		b = appendGoroutine(b, o.r, status, nil, false)
+	}
+	return b
+}
+
+// appendTraceback appends the frames of the Go functions at the return
+// addresses pcs to b, in the format of gc tracebacks:
+//
+//	main.f(...)
+//		/path/to/main.go:12 +0x1d
+//
+// The frames of the runtime are left out unless system is true.
+func appendTraceback(b []byte, pcs []uintptr, system bool) []byte {
+	var buf [8]PCFrame
+	for _, pc := range pcs {
+		pc-- // the call before the return address
+		f := FindFunc(pc)
+		if f == nil {
+			continue
+		}
+		frames := PCFrames(buf[:0], f, pc)
+		for i, fr := range frames {
+			name := fr.Func.Name()
+			if !system && !showFrame(name) {
+				continue
+			}
+			b = append(b, name...)
+			b = append(b, "(...)\n\t"...)
+			b = append(b, fr.Func.File()...)
+			b = append(b, ':')
+			b = appendIntStr(b, int64(fr.Line), false)
+			if i == len(frames)-1 {
+				b = append(b, " +0x"...)
+				b = appendHex(b, uint64(pc+1-f.entry))
+			}
+			b = append(b, '\n')
+		}
+	}
+	return b
+}
+
+// rtPrefix is the prefix of the functions of this package.
+const rtPrefix = "github.com/goplus/llgo/runtime/internal/runtime."
+
+// showFrame reports whether the frame of the function name is shown in
+// tracebacks. Like gc, it hides the runtime, except its exported functions.
+func showFrame(name string) bool {
+	if hasPrefix(name, rtPrefix) {
+		return false
+	}
+	const prefix = "runtime."
+	if hasPrefix(name, prefix) {
+		return len(name) > len(prefix) && 'A' <= name[len(prefix)] && name[len(prefix)] <= 'Z'
+	}
+	return true
+}
+
+func appendHex(b []byte, v uint64) []byte {
+	const digits = "0123456789abcdef"
+	var buf [16]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = digits[v&0xf]
+		if v >>= 4; v == 0 {
+			break
+		}
+	}
+	return append(b, buf[i:]...)
+}
+
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+// -----------------------------------------------------------------------------
+
+const sigSEGV = 11 // the same on all Unix systems, unlike SIGBUS
+
+//llgo:type C
+type faultHandler func(sig, code c.Int, addr, pc uintptr)
+
+//go:linkname faultInit C.llgo_fault_init
+func faultInit(fn faultHandler)
+
+// sigpanic is called by the handler of SIGSEGV and SIGBUS. A fault in Go
+// code at a low address is a nil pointer dereference, which panics as
+// Panicmem does. Other faults are fatal.
+func sigpanic(sig, code c.Int, addr, pc uintptr) {
+	info := sigInfo{code, addr, pc}
+	inGo := FindFunc(pc) != nil
+	if inGo && addr < 0x1000 {
+		p := newPanic(memoryError)
+		p.sig = sig
+		p.sigInfo = info
+		excepKey.Set(unsafe.Pointer(p))
+		Rethrow((*Defer)(c.GoDeferData()))
+		return
+	}
+	if inGo {
+		print("unexpected fault address 0x")
+		print(string(appendHex(nil, uint64(addr))), "\n")
+		print("fatal error: fault\n")
+	} else {
+		print("fatal error: unexpected signal during runtime execution\n")
+	}
+	printSignal(sig, &info)
+	var pcs [maxPanicStack]uintptr
+	n := debug.Backtrace(0, pcs[:])
+	dotraceback(pcs[:n], true, true)
+}
+
+//go:linkname faultAltstack C.llgo_fault_altstack
+func faultAltstack(size c.Ulong) unsafe.Pointer
+
+// minitSigStack installs the alternate signal stack of the current thread,
+// which runs goroutines, so that the fault handler runs when one overflows
+// its stack.
+func minitSigStack() {
+	if stk := faultAltstack(sigStackSize); stk != nil {
+		registerSigStack(stk, sigStackSize)
+	}
+}
+
+// printSignal prints sig and info as gc does:
+//
+//	[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x1234]
+func printSignal(sig c.Int, info *sigInfo) {
+	b := append([]byte(nil), "[signal "...)
+	b = append(b, sigName(sig)...)
+	b = append(b, " code=0x"...)
+	b = appendHex(b, uint64(uint32(info.code)))
+	b = append(b, " addr=0x"...)
+	b = appendHex(b, uint64(info.addr))
+	b = append(b, " pc=0x"...)
+	b = appendHex(b, uint64(info.pc))
+	b = append(b, "]\n"...)
+	print(string(b))
+}
+
+func sigName(sig c.Int) string {
+	if sig == sigSEGV {
+		return "SIGSEGV: segmentation violation"
+	}
+	return "SIGBUS: bus error"
+}
+
+// -----------------------------------------------------------------------------
@@ -334,6 +334,11 @@ func ChanOf(dir int, strChan string, elem *Type) *Type {
 	if t := rtypeList.findElem(abi.Chan, elem, uintptr(dir)); t != nil {
 		return t
 	}
+	str := strChan + " " + elem.String()
+	if abi.ChanDir(dir) == abi.BothDir && elem.Kind() == abi.Chan && elem.ChanDir() == abi.RecvDir {
+		// "<-" associates with the leftmost chan possible
+		str = strChan + " (" + elem.String() + ")"
+	}
 	ret := &abi.ChanType{
 		Type: Type{
 			Size_:       pointerSize,
@@ -344,7 +349,7 @@ func ChanOf(dir int, strChan string, elem *Type) *Type {
 			FieldAlign_: pointerAlign,
 			Kind_:       uint8(abi.Chan),
 			Equal:       memequalptr,
-			Str_:        strChan + " " + elem.String(),
+			Str_:        str,
 		},
 		Elem: elem,
 		Dir:  abi.ChanDir(dir),
@@ -419,7 +419,11 @@ func (b Builder) MakeSlice(t Type, len, cap Expr) (ret Expr) {
 	len = b.fitIntSize(len)
 	cap = b.fitIntSize(cap)
 	telem := prog.Index(t)
-	ret = b.InlineCall(b.Pkg.rtFunc("MakeSlice"), len, cap, prog.IntVal(prog.SizeOf(telem), prog.Int()))
+	fn := "MakeSlice"
+	if prog.noscan(telem) {
+		fn = "MakeSliceNoscan"
+	}
+	ret = b.InlineCall(b.Pkg.rtFunc(fn), len, cap, prog.IntVal(prog.SizeOf(telem), prog.Int()))
 	ret.Type = t
 	return
 }
@@ -188,6 +188,7 @@ type aFunction struct {
 	hasVArg  bool
 
 	diFunc DIFunction
+	pcfi   llvm.Value // func info of PC-line tables, see InitPCLine
 }
 
 // Function represents a function or method.
@@ -294,7 +295,7 @@ func (p Function) NewBuilder() Builder {
 	// TODO(xsw): Finalize may cause panic, so comment it.
 	// b.Finalize()
 	return &aBuilder{b, nil, p, p.Pkg, prog,
-		make(map[Expr]dbgExpr), make(map[*types.Scope]DIScope)}
+		make(map[Expr]dbgExpr), make(map[*types.Scope]DIScope), 0}
 }
 
 // HasBody reports whether the function has a body.
@@ -317,6 +318,10 @@ func (p Function) MakeBlocks(nblk int) []BasicBlock {
 	n := len(p.blks)
 	if n == 0 {
 		p.blks = make([]BasicBlock, 0, nblk)
+		ctx := p.Pkg.mod.Context()
+		for _, attr := range p.Prog.sanitizeAttrs {
+			p.impl.AddFunctionAttr(ctx.CreateEnumAttribute(llvm.AttributeKindID(attr), 0))
+		}
 	}
 	for i := 0; i < nblk; i++ {
 		p.addBlock(n + i)
@@ -961,6 +961,24 @@ func (b Builder) MakeClosure(fn Expr, bindings []Expr) Expr {
 	return b.aggregateValue(prog.Closure(removeCtx(sig)), fn.impl, data)
 }
 
+// MakeStackClosure is like MakeClosure, but for a closure that doesn't
+// outlive the call of the function, whose context is allocated in its stack
+// frame, see AllocStack.
+func (b Builder) MakeStackClosure(fn Expr, bindings []Expr) Expr {
+	if debugInstr {
+		log.Printf("MakeStackClosure %v, %v\n", fn, bindings)
+	}
+	prog := b.Prog
+	tfn := fn.Type
+	sig := tfn.raw.Type.(*types.Signature)
+	tctx := sig.Params().At(0).Type().Underlying().(*types.Pointer).Elem().(*types.Struct)
+	flds := llvmFields(bindings, tctx, b)
+	t := prog.rawType(tctx)
+	data := b.entryAlloca(t)
+	aggregateInit(b.impl, data, t.ll, flds...)
+	return b.aggregateValue(prog.Closure(removeCtx(sig)), fn.impl, data)
+}
+
 func removeCtx(sig *types.Signature) *types.Signature {
 	params := sig.Params()
 	n := params.Len()
@@ -1020,7 +1038,9 @@ func (b Builder) Call(fn Expr, args ...Expr) (ret Expr) {
 		log.Panicf("unreachable: %d(%T), %v\n", kind, raw, fn.RawType())
 	}
 	ret.Type = b.Prog.retType(sig)
-	ret.impl = llvm.CreateCall(b.impl, ll, fn.impl, llvmParamsEx(data, args, sig.Params(), b))
+	params := llvmParamsEx(data, args, sig.Params(), b)
+	b.pcRecord(fn.impl)
+	ret.impl = llvm.CreateCall(b.impl, ll, fn.impl, params)
 	return
 }
 
@@ -1145,24 +1165,28 @@ func (b Builder) BuiltinCall(fn string, args ...Expr) (ret Expr) {
 		if len(args) == 2 {
 			src := args[0]
 			if src.kind == vkSlice {
+				appendFn := "SliceAppend"
+				if b.Prog.noscan(b.Prog.Index(src.Type)) {
+					appendFn = "SliceAppendNoscan"
+				}
 				elem := args[1]
 				switch elem.kind {
 				case vkSlice:
 					etSize := b.Prog.SizeOf(b.Prog.Elem(elem.Type))
 					ret.Type = src.Type
-					ret.impl = b.InlineCall(b.Pkg.rtFunc("SliceAppend"),
+					ret.impl = b.InlineCall(b.Pkg.rtFunc(appendFn),
 						src, b.SliceData(elem), b.SliceLen(elem), b.Prog.Val(int(etSize))).impl
 					return
 				case vkString:
 					etSize := b.Prog.SizeOf(b.Prog.Byte())
 					ret.Type = src.Type
-					ret.impl = b.InlineCall(b.Pkg.rtFunc("SliceAppend"),
+					ret.impl = b.InlineCall(b.Pkg.rtFunc(appendFn),
 						src, b.StringData(elem), b.StringLen(elem), b.Prog.Val(int(etSize))).impl
 					return
 				default:
 					etSize := b.Prog.SizeOf(elem.Type)
 					ret.Type = src.Type
-					ret.impl = b.InlineCall(b.Pkg.rtFunc("SliceAppend"),
+					ret.impl = b.InlineCall(b.Pkg.rtFunc(appendFn),
 						src, elem, b.Const(constant.MakeInt64(1), b.Prog.Int()), b.Prog.Val(int(etSize))).impl
 					return
 				}
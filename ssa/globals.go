@@ -18,6 +18,7 @@ package ssa
 
 import (
 	"fmt"
+	"go/types"
 
 	"github.com/goplus/llvm"
 )
@@ -51,3 +52,77 @@ func (pkg Package) Undefined(names ...string) error {
 	}
 	return nil
 }
+
+// -----------------------------------------------------------------------------
+
+// EmbedFile is a file of an embed.FS variable. The names of directories end
+// with "/", and they have no data.
+type EmbedFile struct {
+	Name string
+	Data string
+	Hash [16]byte // truncated SHA-256 of Data
+}
+
+func (pkg Package) constString(v string) llvm.Value {
+	prog := pkg.Prog
+	size := llvm.ConstInt(prog.tyInt(), uint64(len(v)), false)
+	return llvm.ConstNamedStruct(prog.rtString(), []llvm.Value{pkg.createGlobalStr(v), size})
+}
+
+// constArray returns a private global array of elems.
+func (pkg Package) constArray(elem llvm.Type, elems []llvm.Value, readonly bool) llvm.Value {
+	typ := llvm.ArrayType(elem, len(elems))
+	arr := llvm.AddGlobal(pkg.mod, typ, "")
+	arr.SetInitializer(llvm.ConstArray(elem, elems))
+	arr.SetLinkage(llvm.PrivateLinkage)
+	arr.SetGlobalConstant(readonly)
+	arr.SetAlignment(pkg.Prog.td.ABITypeAlignment(typ))
+	return arr
+}
+
+// InitEmbedString initializes g, a variable of a string type, with the
+// contents of an embedded file, which are read-only.
+func (pkg Package) InitEmbedString(g Global, data string) {
+	g.impl.SetInitializer(pkg.constString(data))
+}
+
+// InitEmbedBytes initializes g, a variable of a []byte type, with the
+// contents of an embedded file. The slice is writable, as in gc.
+func (pkg Package) InitEmbedBytes(g Global, data string) {
+	prog := pkg.Prog
+	typ := llvm.ArrayType(prog.tyInt8(), len(data))
+	arr := llvm.AddGlobal(pkg.mod, typ, "")
+	arr.SetInitializer(prog.ctx.ConstString(data, false))
+	arr.SetLinkage(llvm.PrivateLinkage)
+	arr.SetAlignment(1)
+	size := llvm.ConstInt(prog.tyInt(), uint64(len(data)), false)
+	g.impl.SetInitializer(llvm.ConstNamedStruct(prog.rtSlice(), []llvm.Value{arr, size, size}))
+}
+
+// InitEmbedFS initializes g, an embed.FS variable, with files, which are
+// sorted as embed.FS expects. embed.FS is a struct whose only field points
+// to a read-only []file, where file is {name, data string; hash [16]byte}.
+func (pkg Package) InitEmbedFS(g Global, files []EmbedFile) {
+	prog := pkg.Prog
+	tfs := prog.Elem(g.Type)
+	tfiles := tfs.raw.Type.Underlying().(*types.Struct).Field(0).Type()
+	tfile := prog.Type(tfiles.(*types.Pointer).Elem().(*types.Slice).Elem(), InGo).ll
+	elems := make([]llvm.Value, len(files))
+	for i, f := range files {
+		elems[i] = llvm.ConstNamedStruct(tfile, []llvm.Value{
+			pkg.constString(f.Name),
+			pkg.constString(f.Data),
+			prog.ctx.ConstString(string(f.Hash[:]), false),
+		})
+	}
+	arr := pkg.constArray(tfile, elems, true)
+	size := llvm.ConstInt(prog.tyInt(), uint64(len(files)), false)
+	slice := llvm.AddGlobal(pkg.mod, prog.rtSlice(), "")
+	slice.SetInitializer(llvm.ConstNamedStruct(prog.rtSlice(), []llvm.Value{arr, size, size}))
+	slice.SetLinkage(llvm.PrivateLinkage)
+	slice.SetGlobalConstant(true)
+	slice.SetAlignment(prog.td.ABITypeAlignment(prog.rtSlice()))
+	g.impl.SetInitializer(llvm.ConstNamedStruct(tfs.ll, []llvm.Value{slice}))
+}
+
+// -----------------------------------------------------------------------------
@@ -29,7 +29,7 @@ import (
 func (b Builder) aggregateAllocU(t Type, flds ...llvm.Value) llvm.Value {
 	prog := b.Prog
 	size := prog.SizeOf(t)
-	ptr := b.allocUninited(prog.IntVal(size, prog.Uintptr())).impl
+	ptr := b.allocHeap(t.ll, prog.IntVal(size, prog.Uintptr()), false).impl
 	aggregateInit(b.impl, ptr, t.ll, flds...)
 	return ptr
 }
@@ -106,10 +106,9 @@ func (b Builder) Alloc(elem Type, heap bool) (ret Expr) {
 		log.Printf("Alloc %v, %v\n", elem.RawType(), heap)
 	}
 	prog := b.Prog
-	pkg := b.Pkg
 	size := SizeOf(prog, elem)
 	if heap {
-		ret = b.InlineCall(pkg.rtFunc("AllocZ"), size)
+		ret = b.allocHeap(elem.ll, size, true)
 	} else {
 		ret = Expr{llvm.CreateAlloca(b.impl, elem.ll), prog.VoidPtr()}
 		ret.impl = b.zeroinit(ret, size).impl
@@ -118,15 +117,116 @@ func (b Builder) Alloc(elem Type, heap bool) (ret Expr) {
 	return
 }
 
+// AllocStack allocates zero initialized space for elem in the stack frame
+// of the function, for a value that doesn't outlive the call. The space is
+// reserved in the entry block, so that an allocation in a loop reuses it
+// instead of growing the stack, and zeroed where AllocStack is called.
+func (b Builder) AllocStack(elem Type) (ret Expr) {
+	if debugInstr {
+		log.Printf("AllocStack %v\n", elem.RawType())
+	}
+	prog := b.Prog
+	ret = Expr{b.entryAlloca(elem), prog.VoidPtr()}
+	ret.impl = b.zeroinit(ret, SizeOf(prog, elem)).impl
+	ret.Type = prog.Pointer(elem)
+	return
+}
+
+// entryAlloca reserves space for t in the entry block of the function.
+func (b Builder) entryAlloca(t Type) llvm.Value {
+	if b.blk.Index() == 0 {
+		return llvm.CreateAlloca(b.impl, t.ll)
+	}
+	blk := b.impl.GetInsertBlock()
+	b.SetBlockEx(b.Func.blks[0], AtStart, false)
+	ptr := llvm.CreateAlloca(b.impl, t.ll)
+	b.impl.SetInsertPointAtEnd(blk)
+	return ptr
+}
+
 // AllocU allocates uninitialized space for n*sizeof(elem) bytes.
 func (b Builder) AllocU(elem Type, n ...int64) (ret Expr) {
 	prog := b.Prog
 	size := SizeOf(prog, elem, n...)
-	return Expr{b.allocUninited(size).impl, prog.Pointer(elem)}
+	t := elem.ll
+	if len(n) != 0 {
+		t = llvm.ArrayType(t, int(n[0]))
+	}
+	return Expr{b.allocHeap(t, size, false).impl, prog.Pointer(elem)}
+}
+
+// minTypedWords is the size in words of the smallest values allocated with
+// the mask of their pointer words: bdwgc keeps their layout in an extra
+// word, which may move the smaller ones to a larger size class.
+const minTypedWords = 4
+
+// allocHeap allocates space for size bytes holding a value of the LLVM type
+// t in the heap, zero initialized if zero is set. With typed allocation
+// enabled, the GC doesn't scan the value if it has no pointers, and only
+// scans its pointer words if their mask pays for the extra word.
+func (b Builder) allocHeap(t llvm.Type, size Expr, zero bool) Expr {
+	prog := b.Prog
+	fn := "AllocU"
+	if zero {
+		fn = "AllocZ"
+	}
+	if prog.typedAlloc {
+		var mask uint64
+		if prog.ptrMask(t, 0, &mask) {
+			if mask == 0 {
+				return b.InlineCall(b.Pkg.rtFunc(fn+"Noscan"), size)
+			}
+			words := prog.td.TypeAllocSize(t) / uint64(prog.PointerSize())
+			dense := words < 64 && mask == 1<<words-1
+			if words >= minTypedWords && !dense {
+				return b.InlineCall(b.Pkg.rtFunc("AllocZTyped"), size, prog.IntVal(mask, prog.Uintptr()))
+			}
+		}
+	}
+	return b.InlineCall(b.Pkg.rtFunc(fn), size)
+}
+
+// ptrMask sets the bits of mask for the words of a value of the LLVM type t
+// at offset off that may hold pointers. It returns false if one of them is
+// beyond the bits of a uintptr.
+func (p Program) ptrMask(t llvm.Type, off uint64, mask *uint64) bool {
+	switch t.TypeKind() {
+	case llvm.PointerTypeKind:
+		ptrSize := uint64(p.PointerSize())
+		i := off / ptrSize
+		if i >= ptrSize*8 {
+			return false
+		}
+		*mask |= 1 << i
+	case llvm.StructTypeKind:
+		for i, elem := range t.StructElementTypes() {
+			if !p.ptrMask(elem, off+p.td.ElementOffset(t, i), mask) {
+				return false
+			}
+		}
+	case llvm.ArrayTypeKind:
+		elem := t.ElementType()
+		var elemMask uint64
+		if !p.ptrMask(elem, 0, &elemMask) {
+			return false
+		}
+		if elemMask != 0 {
+			size := p.td.TypeAllocSize(elem)
+			for i, n := 0, t.ArrayLength(); i < n; i++ {
+				if !p.ptrMask(elem, off+uint64(i)*size, mask) {
+					return false
+				}
+			}
+		}
+	}
+	return true
 }
 
-func (b Builder) allocUninited(size Expr) (ret Expr) {
-	return b.InlineCall(b.Pkg.rtFunc("AllocU"), size)
+// noscan reports whether typed allocation is enabled and the values of the
+// type t have no pointers.
+func (p Program) noscan(t Type) bool {
+	var mask uint64
+	return p.typedAlloc && p.ptrMask(t.ll, 0, &mask) && mask == 0
 }
 
 // AllocZ allocates zero initialized space for n bytes.
@@ -183,7 +283,7 @@ func (b Builder) AllocCStr(gostr Expr) (ret Expr) {
 	}
 	n := b.StringLen(gostr)
 	n1 := b.BinOp(token.ADD, n, b.Prog.Val(1))
-	cstr := b.allocUninited(n1)
+	cstr := b.allocHeap(b.Prog.tyInt8(), n1, false)
 	return b.InlineCall(b.Pkg.rtFunc("CStrCopy"), cstr, gostr)
 }
 
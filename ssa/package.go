@@ -22,6 +22,7 @@ import (
 	"go/types"
 	"runtime"
 	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/goplus/llgo/internal/env"
@@ -211,6 +212,9 @@ type aProgram struct {
 	paramObjPtr_ *types.Var
 	linkname     map[string]string // pkgPath.nameInPkg => linkname
 
+	sanitizeAttrs []string // function attributes of the enabled sanitizers
+	typedAlloc    bool     // pass the pointer layouts of the allocations
+
 	ptrSize int
 
 	is32Bits bool
@@ -265,6 +269,26 @@ func NewProgram(target *Target) Program {
 	}
 }
 
+// Fork creates a new program with the settings of p and the type backgrounds
+// and linknames registered to p so far. It has its own LLVM context, so that
+// packages can be compiled by p and its forks concurrently.
+func (p Program) Fork() Program {
+	ret := NewProgram(p.target)
+	ret.sizes = p.sizes
+	ret.rt, ret.rtget = p.rt, p.rtget
+	ret.py, ret.pyget = p.py, p.pyget
+	ret.sanitizeAttrs = append([]string(nil), p.sanitizeAttrs...)
+	ret.typedAlloc = p.typedAlloc
+	p.gocvt.typbg.Range(func(name, bg any) bool {
+		ret.gocvt.typbg.Store(name, bg)
+		return true
+	})
+	for name, link := range p.linkname {
+		ret.linkname[name] = link
+	}
+	return ret
+}
+
 func (p Program) Target() *Target {
 	return p.target
 }
@@ -315,6 +339,21 @@ func (p Program) runtime() *types.Package {
 	return p.rt
 }
 
+// EnableSanitizer instruments the functions given a body from now on for
+// the sanitizer kind, "thread", "address" or "memory". The instrumentation
+// is inserted when the module is compiled with -fsanitize=kind.
+func (p Program) EnableSanitizer(kind string) {
+	p.sanitizeAttrs = append(p.sanitizeAttrs, "sanitize_"+kind)
+}
+
+// EnableTypedAlloc tells the runtime from now on which words of the values
+// allocated in the heap may hold pointers: the values without pointers are
+// allocated where the GC doesn't scan them, and the pointer words of larger
+// ones are passed as a mask. Otherwise the GC scans all of their words.
+func (p Program) EnableTypedAlloc(b bool) {
+	p.typedAlloc = b
+}
+
 // check generic function instantiation
 func (p Program) FuncCompiled(name string) bool {
 	_, ok := p.fnsCompiled[name]
@@ -325,6 +364,19 @@ func (p Program) SetFuncCompiled(name string) {
 	p.fnsCompiled[name] = true
 }
 
+// SetGenericsCompiled replaces the generic instances compiled so far by names,
+// e.g. by the ones compiled by the dependencies of the next package.
+func (p Program) SetGenericsCompiled(names []string) {
+	for name := range p.fnsCompiled {
+		if strings.ContainsRune(name, '[') {
+			delete(p.fnsCompiled, name)
+		}
+	}
+	for _, name := range names {
+		p.fnsCompiled[name] = true
+	}
+}
+
 func (p Program) rtNamed(name string) *types.Named {
 	if rt := p.runtime(); rt != nil {
 		if rtScope := rt.Scope(); rtScope != nil {
@@ -690,6 +742,8 @@ type aPackage struct {
 	fnlink func(string) string
 
 	iRoutine int
+	compiled []string
+	pclnUsed []llvm.Value // func infos of PC-line tables, see EndPCLine
 
 	NeedRuntime bool
 	NeedPyInit  bool
@@ -701,6 +755,17 @@ func (p Package) Module() llvm.Module {
 	return p.mod
 }
 
+// SetFuncCompiled marks a function as compiled in this package.
+func (p Package) SetFuncCompiled(name string) {
+	p.compiled = append(p.compiled, name)
+	p.Prog.SetFuncCompiled(name)
+}
+
+// FuncsCompiled returns names of functions compiled in this package.
+func (p Package) FuncsCompiled() []string {
+	return p.compiled
+}
+
 func (p Package) rtFunc(fnName string) Expr {
 	p.NeedRuntime = true
 	fn := p.Prog.runtime().Scope().Lookup(fnName).(*types.Func)
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2024 The GoPlus Authors (goplus.org). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ssa
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llvm"
+)
+
+// -----------------------------------------------------------------------------
+// PC-line tables
+//
+// Each Go function gets a func info {name, file, startLine, entry} in the
+// __llgo_functab section, and each call in it is preceded by a record
+// {pc, func info, line} in the __llgo_pcln section, where pc is the address
+// of the call. The linker gathers them from all packages, and the runtime
+// maps return addresses back to Go functions and lines with them, without
+// debug information. Sections of the records and func infos of a function
+// are dropped with it when the linker removes unused code.
+
+const (
+	pclnNone = iota
+	pclnELF
+	pclnMachO
+)
+
+// pclnFormat returns the object format of the PC-line tables of the target,
+// or pclnNone if they are not supported.
+func (p Program) pclnFormat() int {
+	goos, goarch := p.target.GOOS, p.target.GOARCH
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	if goarch == "wasm" {
+		return pclnNone
+	}
+	switch goos {
+	case "darwin", "ios":
+		return pclnMachO
+	case "linux", "android", "freebsd", "netbsd", "openbsd":
+		return pclnELF
+	}
+	return pclnNone
+}
+
+// InitPCLine records the Go name, file and start line of the function, so
+// that the runtime can map addresses in it back to them. Calls built after
+// Builder.SetPCLine also record their lines. It does nothing if the target
+// doesn't support PC-line tables.
+func (p Function) InitPCLine(name, file string, line int) {
+	prog := p.Prog
+	format := prog.pclnFormat()
+	if format == pclnNone {
+		return
+	}
+	pkg := p.Pkg
+	ctx := prog.ctx
+	ptr := prog.VoidPtr().ll
+	typ := ctx.StructType([]llvm.Type{ptr, ptr, prog.tyInt32(), ptr}, false)
+	fi := llvm.AddGlobal(pkg.mod, typ, "__llgo_fi")
+	fi.SetInitializer(ctx.ConstStruct([]llvm.Value{
+		llvm.ConstBitCast(pkg.createGlobalStr(name+"\x00"), ptr),
+		llvm.ConstBitCast(pkg.createGlobalStr(file+"\x00"), ptr),
+		llvm.ConstInt(prog.tyInt32(), uint64(line), false),
+		llvm.ConstBitCast(p.impl, ptr),
+	}, false))
+	fi.SetLinkage(llvm.InternalLinkage)
+	fi.SetAlignment(prog.PointerSize())
+	if format == pclnMachO {
+		fi.SetSection("__DATA,__llgo_functab,regular,live_support")
+	} else {
+		fi.SetSection("__llgo_functab")
+		fi.AddMetadata(ctx.MDKindID("associated"), ctx.MDNode([]llvm.Metadata{
+			p.impl.ConstantAsMetadata(),
+		}))
+	}
+	// A tail call would remove the frame of the function from the stack, so
+	// runtime.Caller would skip it, unlike in gc.
+	p.impl.AddFunctionAttr(ctx.CreateStringAttribute("disable-tail-calls", "true"))
+	p.pcfi = fi
+	pkg.pclnUsed = append(pkg.pclnUsed, fi)
+}
+
+// SetPCLine sets the line of the calls built from now on.
+func (b Builder) SetPCLine(line int) {
+	b.pcline = line
+}
+
+// EndPCLine keeps the func infos of the package from being removed as
+// unused. It's called after all functions of the package are built.
+func (p Package) EndPCLine() {
+	if len(p.pclnUsed) == 0 {
+		return
+	}
+	ptr := p.Prog.VoidPtr().ll
+	vals := make([]llvm.Value, len(p.pclnUsed))
+	for i, fi := range p.pclnUsed {
+		vals[i] = llvm.ConstBitCast(fi, ptr)
+	}
+	typ := llvm.ArrayType(ptr, len(vals))
+	used := llvm.AddGlobal(p.mod, typ, "llvm.compiler.used")
+	used.SetInitializer(llvm.ConstArray(ptr, vals))
+	used.SetLinkage(llvm.AppendingLinkage)
+	used.SetSection("llvm.metadata")
+	p.pclnUsed = nil
+}
+
+// pcRecord emits the PC-line record of a call built next.
+func (b Builder) pcRecord(fn llvm.Value) {
+	fi := b.Func.pcfi
+	if fi.IsNil() || b.pcline <= 0 || strings.HasPrefix(fn.Name(), "llvm.") {
+		return
+	}
+	prog := b.Prog
+	word, align := ".quad", "3"
+	if prog.PointerSize() == 4 {
+		word, align = ".long", "2"
+	}
+	line := strconv.Itoa(b.pcline)
+	var asm string
+	if prog.pclnFormat() == pclnMachO {
+		asm = "Lllgo_pc${:uid}:\n" +
+			".pushsection __DATA,__llgo_pcln,regular,live_support\n" +
+			"lllgo_pcln${:uid}:\n" +
+			".p2align " + align + "\n" +
+			word + " Lllgo_pc${:uid}\n" +
+			word + " ${0:c}\n" +
+			word + " " + line + "\n" +
+			".popsection"
+	} else {
+		asm = ".Lllgo_pc${:uid}:\n" +
+			".pushsection __llgo_pcln,\"awo\",%progbits,.Lllgo_pc${:uid}\n" +
+			".p2align " + align + "\n" +
+			word + " .Lllgo_pc${:uid}\n" +
+			word + " ${0:c}\n" +
+			word + " " + line + "\n" +
+			".popsection"
+	}
+	typ := llvm.FunctionType(prog.tyVoid(), []llvm.Type{fi.Type()}, false)
+	rec := llvm.InlineAsm(typ, asm, "i", true, false, llvm.InlineAsmDialectATT, false)
+	b.impl.CreateCall(typ, rec, []llvm.Value{fi}, "")
+}
+
+// -----------------------------------------------------------------------------
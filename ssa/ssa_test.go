@@ -307,6 +307,83 @@ _llgo_0:
 `)
 }
 
+func TestEnableSanitizer(t *testing.T) {
+	prog := NewProgram(nil)
+	prog.EnableSanitizer("thread")
+	pkg := prog.NewPackage("bar", "foo/bar")
+	sig := types.NewSignatureType(nil, nil, nil, nil, nil, false)
+	pkg.NewFunc("fn", sig, InGo).MakeBody(1).Return()
+	pkg.NewFunc("decl", sig, InGo)
+	assertPkg(t, pkg, `; ModuleID = 'foo/bar'
+source_filename = "foo/bar"
+
+; Function Attrs: sanitize_thread
+define void @fn() #0 {
+_llgo_0:
+  ret void
+}
+
+declare void @decl()
+
+attributes #0 = { sanitize_thread }
+`)
+}
+
+func TestPtrMask(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Chdir("../runtime")
+	defer os.Chdir(wd)
+	prog := NewProgram(&Target{GOARCH: "amd64"})
+	prog.SetRuntime(func() *types.Package {
+		fset := token.NewFileSet()
+		imp := packages.NewImporter(fset)
+		pkg, _ := imp.Import(PkgRuntime)
+		return pkg
+	})
+	field := func(name string, typ types.Type) *types.Var {
+		return types.NewField(0, nil, name, typ, false)
+	}
+	intPtr := types.NewPointer(types.Typ[types.Int])
+	tests := []struct {
+		typ  types.Type
+		mask uint64
+		ok   bool
+	}{
+		{types.Typ[types.Int], 0, true},
+		{types.NewArray(types.Typ[types.Byte], 1<<20), 0, true},
+		{intPtr, 1, true},
+		{types.Typ[types.String], 1, true},
+		{types.NewSlice(types.Typ[types.Int]), 1, true},
+		{types.NewInterfaceType(nil, nil), 3, true},
+		{types.NewStruct([]*types.Var{
+			field("a", types.Typ[types.Int]),
+			field("s", types.Typ[types.String]),
+			field("b", types.NewArray(types.Typ[types.Int], 2)),
+			field("p", intPtr),
+		}, nil), 0x22, true},
+		{types.NewArray(types.Typ[types.String], 3), 0x15, true},
+		{types.NewArray(intPtr, 64), 1<<64 - 1, true},
+		{types.NewArray(intPtr, 65), 0, false},
+	}
+	for _, tt := range tests {
+		var mask uint64
+		ok := prog.ptrMask(prog.Type(tt.typ, InGo).ll, 0, &mask)
+		if ok != tt.ok || ok && mask != tt.mask {
+			t.Errorf("ptrMask(%v) = %#x, %v, want %#x, %v", tt.typ, mask, ok, tt.mask, tt.ok)
+		}
+	}
+	if prog.noscan(prog.Byte()) {
+		t.Error("noscan is true without EnableTypedAlloc")
+	}
+	prog.EnableTypedAlloc(true)
+	if !prog.noscan(prog.Byte()) || prog.noscan(prog.String()) {
+		t.Error("noscan of byte and string mismatch")
+	}
+}
+
 func TestFuncParam(t *testing.T) {
 	prog := NewProgram(nil)
 	pkg := prog.NewPackage("bar", "foo/bar")
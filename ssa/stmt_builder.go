@@ -71,6 +71,8 @@ type aBuilder struct {
 
 	dbgVars      map[Expr]dbgExpr         // save copied address and values for debug info
 	diScopeCache map[*types.Scope]DIScope // avoid duplicated DILexicalBlock(s)
+
+	pcline int // line of calls, see SetPCLine
 }
 
 // Builder represents a builder for creating instructions in a function.
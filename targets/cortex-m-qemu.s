@@ -57,3 +57,26 @@ __isr_vector:
     IRQ SysTick_Handler
 
 .size __isr_vector, .-__isr_vector
+
+// Exit QEMU with the exit status, with the semihosting call
+// SYS_EXIT_EXTENDED (0x20) and the reason ADP_Stopped_ApplicationExit
+// (0x20026), so that the emulator exits with the status of the program.
+// SYS_EXIT (0x18) would always exit QEMU with status 0 on 32-bit ARM.
+.section .text._exit
+.weak    _exit
+.type    _exit, %function
+.thumb_func
+_exit:
+    .cfi_startproc
+    sub  sp, #8
+    ldr  r1, =0x20026
+    str  r1, [sp]
+    str  r0, [sp, #4]
+    movs r0, #0x20
+    mov  r1, sp
+    bkpt 0xab
+1:
+    b    1b
+    .cfi_endproc
+.ltorg
+.size _exit, .-_exit